@@ -0,0 +1,346 @@
+package bundle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/jsonbasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/kong/go-apiops/refresolver"
+)
+
+// sectionMap marks a section as a map of entries of the section-type. eg. "schemas"+sectionMap
+// is the 'properties' object of a schema, containing schemas.
+const sectionMap = "-map"
+
+// componentSections are the sections that can be moved into the '/components/' object.
+// Any external reference not in one of these sections will be inlined.
+var componentSections = map[string]bool{
+	"schemas":         true,
+	"responses":       true,
+	"parameters":      true,
+	"examples":        true,
+	"requestBodies":   true,
+	"headers":         true,
+	"securitySchemes": true,
+	"links":           true,
+	"callbacks":       true,
+}
+
+// componentNameRegex matches the characters NOT allowed in component names
+var componentNameRegex = regexp.MustCompile(`[^a-zA-Z0-9\.\-_]`)
+
+type bundler struct {
+	rootFile   string                            // absolute filename of the root document
+	resolver   *refresolver.Resolver             // loads documents and resolves references
+	refs       map[string]string                 // local refs by external ref (document location + fragment)
+	names      map[string]map[string]bool        // component names in use, by section
+	components map[string]map[string]interface{} // new components to add, by section
+}
+
+// childSection returns the section of the child element 'key' of an element in 'section'.
+func childSection(section string, key string) string {
+	if strings.HasSuffix(section, sectionMap) {
+		// we're in a map, so any key is of the map-type
+		return strings.TrimSuffix(section, sectionMap)
+	}
+
+	switch section {
+	case "":
+		// document root
+		switch key {
+		case "paths":
+			return "pathItems" + sectionMap
+		case "components":
+			return "components"
+		}
+	case "components":
+		if componentSections[key] {
+			return key + sectionMap
+		}
+	case "pathItems":
+		switch key {
+		case "parameters":
+			return "parameters"
+		case "servers":
+			return ""
+		}
+		return "operations"
+	case "operations":
+		switch key {
+		case "parameters":
+			return "parameters"
+		case "requestBody":
+			return "requestBodies"
+		case "responses":
+			return "responses" + sectionMap
+		case "callbacks":
+			return "callbacks" + sectionMap
+		}
+	case "parameters", "headers":
+		switch key {
+		case "schema":
+			return "schemas"
+		case "content":
+			return "mediaTypes" + sectionMap
+		case "examples":
+			return "examples" + sectionMap
+		}
+	case "requestBodies":
+		if key == "content" {
+			return "mediaTypes" + sectionMap
+		}
+	case "responses":
+		switch key {
+		case "content":
+			return "mediaTypes" + sectionMap
+		case "headers":
+			return "headers" + sectionMap
+		case "links":
+			return "links" + sectionMap
+		}
+	case "mediaTypes":
+		switch key {
+		case "schema":
+			return "schemas"
+		case "examples":
+			return "examples" + sectionMap
+		}
+	case "callbacks":
+		return "pathItems"
+	case "schemas":
+		switch key {
+		case "properties", "patternProperties", "definitions":
+			return "schemas" + sectionMap
+		case "items", "not", "additionalProperties", "allOf", "anyOf", "oneOf":
+			return "schemas"
+		}
+	}
+	return ""
+}
+
+// componentName returns a new, unused, name for a component based on the external reference.
+func (b *bundler) componentName(section string, filename string, fragment string) string {
+	var name string
+	if fragment != "" {
+		segments := strings.Split(fragment, "/")
+		name = segments[len(segments)-1]
+		name = strings.ReplaceAll(strings.ReplaceAll(name, "~1", "/"), "~0", "~")
+	}
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	name = componentNameRegex.ReplaceAllString(name, "_")
+
+	if b.names[section] == nil {
+		b.names[section] = make(map[string]bool)
+	}
+	newName := name
+	for i := 2; b.names[section][newName]; i++ {
+		newName = name + "_" + strconv.Itoa(i)
+	}
+	b.names[section][newName] = true
+	return newName
+}
+
+// resolveExternal returns the replacement for an external reference; either a reference
+// to a local component, or the inlined content. 'filename' is the location of the document
+// containing the reference.
+func (b *bundler) resolveExternal(ref string, filename string, section string) (interface{}, error) {
+	location, fragment, err := refresolver.Location(ref, filename)
+	if err != nil {
+		return nil, err
+	}
+	key := location + "#" + fragment
+	isComponent := componentSections[section]
+
+	if isComponent {
+		if localRef, found := b.refs[key]; found {
+			return map[string]interface{}{"$ref": localRef}, nil
+		}
+	}
+
+	target, location, err := b.resolver.Resolve(ref, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isComponent {
+		if err := b.resolver.Enter(location, fragment); err != nil {
+			return nil, fmt.Errorf("circular reference '%s' cannot be inlined", ref)
+		}
+		defer b.resolver.Leave(location, fragment)
+		logbasics.Debug("inlining external reference", "ref", ref)
+		return b.walk(target, location, section)
+	}
+
+	name := b.componentName(section, location, fragment)
+	localRef := "#/components/" + section + "/" + name
+	logbasics.Debug("bundling external reference", "ref", ref, "local_ref", localRef)
+	b.refs[key] = localRef // register before walking, so recursive references resolve
+
+	component, err := b.walk(target, location, section)
+	if err != nil {
+		return nil, err
+	}
+	if b.components[section] == nil {
+		b.components[section] = make(map[string]interface{})
+	}
+	b.components[section][name] = component
+	return map[string]interface{}{"$ref": localRef}, nil
+}
+
+// walk returns a copy of 'node' with all external references resolved. 'filename' is the
+// location of the document the node belongs to.
+func (b *bundler) walk(node interface{}, filename string, section string) (interface{}, error) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := n["$ref"].(string); ok {
+			if refDoc, _ := refresolver.SplitRef(ref); refDoc == "" && filename == b.rootFile {
+				// internal reference in the root document, keep it
+				return n, nil
+			}
+			return b.resolveExternal(ref, filename, section)
+		}
+
+		// walk in a fixed order, such that clashing component names are resolved the same
+		// way on every run
+		keys := make([]string, 0, len(n))
+		for key := range n {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		result := make(map[string]interface{}, len(n))
+		for _, key := range keys {
+			value := n[key]
+			newValue, err := b.walk(value, filename, childSection(section, key))
+			if err != nil {
+				return nil, err
+			}
+			result[key] = newValue
+		}
+		return result, nil
+
+	case []interface{}:
+		result := make([]interface{}, len(n))
+		for i, value := range n {
+			newValue, err := b.walk(value, filename, section)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = newValue
+		}
+		return result, nil
+	}
+
+	return node, nil
+}
+
+// MustFile is identical to `File` except that it will panic instead of returning
+// an error.
+func MustFile(filename string) map[string]interface{} {
+	result, err := File(filename)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// Options restricts the external documents that are bundled.
+type Options struct {
+	AllowRemoteRefs bool // fetch remote documents (http and https urls)
+}
+
+// File reads an OpenAPI spec and bundles it into a single document. All external
+// references will be pulled into the `/components` section of the document, and the
+// references will be rewritten to point to the local components. Internal references
+// remain as they are. External references that cannot be stored as a component
+// (eg. path items) will be inlined. Reads from stdin if filename == "-", in which case
+// external references are resolved relative to the current directory. References can only
+// be local files, within the directory of the spec; see FileWithOptions to allow urls.
+func File(filename string) (map[string]interface{}, error) {
+	return FileWithOptions(filename, Options{})
+}
+
+// FileWithOptions is like File, but references to remote documents (urls) are fetched if
+// allowed by the options.
+func FileWithOptions(filename string, opts Options) (map[string]interface{}, error) {
+	rootDoc, err := filebasics.DeserializeFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var rootFile string
+	if filename == "-" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		rootFile = filepath.Join(cwd, "-")
+	} else {
+		if rootFile, err = filepath.Abs(filename); err != nil {
+			return nil, err
+		}
+	}
+
+	b := bundler{
+		rootFile: rootFile,
+		resolver: refresolver.NewWithOptions(refresolver.Options{
+			AllowFiles:  true,
+			AllowRemote: opts.AllowRemoteRefs,
+			BaseDir:     filepath.Dir(rootFile),
+		}),
+		refs:       make(map[string]string),
+		names:      make(map[string]map[string]bool),
+		components: make(map[string]map[string]interface{}),
+	}
+	b.resolver.AddDocument(rootFile, rootDoc)
+
+	// collect the component names already in use
+	if rootComponents, err := jsonbasics.ToObject(rootDoc["components"]); err == nil {
+		for section := range componentSections {
+			if entries, err := jsonbasics.ToObject(rootComponents[section]); err == nil {
+				b.names[section] = make(map[string]bool)
+				for name := range entries {
+					b.names[section][name] = true
+				}
+			}
+		}
+	}
+
+	bundled, err := b.walk(rootDoc, rootFile, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to bundle '%s'; %w", filename, err)
+	}
+	result := bundled.(map[string]interface{})
+
+	if len(b.components) == 0 {
+		return result, nil
+	}
+
+	// inject the bundled components
+	components, err := jsonbasics.ToObject(result["components"])
+	if err != nil {
+		components = make(map[string]interface{})
+		result["components"] = components
+	}
+	for section, entries := range b.components {
+		sectionObj, err := jsonbasics.ToObject(components[section])
+		if err != nil {
+			sectionObj = make(map[string]interface{})
+			components[section] = sectionObj
+		}
+		for name, component := range entries {
+			sectionObj[name] = component
+		}
+	}
+
+	return result, nil
+}
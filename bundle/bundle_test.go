@@ -0,0 +1,206 @@
+package bundle_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/kong/go-apiops/bundle"
+	. "github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/refresolver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bundle", func() {
+	Describe("File", func() {
+		It("moves external references into local components", func() {
+			res, err := bundle.File("./bundle_testfiles/root.yaml")
+			Expect(err).To(BeNil())
+
+			result := MustSerialize(res, OutputFormatJSON)
+			Expect(*result).To(MatchJSON(`{
+				"openapi": "3.0.0",
+				"info": {
+					"title": "bundle test",
+					"version": "1.0.0"
+				},
+				"paths": {
+					"/pets": {
+						"get": {
+							"responses": {
+								"200": {
+									"description": "a list of pets",
+									"content": {
+										"application/json": {
+											"schema": {
+												"type": "array",
+												"items": { "$ref": "#/components/schemas/Pet" }
+											}
+										}
+									}
+								}
+							}
+						},
+						"post": {
+							"requestBody": {
+								"content": {
+									"application/json": {
+										"schema": { "$ref": "#/components/schemas/NewPet" }
+									}
+								}
+							},
+							"responses": {
+								"201": { "description": "created" }
+							}
+						}
+					},
+					"/owners": {
+						"get": {
+							"responses": {
+								"200": {
+									"description": "a list of owners",
+									"content": {
+										"application/json": {
+											"schema": {
+												"type": "array",
+												"items": { "$ref": "#/components/schemas/Owner" }
+											}
+										}
+									}
+								}
+							}
+						}
+					}
+				},
+				"components": {
+					"schemas": {
+						"NewPet": {
+							"type": "object",
+							"properties": {
+								"name": { "type": "string" },
+								"owner": { "$ref": "#/components/schemas/Owner" }
+							}
+						},
+						"Owner": {
+							"type": "object",
+							"properties": {
+								"name": { "type": "string" },
+								"pets": {
+									"type": "array",
+									"items": { "$ref": "#/components/schemas/Pet" }
+								}
+							}
+						},
+						"Pet": {
+							"type": "object",
+							"properties": {
+								"name": { "type": "string" },
+								"owner": { "$ref": "#/components/schemas/Owner" }
+							}
+						}
+					}
+				}
+			}`))
+		})
+
+		It("renames components that clash with existing ones", func() {
+			res, err := bundle.File("./bundle_testfiles/clash.yaml")
+			Expect(err).To(BeNil())
+
+			schemas := res["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+			Expect(schemas).To(HaveKey("Pet"))
+			Expect(schemas).To(HaveKey("Pet_2"))
+			Expect(schemas["Pet"]).To(BeEquivalentTo(map[string]interface{}{"type": "string"}))
+		})
+
+		It("renames clashing components from different external documents consistently", func() {
+			for i := 0; i < 20; i++ {
+				res, err := bundle.File("./bundle_testfiles/clash_external.yaml")
+				Expect(err).To(BeNil())
+
+				// the paths are walked in sorted order; '/admins' (b.yaml) comes first
+				schemas := res["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+				Expect(schemas).To(HaveLen(2))
+				Expect(schemas["User"]).To(HaveKeyWithValue("properties", HaveKey("admin")))
+				Expect(schemas["User_2"]).To(HaveKeyWithValue("properties", Not(HaveKey("admin"))))
+
+				paths := res["paths"].(map[string]interface{})
+				for path, expected := range map[string]string{
+					"/admins": "#/components/schemas/User",
+					"/guests": "#/components/schemas/User_2",
+					"/users":  "#/components/schemas/User_2",
+				} {
+					operation := paths[path].(map[string]interface{})["get"].(map[string]interface{})
+					response := operation["responses"].(map[string]interface{})["200"].(map[string]interface{})
+					content := response["content"].(map[string]interface{})["application/json"]
+					Expect(content).To(HaveKeyWithValue("schema", HaveKeyWithValue("$ref", expected)), path)
+				}
+			}
+		})
+
+		It("returns an error on circular references that must be inlined", func() {
+			_, err := bundle.File("./bundle_testfiles/circular.yaml")
+			Expect(err).To(MatchError(ContainSubstring(
+				"circular reference './circular.yaml#/paths/~1loop' cannot be inlined")))
+		})
+
+		It("only bundles local files within the directory of the spec", func() {
+			dir := GinkgoT().TempDir()
+			spec := filepath.Join(dir, "spec", "openapi.yaml")
+			Expect(os.MkdirAll(filepath.Dir(spec), 0o700)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "outside.yaml"), []byte("User: {type: object}\n"), 0o600)).To(Succeed())
+			Expect(os.WriteFile(spec, []byte(`openapi: 3.0.0
+info: {title: escape, version: 1.0.0}
+paths: {}
+components:
+  schemas:
+    User:
+      $ref: "../outside.yaml#/User"
+`), 0o600)).To(Succeed())
+
+			_, err := bundle.File(spec)
+			Expect(err).To(MatchError(refresolver.ErrNotAllowed))
+		})
+
+		It("only fetches remote documents if allowed", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("User: {type: object}\n"))
+			}))
+			defer server.Close()
+			spec := filepath.Join(GinkgoT().TempDir(), "openapi.yaml")
+			Expect(os.WriteFile(spec, []byte(`openapi: 3.0.0
+info: {title: remote, version: 1.0.0}
+paths: {}
+components:
+  schemas:
+    User:
+      $ref: "`+server.URL+`/schemas.yaml#/User"
+`), 0o600)).To(Succeed())
+
+			_, err := bundle.File(spec)
+			Expect(err).To(MatchError(refresolver.ErrNotAllowed))
+
+			res, err := bundle.FileWithOptions(spec, bundle.Options{AllowRemoteRefs: true})
+			Expect(err).To(BeNil())
+			schemas := res["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+			Expect(schemas).To(HaveKeyWithValue("User_2", map[string]interface{}{"type": "object"}))
+		})
+
+		It("returns an error on unresolvable references", func() {
+			_, err := bundle.File("./bundle_testfiles/dangling.yaml")
+			Expect(err).To(MatchError(ContainSubstring(
+				"failed to resolve reference 'schemas.yaml#/components/schemas/Nope'")))
+		})
+	})
+
+	Describe("MustFile", func() {
+		It("throws error on bad files", func() {
+			t := func() {
+				bundle.MustFile("bad_file.yml")
+			}
+			Expect(t).Should(Panic())
+		})
+	})
+})
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/jsonbasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/spf13/cobra"
+)
+
+// Executes the CLI command "apply-overlay"
+func executeApplyOverlay(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+	if err := applyBackupFlag(cmd); err != nil {
+		return err
+	}
+
+	inputFilenames, err := getInputFilenames(cmd)
+	if err != nil {
+		return err
+	}
+	inputFilename := inputName(inputFilenames)
+
+	overlayFilename, err := cmd.Flags().GetString("overlay")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'overlay'; %w", err)
+	}
+	if overlayFilename == "" {
+		return usageError{errors.New("flag '--overlay' is required")}
+	}
+	if overlayFilename == "-" {
+		for _, filename := range inputFilenames {
+			if filename == "-" {
+				return usageError{errors.New("flags '--input' and '--overlay' cannot both read from stdin ('-')")}
+			}
+		}
+	}
+
+	arrayStrategy, err := cmd.Flags().GetString("array-strategy")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'array-strategy'; %w", err)
+	}
+	if err := jsonbasics.ValidateArrayStrategy(arrayStrategy); err != nil {
+		return usageError{err}
+	}
+
+	outputFormat, err := getOutputFormat(cmd, inputFilenames[0])
+	if err != nil {
+		return err
+	}
+
+	outputFilename, err := getOutputFilename(cmd, inputFilenames[0], outputFormat)
+	if err != nil {
+		return err
+	}
+
+	trackInfo := deckformat.HistoryNewEntry("apply-overlay")
+	trackInfo["input"] = inputFilename
+	trackInfo["overlay"] = overlayFilename
+	trackInfo["array-strategy"] = arrayStrategy
+	trackInfo["output"] = outputFilename
+
+	// do the work: read/merge/write
+	data, err := readInputFiles(inputFilenames)
+	if err != nil {
+		return err
+	}
+	if err := filebasics.ApplyOverlayFile(data, overlayFilename, arrayStrategy); err != nil {
+		return fmt.Errorf("failed to apply overlay '%s' to '%s'; %w", overlayFilename, inputFilename, err)
+	}
+	if err := deckformat.HistoryTryAppend(data, trackInfo); err != nil {
+		return err
+	}
+	if err := filebasics.WriteSerializedFile(outputFilename, data, outputFormat); err != nil {
+		return err
+	}
+	return writeManifest(cmd, outputFilename)
+}
+
+//
+//
+// Define the CLI data for the apply-overlay command
+//
+//
+
+var applyOverlayCmd = &cobra.Command{
+	Use:   "apply-overlay",
+	Short: "Deep-merges an overlay file onto a decK file",
+	Long: `Deep-merges an overlay file (JSON or YAML) onto a decK file.
+
+Objects are merged field by field, recursively. A field in the overlay adds to, or
+overrides, the field in the input. A null field in the overlay removes the field.
+Arrays are replaced by the array in the overlay, or with '--array-strategy append'
+the entries of the overlay are appended. Any other value replaces the input value.
+
+This is simpler than a full patch file for additive changes. Note that entity arrays
+(eg. 'services') are arrays too, so their entries are not merged by name.`,
+	PreRunE: validateOutputDirFlags("input"),
+	RunE:    executeApplyOverlay,
+	Args:    noArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(applyOverlayCmd)
+	addInputFlag(applyOverlayCmd, "decK file to process")
+	applyOverlayCmd.Flags().String("overlay", "", "the overlay file to merge onto the input. "+
+		"Use - to read from stdin")
+	applyOverlayCmd.Flags().String("array-strategy", jsonbasics.ArrayStrategyReplace,
+		fmt.Sprintf("how arrays are merged; '%s' or '%s'", jsonbasics.ArrayStrategyReplace,
+			jsonbasics.ArrayStrategyAppend))
+	applyOverlayCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
+	addOutputDirFlags(applyOverlayCmd)
+	applyOverlayCmd.Flags().StringP("format", "", filebasics.OutputFormatYaml, outputFormatUsage())
+	addManifestFlag(applyOverlayCmd)
+	addBackupFlag(applyOverlayCmd)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_applyOverlay(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "kong.yaml")
+	overlay := filepath.Join(dir, "overlay.yaml")
+	output := filepath.Join(dir, "output.yaml")
+	require.NoError(t, os.WriteFile(input, []byte(`_format_version: "3.0"
+_comment: original
+_workspace: default
+`), 0o600))
+	require.NoError(t, os.WriteFile(overlay, []byte(`_comment: overlaid
+_info:
+  select_tags: [team-a]
+`), 0o600))
+	defer resetInputFlag(applyOverlayCmd)
+	defer applyOverlayCmd.Flags().Set("overlay", "")
+	current := deckformat.ConfigGet()
+	defer deckformat.ConfigSet(current)
+	keepConfig := current
+	keepConfig.KeepHistory = true
+	deckformat.ConfigSet(keepConfig)
+
+	rootCmd.SetArgs([]string{"apply-overlay", "-i", input, "--overlay", overlay, "-o", output})
+	require.NoError(t, rootCmd.Execute())
+
+	data := filebasics.MustDeserializeFile(output)
+	assert.Equal(t, "overlaid", data["_comment"])
+	assert.Equal(t, "default", data["_workspace"])
+	assert.Equal(t, map[string]interface{}{"select_tags": []interface{}{"team-a"}}, data["_info"])
+
+	history := deckformat.HistoryGet(data)
+	require.Len(t, history, 1)
+	entry := history[0].(map[string]interface{})
+	assert.Equal(t, "apply-overlay", entry["command"])
+	assert.Equal(t, overlay, entry["overlay"])
+	assert.Equal(t, "replace", entry["array-strategy"])
+}
@@ -0,0 +1,32 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/spf13/cobra"
+)
+
+// defaultBackupSuffix is the suffix used if '--backup' is given without a value.
+const defaultBackupSuffix = ".bak"
+
+// addBackupFlag adds the '--backup[=suffix]' flag to a command that writes files.
+func addBackupFlag(cmd *cobra.Command) {
+	cmd.Flags().String("backup", "",
+		`before overwriting an existing output file, copy it to the same name with this
+suffix appended (default suffix '`+defaultBackupSuffix+`' if no value is given). Not used for stdout`)
+	cmd.Flags().Lookup("backup").NoOptDefVal = defaultBackupSuffix
+}
+
+// applyBackupFlag configures the file writes according to the '--backup' flag.
+func applyBackupFlag(cmd *cobra.Command) error {
+	suffix, err := cmd.Flags().GetString("backup")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'backup'; %w", err)
+	}
+	filebasics.SetWriteOptions(filebasics.WriteOptions{BackupSuffix: suffix})
+	return nil
+}
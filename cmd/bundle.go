@@ -0,0 +1,80 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/kong/go-apiops/bundle"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/spf13/cobra"
+)
+
+// Executes the CLI command "bundle"
+func executeBundle(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+
+	inputFilename, err := cmd.Flags().GetString("spec")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'spec'; %w", err)
+	}
+
+	outputFilename, err := cmd.Flags().GetString("output-file")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'output-file'; %w", err)
+	}
+
+	allowRemoteRefs, err := cmd.Flags().GetBool("allow-remote-refs")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'allow-remote-refs'; %w", err)
+	}
+
+	outputFormat, err := getOutputFormat(cmd, inputFilename)
+	if err != nil {
+		return err
+	}
+
+	// do the work: read/bundle/write
+	bundled, err := bundle.FileWithOptions(inputFilename, bundle.Options{AllowRemoteRefs: allowRemoteRefs})
+	if err != nil {
+		return err
+	}
+
+	return filebasics.WriteSerializedFile(outputFilename, bundled, outputFormat)
+}
+
+//
+//
+// Define the CLI data for the bundle command
+//
+//
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Bundles an OpenAPI spec and its external references into a single file",
+	Long: `Bundles an OpenAPI spec and its external references into a single file.
+
+All externally referenced documents will be pulled into the '/components' section
+of the spec, and the references will be rewritten to point to those local components.
+Internal references remain as they are, so reuse is preserved. External references
+to elements that cannot be stored as a component (eg. path items) will be inlined.
+
+Referenced local files must be within the directory of the spec (or the current
+directory when reading from stdin). Remote documents (urls) are only fetched with
+'--allow-remote-refs'.`,
+	RunE: executeBundle,
+	Args: noArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(bundleCmd)
+	bundleCmd.Flags().StringP("spec", "s", "-", "OpenAPI spec file to process. Use - to read from stdin")
+	bundleCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
+	bundleCmd.Flags().StringP("format", "", filebasics.OutputFormatYaml, outputFormatUsage())
+	bundleCmd.Flags().Bool("allow-remote-refs", false,
+		`allow '$ref's to remote documents (http and https urls), which are fetched`)
+}
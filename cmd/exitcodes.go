@@ -0,0 +1,77 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/validate"
+	"github.com/spf13/cobra"
+)
+
+// The exit codes of the CLI, based on the error returned by a command.
+const (
+	ExitOK         = 0
+	ExitGeneric    = 1 // any error not covered below
+	ExitUsage      = 2 // invalid flags, flag values, flag combinations, or arguments
+	ExitValidation = 3 // invalid or incompatible input files
+	ExitIO         = 4 // failure reading or writing files
+)
+
+// usageError marks an error as caused by invalid usage of the CLI.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e usageError) Unwrap() error {
+	return e.err
+}
+
+// flagErrorFunc marks the errors from parsing the flags as usage errors.
+func flagErrorFunc(_ *cobra.Command, err error) error {
+	return usageError{err}
+}
+
+// usageArgs marks the errors of a check of the positional arguments as usage errors.
+func usageArgs(check cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := check(cmd, args); err != nil {
+			return usageError{err}
+		}
+		return nil
+	}
+}
+
+// noArgs is cobra.NoArgs, returning a usage error.
+var noArgs = usageArgs(cobra.NoArgs)
+
+// exitCode returns the exit code for the error returned by a command.
+func exitCode(err error) int {
+	var (
+		usageErr usageError
+		pathErr  *fs.PathError
+		linkErr  *os.LinkError
+	)
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &usageErr), errors.Is(err, filebasics.ErrUnknownFormat):
+		return ExitUsage
+	case errors.Is(err, deckformat.ErrIncompatible), errors.Is(err, filebasics.ErrEmptyInput),
+		errors.Is(err, validate.ErrRemovedFields), errors.Is(err, validate.ErrFormatVersion):
+		return ExitValidation
+	case errors.As(err, &pathErr), errors.As(err, &linkErr):
+		return ExitIO
+	default:
+		return ExitGeneric
+	}
+}
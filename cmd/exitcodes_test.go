@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/merge"
+	"github.com/kong/go-apiops/validate"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_exitCode(t *testing.T) {
+	_, incompatibleErr := merge.Into(
+		map[string]interface{}{"_format_version": "1.0"},
+		map[string]interface{}{"_format_version": "3.0"},
+		false)
+	_, formatErr := filebasics.ValidateOutputFormat("toml")
+	_, readErr := filebasics.ReadFile("./non-existing-file.yaml")
+	usageErr := validateFlags(newOpenapi2KongTestCmd(t, "--overwrite"),
+		flagRequires("overwrite", "merge-into"))
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"no error", nil, ExitOK},
+		{"generic error", errors.New("boom"), ExitGeneric},
+		{"invalid flag combination", usageErr, ExitUsage},
+		{"unknown output format", formatErr, ExitUsage},
+		{"flag parsing error", flagErrorFunc(nil, errors.New("unknown flag")), ExitUsage},
+		{"incompatible files", incompatibleErr, ExitValidation},
+		{"empty input", filebasics.ErrEmptyInput, ExitValidation},
+		{"removed fields", fmt.Errorf("failed to validate; %w", validate.ErrRemovedFields), ExitValidation},
+		{"bad format version", fmt.Errorf("failed to validate; %w", validate.ErrFormatVersion), ExitValidation},
+		{"missing file", readErr, ExitIO},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, exitCode(tt.err))
+		})
+	}
+}
+
+func Test_exitCodeUsage(t *testing.T) {
+	rootCmd.SetErr(&bytes.Buffer{})
+	defer rootCmd.SetErr(nil)
+	defer splitCmd.Flags().Set("by", splitByType)
+
+	for _, args := range [][]string{
+		{"split", "-i", "kong.yaml", "--by", "foo"},
+		{"openapi2kong", "extra"},
+		{"merge"},
+	} {
+		rootCmd.SetArgs(args)
+		assert.Equal(t, ExitUsage, exitCode(rootCmd.Execute()), args)
+	}
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/spf13/cobra"
+)
+
+// Executes the CLI command "fingerprint"
+func executeFingerprint(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+
+	inputFilename, err := cmd.Flags().GetString("input")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'input'; %w", err)
+	}
+
+	outputFilename, err := cmd.Flags().GetString("output-file")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'output-file'; %w", err)
+	}
+
+	// do the work: read/hash/write
+	data, err := filebasics.DeserializeFile(inputFilename)
+	if err != nil {
+		return err
+	}
+	fingerprint, err := deckformat.Fingerprint(data)
+	if err != nil {
+		return fmt.Errorf("failed to fingerprint '%s'; %w", inputFilename, err)
+	}
+	output := []byte(fingerprint + "\n")
+	return filebasics.WriteFile(outputFilename, &output)
+}
+
+//
+//
+// Define the CLI data for the fingerprint command
+//
+//
+
+var fingerprintCmd = &cobra.Command{
+	Use:   "fingerprint",
+	Short: "Prints a stable hash of the content of a decK file",
+	Long: `Prints a stable hash (sha256) of the content of a decK file, for change detection.
+
+The history and the order of the entities are ignored, so files with the same
+configuration have the same fingerprint.`,
+	RunE: executeFingerprint,
+	Args: noArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(fingerprintCmd)
+	fingerprintCmd.Flags().StringP("input", "i", "-", "decK file to fingerprint. Use - to read from stdin")
+	fingerprintCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
+}
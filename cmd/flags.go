@@ -0,0 +1,100 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// flagRule checks a combination of flags of a command. It returns an error describing
+// the conflict if the combination is invalid.
+type flagRule func(cmd *cobra.Command) error
+
+// validateFlags runs the rules against the flags of the command, and returns the
+// first error encountered (as a usage error). To be called before doing any actual work.
+func validateFlags(cmd *cobra.Command, rules ...flagRule) error {
+	for _, rule := range rules {
+		if err := rule(cmd); err != nil {
+			return usageError{err}
+		}
+	}
+	return nil
+}
+
+// flagRequires returns a rule that fails if flag 'name' is set, but flag 'required' is not.
+func flagRequires(name string, required string) flagRule {
+	return func(cmd *cobra.Command) error {
+		if cmd.Flags().Changed(name) && !cmd.Flags().Changed(required) {
+			return fmt.Errorf("flag '--%s' can only be used together with '--%s'", name, required)
+		}
+		return nil
+	}
+}
+
+// flagsNotTogether returns a rule that fails if both flags are set.
+func flagsNotTogether(name1 string, name2 string) flagRule {
+	return func(cmd *cobra.Command) error {
+		if cmd.Flags().Changed(name1) && cmd.Flags().Changed(name2) {
+			return fmt.Errorf("flags '--%s' and '--%s' cannot be used together", name1, name2)
+		}
+		return nil
+	}
+}
+
+// flagsNotBothStdin returns a rule that fails if both (string) flags are set to "-", since
+// stdin can only be read once. Default values are taken into account.
+func flagsNotBothStdin(name1 string, name2 string) flagRule {
+	return func(cmd *cobra.Command) error {
+		value1, err := cmd.Flags().GetString(name1)
+		if err != nil {
+			return fmt.Errorf("failed getting cli argument '%s'; %w", name1, err)
+		}
+		value2, err := cmd.Flags().GetString(name2)
+		if err != nil {
+			return fmt.Errorf("failed getting cli argument '%s'; %w", name2, err)
+		}
+		if value1 == "-" && value2 == "-" {
+			return fmt.Errorf("flags '--%s' and '--%s' cannot both read from stdin ('-')", name1, name2)
+		}
+		return nil
+	}
+}
+
+// flagNotWithStdout returns a rule that fails if flag 'name' is set, while the (string)
+// flag 'output' writes to stdout ("-"). Default values are taken into account.
+func flagNotWithStdout(name string, output string) flagRule {
+	return func(cmd *cobra.Command) error {
+		value, err := cmd.Flags().GetString(output)
+		if err != nil {
+			return fmt.Errorf("failed getting cli argument '%s'; %w", output, err)
+		}
+		if cmd.Flags().Changed(name) && value == "-" {
+			return fmt.Errorf("flag '--%s' cannot be used when '--%s' writes to stdout ('-')", name, output)
+		}
+		return nil
+	}
+}
+
+// flagNotWithStdin returns a rule that fails if flag 'name' is set, while the (string)
+// flag 'input' reads from stdin ("-"). Default values are taken into account. If 'input' is
+// a string-array flag, it fails if any of the values is "-".
+func flagNotWithStdin(name string, input string) flagRule {
+	return func(cmd *cobra.Command) error {
+		values, err := getFlagValues(cmd, input)
+		if err != nil {
+			return fmt.Errorf("failed getting cli argument '%s'; %w", input, err)
+		}
+		if !cmd.Flags().Changed(name) {
+			return nil
+		}
+		for _, value := range values {
+			if value == "-" {
+				return fmt.Errorf("flag '--%s' cannot be used when '--%s' reads from stdin ('-')", name, input)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newOpenapi2KongTestCmd returns a command with the flags validated for openapi2kong,
+// parsed from args.
+func newOpenapi2KongTestCmd(t *testing.T, args ...string) *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Flags().StringP("spec", "s", "-", "")
+	cmd.Flags().String("merge-into", "", "")
+	cmd.Flags().Bool("overwrite", false, "")
+	cmd.Flags().StringP("output-file", "o", "-", "")
+	cmd.Flags().String("manifest", "", "")
+	cmd.Flags().Bool("watch", false, "")
+	addOutputDirFlags(cmd)
+	require.NoError(t, cmd.ParseFlags(args))
+	return cmd
+}
+
+func Test_validateOpenapi2KongFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			args: []string{},
+		},
+		{
+			name: "overwrite with merge-into",
+			args: []string{"--spec", "spec.yaml", "--merge-into", "kong.yaml", "--overwrite"},
+		},
+		{
+			name:    "overwrite without merge-into",
+			args:    []string{"--spec", "spec.yaml", "--overwrite"},
+			wantErr: "flag '--overwrite' can only be used together with '--merge-into'",
+		},
+		{
+			name:    "spec and merge-into both stdin",
+			args:    []string{"--merge-into", "-"},
+			wantErr: "flags '--spec' and '--merge-into' cannot both read from stdin ('-')",
+		},
+		{
+			name:    "manifest with stdout",
+			args:    []string{"-s", "spec.yaml", "--manifest", "manifest.json"},
+			wantErr: "flag '--manifest' cannot be used when '--output-file' writes to stdout ('-')",
+		},
+		{
+			name: "manifest with output file",
+			args: []string{"-s", "spec.yaml", "-o", "kong.yaml", "--manifest", "manifest.json"},
+		},
+		{
+			name: "merge-into stdin",
+			args: []string{"-s", "spec.yaml", "--merge-into", "-"},
+		},
+		{
+			name:    "watch with stdin",
+			args:    []string{"--watch"},
+			wantErr: "flag '--watch' cannot be used when '--spec' reads from stdin ('-')",
+		},
+		{
+			name: "watch with spec file",
+			args: []string{"-s", "spec.yaml", "--watch"},
+		},
+		{
+			name:    "output-dir with output-file",
+			args:    []string{"-s", "spec.yaml", "-o", "kong.yaml", "--output-dir", "out"},
+			wantErr: "flags '--output-dir' and '--output-file' cannot be used together",
+		},
+		{
+			name:    "output-template without output-dir",
+			args:    []string{"-s", "spec.yaml", "--output-template", "{basename}.yaml"},
+			wantErr: "flag '--output-template' can only be used together with '--output-dir'",
+		},
+		{
+			name:    "output-dir with stdin",
+			args:    []string{"--output-dir", "out"},
+			wantErr: "flag '--output-dir' cannot be used when '--spec' reads from stdin ('-')",
+		},
+		{
+			name: "manifest with output-dir",
+			args: []string{"-s", "spec.yaml", "--output-dir", "out", "--manifest", "manifest.json"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOpenapi2KongFlags(newOpenapi2KongTestCmd(t, tt.args...), nil)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/spf13/cobra"
+)
+
+// Executes the CLI command "format"
+func executeFormat(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+	if err := applyBackupFlag(cmd); err != nil {
+		return err
+	}
+
+	inputFilename, err := cmd.Flags().GetString("input")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'input'; %w", err)
+	}
+
+	outputFilename, err := cmd.Flags().GetString("output-file")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'output-file'; %w", err)
+	}
+
+	outputFormat, err := getOutputFormat(cmd, inputFilename)
+	if err != nil {
+		return err
+	}
+
+	preserveOrder, err := cmd.Flags().GetBool("preserve-order")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'preserve-order'; %w", err)
+	}
+
+	// do the work: read/convert/write
+	content, err := filebasics.ReadFile(inputFilename)
+	if err != nil {
+		return err
+	}
+	convert := filebasics.ConvertFormat
+	if preserveOrder {
+		convert = filebasics.ConvertFormatOrdered
+	}
+	result, err := convert(content, outputFormat)
+	if err != nil {
+		return fmt.Errorf("failed to convert '%s'; %w", inputFilename, err)
+	}
+	return filebasics.WriteFile(outputFilename, result)
+}
+
+//
+//
+// Define the CLI data for the format command
+//
+//
+
+var formatCmd = &cobra.Command{
+	Use:   "format",
+	Short: "Converts files between JSON and YAML",
+	Long: `Converts files between JSON and YAML.
+
+The input can be either a JSON or YAML file (decK file, OpenAPI spec, etc.). It
+is written in the requested format, without any further processing. All keys,
+including the history, are preserved, and no history is added. By default the keys
+are sorted, use '--preserve-order' to retain their original order.`,
+	RunE: executeFormat,
+	Args: noArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(formatCmd)
+	formatCmd.Flags().StringP("input", "i", "-", "input file to process. Use - to read from stdin")
+	formatCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
+	formatCmd.Flags().StringP("format", "", filebasics.OutputFormatYaml, outputFormatUsage())
+	formatCmd.Flags().Bool("preserve-order", false, "retain the original order of the keys, instead of sorting them")
+	addBackupFlag(formatCmd)
+}
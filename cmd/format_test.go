@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_formatInferOutputFormat(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "kong.yaml")
+	require.NoError(t, os.WriteFile(input, []byte("services:\n- name: svc\n"), 0o600))
+	defer func() {
+		formatCmd.Flags().Set("format", filebasics.OutputFormatYaml)
+		formatCmd.Flags().Lookup("format").Changed = false
+		formatCmd.Flags().Set("output-file", "-")
+	}()
+
+	// inferred from the output filename extension
+	output := filepath.Join(dir, "kong.json")
+	rootCmd.SetArgs([]string{"format", "-i", input, "-o", output})
+	require.NoError(t, rootCmd.Execute())
+	content, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.True(t, json.Valid(content), "expected JSON output, got: %s", content)
+
+	// an unknown extension falls back to the input filename extension
+	output = filepath.Join(dir, "kong.txt")
+	rootCmd.SetArgs([]string{"format", "-i", filepath.Join(dir, "kong.json"), "-o", output})
+	require.NoError(t, rootCmd.Execute())
+	content, err = os.ReadFile(output)
+	require.NoError(t, err)
+	assert.True(t, json.Valid(content), "expected JSON output, got: %s", content)
+
+	// an explicit format always wins
+	output = filepath.Join(dir, "explicit.json")
+	rootCmd.SetArgs([]string{"format", "-i", input, "-o", output, "--format", "yaml"})
+	require.NoError(t, rootCmd.Execute())
+	content, err = os.ReadFile(output)
+	require.NoError(t, err)
+	assert.False(t, json.Valid(content), "expected YAML output, got: %s", content)
+	assert.Equal(t, "services:\n- name: svc\n", string(content))
+}
@@ -0,0 +1,95 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputFormatDOT  = "DOT"
+	outputFormatTree = "TREE"
+)
+
+// Executes the CLI command "graph"
+func executeGraph(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+
+	inputFilename, err := cmd.Flags().GetString("input")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'input'; %w", err)
+	}
+
+	outputFilename, err := cmd.Flags().GetString("output-file")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'output-file'; %w", err)
+	}
+
+	var outputFormat string
+	{
+		outputFormat, err = cmd.Flags().GetString("format")
+		if err != nil {
+			return fmt.Errorf("failed getting cli argument 'format'; %w", err)
+		}
+		outputFormat = strings.ToUpper(outputFormat)
+		if outputFormat != outputFormatTree && outputFormat != outputFormatDOT {
+			return usageError{fmt.Errorf("expected '--format' to be 'tree' or 'dot', got: '%s'", outputFormat)}
+		}
+	}
+
+	// do the work: read/collect/write
+	data, err := filebasics.DeserializeFile(inputFilename)
+	if err != nil {
+		return err
+	}
+	relations, err := deckformat.GetRelations(data)
+	if err != nil {
+		return fmt.Errorf("failed to collect relations from '%s'; %w", inputFilename, err)
+	}
+
+	var output []byte
+	if outputFormat == outputFormatDOT {
+		output = []byte(deckformat.RelationsToDOT(relations))
+	} else {
+		output = []byte(deckformat.RelationsToTree(relations))
+	}
+	return filebasics.WriteFile(outputFilename, &output)
+}
+
+//
+//
+// Define the CLI data for the graph command
+//
+//
+
+var graphCmd = &cobra.Command{
+	Use:     "graph",
+	Aliases: []string{"deps"},
+	Short:   "Shows how routes, services, and upstreams relate",
+	Long: `Shows how routes bind to services, and services to upstreams.
+
+Routes are bound by nesting, or by their 'service' field. Services are bound to an
+upstream if their 'host' matches the upstream name. Routes referring to a service
+that is not in the file are marked as missing.
+
+The 'tree' format lists the services with their upstream and routes, the 'dot'
+format renders a Graphviz graph, eg: kced graph --format dot | dot -Tsvg > deck.svg`,
+	RunE: executeGraph,
+	Args: noArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+	graphCmd.Flags().StringP("input", "i", "-", "decK file to process. Use - to read from stdin")
+	graphCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
+	graphCmd.Flags().StringP("format", "", "tree", "output format, 'tree' or 'dot'")
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/spf13/cobra"
+)
+
+// Executes the CLI command "history show"
+func executeHistoryShow(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+
+	inputFilename, err := cmd.Flags().GetString("input")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'input'; %w", err)
+	}
+
+	outputFilename, err := cmd.Flags().GetString("output-file")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'output-file'; %w", err)
+	}
+
+	var outputFormat string
+	{
+		outputFormat, err = cmd.Flags().GetString("format")
+		if err != nil {
+			return fmt.Errorf("failed getting cli argument 'format'; %w", err)
+		}
+		outputFormat = strings.ToUpper(outputFormat)
+		if outputFormat != outputFormatTable && outputFormat != filebasics.OutputFormatJSON {
+			return usageError{fmt.Errorf("expected '--format' to be 'table' or 'json', got: '%s'", outputFormat)}
+		}
+	}
+
+	// do the work: read/summarize/write
+	data, err := filebasics.DeserializeFile(inputFilename)
+	if err != nil {
+		return err
+	}
+	summaries := deckformat.HistoryGetSummary(data)
+
+	if outputFormat == filebasics.OutputFormatJSON {
+		result := make([]interface{}, len(summaries))
+		for i, summary := range summaries {
+			result[i] = map[string]interface{}{
+				"tool":    summary.Tool,
+				"command": summary.Command,
+				"input":   summary.Input,
+				"output":  summary.Output,
+			}
+		}
+		output, err := filebasics.Serialize(map[string]interface{}{"history": result}, filebasics.OutputFormatJSON)
+		if err != nil {
+			return err
+		}
+		return filebasics.WriteFile(outputFilename, output)
+	}
+
+	var buf bytes.Buffer
+	if len(summaries) == 0 {
+		fmt.Fprintln(&buf, "no history")
+	} else {
+		w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "#\tTOOL\tCOMMAND\tINPUT\tOUTPUT")
+		for i, summary := range summaries {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", i+1, summary.Tool, summary.Command, summary.Input, summary.Output)
+		}
+		w.Flush()
+	}
+	output := buf.Bytes()
+	return filebasics.WriteFile(outputFilename, &output)
+}
+
+//
+//
+// Define the CLI data for the history command
+//
+//
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Inspect the history in decK files",
+	Long:  `Inspect the history in decK files.`,
+	Args:  noArgs,
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Shows the history of a decK file",
+	Long: `Shows the history of a decK file.
+
+The history is stored in the '_ignore' key of the file. For each entry the tool,
+command, input, and output are listed, in the order they were recorded.`,
+	RunE: executeHistoryShow,
+	Args: noArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyShowCmd)
+	historyShowCmd.Flags().StringP("input", "i", "-", "decK file to inspect. Use - to read from stdin")
+	historyShowCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
+	historyShowCmd.Flags().StringP("format", "", "table", "output format: table or json")
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/merge"
+	"github.com/spf13/cobra"
+)
+
+// addInputFlag adds the '--input' flag to a command reading a decK file. The flag can be
+// repeated, to merge multiple files before processing (see readInputFiles).
+func addInputFlag(cmd *cobra.Command, usage string) {
+	cmd.Flags().StringArrayP("input", "i", []string{"-"}, usage+
+		". Use - to read from stdin. Can be repeated, to merge multiple files")
+}
+
+// getFlagValues returns the values of a string, or string-array, flag.
+func getFlagValues(cmd *cobra.Command, name string) ([]string, error) {
+	flag := cmd.Flags().Lookup(name)
+	if flag != nil && flag.Value.Type() == "stringArray" {
+		return cmd.Flags().GetStringArray(name)
+	}
+	value, err := cmd.Flags().GetString(name)
+	if err != nil {
+		return nil, err
+	}
+	return []string{value}, nil
+}
+
+// getInputFilenames returns the filenames of the '--input' flag. Stdin ("-") can only be
+// read once.
+func getInputFilenames(cmd *cobra.Command) ([]string, error) {
+	filenames, err := cmd.Flags().GetStringArray("input")
+	if err != nil {
+		return nil, fmt.Errorf("failed getting cli argument 'input'; %w", err)
+	}
+	if len(filenames) == 0 {
+		return nil, usageError{errors.New("flag '--input' requires at least one filename")}
+	}
+	stdin := 0
+	for _, filename := range filenames {
+		if filename == "-" {
+			stdin++
+		}
+	}
+	if stdin > 1 {
+		return nil, usageError{errors.New("flag '--input' can read from stdin ('-') only once")}
+	}
+	return filenames, nil
+}
+
+// inputName returns the filenames as a single string, for messages and history entries.
+func inputName(filenames []string) string {
+	return strings.Join(filenames, ", ")
+}
+
+// readInputFiles reads the files of the '--input' flag. A single file is returned as is,
+// multiple files are merged in order (see merge.Files), after checking they are compatible.
+func readInputFiles(filenames []string) (map[string]interface{}, error) {
+	if len(filenames) == 1 {
+		return filebasics.DeserializeFile(filenames[0])
+	}
+	data, _, err := merge.Files(filenames)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
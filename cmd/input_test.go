@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resetInputFlag resets the '--input' flag of the command. A string-array flag appends
+// once set, so the value is replaced by a fresh one.
+func resetInputFlag(cmd *cobra.Command) {
+	fresh := pflag.NewFlagSet("fresh", pflag.ContinueOnError)
+	fresh.StringArray("input", []string{"-"}, "")
+	flag := cmd.Flags().Lookup("input")
+	flag.Value = fresh.Lookup("input").Value
+	flag.Changed = false
+}
+
+func Test_mergedInputs(t *testing.T) {
+	dir := t.TempDir()
+	file1 := filepath.Join(dir, "file1.yaml")
+	file2 := filepath.Join(dir, "file2.yaml")
+	output := filepath.Join(dir, "output.yaml")
+	require.NoError(t, os.WriteFile(file1, []byte(`_format_version: "3.0"
+services:
+  - name: one
+    tags: [internal-team, public]
+`), 0o600))
+	require.NoError(t, os.WriteFile(file2, []byte(`_format_version: "3.0"
+services:
+  - name: two
+    tags: [internal-team]
+`), 0o600))
+	defer resetInputFlag(minimizeCmd)
+	defer minimizeCmd.Flags().Set("tag-prefix", "")
+
+	rootCmd.SetArgs([]string{"minimize", "-i", file1, "-i", file2, "-o", output, "--tag-prefix", "internal-"})
+	require.NoError(t, rootCmd.Execute())
+
+	services := filebasics.MustDeserializeFile(output)["services"].([]interface{})
+	require.Len(t, services, 2)
+	assert.Equal(t, "one", services[0].(map[string]interface{})["name"])
+	assert.Equal(t, []interface{}{"public"}, services[0].(map[string]interface{})["tags"])
+	assert.Equal(t, "two", services[1].(map[string]interface{})["name"])
+}
+
+func Test_mergedInputsIncompatible(t *testing.T) {
+	dir := t.TempDir()
+	file1 := filepath.Join(dir, "file1.yaml")
+	file2 := filepath.Join(dir, "file2.yaml")
+	require.NoError(t, os.WriteFile(file1, []byte(`_format_version: "3.0"`), 0o600))
+	require.NoError(t, os.WriteFile(file2, []byte(`_format_version: "1.0"`), 0o600))
+
+	cmd := &cobra.Command{Use: "test"}
+	addInputFlag(cmd, "decK file")
+	require.NoError(t, cmd.Flags().Parse([]string{"-i", file1, "-i", file2}))
+	filenames, err := getInputFilenames(cmd)
+	require.NoError(t, err)
+	_, err = readInputFiles(filenames)
+	assert.ErrorContains(t, err, "failed to merge "+file2)
+
+	cmd = &cobra.Command{Use: "test"}
+	addInputFlag(cmd, "decK file")
+	require.NoError(t, cmd.Flags().Parse([]string{"-i", "-", "-i", "-"}))
+	_, err = getInputFilenames(cmd)
+	assert.EqualError(t, err, "flag '--input' can read from stdin ('-') only once")
+}
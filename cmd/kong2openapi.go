@@ -0,0 +1,82 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/kong2openapi"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/spf13/cobra"
+)
+
+// Executes the CLI command "kong2openapi"
+func executeKong2Openapi(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+
+	inputFilenames, err := getInputFilenames(cmd)
+	if err != nil {
+		return err
+	}
+	inputFilename := inputName(inputFilenames)
+
+	title, err := cmd.Flags().GetString("title")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'title'; %w", err)
+	}
+
+	outputFormat, err := getOutputFormat(cmd, inputFilenames[0])
+	if err != nil {
+		return err
+	}
+
+	outputFilename, err := getOutputFilename(cmd, inputFilenames[0], outputFormat)
+	if err != nil {
+		return err
+	}
+
+	// do the work: read/convert/write
+	data, err := readInputFiles(inputFilenames)
+	if err != nil {
+		return err
+	}
+	spec, err := kong2openapi.Convert(data, kong2openapi.K2oOptions{Title: title})
+	if err != nil {
+		return fmt.Errorf("failed converting decK file '%s'; %w", inputFilename, err)
+	}
+	return filebasics.WriteSerializedFile(outputFilename, spec, outputFormat)
+}
+
+//
+//
+// Define the CLI data for the kong2openapi command
+//
+//
+
+var kong2openapiCmd = &cobra.Command{
+	Use:   "kong2openapi",
+	Short: "Generate an OpenAPI skeleton from a decK file (best-effort)",
+	Long: `Generate an OpenAPI skeleton from a decK file, for documentation purposes.
+
+The services become servers, and the paths and methods of their routes become
+operations. Plugins are added as "x-kong-plugin-<name>" extensions. The conversion is
+best-effort and lossy; eg. regex paths are only converted if they use named captures,
+and request/response details are not available in a decK file.`,
+	PreRunE: validateOutputDirFlags("input"),
+	RunE:    executeKong2Openapi,
+	Args:    noArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(kong2openapiCmd)
+	addInputFlag(kong2openapiCmd, "decK file to process")
+	kong2openapiCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
+	addOutputDirFlags(kong2openapiCmd)
+	kong2openapiCmd.Flags().StringP("format", "", filebasics.OutputFormatYaml, outputFormatUsage())
+	kong2openapiCmd.Flags().StringP("title", "", "",
+		"title of the generated spec (if omitted will use the service name, if there is only 1)")
+}
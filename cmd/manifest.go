@@ -0,0 +1,58 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/spf13/cobra"
+)
+
+// addManifestFlag adds the '--manifest' flag to a command that writes files.
+func addManifestFlag(cmd *cobra.Command) {
+	cmd.Flags().String("manifest", "",
+		`manifest file to write after writing the output, listing the written files with
+their sha256 checksums and sizes (eg. for verification in CI)`)
+}
+
+// writeManifest writes the manifest for the written files, if the '--manifest' flag was given.
+func writeManifest(cmd *cobra.Command, filenames ...string) error {
+	manifestFilename, err := cmd.Flags().GetString("manifest")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'manifest'; %w", err)
+	}
+	if manifestFilename == "" {
+		return nil
+	}
+	logbasics.Info("writing manifest", "filename", manifestFilename)
+	return filebasics.WriteManifest(manifestFilename, filenames)
+}
+
+// validateManifestFlags checks the '--manifest' flag against the '--output-file' flag, for
+// commands writing a single file
+func validateManifestFlags(cmd *cobra.Command, _ []string) error {
+	return validateFlags(cmd, manifestNotWithStdout())
+}
+
+// manifestNotWithStdout returns a rule that fails if the '--manifest' flag is set, while the
+// output is written to stdout. Writing to '--output-dir' is never to stdout.
+func manifestNotWithStdout() flagRule {
+	return func(cmd *cobra.Command) error {
+		if cmd.Flags().Changed("output-dir") {
+			return nil
+		}
+		return flagNotWithStdout("manifest", "output-file")(cmd)
+	}
+}
+
+// validateOutputDirFlags returns a PreRunE function checking the '--manifest' and
+// '--output-dir' flags, for commands writing a single file. 'input' is the flag with the
+// input filename.
+func validateOutputDirFlags(input string) func(cmd *cobra.Command, _ []string) error {
+	return func(cmd *cobra.Command, _ []string) error {
+		return validateFlags(cmd, append(outputDirRules(input), manifestNotWithStdout())...)
+	}
+}
@@ -6,47 +6,121 @@ package cmd
 import (
 	"fmt"
 	"log"
-	"strings"
 
 	"github.com/kong/go-apiops/deckformat"
 	"github.com/kong/go-apiops/filebasics"
 	"github.com/kong/go-apiops/logbasics"
 	"github.com/kong/go-apiops/merge"
+	"github.com/kong/go-apiops/validate"
 	"github.com/spf13/cobra"
 )
 
+// takeSourceHistories returns the concatenated, deduplicated, history of the merged
+// files, and removes it from the file info (as returned by merge.Files) to not repeat it.
+func takeSourceHistories(info []interface{}) []interface{} {
+	histories := make([][]interface{}, 0, len(info))
+	for _, fileInfo := range info {
+		if fileEntry, ok := fileInfo.(map[string]interface{}); ok {
+			if history, ok := fileEntry["info"].([]interface{}); ok {
+				histories = append(histories, history)
+			}
+			delete(fileEntry, "info")
+		}
+	}
+	return deckformat.HistoryMerge(histories...)
+}
+
+// checkReferences fails if the merged file has dangling references (see validate.References),
+// unless they are allowed, then they are logged as warnings.
+func checkReferences(merged map[string]interface{}, allowDangling bool) error {
+	errs := deckformat.NewMultiError(validate.References(merged)...)
+	if errs.Len() == 0 {
+		return nil
+	}
+	if allowDangling {
+		for _, message := range errs.Messages() {
+			logbasics.Warn(message)
+		}
+		return nil
+	}
+	return fmt.Errorf("the merged file has %d dangling reference(s), use '--allow-dangling' to "+
+		"ignore them; %w", len(errs.Messages()), errs)
+}
+
 // Executes the CLI command "merge"
 func executeMerge(cmd *cobra.Command, args []string) error {
 	verbosity, _ := cmd.Flags().GetInt("verbose")
 	logbasics.Initialize(log.LstdFlags, verbosity)
+	if err := applyBackupFlag(cmd); err != nil {
+		return err
+	}
 
 	outputFilename, err := cmd.Flags().GetString("output-file")
 	if err != nil {
 		return fmt.Errorf("failed getting cli argument 'output-file'; %w", err)
 	}
 
-	var outputFormat string
-	{
-		outputFormat, err = cmd.Flags().GetString("format")
-		if err != nil {
-			return fmt.Errorf("failed getting cli argument 'format'; %w", err)
-		}
-		outputFormat = strings.ToUpper(outputFormat)
+	outputFormat, err := getOutputFormat(cmd, args[0])
+	if err != nil {
+		return err
+	}
+
+	strategy, err := cmd.Flags().GetString("strategy")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'strategy'; %w", err)
+	}
+	if err := merge.ValidateStrategy(strategy); err != nil {
+		return usageError{err}
 	}
 
-	// do the work: read/merge
-	merged, info, err := merge.Files(args)
+	keepHistory, err := cmd.Flags().GetBool("keep-history")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'keep-history'; %w", err)
+	}
+
+	allowDangling, err := cmd.Flags().GetBool("allow-dangling")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'allow-dangling'; %w", err)
+	}
+
+	reconcileVersion, err := cmd.Flags().GetBool("reconcile-version")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'reconcile-version'; %w", err)
+	}
+
+	// do the work: read/merge/check
+	merged, info, err := merge.FilesWithOptions(args, merge.Options{
+		Strategy:         strategy,
+		ReconcileVersion: reconcileVersion,
+	})
 	if err != nil {
 		return err
 	}
+	if err := checkReferences(merged, allowDangling); err != nil {
+		return err
+	}
 
 	historyEntry := deckformat.HistoryNewEntry("merge")
 	historyEntry["output"] = outputFilename
 	historyEntry["files"] = info
+	historyEntry["strategy"] = strategy
+	if reconcileVersion {
+		historyEntry["reconcile-version"] = true
+	}
 	deckformat.HistoryClear(merged)
-	deckformat.HistoryAppend(merged, historyEntry)
+	if keepHistory {
+		err = deckformat.HistorySetKeep(merged, append(takeSourceHistories(info), historyEntry))
+	} else {
+		err = deckformat.HistoryTryAppend(merged, historyEntry)
+	}
+	if err != nil {
+		return err
+	}
 
-	return filebasics.WriteSerializedFile(outputFilename, merged, outputFormat)
+	if err := filebasics.WriteSerializedFile(outputFilename, merged, outputFormat); err != nil {
+		return err
+	}
+	return writeManifest(cmd, outputFilename)
 }
 
 //
@@ -60,19 +134,39 @@ var mergeCmd = &cobra.Command{
 	Short: "Merges multiple decK files into one",
 	Long: `Merges multiple decK files into one.
 
-The files can be either json or yaml format. Will merge all top-level arrays by simply
-concatenating them. Any other keys will be copied. The files will be processed in the order
-provided. No checks on content will be done, eg. duplicates, nor any validations.
+The files can be either json or yaml format. Will merge all top-level arrays, where
+entities with the same name are only included once if they are identical. Entities with
+the same name, but different definitions, are resolved by the '--strategy'. Any other
+keys will be copied. The files will be processed in the order provided. The merged file
+is checked for dangling references (eg. a route referring to a service that is not in
+any of the files), which fail the merge unless '--allow-dangling' is given. No further
+checks on content will be done, nor any validations.
 
 If the input files are not compatible an error will be returned. Compatibility is
-determined by the '_transform' and '_format_version' fields.`,
-	RunE: executeMerge,
-	Args: cobra.MinimumNArgs(1),
+determined by the '_transform' and '_format_version' fields. With '--reconcile-version'
+all files are upgraded to the highest '_format_version' before merging, which is recorded
+in the history. Differing major versions still fail the merge.`,
+	PreRunE: validateManifestFlags,
+	RunE:    executeMerge,
+	Args:    usageArgs(cobra.MinimumNArgs(1)),
 }
 
 func init() {
 	rootCmd.AddCommand(mergeCmd)
 	mergeCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
-	mergeCmd.Flags().StringP("format", "", filebasics.OutputFormatYaml, "output format: "+
-		filebasics.OutputFormatJSON+" or "+filebasics.OutputFormatYaml)
+	mergeCmd.Flags().StringP("format", "", filebasics.OutputFormatYaml, outputFormatUsage())
+	addManifestFlag(mergeCmd)
+	addBackupFlag(mergeCmd)
+	mergeCmd.Flags().String("strategy", merge.StrategyError,
+		`how to resolve entities with the same name, but different definitions: 'error'
+fails the merge, 'last-wins' and 'first-wins' keep the entity from the later or earlier
+file, 'skip-duplicates' drops them altogether (with a warning)`)
+	mergeCmd.Flags().Bool("keep-history", false,
+		`keep the history of the input files (concatenated and deduplicated), followed by
+an entry for the merge`)
+	mergeCmd.Flags().Bool("reconcile-version", false,
+		`upgrade all files to the highest '_format_version' (same major version) before merging`)
+	mergeCmd.Flags().Bool("allow-dangling", false,
+		`allow references to entities that are not in the merged file (eg. managed
+elsewhere), they are logged as warnings`)
 }
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_mergeKeepHistory(t *testing.T) {
+	dir := t.TempDir()
+	file1 := filepath.Join(dir, "file1.yaml")
+	file2 := filepath.Join(dir, "file2.yaml")
+	output := filepath.Join(dir, "output.yaml")
+	require.NoError(t, os.WriteFile(file1, []byte(`_format_version: "3.0"
+_ignore:
+  - command: openapi2kong
+    spec: one.yaml
+services:
+  - name: one
+`), 0o600))
+	require.NoError(t, os.WriteFile(file2, []byte(`_format_version: "3.0"
+_ignore:
+  - command: openapi2kong
+    spec: two.yaml
+services:
+  - name: two
+`), 0o600))
+	defer mergeCmd.Flags().Set("keep-history", "false")
+	config := deckformat.ConfigGet()
+
+	rootCmd.SetArgs([]string{"merge", "--keep-history", "-o", output, file1, file2})
+	require.NoError(t, rootCmd.Execute())
+	assert.Equal(t, config, deckformat.ConfigGet(), "expected the global config to be left alone")
+
+	history := deckformat.HistoryGet(filebasics.MustDeserializeFile(output))
+	require.Len(t, history, 3)
+	assert.Equal(t, "one.yaml", history[0].(map[string]interface{})["spec"])
+	assert.Equal(t, "two.yaml", history[1].(map[string]interface{})["spec"])
+	assert.Equal(t, "merge", history[2].(map[string]interface{})["command"])
+
+	// the source history is not repeated in the merge entry
+	files := history[2].(map[string]interface{})["files"].([]interface{})
+	assert.NotContains(t, files[0], "info")
+
+	// by default the history is dropped
+	rootCmd.SetArgs([]string{"merge", "--keep-history=false", "-o", output, file1, file2})
+	require.NoError(t, rootCmd.Execute())
+	assert.Empty(t, deckformat.HistoryGet(filebasics.MustDeserializeFile(output)))
+}
+
+func Test_mergeDanglingReferences(t *testing.T) {
+	dir := t.TempDir()
+	file1 := filepath.Join(dir, "file1.yaml")
+	file2 := filepath.Join(dir, "file2.yaml")
+	output := filepath.Join(dir, "output.yaml")
+	require.NoError(t, os.WriteFile(file1, []byte(`_format_version: "3.0"
+services:
+  - name: one
+`), 0o600))
+	require.NoError(t, os.WriteFile(file2, []byte(`_format_version: "3.0"
+routes:
+  - name: two
+    service: missing
+`), 0o600))
+	defer mergeCmd.Flags().Set("allow-dangling", "false")
+
+	rootCmd.SetArgs([]string{"merge", "-o", output, file1, file2})
+	err := rootCmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the merged file has 1 dangling reference(s), use '--allow-dangling' "+
+		"to ignore them; 'routes' entity 'two' refers to service 'missing', which is not in the file")
+	assert.NoFileExists(t, output)
+
+	rootCmd.SetArgs([]string{"merge", "--allow-dangling", "-o", output, file1, file2})
+	require.NoError(t, rootCmd.Execute())
+	assert.FileExists(t, output)
+}
+
+func Test_mergeReconcileVersion(t *testing.T) {
+	dir := t.TempDir()
+	file1 := filepath.Join(dir, "file1.yaml")
+	file2 := filepath.Join(dir, "file2.yaml")
+	output := filepath.Join(dir, "output.yaml")
+	require.NoError(t, os.WriteFile(file1, []byte(`_format_version: "3.0"
+services:
+  - name: one
+`), 0o600))
+	require.NoError(t, os.WriteFile(file2, []byte(`_format_version: "3.1"
+services:
+  - name: two
+`), 0o600))
+	defer mergeCmd.Flags().Set("reconcile-version", "false")
+	defer mergeCmd.Flags().Set("keep-history", "false")
+
+	rootCmd.SetArgs([]string{"merge", "--reconcile-version", "--keep-history", "-o", output, file1, file2})
+	require.NoError(t, rootCmd.Execute())
+
+	merged := filebasics.MustDeserializeFile(output)
+	assert.Equal(t, "3.1", merged["_format_version"])
+	history := deckformat.HistoryGet(merged)
+	require.Len(t, history, 1)
+	entry := history[0].(map[string]interface{})
+	assert.Equal(t, true, entry["reconcile-version"])
+	files := entry["files"].([]interface{})
+	assert.Equal(t, "3.0", files[0].(map[string]interface{})["upgraded_from"])
+	assert.NotContains(t, files[1], "upgraded_from")
+}
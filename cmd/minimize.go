@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/spf13/cobra"
+)
+
+// Executes the CLI command "minimize"
+func executeMinimize(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+	if err := applyBackupFlag(cmd); err != nil {
+		return err
+	}
+
+	inputFilenames, err := getInputFilenames(cmd)
+	if err != nil {
+		return err
+	}
+	inputFilename := inputName(inputFilenames)
+
+	outputFormat, err := getOutputFormat(cmd, inputFilenames[0])
+	if err != nil {
+		return err
+	}
+
+	outputFilename, err := getOutputFilename(cmd, inputFilenames[0], outputFormat)
+	if err != nil {
+		return err
+	}
+
+	var opts deckformat.MinimizeOptions
+	{
+		if opts.SkipHistory, err = cmd.Flags().GetBool("keep-history"); err != nil {
+			return fmt.Errorf("failed getting cli argument 'keep-history'; %w", err)
+		}
+		if opts.TagPrefix, err = cmd.Flags().GetString("tag-prefix"); err != nil {
+			return fmt.Errorf("failed getting cli argument 'tag-prefix'; %w", err)
+		}
+		if opts.RemovePlugins, err = cmd.Flags().GetStringSlice("remove-plugin"); err != nil {
+			return fmt.Errorf("failed getting cli argument 'remove-plugin'; %w", err)
+		}
+	}
+
+	// do the work: read/minimize/write
+	data, err := readInputFiles(inputFilenames)
+	if err != nil {
+		return err
+	}
+	result, err := deckformat.Minimize(data, opts)
+	if err != nil {
+		return fmt.Errorf("failed to minimize '%s'; %w", inputFilename, err)
+	}
+	if err := filebasics.WriteSerializedFile(outputFilename, result, outputFormat); err != nil {
+		return err
+	}
+	return writeManifest(cmd, outputFilename)
+}
+
+//
+//
+// Define the CLI data for the minimize command
+//
+//
+
+var minimizeCmd = &cobra.Command{
+	Use:     "minimize",
+	Aliases: []string{"publish"},
+	Short:   "Strips internal-only information from a decK file, for publishing",
+	Long: `Strips internal-only information from a decK file, for publishing.
+
+The following steps are taken;
+  - the history is cleared (unless '--keep-history' is given),
+  - tags starting with '--tag-prefix' are removed from all entities,
+  - plugins named by '--remove-plugin' are removed, both top-level and nested.
+
+No history entry is added.`,
+	PreRunE: validateOutputDirFlags("input"),
+	RunE:    executeMinimize,
+	Args:    noArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(minimizeCmd)
+	addInputFlag(minimizeCmd, "decK file to minimize")
+	minimizeCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
+	addOutputDirFlags(minimizeCmd)
+	minimizeCmd.Flags().StringP("format", "", filebasics.OutputFormatYaml, outputFormatUsage())
+	addManifestFlag(minimizeCmd)
+	addBackupFlag(minimizeCmd)
+	minimizeCmd.Flags().Bool("keep-history", false, "do not clear the history")
+	minimizeCmd.Flags().String("tag-prefix", "", "remove the tags starting with this prefix")
+	minimizeCmd.Flags().StringSlice("remove-plugin", []string{},
+		"name of a plugin to remove (can be repeated, or a comma separated list)")
+}
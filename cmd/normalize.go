@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/spf13/cobra"
+)
+
+// Executes the CLI command "normalize"
+func executeNormalize(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+	if err := applyBackupFlag(cmd); err != nil {
+		return err
+	}
+
+	inputFilenames, err := getInputFilenames(cmd)
+	if err != nil {
+		return err
+	}
+	inputFilename := inputName(inputFilenames)
+
+	outputFormat, err := getOutputFormat(cmd, inputFilenames[0])
+	if err != nil {
+		return err
+	}
+
+	outputFilename, err := getOutputFilename(cmd, inputFilenames[0], outputFormat)
+	if err != nil {
+		return err
+	}
+
+	var opts deckformat.NormalizeOptions
+	{
+		if opts.SkipCanonicalize, err = cmd.Flags().GetBool("no-canonicalize"); err != nil {
+			return fmt.Errorf("failed getting cli argument 'no-canonicalize'; %w", err)
+		}
+		if opts.SkipRemoveNulls, err = cmd.Flags().GetBool("no-strip-nulls"); err != nil {
+			return fmt.Errorf("failed getting cli argument 'no-strip-nulls'; %w", err)
+		}
+		if opts.SkipHistory, err = cmd.Flags().GetBool("no-history"); err != nil {
+			return fmt.Errorf("failed getting cli argument 'no-history'; %w", err)
+		}
+	}
+
+	// do the work: read/normalize/write
+	data, err := readInputFiles(inputFilenames)
+	if err != nil {
+		return err
+	}
+	if err := deckformat.Normalize(data, opts); err != nil {
+		return fmt.Errorf("failed to normalize '%s'; %w", inputFilename, err)
+	}
+	if err := filebasics.WriteSerializedFile(outputFilename, data, outputFormat); err != nil {
+		return err
+	}
+	return writeManifest(cmd, outputFilename)
+}
+
+//
+//
+// Define the CLI data for the normalize command
+//
+//
+
+var normalizeCmd = &cobra.Command{
+	Use:   "normalize",
+	Short: "Brings a decK file in a canonical form",
+	Long: `Brings a decK file in a canonical form, suitable for committing.
+
+The following steps are taken (each can be disabled);
+  - null fields are removed,
+  - entity arrays (including nested ones) are sorted by name (or another
+    identifying field), and then by content,
+  - the history is normalized.
+
+Normalizing is idempotent; normalizing a normalized file has no effect. No history
+entry is added.`,
+	PreRunE: validateOutputDirFlags("input"),
+	RunE:    executeNormalize,
+	Args:    noArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(normalizeCmd)
+	addInputFlag(normalizeCmd, "decK file to normalize")
+	normalizeCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
+	addOutputDirFlags(normalizeCmd)
+	normalizeCmd.Flags().StringP("format", "", filebasics.OutputFormatYaml, outputFormatUsage())
+	addManifestFlag(normalizeCmd)
+	addBackupFlag(normalizeCmd)
+	normalizeCmd.Flags().Bool("no-canonicalize", false, "do not sort the entity arrays")
+	normalizeCmd.Flags().Bool("no-strip-nulls", false, "do not remove null fields")
+	normalizeCmd.Flags().Bool("no-history", false, "do not normalize the history")
+}
@@ -4,13 +4,18 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
-	"strings"
+	"os"
+	"os/signal"
+	"path/filepath"
 
 	"github.com/kong/go-apiops/deckformat"
 	"github.com/kong/go-apiops/filebasics"
 	"github.com/kong/go-apiops/logbasics"
+	"github.com/kong/go-apiops/merge"
 	"github.com/kong/go-apiops/openapi2kong"
 	"github.com/spf13/cobra"
 )
@@ -19,20 +24,48 @@ import (
 func executeOpenapi2Kong(cmd *cobra.Command, _ []string) error {
 	verbosity, _ := cmd.Flags().GetInt("verbose")
 	logbasics.Initialize(log.LstdFlags, verbosity)
+	if err := applyBackupFlag(cmd); err != nil {
+		return err
+	}
 
 	inputFilename, err := cmd.Flags().GetString("spec")
 	if err != nil {
 		return fmt.Errorf("failed getting cli argument 'spec'; %w", err)
 	}
 
-	outputFilename, err := cmd.Flags().GetString("output-file")
+	docName, err := cmd.Flags().GetString("uuid-base")
 	if err != nil {
-		return fmt.Errorf("failed getting cli argument 'output-file'; %w", err)
+		return fmt.Errorf("failed getting cli argument 'uuid-base'; %w", err)
 	}
 
-	docName, err := cmd.Flags().GetString("uuid-base")
+	namePrefix, err := cmd.Flags().GetString("name-prefix")
 	if err != nil {
-		return fmt.Errorf("failed getting cli argument 'uuid-base'; %w", err)
+		return fmt.Errorf("failed getting cli argument 'name-prefix'; %w", err)
+	}
+
+	mergeInto, err := cmd.Flags().GetString("merge-into")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'merge-into'; %w", err)
+	}
+
+	overwrite, err := cmd.Flags().GetBool("overwrite")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'overwrite'; %w", err)
+	}
+
+	allowExternalRefs, err := cmd.Flags().GetBool("allow-external-refs")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'allow-external-refs'; %w", err)
+	}
+
+	allowRemoteRefs, err := cmd.Flags().GetBool("allow-remote-refs")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'allow-remote-refs'; %w", err)
+	}
+
+	formatVersion, err := cmd.Flags().GetString("format-version")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'format-version'; %w", err)
 	}
 
 	var entityTags *[]string
@@ -47,36 +80,172 @@ func executeOpenapi2Kong(cmd *cobra.Command, _ []string) error {
 		}
 	}
 
-	var outputFormat string
-	{
-		outputFormat, err = cmd.Flags().GetString("format")
-		if err != nil {
-			return fmt.Errorf("failed getting cli argument 'format'; %w", err)
-		}
-		outputFormat = strings.ToUpper(outputFormat)
+	outputFormat, err := getOutputFormat(cmd, inputFilename)
+	if err != nil {
+		return err
+	}
+
+	outputFilename, err := getOutputFilename(cmd, inputFilename, outputFormat)
+	if err != nil {
+		return err
 	}
 
 	options := openapi2kong.O2kOptions{
-		Tags:    entityTags,
-		DocName: docName,
+		Tags:          entityTags,
+		DocName:       docName,
+		SpecFilename:  inputFilename,
+		NamePrefix:    namePrefix,
+		FormatVersion: formatVersion,
+		// a random uuid-base would generate new IDs on every run
+		RequireDocName:    true,
+		AllowExternalRefs: allowExternalRefs,
+		AllowRemoteRefs:   allowRemoteRefs,
+	}
+	if inputFilename != "-" {
+		// resolve file references relative to the spec file
+		options.BaseDir = filepath.Dir(inputFilename)
 	}
 
 	trackInfo := deckformat.HistoryNewEntry("openapi2kong")
 	trackInfo["input"] = inputFilename
 	trackInfo["output"] = outputFilename
 	trackInfo["uuid-base"] = docName
+	if mergeInto != "" {
+		trackInfo["merge-into"] = mergeInto
+	}
+
+	streaming, err := cmd.Flags().GetBool("stdin-many")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'stdin-many'; %w", err)
+	}
+	if streaming {
+		failFast, err := cmd.Flags().GetBool("fail-fast")
+		if err != nil {
+			return fmt.Errorf("failed getting cli argument 'fail-fast'; %w", err)
+		}
+		parallel, err := cmd.Flags().GetInt("parallel")
+		if err != nil {
+			return fmt.Errorf("failed getting cli argument 'parallel'; %w", err)
+		}
+		if parallel < 1 {
+			return usageError{fmt.Errorf("expected '--parallel' to be at least 1, got %d", parallel)}
+		}
+		return executeOpenapi2KongStream(inputFilename, outputFilename, options, trackInfo, failFast, parallel)
+	}
+
+	watch, err := cmd.Flags().GetBool("watch")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'watch'; %w", err)
+	}
+
+	convert := func() error {
+		return convertOpenapi2Kong(cmd, inputFilename, outputFilename, outputFormat, mergeInto,
+			overwrite, options, trackInfo)
+	}
+	if !watch {
+		return convert()
+	}
 
-	// do the work: read/convert/write
+	// keep regenerating on changes, until interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	regenerate := func() {
+		if err := convert(); err != nil {
+			logbasics.Error(err, "failed to regenerate, watching for the next change", "spec", inputFilename)
+			return
+		}
+		logbasics.Info("regenerated output", "spec", inputFilename, "output", outputFilename)
+	}
+	regenerate()
+	return watchFile(ctx, inputFilename, watchDebounce, regenerate)
+}
+
+// convertOpenapi2Kong does the work of the openapi2kong command: read/convert/write
+func convertOpenapi2Kong(cmd *cobra.Command, inputFilename string, outputFilename string,
+	outputFormat string, mergeInto string, overwrite bool, options openapi2kong.O2kOptions,
+	trackInfo map[string]interface{},
+) error {
 	content, err := filebasics.ReadFile(inputFilename)
 	if err != nil {
 		return err
 	}
-	result, err := openapi2kong.Convert(content, options)
+	warnings := logbasics.WarningCount()
+	result, info, err := openapi2kong.ConvertWithInfo(content, options)
 	if err != nil {
 		return fmt.Errorf("failed converting OpenAPI spec '%s'; %w", inputFilename, err)
 	}
-	deckformat.HistoryAppend(result, trackInfo)
-	return filebasics.WriteSerializedFile(outputFilename, result, outputFormat)
+	if err := writeStats(cmd, info.Stats, logbasics.WarningCount()-warnings); err != nil {
+		return err
+	}
+	trackInfo["uuid-base-resolved"] = info.DocName
+	if err := writeSummary(cmd, info.Summary); err != nil {
+		return err
+	}
+
+	if mergeInto == "" {
+		if err := deckformat.HistoryTryAppend(result, trackInfo); err != nil {
+			return err
+		}
+		if err := filebasics.WriteSerializedFile(outputFilename, result, outputFormat); err != nil {
+			return err
+		}
+		return writeManifest(cmd, outputFilename)
+	}
+
+	existing, err := filebasics.DeserializeFile(mergeInto)
+	if err != nil {
+		return err
+	}
+	merged, err := merge.Into(existing, result, overwrite)
+	if err != nil {
+		return fmt.Errorf("failed to merge into '%s'; %w", mergeInto, err)
+	}
+
+	mergeInfo := deckformat.HistoryNewEntry("merge")
+	mergeInfo["files"] = []interface{}{mergeInto, inputFilename}
+	mergeInfo["overwrite"] = overwrite
+	if err := deckformat.HistoryTryAppend(merged, trackInfo); err != nil {
+		return err
+	}
+	if err := deckformat.HistoryTryAppend(merged, mergeInfo); err != nil {
+		return err
+	}
+	if err := filebasics.WriteSerializedFile(outputFilename, merged, outputFormat); err != nil {
+		return err
+	}
+	return writeManifest(cmd, outputFilename)
+}
+
+// writeStats logs a one-line summary of the conversion at info level, if the '--stats' flag
+// was given.
+func writeStats(cmd *cobra.Command, stats openapi2kong.O2kStats, warnings int) error {
+	showStats, err := cmd.Flags().GetBool("stats")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'stats'; %w", err)
+	}
+	if showStats {
+		logbasics.Info("stats", "services", stats.Services, "routes", stats.Routes, "plugins", stats.Plugins,
+			"skipped_operations", stats.SkippedOperations, "warnings", warnings)
+	}
+	return nil
+}
+
+// writeSummary writes the summary of the generated services and routes as JSON, if the
+// '--summary-file' flag was given.
+func writeSummary(cmd *cobra.Command, summary []openapi2kong.ServiceSummary) error {
+	summaryFilename, err := cmd.Flags().GetString("summary-file")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'summary-file'; %w", err)
+	}
+	if summaryFilename == "" {
+		return nil
+	}
+	content, err := json.MarshalIndent(map[string]interface{}{"services": summary}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to json-serialize the summary; %w", err)
+	}
+	logbasics.Info("writing summary", "filename", summaryFilename)
+	return filebasics.WriteFile(summaryFilename, &content)
 }
 
 //
@@ -93,20 +262,77 @@ var openapi2kongCmd = &cobra.Command{
 The example file has extensive annotations explaining the conversion
 process, as well as all supported custom annotations (x-kong-... directives).
 See: https://github.com/Kong/kced/blob/main/docs/learnservice_oas.yaml`,
-	RunE: executeOpenapi2Kong,
-	Args: cobra.NoArgs,
+	PreRunE: validateOpenapi2KongFlags,
+	RunE:    executeOpenapi2Kong,
+	Args:    noArgs,
+}
+
+// validateOpenapi2KongFlags checks for conflicting flags of the openapi2kong command
+func validateOpenapi2KongFlags(cmd *cobra.Command, _ []string) error {
+	return validateFlags(cmd, append([]flagRule{
+		flagRequires("overwrite", "merge-into"),
+		flagsNotBothStdin("spec", "merge-into"),
+		manifestNotWithStdout(),
+		flagNotWithStdin("watch", "spec"),
+		flagRequires("fail-fast", "stdin-many"),
+		flagRequires("parallel", "stdin-many"),
+		flagsNotTogether("stdin-many", "merge-into"),
+		flagsNotTogether("stdin-many", "watch"),
+		flagsNotTogether("stdin-many", "format"),
+		flagsNotTogether("stdin-many", "uuid-base"),
+		flagsNotTogether("stdin-many", "summary-file"),
+		flagsNotTogether("stdin-many", "stats"),
+	}, outputDirRules("spec")...)...)
 }
 
 func init() {
 	rootCmd.AddCommand(openapi2kongCmd)
 	openapi2kongCmd.Flags().StringP("spec", "s", "-", "OpenAPI spec file to process. Use - to read from stdin")
 	openapi2kongCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
-	openapi2kongCmd.Flags().StringP("format", "", filebasics.OutputFormatYaml, "output format: "+
-		filebasics.OutputFormatJSON+" or "+filebasics.OutputFormatYaml)
+	addOutputDirFlags(openapi2kongCmd)
+	openapi2kongCmd.Flags().StringP("format", "", filebasics.OutputFormatYaml, outputFormatUsage())
+	addManifestFlag(openapi2kongCmd)
+	addBackupFlag(openapi2kongCmd)
 	openapi2kongCmd.Flags().StringP("uuid-base", "", "",
 		`the unique base-string for uuid-v5 generation of enity id's (if omitted
-will use the root-level "x-kong-name" directive, or fall back to 'info.title')`)
+will use the root-level "x-kong-name" directive, or fall back to 'info.title',
+and then the spec filename). An error is returned if none is available`)
+	openapi2kongCmd.Flags().StringP("name-prefix", "", "",
+		`prefix for the names of all generated entities (if omitted will use the
+root-level "x-kong-name-prefix" directive)`)
+	openapi2kongCmd.Flags().String("format-version", "",
+		`the '_format_version' of the output, in 'x.y' format, to match the targeted
+decK version (default "3.0")`)
 	openapi2kongCmd.Flags().StringSlice("select-tag", nil,
 		`select tags to apply to all entities (if omitted will use the "x-kong-tags"
-directive from the file)`)
+directive from the file, they are not merged). The tags are deduplicated and sorted`)
+	openapi2kongCmd.Flags().StringP("merge-into", "", "",
+		`existing decK file to merge the generated entities into. Entities are
+matched by name, identical ones are only included once`)
+	openapi2kongCmd.Flags().Bool("overwrite", false,
+		`when merging, overwrite existing entities with conflicting generated
+ones, instead of failing`)
+	openapi2kongCmd.Flags().Bool("allow-external-refs", false,
+		`allow '$ref's to local files, within the directory of the spec (or the current
+directory when reading from stdin)`)
+	openapi2kongCmd.Flags().Bool("allow-remote-refs", false,
+		`allow '$ref's to remote documents (http and https urls), which are fetched`)
+	openapi2kongCmd.Flags().Bool("stdin-many", false,
+		`streaming mode; read many OpenAPI specs as NDJSON (one JSON spec per line), and
+write the converted decK files as NDJSON (one per line). Failing specs are logged
+and skipped, the command fails at the end if any did`)
+	openapi2kongCmd.Flags().Bool("fail-fast", false,
+		`in streaming mode, stop at the first spec that fails to convert`)
+	openapi2kongCmd.Flags().Int("parallel", 1,
+		`in streaming mode, the number of specs to convert concurrently. The output
+remains in the order of the input`)
+	openapi2kongCmd.Flags().String("summary-file", "",
+		`sidecar JSON file to write, listing the generated services and routes with
+the operations (path and method) they were generated from, their tags, and plugins`)
+	openapi2kongCmd.Flags().Bool("stats", false,
+		"log a one-line summary at info level (shown with '--verbose 1' or higher); the number of generated "+
+			"services, routes, and plugins, of skipped operations, and of warnings")
+	openapi2kongCmd.Flags().Bool("watch", false,
+		`keep running, and regenerate the output each time the spec file changes
+(stop with Ctrl-C). Conversion errors are logged, and watching continues`)
 }
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/kong/go-apiops/openapi2kong"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_openapi2kongStats(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.yaml")
+	output := filepath.Join(dir, "kong.yaml")
+	require.NoError(t, os.WriteFile(spec, []byte(`openapi: 3.0.0
+info:
+  title: stats
+x-kong-plugin-cors: {}
+paths:
+  /one:
+    get:
+      x-kong-plugin-key-auth: {}
+      responses:
+        "200":
+          description: OK
+    post:
+      responses:
+        "200":
+          description: OK
+  /two:
+    get:
+      x-kong-acl: []
+      responses:
+        "200":
+          description: OK
+`), 0o600))
+	var logs []string
+	logger := funcr.New(func(_, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 1})
+	logbasics.SetLogger(&logger)
+	defer logbasics.SetLogger(nil)
+	require.NoError(t, openapi2kongCmd.Flags().Set("stats", "true"))
+	defer openapi2kongCmd.Flags().Set("stats", "false")
+
+	// called without the command, since it (re)initializes the logger
+	options := openapi2kong.O2kOptions{SpecFilename: spec, RequireDocName: true}
+	trackInfo := deckformat.HistoryNewEntry("openapi2kong")
+	require.NoError(t, convertOpenapi2Kong(openapi2kongCmd, spec, output, filebasics.OutputFormatYaml, "",
+		false, options, trackInfo))
+
+	// the empty 'x-kong-acl' is skipped with a warning
+	require.NotEmpty(t, logs)
+	assert.Equal(t, `"level"=1 "msg"="stats" "services"=1 "routes"=3 "plugins"=2 "skipped_operations"=0 `+
+		`"warnings"=1`, logs[len(logs)-1])
+}
+
+func Test_openapi2kongExternalRefs(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.yaml")
+	output := filepath.Join(dir, "kong.yaml")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "responses.yaml"), []byte(`ok:
+  description: OK
+`), 0o600))
+	require.NoError(t, os.WriteFile(spec, []byte(`openapi: 3.0.0
+info:
+  title: refs
+paths:
+  /one:
+    get:
+      responses:
+        "200":
+          $ref: "./responses.yaml#/ok"
+`), 0o600))
+	defer openapi2kongCmd.Flags().Set("allow-external-refs", "false")
+
+	rootCmd.SetArgs([]string{"openapi2kong", "-s", spec, "-o", output})
+	assert.ErrorContains(t, rootCmd.Execute(), "external reference not allowed: local file")
+	assert.NoFileExists(t, output)
+
+	rootCmd.SetArgs([]string{"openapi2kong", "-s", spec, "-o", output, "--allow-external-refs"})
+	require.NoError(t, rootCmd.Execute())
+	assert.FileExists(t, output)
+}
+
+func Test_openapi2kongHistoryUUIDBase(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.yaml")
+	output := filepath.Join(dir, "kong.yaml")
+	require.NoError(t, os.WriteFile(spec, []byte(`openapi: 3.0.0
+info:
+  title: the title
+x-kong-name: my-api
+paths:
+  /one:
+    get:
+      responses:
+        "200":
+          description: OK
+`), 0o600))
+	current := deckformat.ConfigGet()
+	defer deckformat.ConfigSet(current)
+	keepConfig := current
+	keepConfig.KeepHistory = true
+	deckformat.ConfigSet(keepConfig)
+
+	// the 'x-kong-name' takes precedence over the 'info.title'
+	rootCmd.SetArgs([]string{"openapi2kong", "-s", spec, "-o", output})
+	require.NoError(t, rootCmd.Execute())
+
+	history := deckformat.HistoryGet(filebasics.MustDeserializeFile(output))
+	require.Len(t, history, 1)
+	entry := history[0].(map[string]interface{})
+	assert.Equal(t, "openapi2kong", entry["command"])
+	assert.Equal(t, "my-api", entry["uuid-base-resolved"])
+}
@@ -0,0 +1,190 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/kong/go-apiops/openapi2kong"
+)
+
+// maxStreamLineSize is the maximum size of a single spec (line) in the NDJSON input
+const maxStreamLineSize = 64 * 1024 * 1024
+
+// executeOpenapi2KongStream runs the streaming mode of the openapi2kong command, reading the
+// NDJSON input from the file (or stdin), and writing the NDJSON output to the file (or stdout).
+func executeOpenapi2KongStream(inputFilename string, outputFilename string,
+	options openapi2kong.O2kOptions, trackInfo map[string]interface{}, failFast bool, parallel int,
+) error {
+	in := os.Stdin
+	if inputFilename != "-" {
+		f, err := os.Open(inputFilename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	if outputFilename == "-" {
+		return convertOpenapi2KongStream(in, os.Stdout, options, trackInfo, failFast, parallel)
+	}
+
+	// collect the output, to write the file in one go
+	var out bytes.Buffer
+	streamErr := convertOpenapi2KongStream(in, &out, options, trackInfo, failFast, parallel)
+	if streamErr != nil && failFast {
+		return streamErr // stopped halfway, don't write a partial file
+	}
+	content := out.Bytes()
+	if err := filebasics.WriteFile(outputFilename, &content); err != nil {
+		return err
+	}
+	return streamErr
+}
+
+// streamLine is a single (non-empty) line of the NDJSON input.
+type streamLine struct {
+	lineNumber int
+	line       string
+}
+
+// streamResult is the result of converting a single line of the NDJSON input.
+type streamResult struct {
+	lineNumber int
+	output     []byte
+	err        error
+}
+
+// convertOpenapi2KongStream converts the NDJSON input; every (non-empty) line is an OpenAPI
+// spec in JSON format, and the converted decK file is written as a single line of JSON to
+// the output. Up to 'parallel' specs are converted concurrently, the output is written in
+// the order of the input. Failing specs are logged and skipped, unless 'failFast' is set.
+// Returns an error if any spec failed. All conversions have ended when it returns.
+func convertOpenapi2KongStream(in io.Reader, out io.Writer, options openapi2kong.O2kOptions,
+	trackInfo map[string]interface{}, failFast bool, parallel int,
+) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	defer func() {
+		cancel() // stops the conversions still in flight, when returning early
+		workers.Wait()
+	}()
+
+	// The reader only scans the input, it is not waited for, since it could be blocked
+	// reading (eg. stdin). The scan results are only read after 'lines' is closed.
+	lines := make(chan streamLine)
+	lineNumber := 0
+	var scanErr error
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
+		for scanner.Scan() {
+			lineNumber++
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			select {
+			case lines <- streamLine{lineNumber, line}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		scanErr = scanner.Err()
+	}()
+
+	// Start a conversion per line, while less than 'parallel' are in flight, and write the
+	// results in the order of the input. The conversions are only started here, such that
+	// all of them are tracked before waiting for them.
+	queue := make([]chan streamResult, 0, parallel)
+	reading := true
+	total := 0
+	failed := 0
+	for reading || len(queue) > 0 {
+		var nextLine <-chan streamLine // nil blocks, when not reading
+		if reading && len(queue) < parallel {
+			nextLine = lines
+		}
+		var nextResult <-chan streamResult // nil blocks, when nothing is in flight
+		if len(queue) > 0 {
+			nextResult = queue[0]
+		}
+
+		select {
+		case line, ok := <-nextLine:
+			if !ok {
+				reading = false
+				continue
+			}
+			resultChan := make(chan streamResult, 1)
+			queue = append(queue, resultChan)
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				output, err := convertStreamLine(ctx, line.line, options, trackInfo)
+				resultChan <- streamResult{line.lineNumber, output, err}
+			}()
+
+		case result := <-nextResult:
+			queue = queue[1:]
+			total++
+			if result.err == nil {
+				if _, err := out.Write(append(result.output, '\n')); err != nil {
+					return fmt.Errorf("failed to write the output; %w", err)
+				}
+				continue
+			}
+			if failFast {
+				return fmt.Errorf("failed converting the OpenAPI spec on line %d; %w", result.lineNumber, result.err)
+			}
+			logbasics.Error(result.err, "failed converting the OpenAPI spec, skipping it", "line", result.lineNumber)
+			failed++
+		}
+	}
+	if scanErr != nil {
+		return fmt.Errorf("failed reading the input after line %d; %w", lineNumber, scanErr)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed converting %d of %d OpenAPI specs", failed, total)
+	}
+	return nil
+}
+
+// convertStreamLine converts a single JSON encoded spec, and returns the decK file as a
+// single line of JSON.
+func convertStreamLine(ctx context.Context, line string, options openapi2kong.O2kOptions,
+	trackInfo map[string]interface{},
+) ([]byte, error) {
+	spec, err := filebasics.ReadFromReader(strings.NewReader(line), filebasics.OutputFormatJSON)
+	if err != nil {
+		return nil, err
+	}
+	content, _ := json.Marshal(spec)
+	result, info, err := openapi2kong.ConvertWithInfoContext(ctx, &content, options)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := make(map[string]interface{}, len(trackInfo)+1)
+	for key, value := range trackInfo {
+		entry[key] = value
+	}
+	entry["uuid-base-resolved"] = info.DocName
+	if err := deckformat.HistoryTryAppend(result, entry); err != nil {
+		return nil, err
+	}
+	return json.Marshal(result)
+}
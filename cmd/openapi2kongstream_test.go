@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/openapi2kong"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_convertOpenapi2KongStream(t *testing.T) {
+	specs := strings.Join([]string{
+		`{"openapi":"3.0.0","info":{"title":"one"},"paths":{"/one":{"get":{"responses":{"200":{"description":"OK"}}}}}}`,
+		``,
+		`{"openapi":"3.0.0","info":{"title":"two"},"paths":{"/two":{"get":{"responses":{"200":{"description":"OK"}}}}}}`,
+	}, "\n")
+	options := openapi2kong.O2kOptions{RequireDocName: true}
+	trackInfo := map[string]interface{}{"command": "openapi2kong"}
+
+	var out bytes.Buffer
+	require.NoError(t, convertOpenapi2KongStream(strings.NewReader(specs), &out, options, trackInfo, false, 1))
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	for i, name := range []string{"one", "two"} {
+		var result map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(lines[i]), &result))
+		service := result["services"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, name, service["name"])
+	}
+
+	// failures are skipped, unless failing fast
+	specs = strings.Join([]string{
+		`{"openapi":"3.0.0","info":{"title":"one"},"paths":{}}`,
+		`not json`,
+		`{"openapi":"3.0.0","info":{"title":"three"},"paths":{}}`,
+	}, "\n")
+	out.Reset()
+	err := convertOpenapi2KongStream(strings.NewReader(specs), &out, options, trackInfo, false, 1)
+	assert.EqualError(t, err, "failed converting 1 of 3 OpenAPI specs")
+	assert.Len(t, strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n"), 2)
+
+	out.Reset()
+	err = convertOpenapi2KongStream(strings.NewReader(specs), &out, options, trackInfo, true, 1)
+	assert.ErrorContains(t, err, "failed converting the OpenAPI spec on line 2")
+	assert.Len(t, strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n"), 1)
+}
+
+func Test_convertOpenapi2KongStreamParallel(t *testing.T) {
+	lines := make([]string, 0)
+	for i := 0; i < 20; i++ {
+		lines = append(lines, fmt.Sprintf(`{"openapi":"3.0.0","info":{"title":"spec-%d"},`+
+			`"paths":{"/path-%d":{"get":{"responses":{"200":{"description":"OK"}}}}}}`, i, i))
+		if i%7 == 3 {
+			lines = append(lines, `not json`)
+		}
+	}
+	specs := strings.Join(lines, "\n")
+	options := openapi2kong.O2kOptions{RequireDocName: true}
+	trackInfo := map[string]interface{}{"command": "openapi2kong"}
+
+	var serial bytes.Buffer
+	err := convertOpenapi2KongStream(strings.NewReader(specs), &serial, options, trackInfo, false, 1)
+	assert.EqualError(t, err, "failed converting 3 of 23 OpenAPI specs")
+
+	var parallel bytes.Buffer
+	err = convertOpenapi2KongStream(strings.NewReader(specs), &parallel, options, trackInfo, false, 4)
+	assert.EqualError(t, err, "failed converting 3 of 23 OpenAPI specs")
+	assert.Equal(t, serial.String(), parallel.String())
+	assert.Len(t, strings.Split(strings.TrimSuffix(parallel.String(), "\n"), "\n"), 20)
+
+	// failing fast reports the first failure in input order
+	parallel.Reset()
+	err = convertOpenapi2KongStream(strings.NewReader(specs), &parallel, options, trackInfo, true, 4)
+	assert.ErrorContains(t, err, "failed converting the OpenAPI spec on line 5")
+	assert.Len(t, strings.Split(strings.TrimSuffix(parallel.String(), "\n"), "\n"), 4)
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write(_ []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func Test_convertOpenapi2KongStreamReturnsAfterConversions(t *testing.T) {
+	lines := make([]string, 0)
+	for i := 0; i < 50; i++ {
+		lines = append(lines, fmt.Sprintf(`{"openapi":"3.0.0","info":{"title":"spec-%d"},"paths":{}}`, i))
+	}
+	specs := strings.Join(lines, "\n")
+	options := openapi2kong.O2kOptions{RequireDocName: true}
+	trackInfo := map[string]interface{}{"command": "openapi2kong"}
+
+	err := convertOpenapi2KongStream(strings.NewReader(specs), failingWriter{}, options, trackInfo, false, 8)
+	assert.EqualError(t, err, "failed to write the output; disk full")
+
+	// no conversion may still be running (reading the options), checked with -race
+	previous := filebasics.GetReadOptions()
+	filebasics.SetReadOptions(filebasics.ReadOptions{EnvInterpolate: true})
+	filebasics.SetReadOptions(previous)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultOutputTemplate is the default of the '--output-template' flag.
+const defaultOutputTemplate = "{basename}.{format}"
+
+// addOutputDirFlags adds the '--output-dir' and '--output-template' flags to a command that
+// writes a single file, as an alternative to '--output-file'.
+func addOutputDirFlags(cmd *cobra.Command) {
+	cmd.Flags().String("output-dir", "",
+		`directory to write the output file to, named by '--output-template'. Cannot be
+used together with '--output-file'`)
+	cmd.Flags().String("output-template", defaultOutputTemplate,
+		`filename template for '--output-dir'; "{basename}" is the input filename without
+extension, and "{format}" the output format`)
+}
+
+// outputDirRules returns the rules for the '--output-dir' flags, where 'input' is the flag
+// with the input filename (the basename is taken from it).
+func outputDirRules(input string) []flagRule {
+	return []flagRule{
+		flagsNotTogether("output-dir", "output-file"),
+		flagRequires("output-template", "output-dir"),
+		flagNotWithStdin("output-dir", input),
+	}
+}
+
+// expandOutputTemplate returns the filename for the template; "{basename}" is replaced by
+// the input filename without directory and extension, and "{format}" by the output format
+// (in lowercase).
+func expandOutputTemplate(template string, inputFilename string, format string) (string, error) {
+	basename := filepath.Base(inputFilename)
+	basename = strings.TrimSuffix(basename, filepath.Ext(basename))
+	filename := strings.NewReplacer("{basename}", basename, "{format}", strings.ToLower(format)).Replace(template)
+	if strings.ContainsAny(filename, "{}") {
+		return "", fmt.Errorf("unknown placeholder in output template '%s', "+
+			"expected only '{basename}' and '{format}'", template)
+	}
+	if filename == "" {
+		return "", errors.New("the output template resolves to an empty filename")
+	}
+	return filename, nil
+}
+
+// getOutputFilename returns the output filename of a command with the '--output-dir' flags;
+// the '--output-file' flag, or if '--output-dir' is given, the expanded '--output-template'
+// in that directory.
+func getOutputFilename(cmd *cobra.Command, inputFilename string, format string) (string, error) {
+	outputFilename, err := cmd.Flags().GetString("output-file")
+	if err != nil {
+		return "", fmt.Errorf("failed getting cli argument 'output-file'; %w", err)
+	}
+	outputDir, err := cmd.Flags().GetString("output-dir")
+	if err != nil {
+		return "", fmt.Errorf("failed getting cli argument 'output-dir'; %w", err)
+	}
+	if outputDir == "" {
+		return outputFilename, nil
+	}
+	template, err := cmd.Flags().GetString("output-template")
+	if err != nil {
+		return "", fmt.Errorf("failed getting cli argument 'output-template'; %w", err)
+	}
+	filename, err := expandOutputTemplate(template, inputFilename, format)
+	if err != nil {
+		return "", usageError{err}
+	}
+	return filepath.Join(outputDir, filename), nil
+}
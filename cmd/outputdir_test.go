@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_expandOutputTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		input    string
+		format   string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "default template",
+			template: defaultOutputTemplate,
+			input:    "specs/petstore.yaml",
+			format:   filebasics.OutputFormatJSON,
+			want:     "petstore.json",
+		},
+		{
+			name:     "custom template",
+			template: "{basename}.deck.{format}",
+			input:    "/tmp/petstore.openapi.yaml",
+			format:   filebasics.OutputFormatYaml,
+			want:     "petstore.openapi.deck.yaml",
+		},
+		{
+			name:     "unknown placeholder",
+			template: "{spec}.yaml",
+			input:    "petstore.yaml",
+			format:   "yaml",
+			wantErr:  "unknown placeholder in output template '{spec}.yaml', expected only '{basename}' and '{format}'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandOutputTemplate(tt.template, tt.input, tt.format)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_outputDir(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "petstore.yaml")
+	outputDir := filepath.Join(dir, "out")
+	require.NoError(t, os.Mkdir(outputDir, 0o700))
+	require.NoError(t, os.WriteFile(input, []byte(`_format_version: "3.0"
+services:
+  - name: petstore
+`), 0o600))
+	defer func() {
+		for name, value := range map[string]string{
+			"output-dir":      "",
+			"output-template": defaultOutputTemplate,
+			"format":          filebasics.OutputFormatYaml,
+		} {
+			flag := normalizeCmd.Flags().Lookup(name)
+			_ = flag.Value.Set(value)
+			flag.Changed = false
+		}
+	}()
+
+	rootCmd.SetArgs([]string{"normalize", "-i", input, "--output-dir", outputDir,
+		"--output-template", "{basename}.deck.{format}", "--format", "json"})
+	require.NoError(t, rootCmd.Execute())
+
+	data := filebasics.MustDeserializeFile(filepath.Join(outputDir, "petstore.deck.json"))
+	assert.Equal(t, "petstore", data["services"].([]interface{})[0].(map[string]interface{})["name"])
+}
@@ -6,7 +6,6 @@ package cmd
 import (
 	"fmt"
 	"log"
-	"strings"
 
 	"github.com/kong/go-apiops/deckformat"
 	"github.com/kong/go-apiops/filebasics"
@@ -20,6 +19,9 @@ import (
 func executePatch(cmd *cobra.Command, args []string) error {
 	verbosity, _ := cmd.Flags().GetInt("verbose")
 	logbasics.Initialize(log.LstdFlags, verbosity)
+	if err := applyBackupFlag(cmd); err != nil {
+		return err
+	}
 
 	inputFilename, err := cmd.Flags().GetString("state")
 	if err != nil {
@@ -31,13 +33,9 @@ func executePatch(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed getting cli argument 'output-file'; %w", err)
 	}
 
-	var outputFormat string
-	{
-		outputFormat, err = cmd.Flags().GetString("format")
-		if err != nil {
-			return fmt.Errorf("failed getting cli argument 'format'; %w", err)
-		}
-		outputFormat = strings.ToUpper(outputFormat)
+	outputFormat, err := getOutputFormat(cmd, inputFilename)
+	if err != nil {
+		return err
 	}
 
 	var valuesPatch patch.DeckPatch
@@ -93,7 +91,10 @@ func executePatch(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to read input file '%s'; %w", inputFilename, err)
 	}
-	deckformat.HistoryAppend(data, trackInfo) // add before patching, so patch can operate on it
+	// add before patching, so patch can operate on it
+	if err := deckformat.HistoryTryAppend(data, trackInfo); err != nil {
+		return err
+	}
 
 	yamlNode := jsonbasics.ConvertToYamlNode(data)
 
@@ -119,7 +120,10 @@ func executePatch(cmd *cobra.Command, args []string) error {
 
 	data = jsonbasics.ConvertToJSONobject(yamlNode)
 
-	return filebasics.WriteSerializedFile(outputFilename, data, outputFormat)
+	if err := filebasics.WriteSerializedFile(outputFilename, data, outputFormat); err != nil {
+		return err
+	}
+	return writeManifest(cmd, outputFilename)
 }
 
 //
@@ -164,15 +168,17 @@ patches that will be applied in order;
     ]
   }
 `,
-	RunE: executePatch,
+	PreRunE: validateManifestFlags,
+	RunE:    executePatch,
 }
 
 func init() {
 	rootCmd.AddCommand(patchCmd)
 	patchCmd.Flags().StringP("state", "s", "-", "decK file to process. Use - to read from stdin")
 	patchCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
-	patchCmd.Flags().StringP("format", "", filebasics.OutputFormatYaml, "output format: "+
-		filebasics.OutputFormatJSON+" or "+filebasics.OutputFormatYaml)
+	patchCmd.Flags().StringP("format", "", filebasics.OutputFormatYaml, outputFormatUsage())
+	addManifestFlag(patchCmd)
+	addBackupFlag(patchCmd)
 	patchCmd.Flags().StringP("selector", "", "", "json-pointer identifying element to patch")
 	patchCmd.Flags().StringArrayP("value", "", []string{}, "a value to set in the selected entry in "+
 		"format <key:value> (can be specified more than once)")
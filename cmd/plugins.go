@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/spf13/cobra"
+)
+
+// Executes the CLI command "plugins list"
+func executePluginsList(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+
+	inputFilename, err := cmd.Flags().GetString("input")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'input'; %w", err)
+	}
+
+	outputFilename, err := cmd.Flags().GetString("output-file")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'output-file'; %w", err)
+	}
+
+	var outputFormat string
+	{
+		outputFormat, err = cmd.Flags().GetString("format")
+		if err != nil {
+			return fmt.Errorf("failed getting cli argument 'format'; %w", err)
+		}
+		outputFormat = strings.ToUpper(outputFormat)
+		if outputFormat != outputFormatTable && outputFormat != filebasics.OutputFormatJSON {
+			return usageError{fmt.Errorf("expected '--format' to be 'table' or 'json', got: '%s'", outputFormat)}
+		}
+	}
+
+	// do the work: read/collect/write
+	data, err := filebasics.DeserializeFile(inputFilename)
+	if err != nil {
+		return err
+	}
+	bindings, err := deckformat.GetPluginBindings(data)
+	if err != nil {
+		return fmt.Errorf("failed to collect plugins from '%s'; %w", inputFilename, err)
+	}
+
+	if outputFormat == filebasics.OutputFormatJSON {
+		result := make([]interface{}, len(bindings))
+		for i, binding := range bindings {
+			entry := map[string]interface{}{
+				"name":  binding.Name,
+				"scope": binding.Scope,
+			}
+			if binding.ID != "" {
+				entry["id"] = binding.ID
+			}
+			if binding.Target != "" {
+				entry["target"] = binding.Target
+			}
+			if binding.Missing {
+				entry["missing"] = true
+			}
+			result[i] = entry
+		}
+		output, err := filebasics.Serialize(map[string]interface{}{"plugins": result}, filebasics.OutputFormatJSON)
+		if err != nil {
+			return err
+		}
+		return filebasics.WriteFile(outputFilename, output)
+	}
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PLUGIN\tSCOPE\tTARGET")
+	for _, binding := range bindings {
+		target := binding.Target
+		if binding.Missing {
+			target = target + " (MISSING)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", binding.Name, binding.Scope, target)
+	}
+	w.Flush()
+	output := buf.Bytes()
+	return filebasics.WriteFile(outputFilename, &output)
+}
+
+//
+//
+// Define the CLI data for the plugins command
+//
+//
+
+var pluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "Inspect the plugins in decK files",
+	Long:  `Inspect the plugins in decK files.`,
+	Args:  noArgs,
+}
+
+var pluginsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the plugins in a decK file",
+	Long: `Lists the plugins in a decK file, with their scope and target.
+
+Both top-level and nested plugins (eg. in a service) are listed. The scope is
+"global", or the entity types the plugin is bound to (eg. "service", or
+"route+consumer"), the target is the name (or id) of those entities. Plugins
+referring to entities that are not in the file are marked "(MISSING)".`,
+	RunE: executePluginsList,
+	Args: noArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(pluginsCmd)
+	pluginsCmd.AddCommand(pluginsListCmd)
+	pluginsListCmd.Flags().StringP("input", "i", "-", "decK file to inspect. Use - to read from stdin")
+	pluginsListCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
+	pluginsListCmd.Flags().StringP("format", "", "table", "output format: table or json")
+}
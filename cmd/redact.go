@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/spf13/cobra"
+)
+
+// Executes the CLI command "redact"
+func executeRedact(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+	if err := applyBackupFlag(cmd); err != nil {
+		return err
+	}
+
+	inputFilenames, err := getInputFilenames(cmd)
+	if err != nil {
+		return err
+	}
+	inputFilename := inputName(inputFilenames)
+
+	outputFormat, err := getOutputFormat(cmd, inputFilenames[0])
+	if err != nil {
+		return err
+	}
+
+	outputFilename, err := getOutputFilename(cmd, inputFilenames[0], outputFormat)
+	if err != nil {
+		return err
+	}
+
+	var opts deckformat.RedactOptions
+	{
+		if opts.Fields, err = cmd.Flags().GetStringSlice("field"); err != nil {
+			return fmt.Errorf("failed getting cli argument 'field'; %w", err)
+		}
+		if opts.Placeholder, err = cmd.Flags().GetString("placeholder"); err != nil {
+			return fmt.Errorf("failed getting cli argument 'placeholder'; %w", err)
+		}
+	}
+
+	trackInfo := deckformat.HistoryNewEntry("redact")
+	trackInfo["input"] = inputFilename
+	trackInfo["output"] = outputFilename
+
+	// do the work: read/redact/write
+	data, err := readInputFiles(inputFilenames)
+	if err != nil {
+		return err
+	}
+	result, redacted, err := deckformat.Redact(data, opts)
+	if err != nil {
+		return fmt.Errorf("failed to redact '%s'; %w", inputFilename, err)
+	}
+	for _, path := range redacted {
+		fmt.Fprintln(cmd.ErrOrStderr(), "redacted: "+path)
+	}
+	if err := deckformat.HistoryTryAppend(result, trackInfo); err != nil {
+		return err
+	}
+	if err := filebasics.WriteSerializedFile(outputFilename, result, outputFormat); err != nil {
+		return err
+	}
+	return writeManifest(cmd, outputFilename)
+}
+
+//
+//
+// Define the CLI data for the redact command
+//
+//
+
+var redactCmd = &cobra.Command{
+	Use:   "redact",
+	Short: "Replaces the secrets in a decK file with a placeholder",
+	Long: `Replaces the secrets in a decK file with a placeholder, for sharing it.
+
+The secret fields (eg. credentials of consumers, and secrets in plugin configs) are
+replaced, the structure of the file is left intact. A plain field name matches in
+any object, an "entitytype.field" name only on entities of that type (eg.
+"keyauth_credentials.key"). Empty values and vault references are left alone.
+The paths of the redacted fields are reported on stderr.`,
+	PreRunE: validateOutputDirFlags("input"),
+	RunE:    executeRedact,
+	Args:    noArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(redactCmd)
+	addInputFlag(redactCmd, "decK file to redact")
+	redactCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
+	addOutputDirFlags(redactCmd)
+	redactCmd.Flags().StringP("format", "", filebasics.OutputFormatYaml, outputFormatUsage())
+	addManifestFlag(redactCmd)
+	addBackupFlag(redactCmd)
+	redactCmd.Flags().StringSlice("field", deckformat.DefaultRedactFields,
+		"field to redact (can be repeated, or a comma separated list)")
+	redactCmd.Flags().String("placeholder", deckformat.DefaultRedactPlaceholder,
+		`the value to replace the secrets with, eg. a vault reference
+like "{vault://env/secret}"`)
+}
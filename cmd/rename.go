@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/kong/go-apiops/patch"
+	"github.com/spf13/cobra"
+)
+
+// Executes the CLI command "rename"
+func executeRename(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+	if err := applyBackupFlag(cmd); err != nil {
+		return err
+	}
+
+	inputFilenames, err := getInputFilenames(cmd)
+	if err != nil {
+		return err
+	}
+	inputFilename := inputName(inputFilenames)
+
+	entityType, err := cmd.Flags().GetString("type")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'type'; %w", err)
+	}
+
+	oldName, err := cmd.Flags().GetString("old-name")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'old-name'; %w", err)
+	}
+
+	newName, err := cmd.Flags().GetString("new-name")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'new-name'; %w", err)
+	}
+	if entityType == "" || oldName == "" || newName == "" {
+		return usageError{errors.New("flags '--type', '--old-name', and '--new-name' are required")}
+	}
+
+	outputFormat, err := getOutputFormat(cmd, inputFilenames[0])
+	if err != nil {
+		return err
+	}
+
+	outputFilename, err := getOutputFilename(cmd, inputFilenames[0], outputFormat)
+	if err != nil {
+		return err
+	}
+
+	trackInfo := deckformat.HistoryNewEntry("rename")
+	trackInfo["input"] = inputFilename
+	trackInfo["output"] = outputFilename
+	trackInfo["type"] = entityType
+	trackInfo["old-name"] = oldName
+	trackInfo["new-name"] = newName
+
+	// do the work: read/rename/write
+	data, err := readInputFiles(inputFilenames)
+	if err != nil {
+		return err
+	}
+	count, err := patch.RenameEntity(data, entityType, oldName, newName)
+	if err != nil {
+		return fmt.Errorf("failed to rename in '%s'; %w", inputFilename, err)
+	}
+	logbasics.Info("renamed entity", "type", entityType, "old-name", oldName, "new-name", newName,
+		"references", count)
+	if err := deckformat.HistoryTryAppend(data, trackInfo); err != nil {
+		return err
+	}
+	if err := filebasics.WriteSerializedFile(outputFilename, data, outputFormat); err != nil {
+		return err
+	}
+	return writeManifest(cmd, outputFilename)
+}
+
+//
+//
+// Define the CLI data for the rename command
+//
+//
+
+var renameCmd = &cobra.Command{
+	Use:   "rename",
+	Short: "Renames an entity in a decK file, and updates the references to it",
+	Long: `Renames an entity in a decK file, and updates the references to it.
+
+Services, routes, consumers (by username), consumer_groups, and upstreams can be
+renamed. The references by name are updated; eg. the 'service' of routes and
+plugins, or the 'host' of the services and the 'upstream' of the targets for an
+upstream. Fails if an entity with the new name already exists.`,
+	PreRunE: validateOutputDirFlags("input"),
+	RunE:    executeRename,
+	Args:    noArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(renameCmd)
+	addInputFlag(renameCmd, "decK file to process")
+	renameCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
+	addOutputDirFlags(renameCmd)
+	renameCmd.Flags().StringP("format", "", filebasics.OutputFormatYaml, outputFormatUsage())
+	renameCmd.Flags().String("type", "", "the type of the entity to rename, eg. 'services'")
+	renameCmd.Flags().String("old-name", "", "the current name of the entity")
+	renameCmd.Flags().String("new-name", "", "the new name of the entity")
+	addManifestFlag(renameCmd)
+	addBackupFlag(renameCmd)
+}
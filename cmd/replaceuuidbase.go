@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/kong/go-apiops/openapi2kong"
+	uuid "github.com/satori/go.uuid"
+	"github.com/spf13/cobra"
+)
+
+// Executes the CLI command "replace-uuid-base"
+func executeReplaceUUIDBase(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+	if err := applyBackupFlag(cmd); err != nil {
+		return err
+	}
+
+	inputFilenames, err := getInputFilenames(cmd)
+	if err != nil {
+		return err
+	}
+	inputFilename := inputName(inputFilenames)
+
+	oldBase, err := cmd.Flags().GetString("old-base")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'old-base'; %w", err)
+	}
+
+	newBase, err := cmd.Flags().GetString("new-base")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'new-base'; %w", err)
+	}
+	if oldBase == "" || newBase == "" {
+		return usageError{errors.New("flags '--old-base' and '--new-base' are required")}
+	}
+
+	outputFormat, err := getOutputFormat(cmd, inputFilenames[0])
+	if err != nil {
+		return err
+	}
+
+	outputFilename, err := getOutputFilename(cmd, inputFilenames[0], outputFormat)
+	if err != nil {
+		return err
+	}
+
+	trackInfo := deckformat.HistoryNewEntry("replace-uuid-base")
+	trackInfo["input"] = inputFilename
+	trackInfo["output"] = outputFilename
+	trackInfo["old-base"] = oldBase
+	trackInfo["new-base"] = newBase
+
+	// do the work: read/re-seed/write
+	data, err := readInputFiles(inputFilenames)
+	if err != nil {
+		return err
+	}
+	if err := openapi2kong.ReplaceUUIDBase(data, oldBase, newBase, uuid.UUID{}); err != nil {
+		return fmt.Errorf("failed to replace the uuid-base of '%s'; %w", inputFilename, err)
+	}
+	if err := deckformat.HistoryTryAppend(data, trackInfo); err != nil {
+		return err
+	}
+	if err := filebasics.WriteSerializedFile(outputFilename, data, outputFormat); err != nil {
+		return err
+	}
+	return writeManifest(cmd, outputFilename)
+}
+
+//
+//
+// Define the CLI data for the replace-uuid-base command
+//
+//
+
+var replaceUUIDBaseCmd = &cobra.Command{
+	Use:   "replace-uuid-base",
+	Short: "Re-seeds the IDs of a decK file generated by openapi2kong",
+	Long: `Re-seeds the IDs of a decK file generated by openapi2kong, from an old to a new
+uuid-base, without re-running the conversion.
+
+The IDs (and names) derived from the old uuid-base are replaced as if the file was
+generated with the new one, and the references to them (route to service, plugin
+to route or service, service to upstream, sni to certificate) are updated. Use the
+resolved uuid-base, as recorded in the history ('uuid-base-resolved'). Entities
+whose ID does not match the old uuid-base are left alone with a warning.`,
+	PreRunE: validateOutputDirFlags("input"),
+	RunE:    executeReplaceUUIDBase,
+	Args:    noArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(replaceUUIDBaseCmd)
+	addInputFlag(replaceUUIDBaseCmd, "decK file to re-seed")
+	replaceUUIDBaseCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
+	addOutputDirFlags(replaceUUIDBaseCmd)
+	replaceUUIDBaseCmd.Flags().StringP("format", "", filebasics.OutputFormatYaml, outputFormatUsage())
+	replaceUUIDBaseCmd.Flags().String("old-base", "", "the uuid-base the file was generated with")
+	replaceUUIDBaseCmd.Flags().String("new-base", "", "the uuid-base to re-seed the IDs with")
+	addManifestFlag(replaceUUIDBaseCmd)
+	addBackupFlag(replaceUUIDBaseCmd)
+}
@@ -4,8 +4,11 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
+	"github.com/kong/go-apiops/filebasics"
 	"github.com/spf13/cobra"
 )
 
@@ -19,15 +22,54 @@ go-apiops houses an improved APIOps toolset for operating Kong Gateway deploymen
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRunE: applyReadFlags,
+}
+
+// applyReadFlags configures the file reads according to the global flags.
+func applyReadFlags(cmd *cobra.Command, _ []string) error {
+	envInterpolate, err := cmd.Flags().GetBool("env-interpolate")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'env-interpolate'; %w", err)
+	}
+	filebasics.SetReadOptions(filebasics.ReadOptions{EnvInterpolate: envInterpolate})
+	return nil
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// The process exits with a code based on the error returned, see exitCode.
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+// outputFormatUsage returns the usage text for the '--format' flag.
+func outputFormatUsage() string {
+	return "output format: " + strings.ToLower(strings.Join(filebasics.SupportedOutputFormats(), ", ")) +
+		". If not set, it is inferred from the output (or else the input) filename extension"
+}
+
+// getOutputFormat returns the value of the '--format' flag, validated. If the flag is not
+// set, the format is inferred from the extension of the '--output-file', or else the one of
+// the input file (see filebasics.InferOutputFormat).
+func getOutputFormat(cmd *cobra.Command, inputFilename string) (string, error) {
+	if !cmd.Flags().Changed("format") {
+		outputFilename := ""
+		if cmd.Flags().Lookup("output-file") != nil {
+			var err error
+			if outputFilename, err = cmd.Flags().GetString("output-file"); err != nil {
+				return "", fmt.Errorf("failed getting cli argument 'output-file'; %w", err)
+			}
+		}
+		return filebasics.InferOutputFormat(outputFilename, inputFilename), nil
+	}
+	outputFormat, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return "", fmt.Errorf("failed getting cli argument 'format'; %w", err)
 	}
+	return filebasics.ValidateOutputFormat(outputFormat)
 }
 
 func init() {
@@ -35,8 +77,13 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
+	rootCmd.SetFlagErrorFunc(flagErrorFunc)
+
 	rootCmd.PersistentFlags().Int("verbose", 0,
 		"this value sets the verbosity level of the log output (higher == more verbose)")
+	rootCmd.PersistentFlags().Bool("env-interpolate", false,
+		`replace '${VAR}' and '${VAR:-default}' placeholders in the input files by the values
+of the environment variables, before parsing. Fails on undefined variables without a default`)
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/kong/go-apiops/openapi2kong"
+	"github.com/spf13/cobra"
+)
+
+// Executes the CLI command "schema list"
+func executeSchemaList(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+
+	outputFilename, err := cmd.Flags().GetString("output-file")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'output-file'; %w", err)
+	}
+
+	output := []byte(strings.Join(openapi2kong.PluginSchemaNames(), "\n") + "\n")
+	return filebasics.WriteFile(outputFilename, &output)
+}
+
+// Executes the CLI command "schema export"
+func executeSchemaExport(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+
+	pluginName, err := cmd.Flags().GetString("plugin")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'plugin'; %w", err)
+	}
+	if pluginName == "" {
+		return usageError{errors.New("flag '--plugin' is required")}
+	}
+
+	outputFilename, err := cmd.Flags().GetString("output-file")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'output-file'; %w", err)
+	}
+
+	output, err := openapi2kong.GetPluginSchemaJSON(pluginName)
+	if err != nil {
+		return err
+	}
+	return filebasics.WriteFile(outputFilename, &output)
+}
+
+//
+//
+// Define the CLI data for the schema command
+//
+//
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Inspect the bundled plugin config schemas",
+	Long: `Inspect the bundled plugin config schemas.
+
+These are the JSON schemas used by 'openapi2kong' to validate the 'config' of the
+plugins, and can be reused by other tooling.`,
+	Args: noArgs,
+}
+
+var schemaListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the plugins with a bundled config schema",
+	Long:  `Lists the plugins with a bundled config schema, one per line.`,
+	RunE:  executeSchemaList,
+	Args:  noArgs,
+}
+
+var schemaExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Prints the bundled config schema of a plugin",
+	Long: `Prints the bundled JSON schema for the 'config' of a plugin, as used for
+validating the plugin configuration.`,
+	RunE: executeSchemaExport,
+	Args: noArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+	schemaCmd.AddCommand(schemaListCmd)
+	schemaCmd.AddCommand(schemaExportCmd)
+	schemaListCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
+	schemaExportCmd.Flags().String("plugin", "", "name of the plugin to export the schema of, eg. 'rate-limiting'")
+	schemaExportCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_schemaExport(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "schema.json")
+	defer schemaExportCmd.Flags().Set("plugin", "")
+	defer schemaExportCmd.Flags().Set("output-file", "-")
+
+	rootCmd.SetArgs([]string{"schema", "export", "--plugin", "rate-limiting", "-o", output})
+	require.NoError(t, rootCmd.Execute())
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &schema))
+	assert.Contains(t, schema, "properties")
+
+	rootCmd.SetArgs([]string{"schema", "export", "--plugin", "unknown", "-o", output})
+	assert.EqualError(t, rootCmd.Execute(), "no schema available for plugin 'unknown', expected one of "+
+		"'correlation-id', 'cors', 'key-auth', 'rate-limiting', 'request-termination'")
+}
+
+func Test_schemaList(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "list.txt")
+	defer schemaListCmd.Flags().Set("output-file", "-")
+
+	rootCmd.SetArgs([]string{"schema", "list", "-o", output})
+	require.NoError(t, rootCmd.Execute())
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Contains(t, strings.Split(string(data), "\n"), "rate-limiting")
+}
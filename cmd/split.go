@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/spf13/cobra"
+)
+
+const (
+	splitByType = "type"
+	splitByTag  = "tag"
+)
+
+// Executes the CLI command "split"
+func executeSplit(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+	if err := applyBackupFlag(cmd); err != nil {
+		return err
+	}
+
+	inputFilename, err := cmd.Flags().GetString("input")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'input'; %w", err)
+	}
+
+	outputDir, err := cmd.Flags().GetString("dir")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'dir'; %w", err)
+	}
+
+	splitBy, err := cmd.Flags().GetString("by")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'by'; %w", err)
+	}
+	if splitBy != splitByType && splitBy != splitByTag {
+		return usageError{fmt.Errorf("expected '--by' to be '%s' or '%s', got: '%s'", splitByType, splitByTag, splitBy)}
+	}
+
+	outputFormat, err := getOutputFormat(cmd, inputFilename)
+	if err != nil {
+		return err
+	}
+
+	// do the work: read/split/write
+	data, err := filebasics.DeserializeFile(inputFilename)
+	if err != nil {
+		return err
+	}
+
+	var parts map[string]map[string]interface{}
+	if splitBy == splitByType {
+		parts, err = deckformat.SplitByType(data)
+	} else {
+		parts, err = deckformat.SplitByTag(data)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to split '%s'; %w", inputFilename, err)
+	}
+
+	if err := os.MkdirAll(outputDir, 0o750); err != nil {
+		return fmt.Errorf("failed to create directory '%s'; %w", outputDir, err)
+	}
+
+	names := make([]string, 0, len(parts))
+	for name := range parts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	filenames := make([]string, 0, len(names))
+	for _, name := range names {
+		filename := filepath.Join(outputDir, name+"."+strings.ToLower(outputFormat))
+		logbasics.Info("writing file", "filename", filename)
+		if err := filebasics.WriteSerializedFile(filename, parts[name], outputFormat); err != nil {
+			return err
+		}
+		filenames = append(filenames, filename)
+	}
+	return writeManifest(cmd, filenames...)
+}
+
+//
+//
+// Define the CLI data for the split command
+//
+//
+
+var splitCmd = &cobra.Command{
+	Use:   "split",
+	Short: "Splits a decK file into multiple files",
+	Long: `Splits a decK file into multiple files, written to a directory.
+
+With '--by type' a file per entity type is written (eg. 'services.yaml', 'plugins.yaml').
+Nested entities remain with their parent (eg. routes nested in a service).
+
+With '--by tag' a file per tag is written. Top-level entities go into the file of
+their first tag, entities without tags go into 'untagged.yaml'.
+
+Each file is a valid decK file, with the '_format_version' and '_transform' keys
+of the input. The history is not copied. Merging the files (see the 'merge' command)
+reproduces the input, apart from the order of the entities.`,
+	RunE: executeSplit,
+	Args: noArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(splitCmd)
+	splitCmd.Flags().StringP("input", "i", "-", "decK file to split. Use - to read from stdin")
+	splitCmd.Flags().StringP("dir", "", ".", "directory to write the files to")
+	splitCmd.Flags().StringP("by", "", splitByType, "how to split the file: "+splitByType+" or "+splitByTag)
+	splitCmd.Flags().StringP("format", "", filebasics.OutputFormatYaml, outputFormatUsage())
+	addManifestFlag(splitCmd)
+	addBackupFlag(splitCmd)
+}
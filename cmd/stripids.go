@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/spf13/cobra"
+)
+
+// Executes the CLI command "strip-ids"
+func executeStripIDs(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+	if err := applyBackupFlag(cmd); err != nil {
+		return err
+	}
+
+	inputFilenames, err := getInputFilenames(cmd)
+	if err != nil {
+		return err
+	}
+	inputFilename := inputName(inputFilenames)
+
+	outputFormat, err := getOutputFormat(cmd, inputFilenames[0])
+	if err != nil {
+		return err
+	}
+
+	outputFilename, err := getOutputFilename(cmd, inputFilenames[0], outputFormat)
+	if err != nil {
+		return err
+	}
+
+	trackInfo := deckformat.HistoryNewEntry("strip-ids")
+	trackInfo["input"] = inputFilename
+	trackInfo["output"] = outputFilename
+
+	// do the work: read/strip/write
+	data, err := readInputFiles(inputFilenames)
+	if err != nil {
+		return err
+	}
+	if err := deckformat.StripIDs(data); err != nil {
+		return fmt.Errorf("failed to strip the ids from '%s'; %w", inputFilename, err)
+	}
+	if err := deckformat.HistoryTryAppend(data, trackInfo); err != nil {
+		return err
+	}
+	if err := filebasics.WriteSerializedFile(outputFilename, data, outputFormat); err != nil {
+		return err
+	}
+	return writeManifest(cmd, outputFilename)
+}
+
+//
+//
+// Define the CLI data for the strip-ids command
+//
+//
+
+var stripIDsCmd = &cobra.Command{
+	Use:   "strip-ids",
+	Short: "Removes the entity ids from a decK file, for name based workflows",
+	Long: `Removes the entity ids from a decK file, for name based workflows.
+
+The 'id' fields of all entities (including nested ones) are removed, such that Kong
+assigns its own. References by id to entities in the file (the 'service', 'route',
+'consumer', 'consumer_group', and 'upstream' fields) are rewritten to refer to the
+names. Fails, without writing anything, if an entity would be left without a name (or
+other field identifying it to decK), or if an entity without a name (eg. a
+certificate) is referred to by id.`,
+	PreRunE: validateOutputDirFlags("input"),
+	RunE:    executeStripIDs,
+	Args:    noArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(stripIDsCmd)
+	addInputFlag(stripIDsCmd, "decK file to process")
+	stripIDsCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
+	addOutputDirFlags(stripIDsCmd)
+	stripIDsCmd.Flags().StringP("format", "", filebasics.OutputFormatYaml, outputFormatUsage())
+	addManifestFlag(stripIDsCmd)
+	addBackupFlag(stripIDsCmd)
+}
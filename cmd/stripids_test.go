@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_stripIDs(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "kong.yaml")
+	output := filepath.Join(dir, "output.yaml")
+	require.NoError(t, os.WriteFile(input, []byte(`_format_version: "3.0"
+services:
+  - id: 0b4b3e3c-1f1c-4b2e-9e5d-2a1c3b4d5e6f
+    name: svc
+routes:
+  - id: 5d2c1b4a-3e2f-4a1b-8c7d-6e5f4a3b2c1d
+    name: route
+    service:
+      id: 0b4b3e3c-1f1c-4b2e-9e5d-2a1c3b4d5e6f
+`), 0o600))
+	defer resetInputFlag(stripIDsCmd)
+	defer stripIDsCmd.Flags().Set("output-file", "-")
+
+	rootCmd.SetArgs([]string{"strip-ids", "-i", input, "-o", output})
+	require.NoError(t, rootCmd.Execute())
+
+	data := filebasics.MustDeserializeFile(output)
+	service := data["services"].([]interface{})[0].(map[string]interface{})
+	assert.NotContains(t, service, "id")
+	assert.Equal(t, "svc", service["name"])
+	route := data["routes"].([]interface{})[0].(map[string]interface{})
+	assert.NotContains(t, route, "id")
+	assert.Equal(t, map[string]interface{}{"name": "svc"}, route["service"])
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/spf13/cobra"
+)
+
+const outputFormatTable = "TABLE"
+
+// Executes the CLI command "tags list"
+func executeTagsList(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+
+	inputFilename, err := cmd.Flags().GetString("input")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'input'; %w", err)
+	}
+
+	outputFilename, err := cmd.Flags().GetString("output-file")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'output-file'; %w", err)
+	}
+
+	var outputFormat string
+	{
+		outputFormat, err = cmd.Flags().GetString("format")
+		if err != nil {
+			return fmt.Errorf("failed getting cli argument 'format'; %w", err)
+		}
+		outputFormat = strings.ToUpper(outputFormat)
+		if outputFormat != outputFormatTable && outputFormat != filebasics.OutputFormatJSON {
+			return usageError{fmt.Errorf("expected '--format' to be 'table' or 'json', got: '%s'", outputFormat)}
+		}
+	}
+
+	// do the work: read/count/write
+	data, err := filebasics.DeserializeFile(inputFilename)
+	if err != nil {
+		return err
+	}
+	counts, err := deckformat.GetTagCounts(data)
+	if err != nil {
+		return fmt.Errorf("failed to collect tags from '%s'; %w", inputFilename, err)
+	}
+
+	if outputFormat == filebasics.OutputFormatJSON {
+		result := make([]interface{}, len(counts))
+		for i, count := range counts {
+			result[i] = map[string]interface{}{
+				"tag":   count.Tag,
+				"count": count.Count,
+			}
+		}
+		output, err := filebasics.Serialize(map[string]interface{}{"tags": result}, filebasics.OutputFormatJSON)
+		if err != nil {
+			return err
+		}
+		return filebasics.WriteFile(outputFilename, output)
+	}
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TAG\tCOUNT")
+	for _, count := range counts {
+		fmt.Fprintf(w, "%s\t%d\n", count.Tag, count.Count)
+	}
+	w.Flush()
+	output := buf.Bytes()
+	return filebasics.WriteFile(outputFilename, &output)
+}
+
+//
+//
+// Define the CLI data for the tags command
+//
+//
+
+var tagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "Inspect the tags in decK files",
+	Long:  `Inspect the tags in decK files.`,
+	Args:  noArgs,
+}
+
+var tagsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the tags in a decK file",
+	Long: `Lists the tags in a decK file, with the number of entities that have them.
+
+All entities are inspected, including nested ones (eg. routes nested in a service).
+Entities without tags are ignored. Useful to check the tags before doing a
+tag-scoped decK sync.`,
+	RunE: executeTagsList,
+	Args: noArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(tagsCmd)
+	tagsCmd.AddCommand(tagsListCmd)
+	tagsListCmd.Flags().StringP("input", "i", "-", "decK file to inspect. Use - to read from stdin")
+	tagsListCmd.Flags().StringP("output-file", "o", "-", "output file to write. Use - to write to stdout")
+	tagsListCmd.Flags().StringP("format", "", "table", "output format: table or json")
+}
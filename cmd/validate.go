@@ -0,0 +1,129 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	"github.com/kong/go-apiops/validate"
+	"github.com/spf13/cobra"
+)
+
+// Executes the CLI command "validate"
+func executeValidate(cmd *cobra.Command, _ []string) error {
+	verbosity, _ := cmd.Flags().GetInt("verbose")
+	logbasics.Initialize(log.LstdFlags, verbosity)
+
+	inputFilename, err := cmd.Flags().GetString("input")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'input'; %w", err)
+	}
+
+	kongVersion, err := cmd.Flags().GetString("kong-version")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'kong-version'; %w", err)
+	}
+	if kongVersion == "" {
+		return usageError{fmt.Errorf("flag '--kong-version' is required")}
+	}
+
+	fix, err := cmd.Flags().GetBool("fix")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'fix'; %w", err)
+	}
+
+	var fixOpts validate.FixOptions
+	if fixOpts.DefaultTags, err = cmd.Flags().GetStringArray("default-tag"); err != nil {
+		return fmt.Errorf("failed getting cli argument 'default-tag'; %w", err)
+	}
+	if len(fixOpts.DefaultTags) > 0 && !fix {
+		return usageError{errors.New("flag '--default-tag' can only be used with '--fix'")}
+	}
+
+	outputFormat, err := getOutputFormat(cmd, inputFilename)
+	if err != nil {
+		return err
+	}
+
+	outputFilename, err := cmd.Flags().GetString("output-file")
+	if err != nil {
+		return fmt.Errorf("failed getting cli argument 'output-file'; %w", err)
+	}
+
+	// do the work: read/fix/validate/write
+	data, err := filebasics.DeserializeFile(inputFilename)
+	if err != nil {
+		return err
+	}
+	if fix {
+		changes, err := validate.Fix(data, fixOpts)
+		if err != nil {
+			return fmt.Errorf("failed to fix '%s'; %w", inputFilename, err)
+		}
+		for _, change := range changes {
+			fmt.Fprintln(cmd.ErrOrStderr(), "FIXED: "+change)
+		}
+	}
+
+	// report all the problems at once
+	errs := deckformat.NewMultiError(validate.FormatVersion(data))
+	warnings, err := validate.Fields(data, kongVersion)
+	errs.Add(err)
+	for _, warning := range warnings {
+		logbasics.Warn(warning)
+	}
+	if err := errs.ErrorOrNil(); err != nil {
+		return fmt.Errorf("failed to validate '%s'; %w", inputFilename, err)
+	}
+
+	if !fix {
+		return nil
+	}
+	return filebasics.WriteSerializedFile(outputFilename, data, outputFormat)
+}
+
+//
+//
+// Define the CLI data for the validate command
+//
+//
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validates a decK file against a Kong version",
+	Long: `Validates a decK file against the entity schemas of a Kong version.
+
+Fields unknown to the target version (eg. added in a later version) are reported as
+warnings. Fields that were removed in the target version fail the validation. Only
+the field names of the core entities are checked, not their values. A missing, or
+malformed, '_format_version' fails the validation.
+
+With '--fix' the safe corrections are applied before validating, and the fixed file
+is written; a missing '_format_version' is added, null fields of the entities are
+removed, and entities without tags get the '--default-tag' values (if given). Every
+change is reported on stderr. Problems that cannot be fixed still fail the validation,
+and then no file is written. Fixing a fixed file makes no changes.
+
+Bundled Kong versions: ` + strings.Join(validate.SupportedKongVersions(), ", "),
+	RunE: executeValidate,
+	Args: noArgs,
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	validateCmd.Flags().StringP("input", "i", "-", "decK file to validate. Use - to read from stdin")
+	validateCmd.Flags().String("kong-version", "", "the Kong version to validate against, eg. 3.4")
+	validateCmd.Flags().Bool("fix", false, "apply the safe corrections, and write the fixed file")
+	validateCmd.Flags().StringArray("default-tag", []string{},
+		"with '--fix', a tag to add to the entities without tags. Can be repeated")
+	validateCmd.Flags().StringP("output-file", "o", "-", "with '--fix', the output file to write. "+
+		"Use - to write to stdout")
+	validateCmd.Flags().StringP("format", "", filebasics.OutputFormatYaml, outputFormatUsage())
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/validate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_validateFix(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "kong.yaml")
+	output := filepath.Join(dir, "fixed.yaml")
+	require.NoError(t, os.WriteFile(input, []byte("services:\n- name: svc\n  host: example.com\n"), 0o600))
+	defer validateCmd.Flags().Set("fix", "false")
+	defer validateCmd.Flags().Set("output-file", "-")
+	stderr := &bytes.Buffer{}
+	rootCmd.SetErr(stderr)
+	defer rootCmd.SetErr(nil)
+
+	// without fixing, the missing format version fails
+	rootCmd.SetArgs([]string{"validate", "-i", input, "--kong-version", "3.4"})
+	assert.ErrorIs(t, rootCmd.Execute(), validate.ErrFormatVersion)
+
+	// fix and write it
+	rootCmd.SetArgs([]string{"validate", "-i", input, "--kong-version", "3.4", "--fix", "-o", output})
+	require.NoError(t, rootCmd.Execute())
+	assert.Contains(t, stderr.String(), "FIXED: added '_format_version: 3.0'")
+	data, err := filebasics.DeserializeFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "3.0", data["_format_version"])
+
+	// the fixed file validates, and fixing it again changes nothing
+	require.NoError(t, validateCmd.Flags().Set("fix", "false"))
+	rootCmd.SetArgs([]string{"validate", "-i", output, "--kong-version", "3.4"})
+	require.NoError(t, rootCmd.Execute())
+
+	stderr.Reset()
+	rootCmd.SetArgs([]string{"validate", "-i", output, "--kong-version", "3.4", "--fix", "-o", output})
+	require.NoError(t, rootCmd.Execute())
+	assert.NotContains(t, stderr.String(), "FIXED:")
+}
+
+func Test_validateReportsAllProblems(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "kong.yaml")
+	require.NoError(t, os.WriteFile(input, []byte("plugins:\n- name: cors\n  run_on: first\n"), 0o600))
+
+	// both the missing format version, and the removed field, are reported
+	rootCmd.SetArgs([]string{"validate", "-i", input, "--kong-version", "3.4"})
+	err := rootCmd.Execute()
+	assert.ErrorIs(t, err, validate.ErrFormatVersion)
+	assert.ErrorIs(t, err, validate.ErrRemovedFields)
+	assert.Contains(t, err.Error(), "plugins 'cors': field 'run_on' was removed in Kong 3.x")
+}
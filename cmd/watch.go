@@ -0,0 +1,84 @@
+/*
+Copyright © 2023 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/kong/go-apiops/logbasics"
+)
+
+const watchDebounce = 500 * time.Millisecond // how long a changed file must be stable before acting
+
+// newFileWatcher returns a watcher for the directory of the file. The directory is watched,
+// instead of the file itself, since editors often replace a file (rename over it) when
+// saving, which would end a watch on the file.
+func newFileWatcher(filename string) (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch '%s'; %w", filename, err)
+	}
+	if err := watcher.Add(filepath.Dir(filename)); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch '%s'; %w", filename, err)
+	}
+	return watcher, nil
+}
+
+// watchFile calls onChange each time the file changes, until the context is done. Rapid
+// edits are debounced; onChange is only called once the file has been unchanged for the
+// debounce duration.
+func watchFile(ctx context.Context, filename string, debounce time.Duration, onChange func()) error {
+	watcher, err := newFileWatcher(filename)
+	if err != nil {
+		return err
+	}
+	watchEvents(ctx, watcher, filename, debounce, onChange)
+	return nil
+}
+
+// watchEvents handles the events of the watcher (see newFileWatcher) for the file, until
+// the context is done, and closes the watcher.
+func watchEvents(ctx context.Context, watcher *fsnotify.Watcher, filename string, debounce time.Duration,
+	onChange func(),
+) {
+	defer watcher.Close()
+	base := filepath.Base(filename)
+
+	debounced := time.NewTimer(debounce)
+	debounced.Stop()
+	defer debounced.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Base(event.Name) != base || event.Op == fsnotify.Chmod {
+				continue // another file in the directory, or the content did not change
+			}
+			// restart the debounce, dropping a pending expiry of the previous one
+			if !debounced.Stop() {
+				select {
+				case <-debounced.C:
+				default:
+				}
+			}
+			debounced.Reset(debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logbasics.Error(err, "error watching the file, watching continues", "file", filename)
+		case <-debounced.C:
+			onChange()
+		}
+	}
+}
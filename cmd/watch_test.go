@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_watchFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "spec.yaml")
+	require.NoError(t, os.WriteFile(filename, []byte("a"), 0o600))
+
+	// the watcher is ready once created, so no change is missed
+	watcher, err := newFileWatcher(filename)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan string, 10) // the content at each regeneration
+	done := make(chan struct{})
+	go func() {
+		watchEvents(ctx, watcher, filename, 200*time.Millisecond, func() {
+			content, _ := os.ReadFile(filename)
+			changes <- string(content)
+		})
+		close(done)
+	}()
+
+	// other files in the directory are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.yaml"), []byte("a"), 0o600))
+
+	// rapid edits, with the same size, and a replace (as editors do when saving)
+	for _, content := range []string{"b", "c", "d"} {
+		require.NoError(t, os.WriteFile(filename, []byte(content), 0o600))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "spec.yaml.tmp"), []byte("e"), 0o600))
+	require.NoError(t, os.Rename(filepath.Join(dir, "spec.yaml.tmp"), filename))
+
+	select {
+	case content := <-changes:
+		assert.Equal(t, "e", content)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a regeneration after the file changed")
+	}
+
+	// the next edit triggers the next regeneration
+	require.NoError(t, os.WriteFile(filename, []byte("f"), 0o600))
+	select {
+	case content := <-changes:
+		assert.Equal(t, "f", content, "expected the rapid edits to trigger a single regeneration")
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a regeneration after the file changed again")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected watchFile to return when the context is done")
+	}
+	assert.Len(t, changes, 0)
+}
+
+func Test_watchFileMissingDirectory(t *testing.T) {
+	err := watchFile(context.Background(), filepath.Join(t.TempDir(), "missing", "spec.yaml"),
+		watchDebounce, func() {})
+	assert.ErrorContains(t, err, "failed to watch")
+}
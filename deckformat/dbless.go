@@ -8,8 +8,12 @@ import (
 
 // ConvertDBless converts a DBless format to a decK type format. This updates the
 // consumer-groups related entities into nested objects, since that is an operation
-// that is too complex to do via a CLI.
+// that is too complex to do via a CLI. Returns ErrNilDocument if data is nil.
 func ConvertDBless(data map[string]interface{}) (map[string]interface{}, error) {
+	if data == nil {
+		return nil, ErrNilDocument
+	}
+
 	// Step 1.
 	// Rename "consumer_groups[*].consumer_group_plugins" to "consumer_groups[*].plugins", in case
 	// nested entries already exist.
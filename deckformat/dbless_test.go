@@ -222,5 +222,10 @@ var _ = Describe("deckformat", func() {
 			Expect(err.Error()).To(ContainSubstring(
 				"consumer_group 'A-team' referenced by 'consumer_group_plugins[0]' not found"))
 		})
+
+		It("returns an error if data is nil", func() {
+			_, err := ConvertDBless(nil)
+			Expect(err).To(MatchError(ErrNilDocument))
+		})
 	})
 })
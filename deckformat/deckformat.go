@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/kong/go-apiops/jsonbasics"
 )
@@ -15,6 +16,108 @@ const (
 	HistoryKey   = "_ignore" // the top-level key in deck files for storing history info
 )
 
+// ErrNilDocument is returned when a nil document is passed where a deck file is expected.
+var ErrNilDocument = errors.New("expected a non-nil deck file")
+
+// ErrIncompatible is matched by the errors returned when files are not compatible.
+var ErrIncompatible = errors.New("files are incompatible")
+
+// incompatibleError wraps the reason files are incompatible. It matches ErrIncompatible
+// as well as the wrapped reason.
+type incompatibleError struct {
+	reason error
+}
+
+func (e incompatibleError) Error() string {
+	return ErrIncompatible.Error() + "; " + e.reason.Error()
+}
+
+func (e incompatibleError) Unwrap() error {
+	return e.reason
+}
+
+func (e incompatibleError) Is(target error) bool {
+	return target == ErrIncompatible
+}
+
+//
+//
+//  Configuring the top-level (meta) keys used in deck files
+//
+//
+
+// Config defines the top-level keys used in deck files. Empty keys default to the
+// VersionKey, TransformKey, and HistoryKey constants.
+type Config struct {
+	VersionKey   string // key holding the format version
+	TransformKey string // key holding the transform flag
+	HistoryKey   string // key for storing history info
+	// Store the history in the file. By default it is cleared on every update, since decK
+	// does not (yet) support metafields.
+	KeepHistory bool
+	// The default of the transform flag for files omitting it, by the major format version
+	// of the file. Versions not listed, and files without a (valid) version, default to true.
+	TransformDefaults map[int]bool
+}
+
+var config = struct {
+	sync.RWMutex
+	current Config
+}{
+	current: Config{
+		VersionKey:   VersionKey,
+		TransformKey: TransformKey,
+		HistoryKey:   HistoryKey,
+	},
+}
+
+// copyTransformDefaults returns a copy of the map, such that the config does not share it.
+func copyTransformDefaults(defaults map[int]bool) map[int]bool {
+	if defaults == nil {
+		return nil
+	}
+	result := make(map[int]bool, len(defaults))
+	for major, transform := range defaults {
+		result[major] = transform
+	}
+	return result
+}
+
+// ConfigSet sets the keys used by all functions in this package. Empty keys are set
+// to their default values. This is global state; set it once at startup. It is safe for
+// concurrent use, but functions running concurrently may use either the old or the new
+// config.
+func ConfigSet(newConfig Config) {
+	if newConfig.VersionKey == "" {
+		newConfig.VersionKey = VersionKey
+	}
+	if newConfig.TransformKey == "" {
+		newConfig.TransformKey = TransformKey
+	}
+	if newConfig.HistoryKey == "" {
+		newConfig.HistoryKey = HistoryKey
+	}
+	newConfig.TransformDefaults = copyTransformDefaults(newConfig.TransformDefaults)
+	config.Lock()
+	defer config.Unlock()
+	config.current = newConfig
+}
+
+// ConfigGet returns the keys currently in use.
+func ConfigGet() Config {
+	result := getConfig()
+	result.TransformDefaults = copyTransformDefaults(result.TransformDefaults)
+	return result
+}
+
+// getConfig returns the config in use, without copying the TransformDefaults, which must
+// not be modified.
+func getConfig() Config {
+	config.RLock()
+	defer config.RUnlock()
+	return config.current
+}
+
 //
 //
 //  Keeping track of the tool/binary version info (set once at startup)
@@ -22,6 +125,7 @@ const (
 //
 
 var toolInfo = struct {
+	sync.Mutex
 	name    string
 	version string
 	commit  string
@@ -29,10 +133,19 @@ var toolInfo = struct {
 
 // ToolVersionSet can be called once to set the tool info that is reported in the history.
 // The 'version' and 'commit' strings are optional. Omitting them (lower cardinality) makes
-// for a better GitOps experience, but provides less detail.
+// for a better GitOps experience, but provides less detail. It is safe for concurrent use;
+// calling it again with the same info is a no-op, with different info it panics.
 func ToolVersionSet(name string, version string, commit string) {
-	if toolInfo.name != "" || name == "" {
-		panic("the tool information was already set, or cannot be set to an empty string")
+	if name == "" {
+		panic("the tool information cannot be set to an empty string")
+	}
+	toolInfo.Lock()
+	defer toolInfo.Unlock()
+	if toolInfo.name != "" {
+		if toolInfo.name == name && toolInfo.version == version && toolInfo.commit == commit {
+			return
+		}
+		panic("the tool information was already set")
 	}
 	toolInfo.name = name
 	toolInfo.version = version
@@ -41,15 +154,32 @@ func ToolVersionSet(name string, version string, commit string) {
 
 // ToolVersionGet returns the individual components of the info
 func ToolVersionGet() (name string, version string, commit string) {
-	if toolInfo.name == "" {
+	name, version, commit, ok := ToolVersionTryGet()
+	if !ok {
 		panic("the tool information wasn't set, call ToolVersionSet first")
 	}
-	return toolInfo.name, toolInfo.version, toolInfo.commit
+	return name, version, commit
+}
+
+// ToolVersionTryGet returns the individual components of the info, like ToolVersionGet. It
+// returns ok == false, instead of panicking, if the tool info wasn't set.
+func ToolVersionTryGet() (name string, version string, commit string, ok bool) {
+	toolInfo.Lock()
+	defer toolInfo.Unlock()
+	if toolInfo.name == "" {
+		return "", "", "", false
+	}
+	return toolInfo.name, toolInfo.version, toolInfo.commit, true
 }
 
 // ToolVersionString returns the info in a single formatted string. eg. "decK 1.2 (123abc)"
 func ToolVersionString() string {
 	n, v, c := ToolVersionGet()
+	return formatToolVersion(n, v, c)
+}
+
+// formatToolVersion formats the tool info components, see ToolVersionString.
+func formatToolVersion(n string, v string, c string) string {
 	if c != "" {
 		return fmt.Sprintf("%s %s (%s)", n, v, c)
 	}
@@ -65,34 +195,74 @@ func ToolVersionString() string {
 //
 //
 
-// CompatibleTransform checks if 2 files are compatible, by '_transform' keys.
-// Returns nil if compatible, and error otherwise.
-func CompatibleTransform(data1 map[string]interface{}, data2 map[string]interface{}) error {
-	if data1 == nil {
-		panic("expected 'data1' to be non-nil")
+// getTransformDefault returns the default value of the '_transform' field for the file, based
+// on its '_format_version' (see Config.TransformDefaults); true if the version is not listed.
+func getTransformDefault(filedata map[string]interface{}) bool {
+	cfg := getConfig()
+	if filedata == nil || filedata[cfg.VersionKey] == nil {
+		return true
+	}
+	major, _, err := ParseFormatVersion(filedata)
+	if err != nil {
+		return true
 	}
-	if data2 == nil {
-		panic("expected 'data2' to be non-nil")
+	if transform, found := cfg.TransformDefaults[major]; found {
+		return transform
 	}
+	return true
+}
 
-	transform1 := true // this is the default value
-	if data1[TransformKey] != nil {
-		var err error
-		if transform1, err = jsonbasics.GetBoolField(data1, TransformKey); err != nil {
-			return err
-		}
+// GetTransform returns the value of the '_transform' field. If absent it returns the
+// default value for the '_format_version' of the file (see Config.TransformDefaults),
+// true by default. Returns an error if the field is not a boolean.
+func GetTransform(filedata map[string]interface{}) (bool, error) {
+	cfg := getConfig()
+	if filedata == nil || filedata[cfg.TransformKey] == nil {
+		return getTransformDefault(filedata), nil
 	}
-	transform2 := true // this is the default value
-	if data2[TransformKey] != nil {
-		var err error
-		if transform2, err = jsonbasics.GetBoolField(data2, TransformKey); err != nil {
-			return err
-		}
+	return jsonbasics.GetBoolField(filedata, cfg.TransformKey)
+}
+
+// SetTransform sets the value of the '_transform' field. Panics if filedata is nil, use
+// TrySetTransform to get an error instead.
+func SetTransform(filedata map[string]interface{}, transform bool) {
+	if err := TrySetTransform(filedata, transform); err != nil {
+		panic(err)
+	}
+}
+
+// TrySetTransform sets the value of the '_transform' field, like SetTransform. Returns
+// ErrNilDocument if filedata is nil.
+func TrySetTransform(filedata map[string]interface{}, transform bool) error {
+	cfg := getConfig()
+	if filedata == nil {
+		return ErrNilDocument
+	}
+	filedata[cfg.TransformKey] = transform
+	return nil
+}
+
+// CompatibleTransform checks if 2 files are compatible, by '_transform' keys. Omitted keys
+// take the default for the '_format_version' of the file (see GetTransform).
+// Returns nil if compatible, and error otherwise (ErrNilDocument if either is nil).
+func CompatibleTransform(data1 map[string]interface{}, data2 map[string]interface{}) error {
+	cfg := getConfig()
+	if data1 == nil || data2 == nil {
+		return ErrNilDocument
+	}
+
+	transform1, err := GetTransform(data1)
+	if err != nil {
+		return err
+	}
+	transform2, err := GetTransform(data2)
+	if err != nil {
+		return err
 	}
 
 	if transform1 != transform2 {
-		return errors.New("files with '" + TransformKey + ": true' (default) and '" +
-			TransformKey + ": false' are not compatible")
+		return errors.New("files with '" + cfg.TransformKey + ": true' (default) and '" +
+			cfg.TransformKey + ": false' are not compatible")
 	}
 
 	return nil
@@ -100,17 +270,15 @@ func CompatibleTransform(data1 map[string]interface{}, data2 map[string]interfac
 
 // CompatibleVersion checks if 2 files are compatible, by '_format_version'. Version is compatible
 // if they are the same major. Missing versions are assumed to be compatible.
-// Returns nil if compatible, and error otherwise.
+// Returns nil if compatible, and error otherwise (ErrNilDocument if either is nil).
 func CompatibleVersion(data1 map[string]interface{}, data2 map[string]interface{}) error {
-	if data1 == nil {
-		panic("expected 'data1' to be non-nil")
-	}
-	if data2 == nil {
-		panic("expected 'data2' to be non-nil")
+	cfg := getConfig()
+	if data1 == nil || data2 == nil {
+		return ErrNilDocument
 	}
 
-	if data1[VersionKey] == nil {
-		if data2[VersionKey] == nil {
+	if data1[cfg.VersionKey] == nil {
+		if data2[cfg.VersionKey] == nil {
 			return nil // neither given , so assume compatible
 		}
 		// data1 omitted, just validate data2 has a proper version, any version will do
@@ -119,7 +287,7 @@ func CompatibleVersion(data1 map[string]interface{}, data2 map[string]interface{
 	}
 
 	// data1 has a version
-	if data2[VersionKey] == nil {
+	if data2[cfg.VersionKey] == nil {
 		// data2 omitted, just validate data1 has a proper version, any version will do
 		_, _, err := ParseFormatVersion(data1)
 		return err
@@ -142,49 +310,110 @@ func CompatibleVersion(data1 map[string]interface{}, data2 map[string]interface{
 	return nil
 }
 
-// CompatibleFile returns nil if the files are compatible. An error otherwise.
-// see CompatibleVersion and CompatibleTransform for what compatibility means.
+// CompatibleFile returns nil if the files are compatible. An error matching ErrIncompatible
+// otherwise. See CompatibleVersion and CompatibleTransform for what compatibility means.
 func CompatibleFile(data1 map[string]interface{}, data2 map[string]interface{}) error {
-	err := CompatibleTransform(data1, data2)
-	if err != nil {
-		return fmt.Errorf("files are incompatible; %w", err)
-	}
-	err = CompatibleVersion(data1, data2)
-	if err != nil {
-		return fmt.Errorf("files are incompatible; %w", err)
+	if reasons := CompatibleFileReasons(data1, data2); len(reasons) > 0 {
+		return reasons[0]
 	}
 	return nil
 }
 
+// CompatibleFileReasons is the same as CompatibleFile, but returns all the reasons the files
+// are incompatible (transform and version), instead of only the first. Each error matches
+// ErrIncompatible. Returns nil if the files are compatible.
+func CompatibleFileReasons(data1 map[string]interface{}, data2 map[string]interface{}) []error {
+	if data1 == nil || data2 == nil {
+		return []error{incompatibleError{ErrNilDocument}}
+	}
+
+	var reasons []error
+	if err := CompatibleTransform(data1, data2); err != nil {
+		reasons = append(reasons, incompatibleError{err})
+	}
+	if err := CompatibleVersion(data1, data2); err != nil {
+		reasons = append(reasons, incompatibleError{err})
+	}
+	return reasons
+}
+
 // parseFormatVersion parses field `_format_version` and returns major+minor.
 // Field must be present, a string, and have an 'x.y' format. Returns an error otherwise.
 func ParseFormatVersion(data map[string]interface{}) (int, int, error) {
+	cfg := getConfig()
 	// get the file version and check it
-	v, err := jsonbasics.GetStringField(data, VersionKey)
+	v, err := jsonbasics.GetStringField(data, cfg.VersionKey)
 	if err != nil {
-		return 0, 0, errors.New("expected field '." + VersionKey + "' to be a string in 'x.y' format")
+		return 0, 0, errors.New("expected field '." + cfg.VersionKey + "' to be a string in 'x.y' format")
 	}
 	elem := strings.Split(v, ".")
 	if len(elem) > 2 {
-		return 0, 0, errors.New("expected field '." + VersionKey + "' to be a string in 'x.y' format")
+		return 0, 0, errors.New("expected field '." + cfg.VersionKey + "' to be a string in 'x.y' format")
 	}
 
 	majorVersion, err := strconv.Atoi(elem[0])
 	if err != nil {
-		return 0, 0, errors.New("expected field '." + VersionKey + "' to be a string in 'x.y' format")
+		return 0, 0, errors.New("expected field '." + cfg.VersionKey + "' to be a string in 'x.y' format")
 	}
 
 	minorVersion := 0
 	if len(elem) > 1 {
 		minorVersion, err = strconv.Atoi(elem[1])
 		if err != nil {
-			return 0, 0, errors.New("expected field '." + VersionKey + "' to be a string in 'x.y' format")
+			return 0, 0, errors.New("expected field '." + cfg.VersionKey + "' to be a string in 'x.y' format")
 		}
 	}
 
 	return majorVersion, minorVersion, nil
 }
 
+// UpgradeInfo describes the change between 2 format versions.
+type UpgradeInfo struct {
+	FromMajor int
+	FromMinor int
+	ToMajor   int
+	ToMinor   int
+	MajorBump bool // if true, the major version changed and the file requires a transform
+}
+
+// Description returns a short human readable description of the upgrade.
+func (info UpgradeInfo) Description() string {
+	from := fmt.Sprintf("%d.%d", info.FromMajor, info.FromMinor)
+	to := fmt.Sprintf("%d.%d", info.ToMajor, info.ToMinor)
+	if info.MajorBump {
+		return "major upgrade from " + from + " to " + to + "; the file must be transformed"
+	}
+	if info.FromMinor != info.ToMinor {
+		return "minor upgrade from " + from + " to " + to + "; the file is compatible"
+	}
+	return "no upgrade needed, version " + from + " is current"
+}
+
+// VersionUpgradePath parses 2 '_format_version' strings and returns the upgrade info for
+// moving a file from version 'from' to version 'to'. Returns an error if either version is
+// invalid, or if 'to' is older than 'from'.
+func VersionUpgradePath(from string, to string) (UpgradeInfo, error) {
+	cfg := getConfig()
+	var info UpgradeInfo
+	var err error
+
+	info.FromMajor, info.FromMinor, err = ParseFormatVersion(map[string]interface{}{cfg.VersionKey: from})
+	if err != nil {
+		return UpgradeInfo{}, fmt.Errorf("invalid 'from' version '%s'; %w", from, err)
+	}
+	info.ToMajor, info.ToMinor, err = ParseFormatVersion(map[string]interface{}{cfg.VersionKey: to})
+	if err != nil {
+		return UpgradeInfo{}, fmt.Errorf("invalid 'to' version '%s'; %w", to, err)
+	}
+
+	if info.ToMajor < info.FromMajor || (info.ToMajor == info.FromMajor && info.ToMinor < info.FromMinor) {
+		return UpgradeInfo{}, fmt.Errorf("cannot upgrade from version '%s' to older version '%s'", from, to)
+	}
+
+	info.MajorBump = info.ToMajor != info.FromMajor
+	return info, nil
+}
+
 //
 //
 //  Section for tracking history of the file
@@ -194,51 +423,256 @@ func ParseFormatVersion(data map[string]interface{}) (int, int, error) {
 // HistoryGet returns a the history info array. If there is none, or if filedata is nil,
 // it will return an empty one.
 func HistoryGet(filedata map[string]interface{}) (historyArray []interface{}) {
-	if filedata == nil || filedata[HistoryKey] == nil {
+	cfg := getConfig()
+	if filedata == nil || filedata[cfg.HistoryKey] == nil {
 		historyInfo := make([]interface{}, 0)
 		return historyInfo
 	}
 
-	trackInfo, err := jsonbasics.ToArray(filedata[HistoryKey])
+	trackInfo, err := jsonbasics.ToArray(filedata[cfg.HistoryKey])
 	if err != nil {
 		// the entry wasn't an array, so wrap it in one
-		trackInfo = []interface{}{filedata[HistoryKey]}
+		trackInfo = []interface{}{filedata[cfg.HistoryKey]}
 	}
 
 	// Return a copy
 	return *jsonbasics.DeepCopyArray(&trackInfo)
 }
 
-// HistorySet sets the history info array. Setting to nil will delete the history.
+// HistorySet sets the history info array. Setting to nil will delete the history. Panics
+// if filedata is nil, use HistoryTrySet to get an error instead.
 func HistorySet(filedata map[string]interface{}, historyArray []interface{}) {
+	if err := HistoryTrySet(filedata, historyArray); err != nil {
+		panic(err)
+	}
+}
+
+// HistoryTrySet sets the history info array, like HistorySet. Returns ErrNilDocument if
+// filedata is nil.
+func HistoryTrySet(filedata map[string]interface{}, historyArray []interface{}) error {
+	return historySet(filedata, historyArray, getConfig().KeepHistory)
+}
+
+// HistorySetKeep sets the history info array, like HistoryTrySet, but stores it regardless
+// of Config.KeepHistory. For callers that explicitly want to retain the history (eg. a
+// '--keep-history' flag). Returns ErrNilDocument if filedata is nil.
+func HistorySetKeep(filedata map[string]interface{}, historyArray []interface{}) error {
+	return historySet(filedata, historyArray, true)
+}
+
+// historySet sets the history info array, and only stores it if 'keep' is set.
+func historySet(filedata map[string]interface{}, historyArray []interface{}, keep bool) error {
+	if filedata == nil {
+		return ErrNilDocument
+	}
 	if historyArray == nil {
 		HistoryClear(filedata)
-		return
+		return nil
 	}
-	filedata[HistoryKey] = historyArray
+	filedata[getConfig().HistoryKey] = historyArray
 
 	// TODO: remove this after the we get support for metafields in deck
-	HistoryClear(filedata)
+	if !keep {
+		HistoryClear(filedata)
+	}
+	return nil
 }
 
 // HistoryAppend appends an entry (if non-nil) to the history info array. If there is
-// no array, it will create one.
+// no array, it will create one. Panics if filedata is nil, use HistoryTryAppend to get an
+// error instead.
 func HistoryAppend(filedata map[string]interface{}, newEntry interface{}) {
+	if err := HistoryTryAppend(filedata, newEntry); err != nil {
+		panic(err)
+	}
+}
+
+// HistoryTryAppend appends an entry to the history info array, like HistoryAppend. Returns
+// ErrNilDocument if filedata is nil.
+func HistoryTryAppend(filedata map[string]interface{}, newEntry interface{}) error {
 	hist := HistoryGet(filedata)
 	hist = append(hist, newEntry)
-	HistorySet(filedata, hist)
+	return HistoryTrySet(filedata, hist)
+}
+
+// HistoryNormalize rewrites the history info in its canonical form; always an array, and
+// removed if empty. Returns ErrNilDocument if filedata is nil.
+func HistoryNormalize(filedata map[string]interface{}) error {
+	if filedata == nil {
+		return ErrNilDocument
+	}
+	hist := HistoryGet(filedata)
+	if len(hist) == 0 {
+		HistoryClear(filedata)
+		return nil
+	}
+	return HistoryTrySet(filedata, hist)
+}
+
+// HistoryMerge concatenates the history info arrays (eg. of multiple files being merged),
+// in order. Entries that are equal to an earlier one are dropped. Always returns an array.
+func HistoryMerge(histories ...[]interface{}) []interface{} {
+	result := make([]interface{}, 0)
+	for _, history := range histories {
+		for _, entry := range history {
+			duplicate := false
+			for _, existing := range result {
+				if jsonbasics.EqualJSON(existing, entry) {
+					duplicate = true
+					break
+				}
+			}
+			if !duplicate {
+				result = append(result, entry)
+			}
+		}
+	}
+	return result
 }
 
 func HistoryClear(filedata map[string]interface{}) {
-	delete(filedata, HistoryKey)
+	cfg := getConfig()
+	delete(filedata, cfg.HistoryKey)
 }
 
-// HistoryNewEntry returns a new JSONobject with tool version and command keys set.
+// HistoryMigrate moves the history info array from the current history key (see Config)
+// to the top-level 'targetKey' (eg. "_meta_history"), removing the old key. The history
+// is normalized to an array. Set Config.HistoryKey to 'targetKey' afterwards, to read it
+// with HistoryGet. Returns ErrNilDocument if filedata is nil, and an error if 'targetKey'
+// is empty, or already holds data.
+func HistoryMigrate(filedata map[string]interface{}, targetKey string) error {
+	cfg := getConfig()
+	if filedata == nil {
+		return ErrNilDocument
+	}
+	if targetKey == "" {
+		return errors.New("expected a non-empty target key to migrate the history to")
+	}
+	if targetKey == cfg.HistoryKey || filedata[cfg.HistoryKey] == nil {
+		return nil // nothing to migrate
+	}
+	if filedata[targetKey] != nil {
+		return fmt.Errorf("cannot migrate the history to '%s', the key is already in use", targetKey)
+	}
+
+	filedata[targetKey] = HistoryGet(filedata)
+	HistoryClear(filedata)
+	return nil
+}
+
+// HistorySummary is the summary of a history entry, for display purposes.
+type HistorySummary struct {
+	Tool    string `json:"tool"`
+	Command string `json:"command"`
+	Input   string `json:"input"`
+	Output  string `json:"output"`
+}
+
+// historyField returns the string representation of a history field. Arrays (eg. the
+// "files" of a merge) are joined by ", ".
+func historyField(entry map[string]interface{}, fieldName string) string {
+	switch value := entry[fieldName].(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []interface{}:
+		elements := make([]string, len(value))
+		for i, element := range value {
+			if obj, ok := element.(map[string]interface{}); ok && obj["filename"] != nil {
+				elements[i] = fmt.Sprint(obj["filename"]) // the file info as recorded by merge
+			} else {
+				elements[i] = fmt.Sprint(element)
+			}
+		}
+		return strings.Join(elements, ", ")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// HistoryGetSummary returns the summaries of the history entries, in order. The input is
+// taken from the "input" field, or the "files" field if there is no input. Entries that are
+// not objects are reported as the command.
+func HistoryGetSummary(filedata map[string]interface{}) []HistorySummary {
+	hist := HistoryGet(filedata)
+	result := make([]HistorySummary, len(hist))
+	for i, entry := range hist {
+		obj, err := jsonbasics.ToObject(entry)
+		if err != nil {
+			result[i] = HistorySummary{Command: fmt.Sprint(entry)}
+			continue
+		}
+		input := historyField(obj, "input")
+		if input == "" {
+			input = historyField(obj, "files")
+		}
+		result[i] = HistorySummary{
+			Tool:    historyField(obj, "tool"),
+			Command: historyField(obj, "command"),
+			Input:   input,
+			Output:  historyField(obj, "output"),
+		}
+	}
+	return result
+}
+
+// HistoryCommands returns the 'command' values of the history entries, in order. Entries
+// without a command are skipped. If 'dedupe' is true, only the first occurrence of each
+// command is returned.
+func HistoryCommands(filedata map[string]interface{}, dedupe bool) []string {
+	commands := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, entry := range HistoryGet(filedata) {
+		obj, err := jsonbasics.ToObject(entry)
+		if err != nil {
+			continue
+		}
+		command, err := jsonbasics.GetStringField(obj, "command")
+		if err != nil || command == "" {
+			continue
+		}
+		if dedupe && seen[command] {
+			continue
+		}
+		seen[command] = true
+		commands = append(commands, command)
+	}
+	return commands
+}
+
+// HistoryRequire returns nil if the history has an entry with a 'tool' starting with
+// 'tool' (to tolerate version suffixes, eg. "kced 1.2" matches "kced 1.2.3 (abc123)"),
+// and the exact 'command'. An empty 'tool' or 'command' matches any. Returns an error
+// describing the missing entry otherwise, and ErrNilDocument if filedata is nil.
+func HistoryRequire(filedata map[string]interface{}, tool string, command string) error {
+	if filedata == nil {
+		return ErrNilDocument
+	}
+	for _, entry := range HistoryGet(filedata) {
+		obj, err := jsonbasics.ToObject(entry)
+		if err != nil {
+			continue
+		}
+		entryTool, _ := jsonbasics.GetStringField(obj, "tool")
+		entryCommand, _ := jsonbasics.GetStringField(obj, "command")
+		if strings.HasPrefix(entryTool, tool) && (command == "" || entryCommand == command) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no history entry found with tool '%s' and command '%s'", tool, command)
+}
+
+// HistoryNewEntry returns a new JSONobject with tool version and command keys set. The
+// tool key is omitted if the tool info wasn't set, see ToolVersionSet.
 func HistoryNewEntry(cmd string) map[string]interface{} {
-	return map[string]interface{}{
-		"tool":    ToolVersionString(),
+	entry := map[string]interface{}{
 		"command": cmd,
 		// For now: no timestamps in git-ops!
 		// "time":    time.Now().UTC().Format("2006-01-02T15:04:05.000Z"), // ISO8601 format
 	}
+	if n, v, c, ok := ToolVersionTryGet(); ok {
+		entry["tool"] = formatToolVersion(n, v, c)
+	}
+	return entry
 }
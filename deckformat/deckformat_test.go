@@ -1,7 +1,10 @@
 package deckformat_test
 
 import (
+	"sync"
+
 	. "github.com/kong/go-apiops/deckformat"
+	. "github.com/kong/go-apiops/filebasics"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -97,9 +100,133 @@ var _ = Describe("deckformat", func() {
 				ToolVersionSet("another name", "1.2.3", "commit-xyz")
 			}).Should(Panic())
 		})
+
+		It("ToolVersionSet is a no-op when called again with the same info", func() {
+			var wg sync.WaitGroup
+			panics := make(chan interface{}, 10)
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer func() {
+						if r := recover(); r != nil {
+							panics <- r
+						}
+					}()
+					ToolVersionSet("my-name", "1.2.3", "commit-xyz")
+				}()
+			}
+			wg.Wait()
+			close(panics)
+			Expect(panics).To(BeEmpty())
+			Expect(ToolVersionString()).Should(Equal("my-name 1.2.3 (commit-xyz)"))
+
+			Expect(func() {
+				ToolVersionSet("my-name", "1.2.4", "commit-xyz")
+			}).Should(Panic())
+		})
+
+		Context("when the tool info is unset", func() {
+			var name, version, commit string
+			var wasSet bool
+
+			BeforeEach(func() {
+				name, version, commit, wasSet = ToolVersionTryGet()
+				ToolVersionReset()
+			})
+			AfterEach(func() {
+				ToolVersionReset()
+				if wasSet {
+					ToolVersionSet(name, version, commit)
+				}
+			})
+
+			It("ToolVersionTryGet returns not ok", func() {
+				n, v, c, ok := ToolVersionTryGet()
+				Expect(ok).To(BeFalse())
+				Expect(n).To(BeEmpty())
+				Expect(v).To(BeEmpty())
+				Expect(c).To(BeEmpty())
+				Expect(func() { ToolVersionGet() }).Should(Panic())
+			})
+
+			It("HistoryNewEntry omits the tool", func() {
+				Expect(HistoryNewEntry("myCmd")).To(Equal(map[string]interface{}{
+					"command": "myCmd",
+				}))
+			})
+		})
+
+		It("ToolVersionTryGet returns the info when set", func() {
+			n, v, c, ok := ToolVersionTryGet()
+			Expect(ok).To(BeTrue())
+			Expect(n).To(Equal("my-name"))
+			Expect(v).To(Equal("1.2.3"))
+			Expect(c).To(Equal("commit-xyz"))
+		})
+	})
+
+	Describe("transform", func() {
+		It("GetTransform defaults to true if absent", func() {
+			transform, err := GetTransform(map[string]interface{}{})
+			Expect(err).To(BeNil())
+			Expect(transform).To(BeTrue())
+
+			transform, err = GetTransform(nil)
+			Expect(err).To(BeNil())
+			Expect(transform).To(BeTrue())
+		})
+
+		It("GetTransform returns an explicit false", func() {
+			data := map[string]interface{}{
+				TransformKey: false,
+			}
+
+			transform, err := GetTransform(data)
+			Expect(err).To(BeNil())
+			Expect(transform).To(BeFalse())
+		})
+
+		It("GetTransform returns an error if not a boolean", func() {
+			data := map[string]interface{}{
+				TransformKey: "false",
+			}
+
+			transform, err := GetTransform(data)
+			Expect(err).To(MatchError("expected key '_transform' to be a boolean, found string"))
+			Expect(transform).To(BeFalse())
+		})
+
+		It("SetTransform sets the value", func() {
+			data := map[string]interface{}{}
+			SetTransform(data, false)
+			Expect(data[TransformKey]).To(BeFalse())
+
+			transform, err := GetTransform(data)
+			Expect(err).To(BeNil())
+			Expect(transform).To(BeFalse())
+		})
+
+		It("SetTransform panics if data is nil, TrySetTransform returns an error", func() {
+			Expect(func() { SetTransform(nil, false) }).To(PanicWith(ErrNilDocument))
+			Expect(TrySetTransform(nil, false)).To(MatchError(ErrNilDocument))
+
+			data := map[string]interface{}{}
+			Expect(TrySetTransform(data, false)).To(Succeed())
+			Expect(data[TransformKey]).To(BeFalse())
+		})
 	})
 
 	Describe("compatibility", func() {
+		It("returns an error instead of panicking on nil input", func() {
+			data := map[string]interface{}{}
+			Expect(CompatibleTransform(nil, data)).To(MatchError(ErrNilDocument))
+			Expect(CompatibleTransform(data, nil)).To(MatchError(ErrNilDocument))
+			Expect(CompatibleVersion(nil, data)).To(MatchError(ErrNilDocument))
+			Expect(CompatibleVersion(data, nil)).To(MatchError(ErrNilDocument))
+			Expect(CompatibleFile(nil, data)).To(MatchError(ErrNilDocument))
+		})
+
 		DescribeTable("CompatibleTransform",
 			func(transform1 interface{}, transform2 interface{}, expected bool) {
 				res := CompatibleTransform(
@@ -126,6 +253,51 @@ var _ = Describe("deckformat", func() {
 			Entry("9", nil, nil, true),
 		)
 
+		Describe("CompatibleTransform with version dependent defaults", func() {
+			var previous Config
+
+			BeforeEach(func() {
+				previous = ConfigGet()
+				newConfig := previous
+				newConfig.TransformDefaults = map[int]bool{1: false}
+				ConfigSet(newConfig)
+			})
+
+			AfterEach(func() {
+				ConfigSet(previous)
+			})
+
+			It("uses the default of the format version", func() {
+				transform, err := GetTransform(map[string]interface{}{VersionKey: "1.1"})
+				Expect(err).To(BeNil())
+				Expect(transform).To(BeFalse())
+
+				Expect(CompatibleTransform(
+					map[string]interface{}{VersionKey: "1.1"},
+					map[string]interface{}{VersionKey: "1.0", TransformKey: false},
+				)).To(Succeed())
+				Expect(CompatibleTransform(
+					map[string]interface{}{VersionKey: "1.1"},
+					map[string]interface{}{VersionKey: "1.0", TransformKey: true},
+				)).To(HaveOccurred())
+			})
+
+			It("defaults to true for other versions", func() {
+				transform, err := GetTransform(map[string]interface{}{VersionKey: "3.0"})
+				Expect(err).To(BeNil())
+				Expect(transform).To(BeTrue())
+
+				transform, err = GetTransform(map[string]interface{}{})
+				Expect(err).To(BeNil())
+				Expect(transform).To(BeTrue())
+
+				Expect(CompatibleTransform(
+					map[string]interface{}{VersionKey: "3.0"},
+					map[string]interface{}{VersionKey: "3.0", TransformKey: true},
+				)).To(Succeed())
+			})
+		})
+
 		DescribeTable("CompatibleVersion",
 			func(version1 interface{}, version2 interface{}, expected bool) {
 				res := CompatibleVersion(
@@ -169,7 +341,7 @@ var _ = Describe("deckformat", func() {
 					Expect(res).To(BeNil())
 				} else {
 					// not-compatible, then result is an error
-					Expect(res).Should(HaveOccurred())
+					Expect(res).To(MatchError(ErrIncompatible))
 				}
 			},
 			// version1, version2, expected
@@ -177,6 +349,83 @@ var _ = Describe("deckformat", func() {
 			Entry("2", "1.1", true, "1.2", false, false),
 			Entry("3", "1.1", true, "2.1", true, false),
 		)
+
+		Describe("CompatibleFileReasons", func() {
+			It("returns all reasons", func() {
+				reasons := CompatibleFileReasons(
+					map[string]interface{}{VersionKey: "1.1", TransformKey: true},
+					map[string]interface{}{VersionKey: "3.0", TransformKey: false},
+				)
+				Expect(reasons).To(HaveLen(2))
+				Expect(reasons[0]).To(MatchError(ErrIncompatible))
+				Expect(reasons[0].Error()).To(ContainSubstring("_transform"))
+				Expect(reasons[1]).To(MatchError(ErrIncompatible))
+				Expect(reasons[1].Error()).To(ContainSubstring("major versions are incompatible; 1.1 and 3.0"))
+			})
+
+			It("returns nil for compatible files", func() {
+				Expect(CompatibleFileReasons(
+					map[string]interface{}{VersionKey: "3.0"},
+					map[string]interface{}{VersionKey: "3.1"},
+				)).To(BeNil())
+			})
+
+			It("returns ErrNilDocument on nil input", func() {
+				reasons := CompatibleFileReasons(nil, map[string]interface{}{})
+				Expect(reasons).To(HaveLen(1))
+				Expect(reasons[0]).To(MatchError(ErrNilDocument))
+			})
+		})
+	})
+
+	Describe("VersionUpgradePath", func() {
+		It("handles the same version", func() {
+			info, err := VersionUpgradePath("3.0", "3.0")
+			Expect(err).To(BeNil())
+			Expect(info).To(Equal(UpgradeInfo{
+				FromMajor: 3, FromMinor: 0,
+				ToMajor: 3, ToMinor: 0,
+				MajorBump: false,
+			}))
+			Expect(info.Description()).To(Equal("no upgrade needed, version 3.0 is current"))
+		})
+
+		It("handles a minor upgrade", func() {
+			info, err := VersionUpgradePath("3.0", "3.1")
+			Expect(err).To(BeNil())
+			Expect(info).To(Equal(UpgradeInfo{
+				FromMajor: 3, FromMinor: 0,
+				ToMajor: 3, ToMinor: 1,
+				MajorBump: false,
+			}))
+			Expect(info.Description()).To(Equal("minor upgrade from 3.0 to 3.1; the file is compatible"))
+		})
+
+		It("handles a major upgrade", func() {
+			info, err := VersionUpgradePath("1.1", "3")
+			Expect(err).To(BeNil())
+			Expect(info).To(Equal(UpgradeInfo{
+				FromMajor: 1, FromMinor: 1,
+				ToMajor: 3, ToMinor: 0,
+				MajorBump: true,
+			}))
+			Expect(info.Description()).To(Equal("major upgrade from 1.1 to 3.0; the file must be transformed"))
+		})
+
+		It("returns an error on a downgrade", func() {
+			_, err := VersionUpgradePath("3.1", "3.0")
+			Expect(err).To(MatchError("cannot upgrade from version '3.1' to older version '3.0'"))
+		})
+
+		It("returns an error on malformed versions", func() {
+			_, err := VersionUpgradePath("bad", "3.0")
+			Expect(err).To(MatchError("invalid 'from' version 'bad'; expected field '._format_version' " +
+				"to be a string in 'x.y' format"))
+
+			_, err = VersionUpgradePath("3.0", "3.0.1")
+			Expect(err).To(MatchError("invalid 'to' version '3.0.1'; expected field '._format_version' " +
+				"to be a string in 'x.y' format"))
+		})
 	})
 
 	Describe("history", func() {
@@ -245,6 +494,72 @@ var _ = Describe("deckformat", func() {
 				Expect(res).To(BeNil())
 				Expect(found).To(BeFalse())
 			})
+
+			It("HistorySetKeep stores the history, regardless of the config", func() {
+				data := map[string]interface{}{}
+				Expect(HistoryTrySet(data, []interface{}{"one"})).To(Succeed())
+				Expect(data).ToNot(HaveKey(HistoryKey))
+
+				Expect(HistorySetKeep(data, []interface{}{"one"})).To(Succeed())
+				Expect(data[HistoryKey]).To(Equal([]interface{}{"one"}))
+				Expect(ConfigGet().KeepHistory).To(BeFalse())
+
+				Expect(HistorySetKeep(data, nil)).To(Succeed())
+				Expect(data).ToNot(HaveKey(HistoryKey))
+				Expect(HistorySetKeep(nil, []interface{}{"one"})).To(MatchError(ErrNilDocument))
+			})
+
+			It("panics if data is nil, the Try variants return an error", func() {
+				Expect(func() { HistorySet(nil, []interface{}{"one"}) }).To(PanicWith(ErrNilDocument))
+				Expect(func() { HistoryAppend(nil, "one") }).To(PanicWith(ErrNilDocument))
+				Expect(HistoryTrySet(nil, []interface{}{"one"})).To(MatchError(ErrNilDocument))
+				Expect(HistoryTryAppend(nil, "one")).To(MatchError(ErrNilDocument))
+			})
+		})
+
+		Describe("HistoryRequire", func() {
+			data := map[string]interface{}{
+				HistoryKey: []interface{}{
+					"not an object",
+					map[string]interface{}{"tool": "kced 1.2.3 (abc123)", "command": "openapi2kong"},
+					map[string]interface{}{"tool": "kced 1.2.3 (abc123)", "command": "merge"},
+				},
+			}
+
+			It("passes if a matching entry exists", func() {
+				Expect(HistoryRequire(data, "kced 1.2", "openapi2kong")).To(Succeed())
+				Expect(HistoryRequire(data, "kced", "merge")).To(Succeed())
+				Expect(HistoryRequire(data, "", "merge")).To(Succeed())
+				Expect(HistoryRequire(data, "kced 1.2.3 (abc123)", "")).To(Succeed())
+			})
+
+			It("fails if there is no matching entry", func() {
+				Expect(HistoryRequire(data, "kced 1.3", "openapi2kong")).To(MatchError(
+					"no history entry found with tool 'kced 1.3' and command 'openapi2kong'"))
+				Expect(HistoryRequire(data, "kced", "patch")).To(MatchError(
+					"no history entry found with tool 'kced' and command 'patch'"))
+				Expect(HistoryRequire(map[string]interface{}{}, "", "")).To(HaveOccurred())
+			})
+
+			It("returns an error if data is nil", func() {
+				Expect(HistoryRequire(nil, "kced", "merge")).To(MatchError(ErrNilDocument))
+			})
+		})
+
+		Describe("HistoryMerge", func() {
+			It("concatenates the histories, dropping duplicates", func() {
+				entry := map[string]interface{}{"cmd": "openapi2kong"}
+				res := HistoryMerge(
+					[]interface{}{entry, "one"},
+					nil,
+					[]interface{}{map[string]interface{}{"cmd": "openapi2kong"}, "two"},
+				)
+				Expect(res).To(Equal([]interface{}{entry, "one", "two"}))
+			})
+
+			It("returns an empty array if there is no history", func() {
+				Expect(HistoryMerge()).To(Equal([]interface{}{}))
+			})
 		})
 
 		PDescribe("HistoryAppend", func() {
@@ -269,6 +584,153 @@ var _ = Describe("deckformat", func() {
 			})
 		})
 
+		Describe("HistoryGetSummary", func() {
+			It("summarizes the history entries", func() {
+				data := MustDeserializeFile("./history_testfiles/two_entries.yml")
+				Expect(HistoryGetSummary(data)).To(Equal([]HistorySummary{
+					{Tool: "kced 1.0", Command: "openapi2kong", Input: "spec.yaml", Output: "kong.yaml"},
+					{Tool: "kced 1.0", Command: "merge", Input: "kong.yaml, other.yaml", Output: "-"},
+				}))
+			})
+
+			It("returns an empty list if there is no history", func() {
+				Expect(HistoryGetSummary(map[string]interface{}{})).To(BeEmpty())
+			})
+		})
+
+		Describe("with a custom history key", func() {
+			BeforeEach(func() {
+				ConfigSet(Config{HistoryKey: "_history", KeepHistory: true})
+			})
+			AfterEach(func() {
+				ConfigSet(Config{})
+			})
+
+			It("defaults the other keys", func() {
+				Expect(ConfigGet()).To(Equal(Config{
+					VersionKey:   VersionKey,
+					TransformKey: TransformKey,
+					HistoryKey:   "_history",
+					KeepHistory:  true,
+				}))
+			})
+
+			It("reads and writes the history under the custom key", func() {
+				data := map[string]interface{}{
+					"_history": []interface{}{"one"},
+					HistoryKey: []interface{}{"ignored"},
+				}
+				Expect(HistoryTryAppend(data, "two")).To(Succeed())
+
+				Expect(HistoryGet(data)).To(BeEquivalentTo([]interface{}{"one", "two"}))
+				Expect(data["_history"]).To(BeEquivalentTo([]interface{}{"one", "two"}))
+				Expect(data[HistoryKey]).To(BeEquivalentTo([]interface{}{"ignored"}))
+			})
+
+			It("clears the custom key", func() {
+				data := map[string]interface{}{
+					"_history": []interface{}{"one"},
+				}
+				HistoryClear(data)
+				Expect(data).ToNot(HaveKey("_history"))
+			})
+		})
+
+		Describe("config", func() {
+			AfterEach(func() {
+				ConfigSet(Config{})
+			})
+
+			It("does not share the TransformDefaults with the caller", func() {
+				defaults := map[int]bool{1: false}
+				ConfigSet(Config{TransformDefaults: defaults})
+				defaults[1] = true
+				Expect(ConfigGet().TransformDefaults).To(Equal(map[int]bool{1: false}))
+
+				ConfigGet().TransformDefaults[1] = true
+				Expect(ConfigGet().TransformDefaults).To(Equal(map[int]bool{1: false}))
+			})
+
+			It("is safe for concurrent use", func() {
+				var wg sync.WaitGroup
+				for i := 0; i < 10; i++ {
+					wg.Add(2)
+					go func() {
+						defer wg.Done()
+						ConfigSet(Config{KeepHistory: true})
+					}()
+					go func() {
+						defer wg.Done()
+						data := map[string]interface{}{}
+						Expect(HistoryTryAppend(data, "entry")).To(Succeed())
+					}()
+				}
+				wg.Wait()
+			})
+		})
+
+		Describe("HistoryCommands", func() {
+			It("returns the commands in order, skipping entries without a command", func() {
+				data := MustDeserializeFile("./history_testfiles/multi_entries.yml")
+				Expect(HistoryCommands(data, false)).To(Equal([]string{
+					"openapi2kong", "openapi2kong", "merge", "patch", "merge",
+				}))
+			})
+
+			It("dedupes the commands, keeping the first occurrence", func() {
+				data := MustDeserializeFile("./history_testfiles/multi_entries.yml")
+				Expect(HistoryCommands(data, true)).To(Equal([]string{"openapi2kong", "merge", "patch"}))
+			})
+
+			It("returns an empty list if there is no history", func() {
+				Expect(HistoryCommands(nil, true)).To(BeEmpty())
+			})
+		})
+
+		Describe("HistoryMigrate", func() {
+			AfterEach(func() {
+				ConfigSet(Config{})
+			})
+
+			It("moves the history to the target key", func() {
+				data := MustDeserializeFile("./history_testfiles/multi_entries.yml")
+				hist := HistoryGet(data)
+				Expect(hist).ToNot(BeEmpty())
+
+				Expect(HistoryMigrate(data, "_meta_history")).To(Succeed())
+				Expect(data).ToNot(HaveKey(HistoryKey))
+				Expect(HistoryGet(data)).To(BeEmpty())
+
+				ConfigSet(Config{HistoryKey: "_meta_history", KeepHistory: true})
+				Expect(HistoryGet(data)).To(Equal(hist))
+				Expect(HistoryTryAppend(data, "new entry")).To(Succeed())
+				Expect(HistoryGet(data)).To(Equal(append(hist, "new entry")))
+			})
+
+			It("normalizes the history to an array", func() {
+				data := map[string]interface{}{HistoryKey: "single entry"}
+				Expect(HistoryMigrate(data, "_meta_history")).To(Succeed())
+				Expect(data).To(Equal(map[string]interface{}{
+					"_meta_history": []interface{}{"single entry"},
+				}))
+			})
+
+			It("does nothing without history", func() {
+				data := map[string]interface{}{"services": []interface{}{}}
+				Expect(HistoryMigrate(data, "_meta_history")).To(Succeed())
+				Expect(data).To(Equal(map[string]interface{}{"services": []interface{}{}}))
+			})
+
+			It("fails on bad input", func() {
+				data := map[string]interface{}{HistoryKey: "entry", "_meta_history": "in use"}
+				Expect(HistoryMigrate(nil, "_meta_history")).To(MatchError(ErrNilDocument))
+				Expect(HistoryMigrate(data, "")).To(MatchError(
+					"expected a non-empty target key to migrate the history to"))
+				Expect(HistoryMigrate(data, "_meta_history")).To(MatchError(
+					"cannot migrate the history to '_meta_history', the key is already in use"))
+			})
+		})
+
 		Describe("HistoryClear", func() {
 			It("clears the history key", func() {
 				data := map[string]interface{}{
@@ -0,0 +1,36 @@
+package deckformat
+
+// hasDeckMarkers returns true if the data has a '_format_version' field, or any of the
+// top-level entity arrays.
+func hasDeckMarkers(data map[string]interface{}) bool {
+	if _, found := data[getConfig().VersionKey]; found {
+		return true
+	}
+	for entityType := range EntityRegistry {
+		if _, isArray := data[entityType].([]interface{}); isArray {
+			return true
+		}
+	}
+	return false
+}
+
+// hasOpenAPIMarkers returns true if the data has an 'openapi' or 'swagger' field.
+func hasOpenAPIMarkers(data map[string]interface{}) bool {
+	_, openapi := data["openapi"]
+	_, swagger := data["swagger"]
+	return openapi || swagger
+}
+
+// IsDeckFile returns true if the data looks like a deck file; it has a '_format_version'
+// field, or any of the top-level entity arrays (see EntityRegistry). Returns false if it
+// also looks like an OpenAPI spec (see IsOpenAPI), or if data is nil.
+func IsDeckFile(data map[string]interface{}) bool {
+	return hasDeckMarkers(data) && !hasOpenAPIMarkers(data)
+}
+
+// IsOpenAPI returns true if the data looks like an OpenAPI (or Swagger) spec; it has an
+// 'openapi' or 'swagger' field. Returns false if it also looks like a deck file (see
+// IsDeckFile), or if data is nil.
+func IsOpenAPI(data map[string]interface{}) bool {
+	return hasOpenAPIMarkers(data) && !hasDeckMarkers(data)
+}
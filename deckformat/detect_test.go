@@ -0,0 +1,62 @@
+package deckformat_test
+
+import (
+	. "github.com/kong/go-apiops/deckformat"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("detect", func() {
+	deckFile := map[string]interface{}{
+		"_format_version": "3.0",
+		"services":        []interface{}{},
+	}
+	entitiesOnly := map[string]interface{}{
+		"routes": []interface{}{map[string]interface{}{"name": "route1"}},
+	}
+	openapiSpec := map[string]interface{}{
+		"openapi": "3.0.0",
+		"paths":   map[string]interface{}{},
+	}
+	swaggerSpec := map[string]interface{}{
+		"swagger": "2.0",
+	}
+	ambiguous := map[string]interface{}{
+		"_format_version": "3.0",
+		"openapi":         "3.0.0",
+	}
+	arbitrary := map[string]interface{}{
+		"name":     "something",
+		"services": "not an array",
+	}
+
+	Describe("IsDeckFile", func() {
+		It("detects a deck file", func() {
+			Expect(IsDeckFile(deckFile)).To(BeTrue())
+			Expect(IsDeckFile(entitiesOnly)).To(BeTrue())
+		})
+
+		It("rejects other data", func() {
+			Expect(IsDeckFile(openapiSpec)).To(BeFalse())
+			Expect(IsDeckFile(swaggerSpec)).To(BeFalse())
+			Expect(IsDeckFile(ambiguous)).To(BeFalse())
+			Expect(IsDeckFile(arbitrary)).To(BeFalse())
+			Expect(IsDeckFile(nil)).To(BeFalse())
+		})
+	})
+
+	Describe("IsOpenAPI", func() {
+		It("detects an OpenAPI spec", func() {
+			Expect(IsOpenAPI(openapiSpec)).To(BeTrue())
+			Expect(IsOpenAPI(swaggerSpec)).To(BeTrue())
+		})
+
+		It("rejects other data", func() {
+			Expect(IsOpenAPI(deckFile)).To(BeFalse())
+			Expect(IsOpenAPI(entitiesOnly)).To(BeFalse())
+			Expect(IsOpenAPI(ambiguous)).To(BeFalse())
+			Expect(IsOpenAPI(arbitrary)).To(BeFalse())
+			Expect(IsOpenAPI(nil)).To(BeFalse())
+		})
+	})
+})
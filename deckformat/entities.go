@@ -0,0 +1,209 @@
+package deckformat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kong/go-apiops/jsonbasics"
+)
+
+// EntityRegistry lists the Kong entity types that can appear at the top-level of a deck
+// file, together with the entity types that can be nested within them.
+var EntityRegistry = map[string][]string{
+	"acls":                  {},
+	"basicauth_credentials": {},
+	"ca_certificates":       {},
+	"certificates":          {"snis"},
+	"consumer_groups":       {"consumers", "plugins"},
+	"consumers": {
+		"acls", "basicauth_credentials", "hmacauth_credentials", "jwt_secrets",
+		"keyauth_credentials", "mtls_auth_credentials", "oauth2_credentials", "plugins",
+	},
+	"hmacauth_credentials":  {},
+	"jwt_secrets":           {},
+	"keyauth_credentials":   {},
+	"mtls_auth_credentials": {},
+	"oauth2_credentials":    {},
+	"plugins":               {},
+	"routes":                {"plugins"},
+	"services":              {"plugins", "routes"},
+	"snis":                  {},
+	"targets":               {},
+	"upstreams":             {"targets"},
+	"vaults":                {},
+}
+
+// walkEntityArray calls 'visit' for every entity in the array-field 'entityType' of 'parent',
+// with the path of the entity, and recurses into the nested entities. 'path' is the path
+// to the parent (eg. "services[0].").
+func walkEntityArray(
+	parent map[string]interface{},
+	entityType string,
+	path string,
+	visit func(entityType string, path string, entity map[string]interface{}) error,
+) error {
+	entities, err := jsonbasics.GetObjectArrayField(parent, entityType)
+	if err != nil {
+		return fmt.Errorf("failed to read '%s%s'; %w", path, entityType, err)
+	}
+	for i, entity := range entities {
+		entityPath := fmt.Sprintf("%s%s[%d]", path, entityType, i)
+		if err := visit(entityType, entityPath, entity); err != nil {
+			return err
+		}
+		entityPath += "."
+		for _, nestedType := range EntityRegistry[entityType] {
+			if err := walkEntityArray(entity, nestedType, entityPath, visit); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WalkEntities calls 'visit' for every entity in the deck file, including nested ones
+// (eg. routes nested in a service). Only the entity types in EntityRegistry are visited.
+// Top-level entity types are visited in sorted order. If 'visit' returns an error, the walk
+// is aborted and the error is returned. Returns ErrNilDocument if data is nil.
+func WalkEntities(data map[string]interface{}, visit func(entityType string, entity map[string]interface{}) error,
+) error {
+	return walkEntities(data, func(entityType string, _ string, entity map[string]interface{}) error {
+		return visit(entityType, entity)
+	})
+}
+
+// walkEntities is the same as WalkEntities, but also passes the path of the entity to
+// 'visit' (eg. "services[0].routes[1]").
+func walkEntities(data map[string]interface{},
+	visit func(entityType string, path string, entity map[string]interface{}) error,
+) error {
+	if data == nil {
+		return ErrNilDocument
+	}
+
+	entityTypes := make([]string, 0, len(EntityRegistry))
+	for entityType := range EntityRegistry {
+		entityTypes = append(entityTypes, entityType)
+	}
+	sort.Strings(entityTypes)
+
+	for _, entityType := range entityTypes {
+		if err := walkEntityArray(data, entityType, "", visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TagCount is the number of entities a tag is applied to.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// GetTagCounts returns the tags used in the deck file, with the number of entities that
+// have them, sorted by tag. Entities without tags are ignored.
+func GetTagCounts(data map[string]interface{}) ([]TagCount, error) {
+	counts := make(map[string]int)
+	err := WalkEntities(data, func(entityType string, entity map[string]interface{}) error {
+		tags, err := jsonbasics.GetStringArrayField(entity, "tags")
+		if err != nil {
+			return fmt.Errorf("expected 'tags' of an entity in '%s' to be an array; %w", entityType, err)
+		}
+		seen := make(map[string]bool)
+		for _, tag := range tags {
+			if !seen[tag] {
+				seen[tag] = true
+				counts[tag]++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		result = append(result, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Tag < result[j].Tag
+	})
+	return result, nil
+}
+
+// WrapEntities returns a minimal deck file, holding the entities as the top-level array
+// 'entityType' (eg. "services"), with the '_format_version' set to 'version'. The entities
+// are not copied. Returns an error if the entity type is not in EntityRegistry, or if the
+// version is not in 'x.y' format.
+func WrapEntities(entityType string, entities []interface{}, version string) (map[string]interface{}, error) {
+	if _, found := EntityRegistry[entityType]; !found {
+		entityTypes := make([]string, 0, len(EntityRegistry))
+		for t := range EntityRegistry {
+			entityTypes = append(entityTypes, t)
+		}
+		sort.Strings(entityTypes)
+		return nil, fmt.Errorf("unknown entity type '%s', expected one of '%s'", entityType,
+			strings.Join(entityTypes, "', '"))
+	}
+
+	filedata := make(map[string]interface{})
+	if err := SetFormatVersion(filedata, version); err != nil {
+		return nil, err
+	}
+	if entities == nil {
+		entities = make([]interface{}, 0)
+	}
+	filedata[entityType] = entities
+	return filedata, nil
+}
+
+// identityFields are the fields identifying an entity to decK, by entity type. Entity types
+// not listed are identified by their 'name' or 'id'.
+var identityFields = map[string][]string{
+	"acls":                  {"id", "group"},
+	"basicauth_credentials": {"id", "username"},
+	"ca_certificates":       {"id", "cert"},
+	"certificates":          {"id", "cert"},
+	"consumers":             {"id", "username", "custom_id"},
+	"hmacauth_credentials":  {"id", "username"},
+	"jwt_secrets":           {"id", "key"},
+	"keyauth_credentials":   {"id", "key"},
+	"mtls_auth_credentials": {"id", "subject_name"},
+	"oauth2_credentials":    {"id", "client_id"},
+	"targets":               {"id", "target"},
+	"vaults":                {"id", "prefix"},
+}
+
+// EntityRef refers to an entity in a deck file.
+type EntityRef struct {
+	EntityType string `json:"entity_type"` // eg. "routes"
+	Path       string `json:"path"`        // the location in the file, eg. "services[0].routes[1]"
+}
+
+// FindUnidentifiedEntities returns the entities (including nested ones) that have none of the
+// fields identifying them to decK, which decK rejects. Those are a 'name' or 'id' for most
+// entity types, and eg. a 'username', 'custom_id', or 'id' for consumers. Empty strings do
+// not count. Returns ErrNilDocument if filedata is nil.
+func FindUnidentifiedEntities(filedata map[string]interface{}) ([]EntityRef, error) {
+	refs := make([]EntityRef, 0)
+	err := walkEntities(filedata, func(entityType string, path string, entity map[string]interface{}) error {
+		fields, found := identityFields[entityType]
+		if !found {
+			fields = []string{"id", "name"}
+		}
+		for _, field := range fields {
+			if value, ok := entity[field].(string); ok && value != "" {
+				return nil
+			}
+		}
+		refs = append(refs, EntityRef{EntityType: entityType, Path: path})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
@@ -0,0 +1,135 @@
+package deckformat_test
+
+import (
+	. "github.com/kong/go-apiops/deckformat"
+	. "github.com/kong/go-apiops/filebasics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("entities", func() {
+	deck := []byte(`{
+		"_format_version": "3.0",
+		"services": [
+			{
+				"name": "svc1",
+				"tags": [ "team-a", "public" ],
+				"routes": [
+					{ "name": "route1", "tags": [ "team-a" ] },
+					{ "name": "route2" }
+				]
+			},
+			{ "name": "svc2" }
+		],
+		"upstreams": [
+			{
+				"name": "upstream1",
+				"tags": [ "team-b" ],
+				"targets": [ { "target": "host:80", "tags": [ "team-b", "team-b" ] } ]
+			}
+		],
+		"unknown_entities": [ { "tags": [ "ignored" ] } ]
+	}`)
+
+	Describe("WalkEntities", func() {
+		It("visits all entities, including nested ones", func() {
+			visited := make([]string, 0)
+			err := WalkEntities(MustDeserialize(&deck), func(entityType string, entity map[string]interface{}) error {
+				visited = append(visited, entityType)
+				return nil
+			})
+
+			Expect(err).To(BeNil())
+			Expect(visited).To(Equal([]string{
+				"services", "routes", "routes", "services", "upstreams", "targets",
+			}))
+		})
+
+		It("returns an error if data is nil", func() {
+			err := WalkEntities(nil, func(string, map[string]interface{}) error { return nil })
+			Expect(err).To(MatchError(ErrNilDocument))
+		})
+	})
+
+	Describe("GetTagCounts", func() {
+		It("counts the entities per tag", func() {
+			counts, err := GetTagCounts(MustDeserialize(&deck))
+
+			Expect(err).To(BeNil())
+			Expect(counts).To(Equal([]TagCount{
+				{Tag: "public", Count: 1},
+				{Tag: "team-a", Count: 2},
+				{Tag: "team-b", Count: 2},
+			}))
+		})
+
+		It("returns an error on invalid tags", func() {
+			data := []byte(`{ "services": [ { "tags": "not-an-array" } ] }`)
+			_, err := GetTagCounts(MustDeserialize(&data))
+
+			Expect(err).To(MatchError(
+				"expected 'tags' of an entity in 'services' to be an array; expected an array, found string"))
+		})
+	})
+
+	Describe("WrapEntities", func() {
+		It("wraps a services array into a deck file", func() {
+			services := []interface{}{
+				map[string]interface{}{"name": "svc1", "host": "backend"},
+			}
+			data, err := WrapEntities("services", services, "3.0")
+
+			Expect(err).To(BeNil())
+			Expect(data).To(Equal(map[string]interface{}{
+				VersionKey: "3.0",
+				"services": services,
+			}))
+			Expect(CompatibleFile(data, map[string]interface{}{VersionKey: "3.1"})).To(Succeed())
+		})
+
+		It("returns an error on an unknown entity type, or a bad version", func() {
+			_, err := WrapEntities("servicez", nil, "3.0")
+			Expect(err).To(MatchError(ContainSubstring("unknown entity type 'servicez', expected one of 'acls', ")))
+
+			_, err = WrapEntities("services", nil, "three")
+			Expect(err).To(MatchError("expected field '._format_version' to be a string in 'x.y' format"))
+		})
+	})
+
+	Describe("FindUnidentifiedEntities", func() {
+		It("returns the entities without a name or id", func() {
+			data := map[string]interface{}{
+				"services": []interface{}{
+					map[string]interface{}{"name": "named"},
+					map[string]interface{}{
+						"host": "example.com",
+						"routes": []interface{}{
+							map[string]interface{}{"id": "e5b6cf62-4b8b-4b9b-9c4a-3d8c9b2a1f00"},
+							map[string]interface{}{"name": "", "paths": []interface{}{"/"}},
+						},
+					},
+				},
+				"consumers": []interface{}{
+					map[string]interface{}{"username": "alice"},
+					map[string]interface{}{"custom_id": "bob"},
+				},
+			}
+
+			refs, err := FindUnidentifiedEntities(data)
+			Expect(err).To(BeNil())
+			Expect(refs).To(Equal([]EntityRef{
+				{EntityType: "services", Path: "services[1]"},
+				{EntityType: "routes", Path: "services[1].routes[1]"},
+			}))
+		})
+
+		It("returns an empty list if all entities are identified", func() {
+			refs, err := FindUnidentifiedEntities(map[string]interface{}{})
+			Expect(err).To(BeNil())
+			Expect(refs).To(BeEmpty())
+
+			_, err = FindUnidentifiedEntities(nil)
+			Expect(err).To(MatchError(ErrNilDocument))
+		})
+	})
+})
@@ -0,0 +1,10 @@
+package deckformat
+
+// ToolVersionReset clears the tool info, to test the unset case. Only available in tests.
+func ToolVersionReset() {
+	toolInfo.Lock()
+	defer toolInfo.Unlock()
+	toolInfo.name = ""
+	toolInfo.version = ""
+	toolInfo.commit = ""
+}
@@ -0,0 +1,34 @@
+package deckformat
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/kong/go-apiops/jsonbasics"
+)
+
+// Fingerprint returns a stable hash (hex encoded sha256) of the content of a deck file, for
+// change detection. The history and the order of the entities are ignored, so semantically
+// equal files have the same fingerprint. The data itself is not modified. Returns
+// ErrNilDocument if filedata is nil.
+func Fingerprint(filedata map[string]interface{}) (string, error) {
+	if filedata == nil {
+		return "", ErrNilDocument
+	}
+
+	data := *jsonbasics.DeepCopyObject(&filedata)
+	HistoryClear(data)
+	if err := Canonicalize(data); err != nil {
+		return "", err
+	}
+
+	// json.Marshal sorts the object keys, so the serialization is deterministic
+	serialized, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize the file; %w", err)
+	}
+	hash := sha256.Sum256(serialized)
+	return hex.EncodeToString(hash[:]), nil
+}
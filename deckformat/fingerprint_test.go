@@ -0,0 +1,66 @@
+package deckformat_test
+
+import (
+	. "github.com/kong/go-apiops/deckformat"
+	. "github.com/kong/go-apiops/filebasics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("fingerprint", func() {
+	Describe("Fingerprint", func() {
+		deck := []byte(`{
+			"_format_version": "3.0",
+			"services": [
+				{ "name": "svc1", "routes": [ { "name": "r1" }, { "name": "r2" } ] },
+				{ "name": "svc2" }
+			]
+		}`)
+
+		It("ignores the order of the entities", func() {
+			reordered := []byte(`{
+				"services": [
+					{ "name": "svc2" },
+					{ "routes": [ { "name": "r2" }, { "name": "r1" } ], "name": "svc1" }
+				],
+				"_format_version": "3.0"
+			}`)
+			print1, err := Fingerprint(MustDeserialize(&deck))
+			Expect(err).To(BeNil())
+			print2, err := Fingerprint(MustDeserialize(&reordered))
+			Expect(err).To(BeNil())
+			Expect(print1).To(Equal(print2))
+			Expect(print1).To(HaveLen(64))
+		})
+
+		It("ignores the history", func() {
+			data := MustDeserialize(&deck)
+			print1, err := Fingerprint(data)
+			Expect(err).To(BeNil())
+
+			data[HistoryKey] = []interface{}{map[string]interface{}{"command": "openapi2kong"}}
+			print2, err := Fingerprint(data)
+			Expect(err).To(BeNil())
+			Expect(print1).To(Equal(print2))
+			Expect(data).To(HaveKey(HistoryKey)) // input is not modified
+		})
+
+		It("reflects changes in the content", func() {
+			changed := []byte(`{
+				"_format_version": "3.0",
+				"services": [
+					{ "name": "svc1", "routes": [ { "name": "r1" }, { "name": "r3" } ] },
+					{ "name": "svc2" }
+				]
+			}`)
+			print1, _ := Fingerprint(MustDeserialize(&deck))
+			print2, _ := Fingerprint(MustDeserialize(&changed))
+			Expect(print1).ToNot(Equal(print2))
+		})
+
+		It("returns an error if data is nil", func() {
+			_, err := Fingerprint(nil)
+			Expect(err).To(MatchError(ErrNilDocument))
+		})
+	})
+})
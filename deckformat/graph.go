@@ -0,0 +1,200 @@
+package deckformat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kong/go-apiops/jsonbasics"
+)
+
+// Relation is a reference from one entity to another, eg. from a route to its service.
+// Entities are identified by their name, or their id if they have no name.
+type Relation struct {
+	FromType string // entity type of the referring entity, eg. "routes"
+	From     string // name of the referring entity
+	ToType   string // entity type of the referred entity, eg. "services"
+	To       string // name of the referred entity
+	Dangling bool   // the referred entity is not in the file
+}
+
+// getEntityID returns the name of an entity, or its id if it has no name.
+func getEntityID(entity map[string]interface{}) string {
+	if name, err := jsonbasics.GetStringField(entity, "name"); err == nil && name != "" {
+		return name
+	}
+	id, _ := jsonbasics.GetStringField(entity, "id")
+	return id
+}
+
+// getForeignKey returns the value of a foreign key field, which is either a string, or an
+// object with a 'name' or 'id'. Returns "" if not set.
+func getForeignKey(entity map[string]interface{}, field string) string {
+	switch ref := entity[field].(type) {
+	case string:
+		return ref
+	case map[string]interface{}:
+		return getEntityID(ref)
+	}
+	return ""
+}
+
+// sortRelations sorts the relations by type and name, to be deterministic.
+func sortRelations(relations []Relation) {
+	sort.Slice(relations, func(i, j int) bool {
+		r1 := relations[i]
+		r2 := relations[j]
+		k1 := strings.Join([]string{r1.ToType, r1.To, r1.FromType, r1.From}, "\x00")
+		k2 := strings.Join([]string{r2.ToType, r2.To, r2.FromType, r2.From}, "\x00")
+		return k1 < k2
+	})
+}
+
+// GetRelations returns how the routes bind to services (nested, or by the 'service' foreign
+// key), and how the services bind to upstreams (by a 'host' matching an upstream name).
+// Routes referring to a service that is not in the file are marked Dangling.
+// Returns ErrNilDocument if data is nil.
+func GetRelations(data map[string]interface{}) ([]Relation, error) {
+	if data == nil {
+		return nil, ErrNilDocument
+	}
+
+	services, err := jsonbasics.GetObjectArrayField(data, "services")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read 'services'; %w", err)
+	}
+	upstreams, err := jsonbasics.GetObjectArrayField(data, "upstreams")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read 'upstreams'; %w", err)
+	}
+	routes, err := jsonbasics.GetObjectArrayField(data, "routes")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read 'routes'; %w", err)
+	}
+
+	upstreamNames := make(map[string]bool)
+	for _, upstream := range upstreams {
+		if name, err := jsonbasics.GetStringField(upstream, "name"); err == nil {
+			upstreamNames[name] = true
+		}
+	}
+
+	relations := make([]Relation, 0)
+	serviceIDs := make(map[string]string) // name and id, to the service name
+	for _, service := range services {
+		serviceName := getEntityID(service)
+		serviceIDs[serviceName] = serviceName
+		if id, err := jsonbasics.GetStringField(service, "id"); err == nil {
+			serviceIDs[id] = serviceName
+		}
+
+		if host, err := jsonbasics.GetStringField(service, "host"); err == nil && upstreamNames[host] {
+			relations = append(relations, Relation{
+				FromType: "services",
+				From:     serviceName,
+				ToType:   "upstreams",
+				To:       host,
+			})
+		}
+
+		nestedRoutes, err := jsonbasics.GetObjectArrayField(service, "routes")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read 'routes' of service '%s'; %w", serviceName, err)
+		}
+		for _, route := range nestedRoutes {
+			relations = append(relations, Relation{
+				FromType: "routes",
+				From:     getEntityID(route),
+				ToType:   "services",
+				To:       serviceName,
+			})
+		}
+	}
+
+	for _, route := range routes {
+		ref := getForeignKey(route, "service")
+		if ref == "" {
+			continue // not bound to a service
+		}
+		serviceName, found := serviceIDs[ref]
+		if !found {
+			serviceName = ref
+		}
+		relations = append(relations, Relation{
+			FromType: "routes",
+			From:     getEntityID(route),
+			ToType:   "services",
+			To:       serviceName,
+			Dangling: !found,
+		})
+	}
+
+	sortRelations(relations)
+	return relations, nil
+}
+
+// RelationsToDOT renders the relations as a graph in the DOT language (Graphviz). Dangling
+// references, and the missing entities, are highlighted in red.
+func RelationsToDOT(relations []Relation) string {
+	var sb strings.Builder
+	sb.WriteString("digraph deck {\n")
+	missing := make(map[string]bool)
+	for _, relation := range relations {
+		from := relation.FromType + "/" + relation.From
+		to := relation.ToType + "/" + relation.To
+		if relation.Dangling {
+			fmt.Fprintf(&sb, "  %q -> %q [color=red, style=dashed];\n", from, to)
+			if !missing[to] {
+				missing[to] = true
+				fmt.Fprintf(&sb, "  %q [color=red, label=%q];\n", to, to+" (missing)")
+			}
+		} else {
+			fmt.Fprintf(&sb, "  %q -> %q;\n", from, to)
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// RelationsToTree renders the relations as a plain-text tree, listing each service with
+// its upstream (if any), and its routes. Missing services are marked "(MISSING)".
+func RelationsToTree(relations []Relation) string {
+	serviceNames := make([]string, 0)
+	upstream := make(map[string]string)
+	routes := make(map[string][]string)
+	missing := make(map[string]bool)
+	seen := make(map[string]bool)
+	for _, relation := range relations {
+		var serviceName string
+		if relation.FromType == "services" {
+			serviceName = relation.From
+			upstream[serviceName] = relation.To
+		} else {
+			serviceName = relation.To
+			routes[serviceName] = append(routes[serviceName], relation.From)
+			missing[serviceName] = missing[serviceName] || relation.Dangling
+		}
+		if !seen[serviceName] {
+			seen[serviceName] = true
+			serviceNames = append(serviceNames, serviceName)
+		}
+	}
+	sort.Strings(serviceNames)
+
+	var sb strings.Builder
+	for _, serviceName := range serviceNames {
+		sb.WriteString("service " + serviceName)
+		if upstream[serviceName] != "" {
+			sb.WriteString(" -> upstream " + upstream[serviceName])
+		}
+		if missing[serviceName] {
+			sb.WriteString(" (MISSING)")
+		}
+		sb.WriteString("\n")
+		sort.Strings(routes[serviceName])
+		for _, route := range routes[serviceName] {
+			sb.WriteString("  route " + route + "\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,80 @@
+package deckformat_test
+
+import (
+	. "github.com/kong/go-apiops/deckformat"
+	. "github.com/kong/go-apiops/filebasics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("graph", func() {
+	deck := []byte(`{
+		"_format_version": "3.0",
+		"services": [
+			{
+				"name": "svc1",
+				"id": "svc1-id",
+				"host": "upstream1",
+				"routes": [ { "name": "route1" } ]
+			},
+			{ "name": "svc2", "host": "example.com" }
+		],
+		"routes": [
+			{ "name": "route2", "service": { "id": "svc1-id" } },
+			{ "name": "route3", "service": "svc2" },
+			{ "name": "route4", "service": { "name": "missing" } },
+			{ "name": "route5" }
+		],
+		"upstreams": [ { "name": "upstream1" } ]
+	}`)
+
+	Describe("GetRelations", func() {
+		It("collects nested and referenced relations", func() {
+			relations, err := GetRelations(MustDeserialize(&deck))
+			Expect(err).To(BeNil())
+			Expect(relations).To(Equal([]Relation{
+				{FromType: "routes", From: "route4", ToType: "services", To: "missing", Dangling: true},
+				{FromType: "routes", From: "route1", ToType: "services", To: "svc1"},
+				{FromType: "routes", From: "route2", ToType: "services", To: "svc1"},
+				{FromType: "routes", From: "route3", ToType: "services", To: "svc2"},
+				{FromType: "services", From: "svc1", ToType: "upstreams", To: "upstream1"},
+			}))
+		})
+
+		It("returns an error on a nil document", func() {
+			_, err := GetRelations(nil)
+			Expect(err).To(MatchError(ErrNilDocument))
+		})
+	})
+
+	Describe("RelationsToDOT", func() {
+		It("renders a DOT graph, highlighting dangling references", func() {
+			relations, err := GetRelations(MustDeserialize(&deck))
+			Expect(err).To(BeNil())
+			Expect(RelationsToDOT(relations)).To(Equal(`digraph deck {
+  "routes/route4" -> "services/missing" [color=red, style=dashed];
+  "services/missing" [color=red, label="services/missing (missing)"];
+  "routes/route1" -> "services/svc1";
+  "routes/route2" -> "services/svc1";
+  "routes/route3" -> "services/svc2";
+  "services/svc1" -> "upstreams/upstream1";
+}
+`))
+		})
+	})
+
+	Describe("RelationsToTree", func() {
+		It("renders a tree by service", func() {
+			relations, err := GetRelations(MustDeserialize(&deck))
+			Expect(err).To(BeNil())
+			Expect(RelationsToTree(relations)).To(Equal(`service missing (MISSING)
+  route route4
+service svc1 -> upstream upstream1
+  route route1
+  route route2
+service svc2
+  route route3
+`))
+		})
+	})
+})
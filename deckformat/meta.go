@@ -0,0 +1,83 @@
+package deckformat
+
+import (
+	"fmt"
+
+	"github.com/kong/go-apiops/jsonbasics"
+)
+
+const (
+	WorkspaceKey = "_workspace" // the top-level key in deck files for the target workspace
+	CommentKey   = "_comment"   // the top-level key in deck files for an opaque comment
+)
+
+// getMetaString returns the value of a top-level string meta field. Returns "" if absent,
+// and an error if it is not a string.
+func getMetaString(filedata map[string]interface{}, key string) (string, error) {
+	if filedata == nil || filedata[key] == nil {
+		return "", nil
+	}
+	value, err := jsonbasics.GetStringField(filedata, key)
+	if err != nil {
+		return "", fmt.Errorf("expected field '.%s' to be a string", key)
+	}
+	return value, nil
+}
+
+// setMetaString sets a top-level string meta field, an empty value removes the field.
+// Returns ErrNilDocument if filedata is nil.
+func setMetaString(filedata map[string]interface{}, key string, value string) error {
+	if filedata == nil {
+		return ErrNilDocument
+	}
+	if value == "" {
+		delete(filedata, key)
+	} else {
+		filedata[key] = value
+	}
+	return nil
+}
+
+// GetFormatVersion returns the value of the '_format_version' field. Returns "" if absent,
+// and an error if it is not a string.
+func GetFormatVersion(filedata map[string]interface{}) (string, error) {
+	return getMetaString(filedata, getConfig().VersionKey)
+}
+
+// SetFormatVersion sets the value of the '_format_version' field. Returns an error if the
+// version is not in 'x.y' format, or ErrNilDocument if filedata is nil.
+func SetFormatVersion(filedata map[string]interface{}, version string) error {
+	cfg := getConfig()
+	if filedata == nil {
+		return ErrNilDocument
+	}
+	if _, _, err := ParseFormatVersion(map[string]interface{}{cfg.VersionKey: version}); err != nil {
+		return err
+	}
+	filedata[cfg.VersionKey] = version
+	return nil
+}
+
+// GetWorkspace returns the value of the '_workspace' field. Returns "" if absent, and an
+// error if it is not a string.
+func GetWorkspace(filedata map[string]interface{}) (string, error) {
+	return getMetaString(filedata, WorkspaceKey)
+}
+
+// SetWorkspace sets the value of the '_workspace' field, an empty name removes it. Returns
+// ErrNilDocument if filedata is nil.
+func SetWorkspace(filedata map[string]interface{}, workspace string) error {
+	return setMetaString(filedata, WorkspaceKey, workspace)
+}
+
+// GetComment returns the value of the top-level '_comment' field. Returns "" if absent, and
+// an error if it is not a string.
+func GetComment(filedata map[string]interface{}) (string, error) {
+	return getMetaString(filedata, CommentKey)
+}
+
+// SetComment sets the value of the top-level '_comment' field, an empty comment removes it.
+// Returns ErrNilDocument if filedata is nil.
+func SetComment(filedata map[string]interface{}, comment string) error {
+	return setMetaString(filedata, CommentKey, comment)
+}
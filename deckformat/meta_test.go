@@ -0,0 +1,73 @@
+package deckformat_test
+
+import (
+	. "github.com/kong/go-apiops/deckformat"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("meta", func() {
+	Describe("workspace", func() {
+		It("GetWorkspace returns an empty string if absent", func() {
+			workspace, err := GetWorkspace(map[string]interface{}{})
+			Expect(err).To(BeNil())
+			Expect(workspace).To(Equal(""))
+
+			workspace, err = GetWorkspace(nil)
+			Expect(err).To(BeNil())
+			Expect(workspace).To(Equal(""))
+		})
+
+		It("GetWorkspace returns an error if not a string", func() {
+			_, err := GetWorkspace(map[string]interface{}{WorkspaceKey: 123})
+			Expect(err).To(MatchError("expected field '._workspace' to be a string"))
+		})
+
+		It("SetWorkspace sets and removes the value", func() {
+			data := map[string]interface{}{}
+			Expect(SetWorkspace(data, "team-a")).To(Succeed())
+			Expect(data[WorkspaceKey]).To(Equal("team-a"))
+
+			workspace, err := GetWorkspace(data)
+			Expect(err).To(BeNil())
+			Expect(workspace).To(Equal("team-a"))
+
+			Expect(SetWorkspace(data, "")).To(Succeed())
+			Expect(data).ToNot(HaveKey(WorkspaceKey))
+		})
+
+		It("SetWorkspace returns an error if data is nil", func() {
+			Expect(SetWorkspace(nil, "team-a")).To(MatchError(ErrNilDocument))
+		})
+	})
+
+	Describe("comment", func() {
+		It("SetComment sets the value", func() {
+			data := map[string]interface{}{}
+			Expect(SetComment(data, "generated, do not edit")).To(Succeed())
+
+			comment, err := GetComment(data)
+			Expect(err).To(BeNil())
+			Expect(comment).To(Equal("generated, do not edit"))
+		})
+	})
+
+	Describe("format version", func() {
+		It("SetFormatVersion sets the value", func() {
+			data := map[string]interface{}{}
+			Expect(SetFormatVersion(data, "3.0")).To(Succeed())
+			Expect(data[VersionKey]).To(Equal("3.0"))
+
+			version, err := GetFormatVersion(data)
+			Expect(err).To(BeNil())
+			Expect(version).To(Equal("3.0"))
+		})
+
+		It("SetFormatVersion returns an error if not in 'x.y' format", func() {
+			data := map[string]interface{}{}
+			Expect(SetFormatVersion(data, "three")).To(
+				MatchError("expected field '._format_version' to be a string in 'x.y' format"))
+			Expect(data).ToNot(HaveKey(VersionKey))
+		})
+	})
+})
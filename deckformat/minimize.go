@@ -0,0 +1,101 @@
+package deckformat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kong/go-apiops/jsonbasics"
+)
+
+// MinimizeOptions selects the steps taken by Minimize.
+type MinimizeOptions struct {
+	SkipHistory   bool     // do not clear the history
+	TagPrefix     string   // remove the tags starting with this prefix, none if empty
+	RemovePlugins []string // names of the plugins to remove (top-level and nested)
+}
+
+// filterPlugins removes the plugins named in 'remove' from the array-field 'plugins' of 'parent'.
+// 'path' is the path to the parent, for error messages.
+func filterPlugins(parent map[string]interface{}, remove map[string]bool, path string) error {
+	if parent["plugins"] == nil {
+		return nil
+	}
+	plugins, err := jsonbasics.GetObjectArrayField(parent, "plugins")
+	if err != nil {
+		return fmt.Errorf("failed to read '%splugins'; %w", path, err)
+	}
+	kept := make([]map[string]interface{}, 0, len(plugins))
+	for _, plugin := range plugins {
+		if name, _ := jsonbasics.GetStringField(plugin, "name"); !remove[name] {
+			kept = append(kept, plugin)
+		}
+	}
+	jsonbasics.SetObjectArrayField(parent, "plugins", kept)
+	return nil
+}
+
+// filterTags removes the tags starting with 'prefix' from the entity. The 'tags' field is
+// removed if no tags are left.
+func filterTags(entityType string, entity map[string]interface{}, prefix string) error {
+	if entity["tags"] == nil {
+		return nil
+	}
+	tags, err := jsonbasics.GetStringArrayField(entity, "tags")
+	if err != nil {
+		return fmt.Errorf("expected 'tags' of an entity in '%s' to be an array; %w", entityType, err)
+	}
+	kept := make([]interface{}, 0, len(tags))
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag, prefix) {
+			kept = append(kept, tag)
+		}
+	}
+	if len(kept) == 0 {
+		delete(entity, "tags")
+		return nil
+	}
+	jsonbasics.SetArrayField(entity, "tags", kept)
+	return nil
+}
+
+// Minimize returns a copy of the deck file, stripped of internal-only information for
+// publishing; the history is cleared, the tags matching TagPrefix are removed, and the
+// plugins named in RemovePlugins are removed. The input is not modified. Returns
+// ErrNilDocument if filedata is nil.
+func Minimize(filedata map[string]interface{}, opts MinimizeOptions) (map[string]interface{}, error) {
+	if filedata == nil {
+		return nil, ErrNilDocument
+	}
+	result := *jsonbasics.DeepCopyObject(&filedata)
+
+	if !opts.SkipHistory {
+		HistoryClear(result)
+	}
+
+	remove := make(map[string]bool, len(opts.RemovePlugins))
+	for _, name := range opts.RemovePlugins {
+		remove[name] = true
+	}
+	if len(remove) > 0 {
+		if err := filterPlugins(result, remove, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	err := WalkEntities(result, func(entityType string, entity map[string]interface{}) error {
+		if len(remove) > 0 && entityType != "plugins" {
+			// filtered before the walk recurses into the nested plugins
+			if err := filterPlugins(entity, remove, entityType+"."); err != nil {
+				return err
+			}
+		}
+		if opts.TagPrefix != "" {
+			return filterTags(entityType, entity, opts.TagPrefix)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,89 @@
+package deckformat_test
+
+import (
+	. "github.com/kong/go-apiops/deckformat"
+	. "github.com/kong/go-apiops/filebasics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("minimize", func() {
+	deck := []byte(`{
+		"_format_version": "3.0",
+		"_ignore": [ { "cmd": "openapi2kong" } ],
+		"services": [
+			{
+				"name": "svc1",
+				"tags": [ "internal-team-a", "public" ],
+				"plugins": [
+					{ "name": "cors" },
+					{ "name": "file-log", "tags": [ "internal-debug" ] }
+				],
+				"routes": [
+					{
+						"name": "route1",
+						"tags": [ "internal-team-a" ],
+						"plugins": [ { "name": "file-log" }, { "name": "key-auth" } ]
+					}
+				]
+			}
+		],
+		"plugins": [ { "name": "file-log" }, { "name": "prometheus" } ]
+	}`)
+
+	Describe("Minimize", func() {
+		It("strips the tags matching the prefix", func() {
+			data := MustDeserialize(&deck)
+			result, err := Minimize(data, MinimizeOptions{TagPrefix: "internal-"})
+			Expect(err).ToNot(HaveOccurred())
+
+			service := result["services"].([]interface{})[0].(map[string]interface{})
+			Expect(service["tags"]).To(Equal([]interface{}{"public"}))
+			plugin := service["plugins"].([]interface{})[1].(map[string]interface{})
+			Expect(plugin).ToNot(HaveKey("tags"))
+			route := service["routes"].([]interface{})[0].(map[string]interface{})
+			Expect(route).ToNot(HaveKey("tags"))
+		})
+
+		It("removes the named plugins", func() {
+			data := MustDeserialize(&deck)
+			result, err := Minimize(data, MinimizeOptions{RemovePlugins: []string{"file-log"}})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(result["plugins"]).To(Equal([]interface{}{
+				map[string]interface{}{"name": "prometheus"},
+			}))
+			service := result["services"].([]interface{})[0].(map[string]interface{})
+			Expect(service["plugins"]).To(Equal([]interface{}{
+				map[string]interface{}{"name": "cors"},
+			}))
+			route := service["routes"].([]interface{})[0].(map[string]interface{})
+			Expect(route["plugins"]).To(Equal([]interface{}{
+				map[string]interface{}{"name": "key-auth"},
+			}))
+		})
+
+		It("clears the history, unless skipped", func() {
+			data := MustDeserialize(&deck)
+			result, err := Minimize(data, MinimizeOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).ToNot(HaveKey("_ignore"))
+
+			result, err = Minimize(data, MinimizeOptions{SkipHistory: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(HaveKey("_ignore"))
+		})
+
+		It("does not modify the input", func() {
+			data := MustDeserialize(&deck)
+			_, err := Minimize(data, MinimizeOptions{TagPrefix: "internal-", RemovePlugins: []string{"file-log"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(MustDeserialize(&deck)))
+		})
+
+		It("returns an error if data is nil", func() {
+			_, err := Minimize(nil, MinimizeOptions{})
+			Expect(err).To(MatchError(ErrNilDocument))
+		})
+	})
+})
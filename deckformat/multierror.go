@@ -0,0 +1,124 @@
+package deckformat
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// locatedError is an error prefixed by the location it applies to, eg. a filename, or an
+// entity path like "services[0].routes[1]".
+type locatedError struct {
+	location string
+	err      error
+}
+
+func (e locatedError) Error() string {
+	return e.location + ": " + e.err.Error()
+}
+
+func (e locatedError) Unwrap() error {
+	return e.err
+}
+
+// MultiError aggregates errors, to report all the problems found at once, instead of only
+// the first. The messages are rendered sorted and without duplicates, such that the output
+// is deterministic. It matches (errors.Is and errors.As) any of the errors it holds. The
+// zero value is an empty MultiError, ready to use.
+type MultiError struct {
+	errs []error
+}
+
+// NewMultiError returns a MultiError holding the errors. Nil errors are skipped.
+func NewMultiError(errs ...error) *MultiError {
+	m := &MultiError{}
+	m.Add(errs...)
+	return m
+}
+
+// Add adds the errors. Nil errors are skipped, and the errors of another MultiError are
+// added individually.
+func (m *MultiError) Add(errs ...error) {
+	for _, err := range errs {
+		if multi, ok := err.(*MultiError); ok {
+			if multi != nil {
+				m.errs = append(m.errs, multi.errs...)
+			}
+		} else if err != nil {
+			m.errs = append(m.errs, err)
+		}
+	}
+}
+
+// AddAt adds the error, prefixed by its location (rendered as "<location>: <message>"). The
+// location is skipped if empty. A nil error is skipped.
+func (m *MultiError) AddAt(location string, err error) {
+	if err == nil {
+		return
+	}
+	if location == "" {
+		m.Add(err)
+		return
+	}
+	m.errs = append(m.errs, locatedError{location: location, err: err})
+}
+
+// Len returns the number of errors held, including duplicates.
+func (m *MultiError) Len() int {
+	return len(m.errs)
+}
+
+// ErrorOrNil returns the MultiError, or nil if it holds no errors. To be used as the
+// returned error, since a nil *MultiError is not a nil error.
+func (m *MultiError) ErrorOrNil() error {
+	if m == nil || len(m.errs) == 0 {
+		return nil
+	}
+	return m
+}
+
+// Messages returns the messages of the errors, sorted, without duplicates.
+func (m *MultiError) Messages() []string {
+	seen := make(map[string]bool, len(m.errs))
+	messages := make([]string, 0, len(m.errs))
+	for _, err := range m.errs {
+		message := err.Error()
+		if !seen[message] {
+			seen[message] = true
+			messages = append(messages, message)
+		}
+	}
+	sort.Strings(messages)
+	return messages
+}
+
+// Error returns the messages (see Messages), separated by "; ".
+func (m *MultiError) Error() string {
+	return strings.Join(m.Messages(), "; ")
+}
+
+// Unwrap returns a copy of the errors held, in the order they were added.
+func (m *MultiError) Unwrap() []error {
+	return append(make([]error, 0, len(m.errs)), m.errs...)
+}
+
+// Is returns true if any of the errors held matches the target. Implemented explicitly,
+// since errors.Is only checks 'Unwrap() []error' from Go 1.20 on.
+func (m *MultiError) Is(target error) bool {
+	for _, err := range m.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error held that matches the target (see errors.As).
+func (m *MultiError) As(target interface{}) bool {
+	for _, err := range m.errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package deckformat_test
+
+import (
+	"errors"
+	"io/fs"
+
+	. "github.com/kong/go-apiops/deckformat"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("multierror", func() {
+	Describe("MultiError", func() {
+		It("renders the messages sorted, without duplicates, with their location", func() {
+			multi := NewMultiError(errors.New("second"), nil, errors.New("first"))
+			multi.AddAt("services[0]", errors.New("no host"))
+			multi.AddAt("", errors.New("first"))
+			multi.AddAt("routes[1]", nil)
+
+			Expect(multi.Len()).To(Equal(4))
+			Expect(multi.Messages()).To(Equal([]string{"first", "second", "services[0]: no host"}))
+			Expect(multi.Error()).To(Equal("first; second; services[0]: no host"))
+		})
+
+		It("renders the same, independent of the order the errors were added", func() {
+			multi1 := NewMultiError(errors.New("b"), errors.New("a"))
+			multi2 := NewMultiError(errors.New("a"), errors.New("b"), errors.New("a"))
+			Expect(multi1.Error()).To(Equal(multi2.Error()))
+		})
+
+		It("flattens another MultiError", func() {
+			multi := NewMultiError(errors.New("a"))
+			multi.Add(NewMultiError(errors.New("b"), errors.New("c")))
+			Expect(multi.Len()).To(Equal(3))
+			Expect(multi.Error()).To(Equal("a; b; c"))
+		})
+
+		It("unwraps to the errors held, in order", func() {
+			err1 := errors.New("b")
+			err2 := errors.New("a")
+			multi := NewMultiError(err1, err2)
+			Expect(multi.Unwrap()).To(Equal([]error{err1, err2}))
+		})
+
+		It("matches any of the errors held, including located ones", func() {
+			multi := NewMultiError(errors.New("other"))
+			multi.AddAt("kong.yaml", fs.ErrNotExist)
+			Expect(errors.Is(multi, fs.ErrNotExist)).To(BeTrue())
+			Expect(errors.Is(multi, ErrIncompatible)).To(BeFalse())
+
+			var pathErr *fs.PathError
+			Expect(errors.As(multi, &pathErr)).To(BeFalse())
+			multi.Add(&fs.PathError{Op: "open", Path: "kong.yaml", Err: fs.ErrPermission})
+			Expect(errors.As(multi, &pathErr)).To(BeTrue())
+			Expect(pathErr.Path).To(Equal("kong.yaml"))
+		})
+
+		It("returns nil from ErrorOrNil if it is empty", func() {
+			var multi MultiError
+			Expect(multi.ErrorOrNil()).To(BeNil())
+			multi.Add(nil)
+			Expect(multi.ErrorOrNil()).To(BeNil())
+			multi.Add(errors.New("failed"))
+			Expect(multi.ErrorOrNil()).To(MatchError("failed"))
+		})
+	})
+})
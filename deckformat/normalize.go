@@ -0,0 +1,143 @@
+package deckformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/kong/go-apiops/jsonbasics"
+)
+
+// sortKeyFields are the fields used to sort entities, in order of preference. Entities
+// with equal keys are sorted by their serialized content.
+var sortKeyFields = []string{"name", "username", "custom_id", "id", "target", "cert", "key", "group"}
+
+// entitySortKey returns the value to sort an entity by.
+func entitySortKey(entity map[string]interface{}) string {
+	for _, fieldName := range sortKeyFields {
+		if value, ok := entity[fieldName].(string); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// canonicalizeEntityArray sorts the entities in the array-field 'entityType' of 'parent',
+// and recurses into the nested entities. 'path' is the path to the parent, for error messages.
+func canonicalizeEntityArray(parent map[string]interface{}, entityType string, path string) error {
+	if parent[entityType] == nil {
+		return nil
+	}
+	entities, err := jsonbasics.GetObjectArrayField(parent, entityType)
+	if err != nil {
+		return fmt.Errorf("failed to read '%s%s'; %w", path, entityType, err)
+	}
+
+	keys := make([]string, len(entities))
+	content := make([]string, len(entities))
+	for i, entity := range entities {
+		keys[i] = entitySortKey(entity)
+		serialized, _ := json.Marshal(entity)
+		content[i] = string(serialized)
+	}
+	indices := make([]int, len(entities))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		a, b := indices[i], indices[j]
+		if keys[a] != keys[b] {
+			return keys[a] < keys[b]
+		}
+		return content[a] < content[b]
+	})
+
+	sorted := make([]map[string]interface{}, len(entities))
+	for i, index := range indices {
+		sorted[i] = entities[index]
+	}
+	jsonbasics.SetObjectArrayField(parent, entityType, sorted)
+
+	for i, entity := range sorted {
+		entityPath := fmt.Sprintf("%s%s[%d].", path, entityType, i)
+		for _, nestedType := range EntityRegistry[entityType] {
+			if err := canonicalizeEntityArray(entity, nestedType, entityPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SortAllTags sorts the 'tags' of all entities in the deck file (including nested ones) in
+// place. Only the entity types in EntityRegistry are visited, other arrays are not touched.
+// Returns ErrNilDocument if filedata is nil.
+func SortAllTags(filedata map[string]interface{}) error {
+	return WalkEntities(filedata, func(entityType string, entity map[string]interface{}) error {
+		if entity["tags"] == nil {
+			return nil
+		}
+		tags, err := jsonbasics.GetStringArrayField(entity, "tags")
+		if err != nil {
+			return fmt.Errorf("expected 'tags' of an entity in '%s' to be an array; %w", entityType, err)
+		}
+		sort.Strings(tags)
+		sorted := make([]interface{}, len(tags))
+		for i, tag := range tags {
+			sorted[i] = tag
+		}
+		jsonbasics.SetArrayField(entity, "tags", sorted)
+		return nil
+	})
+}
+
+// Canonicalize sorts all entity arrays in the deck file (including nested ones) in place,
+// so that equal files have equal serializations. Entities are sorted by name (or another
+// identifying field if they have no name), and then by content. The tags of the entities
+// are sorted as well, see SortAllTags. Returns ErrNilDocument if data is nil.
+func Canonicalize(data map[string]interface{}) error {
+	if data == nil {
+		return ErrNilDocument
+	}
+	// sort the tags first, they are part of the content the entities are sorted by
+	if err := SortAllTags(data); err != nil {
+		return err
+	}
+	for entityType := range EntityRegistry {
+		if err := canonicalizeEntityArray(data, entityType, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NormalizeOptions selects the steps taken by Normalize.
+type NormalizeOptions struct {
+	SkipCanonicalize bool // do not sort the entity arrays
+	SkipRemoveNulls  bool // do not remove null fields
+	SkipHistory      bool // do not normalize the history info
+}
+
+// Normalize brings a deck file in place in a canonical form, suitable for committing; it
+// removes null fields, sorts the entity arrays, and normalizes the history info. The result
+// is idempotent, normalizing a normalized file has no effect. Returns ErrNilDocument if
+// data is nil.
+func Normalize(data map[string]interface{}, opts NormalizeOptions) error {
+	if data == nil {
+		return ErrNilDocument
+	}
+	if !opts.SkipRemoveNulls {
+		jsonbasics.RemoveNulls(data)
+	}
+	if !opts.SkipCanonicalize {
+		if err := Canonicalize(data); err != nil {
+			return err
+		}
+	}
+	if !opts.SkipHistory {
+		if err := HistoryNormalize(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package deckformat_test
+
+import (
+	. "github.com/kong/go-apiops/deckformat"
+	. "github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/internal/testutil"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("normalize", func() {
+	Describe("Normalize", func() {
+		It("brings a file in canonical form", func() {
+			data := MustDeserializeFile("./normalize_testfiles/input.yml")
+			Expect(Normalize(data, NormalizeOptions{})).To(Succeed())
+			MustWriteSerializedFile("./normalize_testfiles/generated.json", data, OutputFormatJSON)
+
+			result := MustSerialize(data, OutputFormatJSON)
+			expected := MustReadFile("./normalize_testfiles/expected.json")
+			Expect(*result).To(MatchJSON(*expected))
+		})
+
+		It("is idempotent", func() {
+			normalize := func(input []byte) []byte {
+				data := MustDeserialize(&input)
+				Expect(Normalize(data, NormalizeOptions{})).To(Succeed())
+				return *MustSerialize(data, OutputFormatJSON)
+			}
+			input := MustReadFile("./normalize_testfiles/input.yml")
+			Expect(testutil.AssertIdempotent(GinkgoT(), normalize, *input)).To(BeTrue())
+		})
+
+		It("skips the disabled steps", func() {
+			data := MustDeserializeFile("./normalize_testfiles/input.yml")
+			Expect(Normalize(data, NormalizeOptions{
+				SkipCanonicalize: true,
+				SkipRemoveNulls:  true,
+			})).To(Succeed())
+
+			services, _ := data["services"].([]interface{})
+			Expect(services[0].(map[string]interface{})["name"]).To(Equal("zebra"))
+			Expect(services[0].(map[string]interface{})).To(HaveKeyWithValue("path", BeNil()))
+		})
+
+		It("returns an error if data is nil", func() {
+			Expect(Normalize(nil, NormalizeOptions{})).To(MatchError(ErrNilDocument))
+		})
+	})
+
+	Describe("SortAllTags", func() {
+		It("sorts the tags of all entity types, including nested ones", func() {
+			input := []byte(`
+services:
+  - name: svc
+    tags: [ zulu, alpha ]
+    hosts: [ z.example.com, a.example.com ]
+    routes:
+      - name: route
+        tags: [ mike, bravo ]
+        methods: [ POST, GET ]
+        plugins:
+          - name: cors
+            tags: [ yankee, charlie ]
+consumers:
+  - username: john
+    tags: [ xray, delta ]
+upstreams:
+  - name: up
+    targets:
+      - target: 1.2.3.4:80
+        tags: [ whiskey, echo ]
+`)
+			data := MustDeserialize(&input)
+			Expect(SortAllTags(data)).To(Succeed())
+			Expect(*MustSerialize(data, OutputFormatJSON)).To(MatchJSON(`{
+				"services": [{
+					"name": "svc",
+					"tags": ["alpha", "zulu"],
+					"hosts": ["z.example.com", "a.example.com"],
+					"routes": [{
+						"name": "route",
+						"tags": ["bravo", "mike"],
+						"methods": ["POST", "GET"],
+						"plugins": [{ "name": "cors", "tags": ["charlie", "yankee"] }]
+					}]
+				}],
+				"consumers": [{ "username": "john", "tags": ["delta", "xray"] }],
+				"upstreams": [{
+					"name": "up",
+					"targets": [{ "target": "1.2.3.4:80", "tags": ["echo", "whiskey"] }]
+				}]
+			}`))
+
+			first := MustSerialize(data, OutputFormatJSON)
+			Expect(SortAllTags(data)).To(Succeed())
+			Expect(*MustSerialize(data, OutputFormatJSON)).To(Equal(*first))
+		})
+
+		It("is applied by Canonicalize", func() {
+			input := []byte(`{ "services": [{ "name": "svc", "tags": ["b", "a"] }] }`)
+			data := MustDeserialize(&input)
+			Expect(Canonicalize(data)).To(Succeed())
+			Expect(*MustSerialize(data, OutputFormatJSON)).To(MatchJSON(
+				`{ "services": [{ "name": "svc", "tags": ["a", "b"] }] }`))
+		})
+
+		It("returns an error if data is nil", func() {
+			Expect(SortAllTags(nil)).To(MatchError(ErrNilDocument))
+		})
+	})
+})
@@ -0,0 +1,164 @@
+package deckformat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kong/go-apiops/jsonbasics"
+)
+
+// pluginScopes are the entity types a plugin can be scoped to, with the foreign key field
+// used on a top-level plugin, in output order.
+var pluginScopes = []struct {
+	EntityType string
+	Field      string
+}{
+	{"services", "service"},
+	{"routes", "route"},
+	{"consumers", "consumer"},
+	{"consumer_groups", "consumer_group"},
+}
+
+// PluginBinding is a plugin in a deck file, with the entities it is scoped to. Entities are
+// identified by their name (username for consumers), or their id if they have no name.
+type PluginBinding struct {
+	Name    string // plugin name
+	ID      string // plugin id, if set
+	Scope   string // "global", or the entity types joined by "+" (eg. "service+consumer")
+	Target  string // the entity names, joined by "+", empty for global plugins
+	Missing bool   // (one of) the target entities is not in the file
+}
+
+// getBindingName returns the name of an entity, its username if it has none, or its id.
+func getBindingName(entity map[string]interface{}) string {
+	if name, err := jsonbasics.GetStringField(entity, "name"); err == nil && name != "" {
+		return name
+	}
+	if username, err := jsonbasics.GetStringField(entity, "username"); err == nil && username != "" {
+		return username
+	}
+	id, _ := jsonbasics.GetStringField(entity, "id")
+	return id
+}
+
+// getBindingRef returns the value of a foreign key field, which is either a string, or an
+// object with a 'name', 'username', or 'id'. Returns "" if not set.
+func getBindingRef(entity map[string]interface{}, field string) string {
+	switch ref := entity[field].(type) {
+	case string:
+		return ref
+	case map[string]interface{}:
+		return getBindingName(ref)
+	}
+	return ""
+}
+
+// GetPluginBindings returns all plugins in the deck file, top-level and nested, with their
+// scope and target entities. Nested plugins are scoped to their parent entity, top-level
+// ones by their foreign keys. Targets are resolved by name or id, plugins referring to
+// entities not in the file are marked Missing. The result is sorted by plugin name, scope,
+// and target. Returns ErrNilDocument if data is nil.
+func GetPluginBindings(data map[string]interface{}) ([]PluginBinding, error) {
+	if data == nil {
+		return nil, ErrNilDocument
+	}
+
+	// index the entities that plugins can be scoped to, by name and id
+	entityNames := make(map[string]map[string]string)
+	for _, scope := range pluginScopes {
+		entityNames[scope.EntityType] = make(map[string]string)
+	}
+	err := WalkEntities(data, func(entityType string, entity map[string]interface{}) error {
+		names := entityNames[entityType]
+		if names == nil {
+			return nil
+		}
+		name := getBindingName(entity)
+		names[name] = name
+		if id, err := jsonbasics.GetStringField(entity, "id"); err == nil && id != "" {
+			names[id] = name
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bindings := make([]PluginBinding, 0)
+	var collect func(parent map[string]interface{}, parentType string, nestedTypes []string, path string) error
+	collect = func(parent map[string]interface{}, parentType string, nestedTypes []string, path string) error {
+		for _, nestedType := range nestedTypes {
+			entities, err := jsonbasics.GetObjectArrayField(parent, nestedType)
+			if err != nil {
+				return fmt.Errorf("failed to read '%s%s'; %w", path, nestedType, err)
+			}
+			for i, entity := range entities {
+				entityPath := fmt.Sprintf("%s%s[%d].", path, nestedType, i)
+				if nestedType != "plugins" {
+					if err := collect(entity, nestedType, EntityRegistry[nestedType], entityPath); err != nil {
+						return err
+					}
+					continue
+				}
+				bindings = append(bindings, getPluginBinding(entity, parent, parentType, entityNames))
+			}
+		}
+		return nil
+	}
+
+	// the document has all entity types nested
+	topLevel := make([]string, 0, len(EntityRegistry))
+	for entityType := range EntityRegistry {
+		topLevel = append(topLevel, entityType)
+	}
+	sort.Strings(topLevel)
+	if err := collect(data, "", topLevel, ""); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(bindings, func(i, j int) bool {
+		k1 := strings.Join([]string{bindings[i].Name, bindings[i].Scope, bindings[i].Target}, "\x00")
+		k2 := strings.Join([]string{bindings[j].Name, bindings[j].Scope, bindings[j].Target}, "\x00")
+		return k1 < k2
+	})
+	return bindings, nil
+}
+
+// getPluginBinding returns the binding of a plugin, nested in 'parent' of type 'parentType'
+// ("" for a top-level plugin).
+func getPluginBinding(plugin map[string]interface{}, parent map[string]interface{}, parentType string,
+	entityNames map[string]map[string]string,
+) PluginBinding {
+	binding := PluginBinding{}
+	binding.Name, _ = jsonbasics.GetStringField(plugin, "name")
+	binding.ID, _ = jsonbasics.GetStringField(plugin, "id")
+
+	scopes := make([]string, 0)
+	targets := make([]string, 0)
+	for _, scope := range pluginScopes {
+		var target string
+		if scope.EntityType == parentType {
+			target = getBindingName(parent)
+		} else if ref := getBindingRef(plugin, scope.Field); ref != "" {
+			name, found := entityNames[scope.EntityType][ref]
+			if !found {
+				name = ref
+				binding.Missing = true
+			}
+			target = name
+		} else {
+			continue
+		}
+		scopes = append(scopes, scope.Field)
+		targets = append(targets, target)
+	}
+
+	if len(scopes) == 0 {
+		binding.Scope = "global"
+	} else {
+		binding.Scope = strings.Join(scopes, "+")
+		binding.Target = strings.Join(targets, "+")
+	}
+	return binding
+}
@@ -0,0 +1,64 @@
+package deckformat_test
+
+import (
+	. "github.com/kong/go-apiops/deckformat"
+	. "github.com/kong/go-apiops/filebasics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("plugins", func() {
+	Describe("GetPluginBindings", func() {
+		It("lists the global and scoped plugins", func() {
+			deck := []byte(`{
+				"_format_version": "3.0",
+				"plugins": [
+					{ "name": "prometheus" },
+					{ "name": "cors", "service": "svc1" },
+					{ "name": "acl", "route": { "id": "route-id-1" }, "consumer": "alice" },
+					{ "name": "rate-limiting", "service": "missing-svc" }
+				],
+				"services": [
+					{
+						"name": "svc1",
+						"plugins": [ { "name": "key-auth", "id": "plugin-id-1" } ],
+						"routes": [
+							{
+								"name": "route1",
+								"id": "route-id-1",
+								"plugins": [ { "name": "request-termination" } ]
+							}
+						]
+					}
+				],
+				"consumers": [
+					{ "username": "alice", "plugins": [ { "name": "rate-limiting" } ] }
+				]
+			}`)
+			data := MustDeserialize(&deck)
+
+			bindings, err := GetPluginBindings(data)
+			Expect(err).To(BeNil())
+			Expect(bindings).To(Equal([]PluginBinding{
+				{Name: "acl", Scope: "route+consumer", Target: "route1+alice"},
+				{Name: "cors", Scope: "service", Target: "svc1"},
+				{Name: "key-auth", ID: "plugin-id-1", Scope: "service", Target: "svc1"},
+				{Name: "prometheus", Scope: "global"},
+				{Name: "rate-limiting", Scope: "consumer", Target: "alice"},
+				{Name: "rate-limiting", Scope: "service", Target: "missing-svc", Missing: true},
+				{Name: "request-termination", Scope: "route", Target: "route1"},
+			}))
+		})
+
+		It("returns an empty list if there are no plugins", func() {
+			bindings, err := GetPluginBindings(map[string]interface{}{})
+			Expect(err).To(BeNil())
+			Expect(bindings).To(BeEmpty())
+		})
+
+		It("returns an error if data is nil", func() {
+			_, err := GetPluginBindings(nil)
+			Expect(err).To(MatchError(ErrNilDocument))
+		})
+	})
+})
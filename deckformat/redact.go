@@ -0,0 +1,130 @@
+package deckformat
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/kong/go-apiops/jsonbasics"
+)
+
+// DefaultRedactPlaceholder is the value secrets are replaced with by Redact, if no
+// placeholder is given.
+const DefaultRedactPlaceholder = "<redacted>"
+
+// DefaultRedactFields are the fields redacted by Redact, if none are given. A plain field
+// name matches in any object (eg. in a plugin config), an "entitytype.field" name only
+// matches on entities of that type.
+var DefaultRedactFields = []string{
+	"api_key",
+	"client_secret",
+	"password",
+	"private_key",
+	"secret",
+	"secret_key",
+	"token",
+	"certificates.key",
+	"keyauth_credentials.key",
+}
+
+// RedactOptions configures Redact.
+type RedactOptions struct {
+	Fields      []string // the fields to redact, DefaultRedactFields if empty
+	Placeholder string   // the replacement value (eg. a vault reference), DefaultRedactPlaceholder if empty
+}
+
+// isRedactField returns true if the field of an object matches one of the fields to redact.
+// 'entityType' is the array the object is in, or "".
+func isRedactField(entityType string, field string, fields map[string]bool) bool {
+	return fields[field] || (entityType != "" && fields[entityType+"."+field])
+}
+
+// redactValue returns the redacted value, and whether anything was replaced. Empty strings
+// and vault references are left alone, arrays of strings are redacted per entry.
+func redactValue(value interface{}, placeholder string) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		if v == "" || strings.HasPrefix(v, "{vault://") {
+			return v, false
+		}
+		return placeholder, true
+	case []interface{}:
+		redacted := false
+		for i, entry := range v {
+			if _, isString := entry.(string); !isString {
+				continue
+			}
+			if newValue, ok := redactValue(entry, placeholder); ok {
+				v[i] = newValue
+				redacted = true
+			}
+		}
+		return v, redacted
+	}
+	return value, false
+}
+
+// Redact returns a copy of the deck file with the secret fields (eg. credentials of
+// consumers, and secrets in plugin configs) replaced with a placeholder, leaving the
+// structure intact. Also returns the paths of the redacted fields, sorted, eg.
+// "consumers[0].keyauth_credentials[0].key". The input is not modified. Returns
+// ErrNilDocument if filedata is nil.
+func Redact(filedata map[string]interface{}, opts RedactOptions) (map[string]interface{}, []string, error) {
+	if filedata == nil {
+		return nil, nil, ErrNilDocument
+	}
+	result := *jsonbasics.DeepCopyObject(&filedata)
+
+	fieldList := opts.Fields
+	if len(fieldList) == 0 {
+		fieldList = DefaultRedactFields
+	}
+	fields := make(map[string]bool, len(fieldList))
+	for _, field := range fieldList {
+		fields[field] = true
+	}
+	placeholder := opts.Placeholder
+	if placeholder == "" {
+		placeholder = DefaultRedactPlaceholder
+	}
+
+	redacted := make([]string, 0)
+	err := jsonbasics.Walk(result, func(path []string, value interface{}) error {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		// an object in an array is an entity of the type named by the array
+		entityType := ""
+		if len(path) >= 2 && strings.HasPrefix(path[len(path)-1], "[") {
+			entityType = path[len(path)-2]
+		}
+		for field, fieldValue := range obj {
+			if !isRedactField(entityType, field, fields) {
+				continue
+			}
+			if newValue, ok := redactValue(fieldValue, placeholder); ok {
+				obj[field] = newValue
+				redacted = append(redacted, formatRedactPath(append(path, field)))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.Strings(redacted)
+	return result, redacted, nil
+}
+
+// formatRedactPath formats a path as returned by jsonbasics.Walk, eg. "plugins[0].config".
+func formatRedactPath(path []string) string {
+	var b strings.Builder
+	for i, element := range path {
+		if i > 0 && !strings.HasPrefix(element, "[") {
+			b.WriteString(".")
+		}
+		b.WriteString(element)
+	}
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package deckformat_test
+
+import (
+	. "github.com/kong/go-apiops/deckformat"
+	. "github.com/kong/go-apiops/filebasics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("redact", func() {
+	deck := []byte(`{
+		"_format_version": "3.0",
+		"consumers": [
+			{
+				"username": "alice",
+				"keyauth_credentials": [ { "key": "alice-secret-key", "tags": [ "team-a" ] } ],
+				"basicauth_credentials": [ { "username": "alice", "password": "{vault://env/alice-pw}" } ]
+			}
+		],
+		"plugins": [
+			{
+				"name": "openid-connect",
+				"config": {
+					"issuer": "https://idp.example.com",
+					"client_id": [ "my-client" ],
+					"client_secret": [ "my-secret" ]
+				}
+			}
+		]
+	}`)
+
+	Describe("Redact", func() {
+		It("replaces a key-auth key, preserving the rest", func() {
+			data := MustDeserialize(&deck)
+			result, redacted, err := Redact(data, RedactOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			consumer := result["consumers"].([]interface{})[0].(map[string]interface{})
+			Expect(consumer["username"]).To(Equal("alice"))
+			Expect(consumer["keyauth_credentials"]).To(Equal([]interface{}{
+				map[string]interface{}{"key": DefaultRedactPlaceholder, "tags": []interface{}{"team-a"}},
+			}))
+			Expect(redacted).To(ContainElement("consumers[0].keyauth_credentials[0].key"))
+
+			// the input is not modified
+			original := data["consumers"].([]interface{})[0].(map[string]interface{})
+			Expect(original["keyauth_credentials"].([]interface{})[0]).To(HaveKeyWithValue("key", "alice-secret-key"))
+		})
+
+		It("redacts plugin configs, and skips vault references", func() {
+			data := MustDeserialize(&deck)
+			result, redacted, err := Redact(data, RedactOptions{Placeholder: "{vault://env/secret}"})
+			Expect(err).ToNot(HaveOccurred())
+
+			config := result["plugins"].([]interface{})[0].(map[string]interface{})["config"].(map[string]interface{})
+			Expect(config["client_secret"]).To(Equal([]interface{}{"{vault://env/secret}"}))
+			Expect(config["client_id"]).To(Equal([]interface{}{"my-client"}))
+			Expect(config["issuer"]).To(Equal("https://idp.example.com"))
+			Expect(redacted).To(Equal([]string{
+				"consumers[0].keyauth_credentials[0].key",
+				"plugins[0].config.client_secret",
+			}))
+		})
+
+		It("only redacts the given fields", func() {
+			data := MustDeserialize(&deck)
+			_, redacted, err := Redact(data, RedactOptions{Fields: []string{"client_secret"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(redacted).To(Equal([]string{"plugins[0].config.client_secret"}))
+		})
+
+		It("fails on a nil document", func() {
+			_, _, err := Redact(nil, RedactOptions{})
+			Expect(err).To(MatchError(ErrNilDocument))
+		})
+	})
+})
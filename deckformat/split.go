@@ -0,0 +1,121 @@
+package deckformat
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/kong/go-apiops/jsonbasics"
+)
+
+// untaggedPart is the name of the part holding the entities without tags, when splitting by tag.
+const untaggedPart = "untagged"
+
+// partNameRegex matches the characters NOT allowed in part names (they are used as filenames).
+var partNameRegex = regexp.MustCompile(`[^a-zA-Z0-9\.\-_]`)
+
+// newPart returns a new, empty, deck file with the '_format_version' and '_transform' keys
+// of 'data' (if set).
+func newPart(data map[string]interface{}) map[string]interface{} {
+	cfg := getConfig()
+	part := make(map[string]interface{})
+	for _, key := range []string{cfg.VersionKey, cfg.TransformKey} {
+		if value, found := data[key]; found {
+			part[key] = value
+		}
+	}
+	return part
+}
+
+// isMetaKey returns true for the top-level keys that are copied into every part, or dropped.
+func isMetaKey(key string) bool {
+	cfg := getConfig()
+	return key == cfg.VersionKey || key == cfg.TransformKey || key == cfg.HistoryKey
+}
+
+// SplitByType splits a deck file into parts, one per top-level key (eg. "services",
+// "plugins"). Nested entities remain with their parent (eg. routes nested in a service
+// end up in "services"). Each part is a valid deck file, with the '_format_version' and
+// '_transform' keys of the original. The history (if any) is dropped. Merging the parts
+// reproduces the original. Returns ErrNilDocument if data is nil.
+func SplitByType(data map[string]interface{}) (map[string]map[string]interface{}, error) {
+	if data == nil {
+		return nil, ErrNilDocument
+	}
+
+	parts := make(map[string]map[string]interface{})
+	for key, value := range data {
+		if isMetaKey(key) {
+			continue
+		}
+		part := newPart(data)
+		part[key] = value
+		parts[key] = part
+	}
+	return parts, nil
+}
+
+// SplitByTag splits a deck file into parts, one per tag. Top-level entities go into the
+// part of their first tag. Entities without tags, and any other top-level keys, go into the
+// "untagged" part. Part names are sanitized (for use as filenames). Each part is a valid deck
+// file, with the '_format_version' and '_transform' keys of the original. The history (if
+// any) is dropped. Merging the parts reproduces the original (modulo ordering). Returns
+// ErrNilDocument if data is nil.
+func SplitByTag(data map[string]interface{}) (map[string]map[string]interface{}, error) {
+	if data == nil {
+		return nil, ErrNilDocument
+	}
+
+	parts := make(map[string]map[string]interface{})
+	partTags := make(map[string]string) // the tag by part name, to detect collisions
+	getPart := func(name string) map[string]interface{} {
+		if parts[name] == nil {
+			parts[name] = newPart(data)
+		}
+		return parts[name]
+	}
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if isMetaKey(key) {
+			continue
+		}
+		if _, isEntity := EntityRegistry[key]; !isEntity {
+			getPart(untaggedPart)[key] = data[key]
+			continue
+		}
+
+		entities, err := jsonbasics.GetObjectArrayField(data, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read '%s'; %w", key, err)
+		}
+		for i, entity := range entities {
+			tags, err := jsonbasics.GetStringArrayField(entity, "tags")
+			if err != nil {
+				return nil, fmt.Errorf("expected '%s[%d].tags' to be an array; %w", key, i, err)
+			}
+
+			name := untaggedPart
+			if len(tags) > 0 {
+				name = partNameRegex.ReplaceAllString(tags[0], "_")
+				if name == untaggedPart {
+					return nil, fmt.Errorf("tag '%s' collides with the name of the part for untagged entities", tags[0])
+				}
+				if existing, found := partTags[name]; found && existing != tags[0] {
+					return nil, fmt.Errorf("tags '%s' and '%s' have the same sanitized name '%s'", existing, tags[0], name)
+				}
+				partTags[name] = tags[0]
+			}
+
+			part := getPart(name)
+			entityArray, _ := part[key].([]interface{})
+			part[key] = append(entityArray, entity)
+		}
+	}
+	return parts, nil
+}
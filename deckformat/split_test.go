@@ -0,0 +1,103 @@
+package deckformat_test
+
+import (
+	"path/filepath"
+	"sort"
+
+	. "github.com/kong/go-apiops/deckformat"
+	. "github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/merge"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("split", func() {
+	deck := []byte(`{
+		"_format_version": "3.0",
+		"_transform": true,
+		"_ignore": [ { "command": "openapi2kong" } ],
+		"services": [
+			{ "name": "svc1", "tags": [ "team-a" ], "routes": [ { "name": "route1" } ] },
+			{ "name": "svc2", "tags": [ "team-b", "team-a" ] }
+		],
+		"routes": [
+			{ "name": "route2", "service": { "name": "svc1" } }
+		],
+		"plugins": [
+			{ "name": "cors", "tags": [ "team-a" ] }
+		]
+	}`)
+
+	// writeParts writes the parts to a temp directory, and returns the sorted filenames
+	writeParts := func(parts map[string]map[string]interface{}) []string {
+		dir := GinkgoT().TempDir()
+		filenames := make([]string, 0, len(parts))
+		for name, part := range parts {
+			filename := filepath.Join(dir, name+".yaml")
+			MustWriteSerializedFile(filename, part, OutputFormatYaml)
+			filenames = append(filenames, filename)
+		}
+		sort.Strings(filenames)
+		return filenames
+	}
+
+	// expectRoundTrip merges the files, and compares the result to the original (without history)
+	expectRoundTrip := func(filenames []string) {
+		merged, _ := merge.MustFiles(filenames)
+		Expect(Canonicalize(merged)).To(Succeed())
+
+		original := MustDeserialize(&deck)
+		HistoryClear(original)
+		Expect(Canonicalize(original)).To(Succeed())
+
+		Expect(*MustSerialize(merged, OutputFormatJSON)).To(MatchJSON(*MustSerialize(original, OutputFormatJSON)))
+	}
+
+	Describe("SplitByType", func() {
+		It("creates a file per type, which merge back into the original", func() {
+			parts, err := SplitByType(MustDeserialize(&deck))
+			Expect(err).To(BeNil())
+			Expect(parts).To(HaveLen(3))
+			Expect(parts).To(HaveKey("services"))
+			Expect(parts).To(HaveKey("routes"))
+			Expect(parts).To(HaveKey("plugins"))
+			for _, part := range parts {
+				Expect(part).To(HaveKeyWithValue(VersionKey, "3.0"))
+				Expect(part).To(HaveKeyWithValue(TransformKey, true))
+				Expect(part).ToNot(HaveKey(HistoryKey))
+				Expect(part).To(HaveLen(3))
+			}
+
+			expectRoundTrip(writeParts(parts))
+		})
+
+		It("returns an error if data is nil", func() {
+			_, err := SplitByType(nil)
+			Expect(err).To(MatchError(ErrNilDocument))
+		})
+	})
+
+	Describe("SplitByTag", func() {
+		It("creates a file per tag, which merge back into the original", func() {
+			parts, err := SplitByTag(MustDeserialize(&deck))
+			Expect(err).To(BeNil())
+			Expect(parts).To(HaveLen(3))
+			Expect(parts["team-a"]["services"]).To(HaveLen(1))
+			Expect(parts["team-a"]["plugins"]).To(HaveLen(1))
+			Expect(parts["team-b"]["services"]).To(HaveLen(1))
+			Expect(parts["untagged"]["routes"]).To(HaveLen(1))
+			Expect(parts["untagged"]).To(HaveKeyWithValue(VersionKey, "3.0"))
+
+			expectRoundTrip(writeParts(parts))
+		})
+
+		It("returns an error on colliding tags", func() {
+			data := []byte(`{ "services": [
+				{ "name": "svc1", "tags": [ "a/b" ] },
+				{ "name": "svc2", "tags": [ "a:b" ] }
+			]}`)
+			_, err := SplitByTag(MustDeserialize(&data))
+			Expect(err).To(MatchError("tags 'a/b' and 'a:b' have the same sanitized name 'a_b'"))
+		})
+	})
+})
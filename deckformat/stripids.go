@@ -0,0 +1,157 @@
+package deckformat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// stripIDReferences are the fields referring to other entities that StripIDs rewrites from
+// an id to a name, with the entity type they refer to.
+var stripIDReferences = []struct {
+	Field      string
+	EntityType string
+}{
+	{"service", "services"},
+	{"route", "routes"},
+	{"consumer", "consumers"},
+	{"consumer_group", "consumer_groups"},
+	{"upstream", "upstreams"},
+}
+
+// idOnlyReferences are the fields referring to entities by id only, since the entity types
+// they refer to have no name.
+var idOnlyReferences = []string{"ca_certificates", "certificate", "client_certificate"}
+
+// getReferenceID returns the id a reference field refers to. The field is either a string,
+// or an object with an 'id'. Returns "" if not set, or if the object has no 'id'.
+func getReferenceID(entity map[string]interface{}, field string) string {
+	switch ref := entity[field].(type) {
+	case string:
+		return ref
+	case map[string]interface{}:
+		id, _ := ref["id"].(string)
+		return id
+	}
+	return ""
+}
+
+// referenceName returns the field holding the name an entity type is referred to by;
+// "username" for consumers, "name" for others.
+func referenceName(entityType string) string {
+	if entityType == "consumers" {
+		return "username"
+	}
+	return "name"
+}
+
+// StripIDs removes the 'id' field from all entities in the deck file (including nested
+// ones), for workflows identifying the entities by name, where Kong assigns the ids. The
+// references by id to entities in the file (the 'service', 'route', 'consumer',
+// 'consumer_group', and 'upstream' fields) are rewritten to refer to their name instead. References to
+// entities that are not in the file are left as is. The file is modified in place.
+//
+// Returns an error (a MultiError), without modifying the file, if an entity would be left
+// without the fields identifying it (see FindUnidentifiedEntities), if a referred entity
+// has no name, or if an entity without a name (eg. a certificate) is referred to by id.
+func StripIDs(filedata map[string]interface{}) error {
+	if filedata == nil {
+		return ErrNilDocument
+	}
+
+	// collect the names by id of the entities that can be referred to, and check that every
+	// entity can still be identified without its id
+	names := make(map[string]map[string]string)
+	for _, ref := range stripIDReferences {
+		names[ref.EntityType] = make(map[string]string)
+	}
+	idOnly := make(map[string]bool) // the ids of the entities that can only be referred to by id
+	errs := &MultiError{}
+	err := walkEntities(filedata, func(entityType string, path string, entity map[string]interface{}) error {
+		id, _ := entity["id"].(string)
+		if id == "" {
+			return nil
+		}
+		if names[entityType] != nil {
+			name, _ := entity[referenceName(entityType)].(string)
+			names[entityType][id] = name
+		}
+		if entityType == "certificates" || entityType == "ca_certificates" {
+			idOnly[id] = true
+		}
+
+		fields, found := identityFields[entityType]
+		if !found {
+			fields = []string{"id", "name"}
+		}
+		others := make([]string, 0, len(fields))
+		for _, field := range fields {
+			if field == "id" {
+				continue
+			}
+			if value, ok := entity[field].(string); ok && value != "" {
+				return nil
+			}
+			others = append(others, "'"+field+"'")
+		}
+		errs.AddAt(path, fmt.Errorf("cannot strip the id, the '%s' entity has no %s to identify it by",
+			entityType, strings.Join(others, " or ")))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// find the references to rewrite, and check they can be
+	rewrites := make([]func(), 0)
+	err = walkEntities(filedata, func(entityType string, path string, entity map[string]interface{}) error {
+		for _, ref := range stripIDReferences {
+			id := getReferenceID(entity, ref.Field)
+			name, found := names[ref.EntityType][id]
+			if id == "" || !found {
+				continue // not by id, or not in the file
+			}
+			if name == "" {
+				errs.AddAt(path, fmt.Errorf("cannot rewrite '%s' to a name, the '%s' entity with id '%s' has no '%s'",
+					ref.Field, ref.EntityType, id, referenceName(ref.EntityType)))
+				continue
+			}
+			field, nameField := ref.Field, referenceName(ref.EntityType)
+			rewrites = append(rewrites, func() {
+				if obj, ok := entity[field].(map[string]interface{}); ok {
+					delete(obj, "id")
+					obj[nameField] = name
+				} else {
+					entity[field] = name
+				}
+			})
+		}
+
+		for _, field := range idOnlyReferences {
+			refs, isArray := entity[field].([]interface{})
+			if !isArray {
+				refs = []interface{}{getReferenceID(entity, field)}
+			}
+			for _, ref := range refs {
+				if id, _ := ref.(string); idOnly[id] {
+					errs.AddAt(path, fmt.Errorf("cannot strip the id '%s' it refers to in '%s', the entity "+
+						"has no name", id, field))
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if errs.Len() > 0 {
+		return errs
+	}
+
+	for _, rewrite := range rewrites {
+		rewrite()
+	}
+	return walkEntities(filedata, func(_ string, _ string, entity map[string]interface{}) error {
+		delete(entity, "id")
+		return nil
+	})
+}
@@ -0,0 +1,83 @@
+package deckformat_test
+
+import (
+	. "github.com/kong/go-apiops/deckformat"
+	. "github.com/kong/go-apiops/filebasics"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("stripids", func() {
+	Describe("StripIDs", func() {
+		It("removes the ids, and rewrites the references to names", func() {
+			data := []byte(`{
+				"services": [
+					{ "id": "svc-id", "name": "svc", "routes": [
+						{ "id": "route-id", "name": "route1", "plugins": [ { "id": "p1", "name": "cors" } ] }
+					] }
+				],
+				"routes": [ { "id": "r2", "name": "route2", "service": { "id": "svc-id" } } ],
+				"consumers": [ { "id": "c-id", "username": "alice" } ],
+				"plugins": [
+					{ "id": "p2", "name": "key-auth", "route": "route-id", "consumer": { "id": "c-id" } },
+					{ "id": "p3", "name": "acl", "service": "svc" },
+					{ "name": "cors", "service": "external-id" }
+				]
+			}`)
+			filedata := MustDeserialize(&data)
+			Expect(StripIDs(filedata)).To(Succeed())
+
+			expected := []byte(`{
+				"services": [
+					{ "name": "svc", "routes": [
+						{ "name": "route1", "plugins": [ { "name": "cors" } ] }
+					] }
+				],
+				"routes": [ { "name": "route2", "service": { "name": "svc" } } ],
+				"consumers": [ { "username": "alice" } ],
+				"plugins": [
+					{ "name": "key-auth", "route": "route1", "consumer": { "username": "alice" } },
+					{ "name": "acl", "service": "svc" },
+					{ "name": "cors", "service": "external-id" }
+				]
+			}`)
+			Expect(filedata).To(Equal(MustDeserialize(&expected)))
+		})
+
+		It("rewrites the references of targets to their upstream", func() {
+			data := []byte(`{
+				"upstreams": [ { "id": "up-id", "name": "backend", "targets": [ { "id": "t1", "target": "a:80" } ] } ],
+				"targets": [ { "id": "t2", "target": "b:80", "upstream": "up-id" } ]
+			}`)
+			filedata := MustDeserialize(&data)
+			Expect(StripIDs(filedata)).To(Succeed())
+
+			expected := []byte(`{
+				"upstreams": [ { "name": "backend", "targets": [ { "target": "a:80" } ] } ],
+				"targets": [ { "target": "b:80", "upstream": "backend" } ]
+			}`)
+			Expect(filedata).To(Equal(MustDeserialize(&expected)))
+		})
+
+		It("fails, without modifying the file, if entities cannot be identified by name", func() {
+			data := []byte(`{
+				"services": [ { "id": "svc-id", "host": "example.com" } ],
+				"consumers": [ { "id": "c-id", "custom_id": "123" } ],
+				"plugins": [ { "id": "p1", "name": "key-auth", "consumer": "c-id" } ],
+				"certificates": [ { "id": "cert-id", "cert": "x", "key": "y" } ],
+				"snis": [ { "name": "example.com", "certificate": { "id": "cert-id" } } ]
+			}`)
+			filedata := MustDeserialize(&data)
+			err := StripIDs(filedata)
+			Expect(err).To(MatchError("plugins[0]: cannot rewrite 'consumer' to a name, the 'consumers' " +
+				"entity with id 'c-id' has no 'username'; " +
+				"services[0]: cannot strip the id, the 'services' entity has no 'name' to identify it by; " +
+				"snis[0]: cannot strip the id 'cert-id' it refers to in 'certificate', the entity has no name"))
+			Expect(filedata).To(Equal(MustDeserialize(&data)))
+		})
+
+		It("fails on a nil document", func() {
+			Expect(StripIDs(nil)).To(MatchError(ErrNilDocument))
+		})
+	})
+})
@@ -1,14 +1,22 @@
 package filebasics
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 
+	"github.com/kong/go-apiops/jsonbasics"
+	yamlv3 "gopkg.in/yaml.v3"
 	"sigs.k8s.io/yaml"
 )
 
@@ -18,26 +26,177 @@ const (
 	OutputFormatJSON  = "JSON"
 )
 
+// SupportedOutputFormats returns the formats accepted by Serialize, eg. "JSON".
+func SupportedOutputFormats() []string {
+	return []string{OutputFormatJSON, OutputFormatYaml}
+}
+
+// ValidateOutputFormat returns the format in upper case (as used by Serialize), or an error
+// listing the supported formats if it is unknown. The format is case-insensitive.
+func ValidateOutputFormat(format string) (string, error) {
+	upperFormat := strings.ToUpper(format)
+	for _, supported := range SupportedOutputFormats() {
+		if upperFormat == supported {
+			return upperFormat, nil
+		}
+	}
+	return "", fmt.Errorf("%w '%s', expected one of: %s", ErrUnknownFormat, format,
+		strings.ToLower(strings.Join(SupportedOutputFormats(), ", ")))
+}
+
+// ErrUnknownFormat is wrapped by ValidateOutputFormat if the format is not supported.
+var ErrUnknownFormat = errors.New("unknown output format")
+
+// FormatFromFilename returns the format matching the extension of the filename; ".json"
+// returns OutputFormatJSON, ".yaml" and ".yml" return OutputFormatYaml (case-insensitive).
+// Returns "" for any other extension, and for stdin/stdout ("-").
+func FormatFromFilename(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return OutputFormatJSON
+	case ".yaml", ".yml":
+		return OutputFormatYaml
+	}
+	return ""
+}
+
+// InferOutputFormat returns the output format to use if none was explicitly set. In order;
+// the format matching the extension of the output filename, the one matching the input
+// filename, or OutputFormatYaml as the default. Both filenames can be "" if unknown.
+func InferOutputFormat(outputFilename string, inputFilename string) string {
+	if format := FormatFromFilename(outputFilename); format != "" {
+		return format
+	}
+	if format := FormatFromFilename(inputFilename); format != "" {
+		return format
+	}
+	return OutputFormatYaml
+}
+
+// ErrEmptyInput is returned when deserializing empty input (eg. nothing was piped into stdin).
+var ErrEmptyInput = errors.New("empty input, expected a JSON or YAML object")
+
+// binarySniffLength is the number of leading bytes inspected to detect binary content.
+const binarySniffLength = 8000
+
+// ReadGuards defines the checks applied by ReadFile to the content read.
+type ReadGuards struct {
+	MaxSize      int64 // maximum number of bytes to read, 0 for unlimited
+	RejectBinary bool  // reject content that looks binary (contains a NUL byte)
+}
+
+var readGuards = struct {
+	sync.RWMutex
+	current ReadGuards
+}{}
+
+// SetReadGuards sets the checks applied by ReadFile. By default no checks are applied. It
+// is safe for concurrent use.
+func SetReadGuards(guards ReadGuards) {
+	readGuards.Lock()
+	defer readGuards.Unlock()
+	readGuards.current = guards
+}
+
+// GetReadGuards returns the checks currently applied by ReadFile.
+func GetReadGuards() ReadGuards {
+	readGuards.RLock()
+	defer readGuards.RUnlock()
+	return readGuards.current
+}
+
 // ReadFile reads file contents.
-// Reads from stdin if filename == "-"
+// Reads from stdin if filename == "-". The checks set by SetReadGuards are applied, and
+// environment variables are interpolated if set by SetReadOptions.
 func ReadFile(filename string) (*[]byte, error) {
+	f, err := openFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readContent(f, "file '"+filename+"'")
+}
+
+// openFile opens the file for reading, or stdin if filename == "-".
+func openFile(filename string) (io.ReadCloser, error) {
+	if filename == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(filename)
+}
+
+// readContent reads all content from the reader. The checks set by SetReadGuards are
+// applied, and environment variables are interpolated if set by SetReadOptions. 'source'
+// describes the reader in error messages, eg. "file 'kong.yaml'".
+func readContent(r io.Reader, source string) (*[]byte, error) {
 	var (
 		body []byte
 		err  error
 	)
 
-	if filename == "-" {
-		body, err = io.ReadAll(os.Stdin)
+	guards := GetReadGuards()
+	if guards.MaxSize > 0 {
+		// read 1 byte more than allowed, to detect exceeding the limit
+		body, err = io.ReadAll(io.LimitReader(r, guards.MaxSize+1))
+		if err == nil && int64(len(body)) > guards.MaxSize {
+			return nil, fmt.Errorf("%s exceeds the maximum size of %d bytes", source, guards.MaxSize)
+		}
 	} else {
-		body, err = os.ReadFile(filename)
+		body, err = io.ReadAll(r)
 	}
 
 	if err != nil {
 		return nil, err
 	}
+
+	if guards.RejectBinary {
+		sniff := body
+		if len(sniff) > binarySniffLength {
+			sniff = sniff[:binarySniffLength]
+		}
+		if bytes.IndexByte(sniff, 0) != -1 {
+			return nil, fmt.Errorf("%s appears to be binary, expected text content", source)
+		}
+	}
+
+	if GetReadOptions().EnvInterpolate {
+		if body, err = InterpolateEnv(body); err != nil {
+			return nil, fmt.Errorf("failed to interpolate %s; %w", source, err)
+		}
+	}
 	return &body, nil
 }
 
+// ReadFromReader reads a JSON or YAML object from the reader, and returns the top-level
+// object. 'format' is either OutputFormatJSON, OutputFormatYaml, or "" to accept both. The
+// same checks and interpolation as ReadFile are applied. Returns ErrEmptyInput if there
+// is no content.
+func ReadFromReader(r io.Reader, format string) (map[string]interface{}, error) {
+	return readFromReader(r, format, "input")
+}
+
+// readFromReader implements ReadFromReader. 'source' describes the reader in error
+// messages, see readContent.
+func readFromReader(r io.Reader, format string, source string) (map[string]interface{}, error) {
+	if format != "" {
+		var err error
+		if format, err = ValidateOutputFormat(format); err != nil {
+			return nil, err
+		}
+	}
+	body, err := readContent(r, source)
+	if err != nil {
+		return nil, err
+	}
+	if format == OutputFormatJSON {
+		if len(bytes.TrimSpace(*body)) != 0 && !json.Valid(*body) {
+			return nil, errors.New("failed deserializing data as JSON")
+		}
+	}
+	// JSON is a subset of YAML, so Deserialize handles both
+	return Deserialize(body)
+}
+
 // MustReadFile reads file contents. Will panic if reading fails.
 // Reads from stdin if filename == "-"
 func MustReadFile(filename string) *[]byte {
@@ -49,27 +208,159 @@ func MustReadFile(filename string) *[]byte {
 	return body
 }
 
+// WriteOptions defines the behaviour of WriteFile.
+type WriteOptions struct {
+	// If set, an existing regular file is copied to "<filename><BackupSuffix>" before
+	// it is replaced (eg. ".bak")
+	BackupSuffix string
+	// If set, nothing is written (not even to stdout). Use WriteSerializedFileWithOptions to
+	// get the content that would have been written.
+	DryRun bool
+}
+
+var writeOptions = struct {
+	sync.RWMutex
+	current WriteOptions
+}{}
+
+// SetWriteOptions sets the behaviour of WriteFile. By default no backups are made, and
+// files are written. It is safe for concurrent use, but the options apply to all writes of
+// the process; it is meant for CLIs. To set the options of a single write, use
+// WriteFileWithOptions or WriteSerializedFileWithOptions.
+func SetWriteOptions(opts WriteOptions) {
+	writeOptions.Lock()
+	defer writeOptions.Unlock()
+	writeOptions.current = opts
+}
+
+// GetWriteOptions returns the behaviour currently applied by WriteFile.
+func GetWriteOptions() WriteOptions {
+	writeOptions.RLock()
+	defer writeOptions.RUnlock()
+	return writeOptions.current
+}
+
 // WriteFile writes the output to a file.
-// Writes to stdout if filename == "-"
+// Writes to stdout if filename == "-". Regular files are written atomically, by writing
+// to a temporary file first and then renaming it (after making a backup of the existing
+// file, if set by SetWriteOptions). Outputs that are not regular files (pipes, devices,
+// etc) are written to directly.
 func WriteFile(filename string, content *[]byte) error {
-	var f *os.File
-	var err error
+	return WriteFileWithOptions(filename, content, GetWriteOptions())
+}
 
-	if filename != "-" {
-		// write to file
-		f, err = os.Create(filename)
+// WriteFileWithOptions writes the output to a file, like WriteFile, but with the given
+// options instead of the ones set by SetWriteOptions.
+func WriteFileWithOptions(filename string, content *[]byte, opts WriteOptions) error {
+	return writeFile(filename, opts, func(w io.Writer) error {
+		if _, err := w.Write(*content); err != nil {
+			return fmt.Errorf("failed to write to output file '%s'; %w", filename, err)
+		}
+		return nil
+	})
+}
+
+// writeFile opens the output file, and has 'write' write the content to it, see
+// WriteFileWithOptions. In dry-run mode 'write' writes to io.Discard.
+func writeFile(filename string, opts WriteOptions, write func(w io.Writer) error) error {
+	if opts.DryRun {
+		return write(io.Discard)
+	}
+	if filename == "-" {
+		// writing to stdout
+		return write(os.Stdout)
+	}
+
+	filename = resolveOutputFilename(filename)
+	info, err := os.Stat(filename)
+	if err == nil && !info.Mode().IsRegular() {
+		// not a regular file, so stream to it, renaming is not possible
+		f, err := os.OpenFile(filename, os.O_WRONLY, 0)
 		if err != nil {
-			return fmt.Errorf("failed to create output file '%s'; %w", filename, err)
+			return fmt.Errorf("failed to open output file '%s'; %w", filename, err)
 		}
 		defer f.Close()
-	} else {
-		// writing to stdout
-		f = os.Stdout
+		return write(f)
 	}
-	_, err = f.Write(*content)
+
+	return writeAtomic(filename, write, info, opts.BackupSuffix)
+}
+
+// resolveOutputFilename resolves symlinks, so we replace the target, not the link itself.
+func resolveOutputFilename(filename string) string {
+	if filename == "-" {
+		return filename
+	}
+	if resolved, err := filepath.EvalSymlinks(filename); err == nil {
+		return resolved
+	}
+	return filename
+}
+
+// writeAtomic has 'write' write the content to a temporary file in the same directory and
+// then renames it to filename. 'existing' is the file info of the file being replaced (if
+// any), so its permissions can be retained, and it is backed up if 'backupSuffix' is set.
+func writeAtomic(filename string, write func(w io.Writer) error, existing os.FileInfo, backupSuffix string,
+) error {
+	f, err := createTemp(filename)
 	if err != nil {
+		return fmt.Errorf("failed to create output file '%s'; %w", filename, err)
+	}
+	tmpName := f.Name()
+	defer os.Remove(tmpName) // no-op after a successful rename
+
+	if err = write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
 		return fmt.Errorf("failed to write to output file '%s'; %w", filename, err)
 	}
+	if existing != nil {
+		// keep the permissions of the file being replaced
+		mode := existing.Mode().Perm()
+		if err = os.Chmod(tmpName, mode); err != nil {
+			return fmt.Errorf("failed to set permissions on output file '%s'; %w", filename, err)
+		}
+		if backupSuffix != "" {
+			if err = writeBackup(filename, filename+backupSuffix, mode); err != nil {
+				return err
+			}
+		}
+	}
+	if err = os.Rename(tmpName, filename); err != nil {
+		return fmt.Errorf("failed to create output file '%s'; %w", filename, err)
+	}
+	return nil
+}
+
+// createTemp creates a new temporary file in the directory of filename. Unlike
+// os.CreateTemp, its permissions are those of os.Create (0666 minus the umask), since it
+// becomes the output file.
+func createTemp(filename string) (*os.File, error) {
+	prefix := filepath.Join(filepath.Dir(filename), "."+filepath.Base(filename)+".")
+	for i := 0; i < 100; i++ {
+		var random [8]byte
+		if _, err := rand.Read(random[:]); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(prefix+hex.EncodeToString(random[:])+".tmp", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o666)
+		if !errors.Is(err, fs.ErrExist) {
+			return f, err
+		}
+	}
+	return nil, fmt.Errorf("failed to create a unique temporary file for '%s'", filename)
+}
+
+// writeBackup copies the file to the backup file, replacing any existing backup.
+func writeBackup(filename string, backupFilename string, mode os.FileMode) error {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read output file '%s' for backup; %w", filename, err)
+	}
+	if err = os.WriteFile(backupFilename, content, mode); err != nil {
+		return fmt.Errorf("failed to write backup file '%s'; %w", backupFilename, err)
+	}
 	return nil
 }
 
@@ -82,13 +373,22 @@ func MustWriteFile(filename string, content *[]byte) {
 	}
 }
 
-// Serialize will serialize the result as a JSON/YAML.
+// Serialize will serialize the result as a JSON/YAML. A nil map is serialized as an
+// empty object.
 func Serialize(content map[string]interface{}, format string) (*[]byte, error) {
 	var (
 		str []byte
 		err error
 	)
 
+	if content == nil {
+		content = make(map[string]interface{})
+	}
+
+	if format, err = ValidateOutputFormat(format); err != nil {
+		return nil, err
+	}
+
 	switch format {
 	case OutputFormatYaml:
 		str, err = yaml.Marshal(content)
@@ -100,9 +400,6 @@ func Serialize(content map[string]interface{}, format string) (*[]byte, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to json-serialize the resulting file; %w", err)
 		}
-	default:
-		return nil, fmt.Errorf("expected 'format' to be either '%s' or '%s', got: '%s'",
-			strings.ToLower(OutputFormatYaml), strings.ToLower(OutputFormatJSON), format)
 	}
 
 	return &str, nil
@@ -119,16 +416,23 @@ func MustSerialize(content map[string]interface{}, format string) *[]byte {
 }
 
 // Deserialize will deserialize data as a JSON or YAML object. Will return an error
-// if deserializing fails or if it isn't an object.
+// if deserializing fails or if it isn't an object. Returns ErrEmptyInput if the data
+// is empty (or only whitespace).
 func Deserialize(data *[]byte) (map[string]interface{}, error) {
 	var output interface{}
 
+	if data == nil || len(bytes.TrimSpace(*data)) == 0 {
+		return nil, ErrEmptyInput
+	}
+
 	err1 := json.Unmarshal(*data, &output)
 	if err1 != nil {
 		err2 := yaml.Unmarshal(*data, &output)
 		if err2 != nil {
 			return nil, errors.New("failed deserializing data as JSON and as YAML")
 		}
+		// YAML allows non-string keys, which do not map onto JSON objects
+		output = jsonbasics.NormalizeYAMLMaps(output)
 	}
 
 	switch output := output.(type) {
@@ -149,16 +453,139 @@ func MustDeserialize(data *[]byte) map[string]interface{} {
 	return jsondata
 }
 
+// ConvertFormat converts JSON or YAML data into the requested format. This is purely a
+// serialization change, the content remains the same.
+func ConvertFormat(data *[]byte, format string) (*[]byte, error) {
+	content, err := Deserialize(data)
+	if err != nil {
+		return nil, err
+	}
+	return Serialize(content, format)
+}
+
+// SerializeOrdered is like Serialize, but writes the object keys in the order of the
+// OrderedMap. A nil map is serialized as an empty object.
+func SerializeOrdered(content *jsonbasics.OrderedMap, format string) (*[]byte, error) {
+	if content == nil {
+		content = jsonbasics.NewOrderedMap()
+	}
+
+	var (
+		str []byte
+		err error
+	)
+
+	if format, err = ValidateOutputFormat(format); err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case OutputFormatYaml:
+		var buf bytes.Buffer
+		encoder := yamlv3.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err = encoder.Encode(content); err != nil {
+			return nil, fmt.Errorf("failed to yaml-serialize the resulting file; %w", err)
+		}
+		if err = encoder.Close(); err != nil {
+			return nil, fmt.Errorf("failed to yaml-serialize the resulting file; %w", err)
+		}
+		str = buf.Bytes()
+	case OutputFormatJSON:
+		str, err = json.MarshalIndent(content, "", defaultJSONIndent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to json-serialize the resulting file; %w", err)
+		}
+	}
+
+	return &str, nil
+}
+
+// DeserializeOrdered is like Deserialize, but retains the order of the object keys.
+// This is heavier than Deserialize, so only use it when the order matters.
+func DeserializeOrdered(data *[]byte) (*jsonbasics.OrderedMap, error) {
+	if data == nil || len(bytes.TrimSpace(*data)) == 0 {
+		return nil, ErrEmptyInput
+	}
+
+	output := jsonbasics.NewOrderedMap()
+	if json.Valid(*data) {
+		if err := json.Unmarshal(*data, output); err != nil {
+			return nil, err
+		}
+		return output, nil
+	}
+
+	var node yamlv3.Node
+	if err := yamlv3.Unmarshal(*data, &node); err != nil {
+		return nil, errors.New("failed deserializing data as JSON and as YAML")
+	}
+	if err := output.UnmarshalYAML(&node); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// ConvertFormatOrdered is like ConvertFormat, but retains the order of the object keys.
+func ConvertFormatOrdered(data *[]byte, format string) (*[]byte, error) {
+	content, err := DeserializeOrdered(data)
+	if err != nil {
+		return nil, err
+	}
+	return SerializeOrdered(content, format)
+}
+
+// WriteResult describes the output of WriteSerializedFileResult.
+type WriteResult struct {
+	Filename string  // the target file, with symlinks resolved ("-" for stdout)
+	Content  *[]byte // the serialized content
+	Written  bool    // false if in dry-run mode, see WriteOptions
+}
+
+// WriteSerializedFileResult will serialize the data and write it to a file, like
+// WriteSerializedFile. Returns what was written, and where. In dry-run mode (see
+// WriteOptions) nothing is written, but the result is returned the same.
+func WriteSerializedFileResult(filename string, content map[string]interface{}, format string,
+) (*WriteResult, error) {
+	return WriteSerializedFileWithOptions(filename, content, format, GetWriteOptions())
+}
+
+// WriteSerializedFileWithOptions is like WriteSerializedFileResult, but with the given
+// options instead of the ones set by SetWriteOptions.
+func WriteSerializedFileWithOptions(filename string, content map[string]interface{}, format string,
+	opts WriteOptions,
+) (*WriteResult, error) {
+	var written bytes.Buffer // a copy of the content written, also in dry-run mode
+	err := writeFile(filename, opts, func(w io.Writer) error {
+		return WriteToWriter(io.MultiWriter(w, &written), content, format)
+	})
+	if err != nil {
+		return nil, err
+	}
+	serializedContent := written.Bytes()
+	return &WriteResult{
+		Filename: resolveOutputFilename(filename),
+		Content:  &serializedContent,
+		Written:  !opts.DryRun,
+	}, nil
+}
+
 // WriteSerializedFile will serialize the data and write it to a file.
 // Writes to stdout if filename == "-"
 func WriteSerializedFile(filename string, content map[string]interface{}, format string) error {
+	_, err := WriteSerializedFileResult(filename, content, format)
+	return err
+}
+
+// WriteToWriter will serialize the data and write it to the writer. Unlike WriteFile, the
+// DryRun write option does not apply, the caller controls the writer.
+func WriteToWriter(w io.Writer, content map[string]interface{}, format string) error {
 	serializedContent, err := Serialize(content, format)
 	if err != nil {
 		return err
 	}
-	err = WriteFile(filename, serializedContent)
-	if err != nil {
-		return err
+	if _, err := w.Write(*serializedContent); err != nil {
+		return fmt.Errorf("failed to write the output; %w", err)
 	}
 	return nil
 }
@@ -166,17 +593,27 @@ func WriteSerializedFile(filename string, content map[string]interface{}, format
 // MustWriteSerializedFile will serialize the data and write it to a file. Will
 // panic if it fails. Writes to stdout if filename == "-"
 func MustWriteSerializedFile(filename string, content map[string]interface{}, format string) {
-	MustWriteFile(filename, MustSerialize(content, format))
+	if err := WriteSerializedFile(filename, content, format); err != nil {
+		panic(err)
+	}
 }
 
 // DeserializeFile will read a JSON or YAML file and return the top-level object. Will return an
 // error if it fails reading or the content isn't an object. Reads from stdin if filename == "-".
+// Returns an error wrapping ErrEmptyInput if the file (or stdin) is empty.
 func DeserializeFile(filename string) (map[string]interface{}, error) {
-	bytedata, err := ReadFile(filename)
+	f, err := openFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	data, err := Deserialize(bytedata)
+	defer f.Close()
+	data, err := readFromReader(f, "", "file '"+filename+"'")
+	if errors.Is(err, ErrEmptyInput) {
+		if filename == "-" {
+			return nil, fmt.Errorf("nothing to read from stdin; %w", err)
+		}
+		return nil, fmt.Errorf("file '%s' is empty; %w", filename, err)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -187,5 +624,24 @@ func DeserializeFile(filename string) (map[string]interface{}, error) {
 // panic if it fails reading or the content isn't an object. Reads from stdin if filename == "-".
 // This will never return nil.
 func MustDeserializeFile(filename string) map[string]interface{} {
-	return MustDeserialize(MustReadFile(filename))
+	data, err := DeserializeFile(filename)
+	if err != nil {
+		log.Fatalf("unable to read file: %v", err)
+	}
+	return data
+}
+
+// ApplyOverlayFile reads the JSON or YAML overlay file, and deep-merges it onto the data,
+// in place (see jsonbasics.Merge for the rules, and the array strategies). Reads from stdin
+// if overlayFilename == "-".
+func ApplyOverlayFile(data map[string]interface{}, overlayFilename string, arrayStrategy string) error {
+	if err := jsonbasics.ValidateArrayStrategy(arrayStrategy); err != nil {
+		return err
+	}
+	overlay, err := DeserializeFile(overlayFilename)
+	if err != nil {
+		return err
+	}
+	jsonbasics.Merge(data, overlay, arrayStrategy)
+	return nil
 }
@@ -1,12 +1,151 @@
 package filebasics_test
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+
+	. "github.com/kong/go-apiops/filebasics"
 	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("filebasics", func() {
 	Describe("ReadFile", func() {
-		PIt("still to do", func() {
+		var filename string
+
+		BeforeEach(func() {
+			filename = filepath.Join(GinkgoT().TempDir(), "input.yaml")
+		})
+
+		AfterEach(func() {
+			SetReadGuards(ReadGuards{})
+		})
+
+		It("reads files without guards by default", func() {
+			Expect(os.WriteFile(filename, []byte("some\x00content"), 0o600)).To(Succeed())
+
+			content, err := ReadFile(filename)
+			Expect(err).To(BeNil())
+			Expect(*content).To(BeEquivalentTo("some\x00content"))
+		})
+
+		It("accepts files up to the maximum size", func() {
+			Expect(os.WriteFile(filename, []byte("12345"), 0o600)).To(Succeed())
+			SetReadGuards(ReadGuards{MaxSize: 5})
+
+			content, err := ReadFile(filename)
+			Expect(err).To(BeNil())
+			Expect(*content).To(BeEquivalentTo("12345"))
+		})
+
+		It("rejects files exceeding the maximum size", func() {
+			Expect(os.WriteFile(filename, []byte("123456"), 0o600)).To(Succeed())
+			SetReadGuards(ReadGuards{MaxSize: 5})
+
+			content, err := ReadFile(filename)
+			Expect(err).To(MatchError("file '" + filename + "' exceeds the maximum size of 5 bytes"))
+			Expect(content).To(BeNil())
+		})
+
+		It("rejects binary content", func() {
+			Expect(os.WriteFile(filename, []byte("some\x00content"), 0o600)).To(Succeed())
+			SetReadGuards(ReadGuards{RejectBinary: true})
+
+			content, err := ReadFile(filename)
+			Expect(err).To(MatchError("file '" + filename + "' appears to be binary, expected text content"))
+			Expect(content).To(BeNil())
+		})
+
+		It("can set the guards while reading concurrently", func() {
+			Expect(os.WriteFile(filename, []byte("12345"), 0o600)).To(Succeed())
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					SetReadGuards(ReadGuards{MaxSize: 10})
+				}()
+				go func() {
+					defer wg.Done()
+					_, err := ReadFile(filename)
+					Expect(err).To(BeNil())
+				}()
+			}
+			wg.Wait()
+		})
+
+		Describe("with environment interpolation", func() {
+			BeforeEach(func() {
+				SetReadOptions(ReadOptions{EnvInterpolate: true})
+			})
+
+			AfterEach(func() {
+				SetReadOptions(ReadOptions{})
+			})
+
+			It("can set the options while reading concurrently", func() {
+				Expect(os.WriteFile(filename, []byte("host: ${KCED_TEST_UNSET:-localhost}"), 0o600)).To(Succeed())
+				var wg sync.WaitGroup
+				for i := 0; i < 10; i++ {
+					wg.Add(2)
+					go func() {
+						defer wg.Done()
+						SetReadOptions(ReadOptions{EnvInterpolate: true})
+					}()
+					go func() {
+						defer wg.Done()
+						content, err := ReadFile(filename)
+						Expect(err).To(BeNil())
+						Expect(*content).To(BeEquivalentTo("host: localhost"))
+					}()
+				}
+				wg.Wait()
+			})
+
+			It("replaces defined variables", func() {
+				GinkgoT().Setenv("KCED_TEST_HOST", "example.com")
+				Expect(os.WriteFile(filename, []byte("host: ${KCED_TEST_HOST}"), 0o600)).To(Succeed())
+
+				content, err := ReadFile(filename)
+				Expect(err).To(BeNil())
+				Expect(*content).To(BeEquivalentTo("host: example.com"))
+			})
+
+			It("uses the default for unset and empty variables", func() {
+				GinkgoT().Setenv("KCED_TEST_EMPTY", "")
+				Expect(os.WriteFile(filename,
+					[]byte("host: ${KCED_TEST_UNSET:-localhost}, port: ${KCED_TEST_EMPTY:-8000}"), 0o600)).To(Succeed())
+
+				content, err := ReadFile(filename)
+				Expect(err).To(BeNil())
+				Expect(*content).To(BeEquivalentTo("host: localhost, port: 8000"))
+			})
+
+			It("fails on undefined variables without a default", func() {
+				Expect(os.WriteFile(filename, []byte("host: ${KCED_TEST_UNSET}"), 0o600)).To(Succeed())
+
+				content, err := ReadFile(filename)
+				Expect(err).To(MatchError("failed to interpolate file '" + filename + "'; " +
+					"undefined environment variables without a default: KCED_TEST_UNSET"))
+				Expect(content).To(BeNil())
+			})
+
+			It("is disabled by default", func() {
+				SetReadOptions(ReadOptions{})
+				Expect(os.WriteFile(filename, []byte("host: ${KCED_TEST_UNSET}"), 0o600)).To(Succeed())
+
+				content, err := ReadFile(filename)
+				Expect(err).To(BeNil())
+				Expect(*content).To(BeEquivalentTo("host: ${KCED_TEST_UNSET}"))
+			})
 		})
 	})
 
@@ -15,18 +154,429 @@ var _ = Describe("filebasics", func() {
 		})
 	})
 
+	Describe("ReadFromReader", func() {
+		It("reads a YAML object", func() {
+			data, err := ReadFromReader(bytes.NewBufferString("hello: world\n"), "")
+			Expect(err).To(BeNil())
+			Expect(data).To(Equal(map[string]interface{}{"hello": "world"}))
+		})
+
+		It("reads a JSON object", func() {
+			data, err := ReadFromReader(bytes.NewBufferString(`{"hello":"world"}`), OutputFormatJSON)
+			Expect(err).To(BeNil())
+			Expect(data).To(Equal(map[string]interface{}{"hello": "world"}))
+		})
+
+		It("fails on YAML if JSON is expected", func() {
+			_, err := ReadFromReader(bytes.NewBufferString("hello: world\n"), OutputFormatJSON)
+			Expect(err).To(MatchError("failed deserializing data as JSON"))
+		})
+
+		It("fails on an unknown format", func() {
+			_, err := ReadFromReader(bytes.NewBufferString("hello: world\n"), "xml")
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("returns ErrEmptyInput on empty input", func() {
+			_, err := ReadFromReader(&bytes.Buffer{}, "")
+			Expect(err).To(MatchError(ErrEmptyInput))
+		})
+
+		It("applies the read guards", func() {
+			SetReadGuards(ReadGuards{MaxSize: 5})
+			defer SetReadGuards(ReadGuards{})
+
+			_, err := ReadFromReader(bytes.NewBufferString("hello: world\n"), "")
+			Expect(err).To(MatchError("input exceeds the maximum size of 5 bytes"))
+		})
+	})
+
+	Describe("WriteFile", func() {
+		It("writes a regular file atomically", func() {
+			dir := GinkgoT().TempDir()
+			filename := filepath.Join(dir, "output.yaml")
+			Expect(os.WriteFile(filename, []byte("old content"), 0o600)).To(Succeed())
+
+			content := []byte("new content")
+			Expect(WriteFile(filename, &content)).To(Succeed())
+
+			Expect(os.ReadFile(filename)).To(BeEquivalentTo("new content"))
+			info, err := os.Stat(filename)
+			Expect(err).To(BeNil())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o600)))
+
+			// no temporary files left behind
+			entries, err := os.ReadDir(dir)
+			Expect(err).To(BeNil())
+			Expect(entries).To(HaveLen(1))
+		})
+
+		It("creates a new file with the same permissions as os.Create", func() {
+			dir := GinkgoT().TempDir()
+			reference := filepath.Join(dir, "reference.yaml")
+			f, err := os.Create(reference)
+			Expect(err).To(BeNil())
+			Expect(f.Close()).To(Succeed())
+			expected, err := os.Stat(reference)
+			Expect(err).To(BeNil())
+
+			filename := filepath.Join(dir, "output.yaml")
+			content := []byte("new content")
+			Expect(WriteFile(filename, &content)).To(Succeed())
+
+			info, err := os.Stat(filename)
+			Expect(err).To(BeNil())
+			Expect(info.Mode().Perm()).To(Equal(expected.Mode().Perm()))
+		})
+
+		Context("with a backup suffix", func() {
+			BeforeEach(func() {
+				SetWriteOptions(WriteOptions{BackupSuffix: ".bak"})
+			})
+			AfterEach(func() {
+				SetWriteOptions(WriteOptions{})
+			})
+
+			It("backs up the previous content before replacing it", func() {
+				dir := GinkgoT().TempDir()
+				filename := filepath.Join(dir, "output.yaml")
+				Expect(os.WriteFile(filename, []byte("old content"), 0o600)).To(Succeed())
+
+				content := []byte("new content")
+				Expect(WriteFile(filename, &content)).To(Succeed())
+
+				Expect(os.ReadFile(filename)).To(BeEquivalentTo("new content"))
+				Expect(os.ReadFile(filename + ".bak")).To(BeEquivalentTo("old content"))
+				info, err := os.Stat(filename + ".bak")
+				Expect(err).To(BeNil())
+				Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o600)))
+
+				// a next write replaces the backup
+				content = []byte("newer content")
+				Expect(WriteFile(filename, &content)).To(Succeed())
+				Expect(os.ReadFile(filename + ".bak")).To(BeEquivalentTo("new content"))
+			})
+
+			It("makes no backup if there is no previous file", func() {
+				dir := GinkgoT().TempDir()
+				filename := filepath.Join(dir, "output.yaml")
+
+				content := []byte("new content")
+				Expect(WriteFile(filename, &content)).To(Succeed())
+
+				entries, err := os.ReadDir(dir)
+				Expect(err).To(BeNil())
+				Expect(entries).To(HaveLen(1))
+			})
+
+			It("can set the options while writing concurrently", func() {
+				filename := filepath.Join(GinkgoT().TempDir(), "output.yaml")
+				var wg sync.WaitGroup
+				for i := 0; i < 10; i++ {
+					wg.Add(2)
+					go func() {
+						defer wg.Done()
+						SetWriteOptions(WriteOptions{BackupSuffix: ".bak"})
+					}()
+					go func(i int) {
+						defer wg.Done()
+						content := []byte(fmt.Sprintf("content %d", i))
+						Expect(WriteFile(filename+strconv.Itoa(i), &content)).To(Succeed())
+					}(i)
+				}
+				wg.Wait()
+			})
+		})
+
+		It("streams to a pipe without renaming", func() {
+			if runtime.GOOS == "windows" {
+				Skip("no /dev/fd on windows")
+			}
+			r, w, err := os.Pipe()
+			Expect(err).To(BeNil())
+			defer r.Close()
+
+			filename := fmt.Sprintf("/dev/fd/%d", w.Fd())
+			content := []byte("streamed content")
+			Expect(WriteFile(filename, &content)).To(Succeed())
+			w.Close()
+
+			// a rename would have replaced the pipe, and the content would never arrive
+			Expect(io.ReadAll(r)).To(BeEquivalentTo("streamed content"))
+		})
+	})
+
 	Describe("MustWriteFile", func() {
 		PIt("still to do", func() {
 		})
 	})
 
+	Describe("WriteSerializedFileResult", func() {
+		data := map[string]interface{}{"hello": "world"}
+
+		It("writes the file, and returns what was written", func() {
+			dir, _ := filepath.EvalSymlinks(GinkgoT().TempDir()) // the returned name is resolved
+			filename := filepath.Join(dir, "output.json")
+			result, err := WriteSerializedFileResult(filename, data, OutputFormatJSON)
+			Expect(err).To(BeNil())
+			Expect(result.Filename).To(Equal(filename))
+			Expect(result.Written).To(BeTrue())
+			Expect(*result.Content).To(MatchJSON(`{"hello":"world"}`))
+			Expect(os.ReadFile(filename)).To(Equal(*result.Content))
+		})
+
+		Context("in dry-run mode", func() {
+			BeforeEach(func() {
+				SetWriteOptions(WriteOptions{DryRun: true})
+			})
+			AfterEach(func() {
+				SetWriteOptions(WriteOptions{})
+			})
+
+			It("returns the content without creating the file", func() {
+				dir, _ := filepath.EvalSymlinks(GinkgoT().TempDir())
+				filename := filepath.Join(dir, "output.json")
+				result, err := WriteSerializedFileResult(filename, data, OutputFormatJSON)
+				Expect(err).To(BeNil())
+				Expect(result.Filename).To(Equal(filename))
+				Expect(result.Written).To(BeFalse())
+				Expect(*result.Content).To(MatchJSON(`{"hello":"world"}`))
+
+				entries, err := os.ReadDir(dir)
+				Expect(err).To(BeNil())
+				Expect(entries).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("WriteSerializedFileWithOptions", func() {
+		data := map[string]interface{}{"hello": "world"}
+
+		It("applies the options to this write only", func() {
+			dir, _ := filepath.EvalSymlinks(GinkgoT().TempDir())
+			filename := filepath.Join(dir, "output.json")
+			result, err := WriteSerializedFileWithOptions(filename, data, OutputFormatJSON, WriteOptions{DryRun: true})
+			Expect(err).To(BeNil())
+			Expect(result.Written).To(BeFalse())
+			Expect(*result.Content).To(MatchJSON(`{"hello":"world"}`))
+			Expect(filename).ToNot(BeAnExistingFile())
+
+			// the global options are not affected
+			Expect(GetWriteOptions()).To(Equal(WriteOptions{}))
+			Expect(WriteSerializedFile(filename, data, OutputFormatJSON)).To(Succeed())
+			Expect(filename).To(BeAnExistingFile())
+		})
+
+		It("ignores the global options", func() {
+			SetWriteOptions(WriteOptions{DryRun: true})
+			defer SetWriteOptions(WriteOptions{})
+
+			dir, _ := filepath.EvalSymlinks(GinkgoT().TempDir())
+			filename := filepath.Join(dir, "output.json")
+			result, err := WriteSerializedFileWithOptions(filename, data, OutputFormatJSON, WriteOptions{})
+			Expect(err).To(BeNil())
+			Expect(result.Written).To(BeTrue())
+			Expect(os.ReadFile(filename)).To(Equal(*result.Content))
+		})
+	})
+
+	Describe("ConvertFormat", func() {
+		It("round-trips yaml to json to yaml, preserving the content", func() {
+			yamlIn := []byte(`_format_version: "3.0"
+_ignore:
+- command: openapi2kong
+services:
+- name: my-service
+  port: 443
+  routes:
+  - name: my-route
+    paths:
+    - ~/users$
+    strip_path: false
+`)
+			jsonOut, err := ConvertFormat(&yamlIn, OutputFormatJSON)
+			Expect(err).To(BeNil())
+			Expect(*jsonOut).To(MatchJSON(`{
+				"_format_version": "3.0",
+				"_ignore": [ { "command": "openapi2kong" } ],
+				"services": [
+					{
+						"name": "my-service",
+						"port": 443,
+						"routes": [
+							{ "name": "my-route", "paths": [ "~/users$" ], "strip_path": false }
+						]
+					}
+				]
+			}`))
+
+			yamlOut, err := ConvertFormat(jsonOut, OutputFormatYaml)
+			Expect(err).To(BeNil())
+			Expect(*yamlOut).To(MatchYAML(yamlIn))
+		})
+
+		It("returns an error on an unknown format", func() {
+			data := []byte(`{}`)
+			_, err := ConvertFormat(&data, "XML")
+			Expect(err).To(MatchError("unknown output format 'XML', expected one of: json, yaml"))
+		})
+
+		It("accepts the format in lower case", func() {
+			data := []byte(`{ "key": "value" }`)
+			result, err := ConvertFormat(&data, "yaml")
+			Expect(err).To(BeNil())
+			Expect(result).ToNot(BeNil())
+			Expect(string(*result)).To(Equal("key: value\n"))
+
+			result, err = Serialize(map[string]interface{}{"key": "value"}, "json")
+			Expect(err).To(BeNil())
+			Expect(result).ToNot(BeNil())
+			Expect(string(*result)).To(Equal("{\n  \"key\": \"value\"\n}"))
+		})
+	})
+
+	Describe("ValidateOutputFormat", func() {
+		It("accepts the supported formats, case-insensitive", func() {
+			Expect(SupportedOutputFormats()).To(Equal([]string{OutputFormatJSON, OutputFormatYaml}))
+			format, err := ValidateOutputFormat("json")
+			Expect(err).To(BeNil())
+			Expect(format).To(Equal(OutputFormatJSON))
+			format, err = ValidateOutputFormat("Yaml")
+			Expect(err).To(BeNil())
+			Expect(format).To(Equal(OutputFormatYaml))
+		})
+
+		It("rejects an unknown format, listing the valid options", func() {
+			_, err := ValidateOutputFormat("toml")
+			Expect(err).To(MatchError("unknown output format 'toml', expected one of: json, yaml"))
+			Expect(err).To(MatchError(ErrUnknownFormat))
+		})
+	})
+
+	Describe("FormatFromFilename", func() {
+		It("returns the format matching the extension, case-insensitive", func() {
+			Expect(FormatFromFilename("kong.json")).To(Equal(OutputFormatJSON))
+			Expect(FormatFromFilename("dir/kong.YAML")).To(Equal(OutputFormatYaml))
+			Expect(FormatFromFilename("kong.yml")).To(Equal(OutputFormatYaml))
+		})
+
+		It("returns an empty string for other extensions, and stdin/stdout", func() {
+			Expect(FormatFromFilename("kong.toml")).To(Equal(""))
+			Expect(FormatFromFilename("kong")).To(Equal(""))
+			Expect(FormatFromFilename("-")).To(Equal(""))
+			Expect(FormatFromFilename("")).To(Equal(""))
+		})
+	})
+
+	Describe("InferOutputFormat", func() {
+		It("prefers the output filename extension", func() {
+			Expect(InferOutputFormat("out.json", "in.yaml")).To(Equal(OutputFormatJSON))
+			Expect(InferOutputFormat("out.yml", "in.json")).To(Equal(OutputFormatYaml))
+		})
+
+		It("falls back to the input filename extension", func() {
+			Expect(InferOutputFormat("-", "in.json")).To(Equal(OutputFormatJSON))
+			Expect(InferOutputFormat("out.toml", "in.json")).To(Equal(OutputFormatJSON))
+		})
+
+		It("defaults to yaml", func() {
+			Expect(InferOutputFormat("-", "-")).To(Equal(OutputFormatYaml))
+			Expect(InferOutputFormat("", "")).To(Equal(OutputFormatYaml))
+		})
+	})
+
 	Describe("MustSerialize", func() {
 		PIt("still to do", func() {
 		})
 	})
 
 	Describe("Deserialize", func() {
-		PIt("still to do", func() {
+		It("returns ErrEmptyInput on empty data", func() {
+			data := []byte(" \n\t\n")
+			_, err := Deserialize(&data)
+			Expect(err).To(MatchError(ErrEmptyInput))
+		})
+		It("returns string keys for YAML integer and boolean keys", func() {
+			data := []byte("codes:\n  200: ok\n  true: enabled\n")
+			obj, err := Deserialize(&data)
+			Expect(err).To(BeNil())
+			Expect(obj).To(Equal(map[string]interface{}{
+				"codes": map[string]interface{}{
+					"200":  "ok",
+					"true": "enabled",
+				},
+			}))
+		})
+	})
+
+	Describe("DeserializeOrdered", func() {
+		It("retains the original key order on a read/write cycle", func() {
+			data := []byte(`_format_version: "3.0"
+services:
+  - name: my-service
+    url: https://example.com
+    routes:
+      - paths:
+          - /
+        name: my-route
+zzz: last-but-one
+aaa: last
+`)
+			content, err := DeserializeOrdered(&data)
+			Expect(err).To(BeNil())
+			Expect(content.Keys()).To(Equal([]string{"_format_version", "services", "zzz", "aaa"}))
+
+			result, err := SerializeOrdered(content, OutputFormatYaml)
+			Expect(err).To(BeNil())
+			Expect(string(*result)).To(Equal(string(data)))
+
+			result, err = ConvertFormatOrdered(&data, OutputFormatJSON)
+			Expect(err).To(BeNil())
+			Expect(string(*result)).To(Equal(`{
+  "_format_version": "3.0",
+  "services": [
+    {
+      "name": "my-service",
+      "url": "https://example.com",
+      "routes": [
+        {
+          "paths": [
+            "/"
+          ],
+          "name": "my-route"
+        }
+      ]
+    }
+  ],
+  "zzz": "last-but-one",
+  "aaa": "last"
+}`))
+			result, err = ConvertFormatOrdered(result, OutputFormatYaml)
+			Expect(err).To(BeNil())
+			Expect(string(*result)).To(Equal(string(data)))
+		})
+
+		It("accepts the format in lower case", func() {
+			data := []byte(`{ "b": 1, "a": 2 }`)
+			result, err := ConvertFormatOrdered(&data, "yaml")
+			Expect(err).To(BeNil())
+			Expect(result).ToNot(BeNil())
+			Expect(string(*result)).To(Equal("b: 1\na: 2\n"))
+
+			_, err = ConvertFormatOrdered(&data, "xml")
+			Expect(err).To(MatchError(ErrUnknownFormat))
+		})
+
+		It("returns ErrEmptyInput on empty data", func() {
+			data := []byte(" \n")
+			_, err := DeserializeOrdered(&data)
+			Expect(err).To(MatchError(ErrEmptyInput))
+		})
+
+		It("fails on data that is not an object", func() {
+			data := []byte("- one\n- two\n")
+			_, err := DeserializeOrdered(&data)
+			Expect(err).To(MatchError("expected the data to be an Object"))
 		})
 	})
 
@@ -36,12 +586,65 @@ var _ = Describe("filebasics", func() {
 	})
 
 	Describe("MustWriteSerializedFile", func() {
-		PIt("still to do", func() {
+		It("writes an empty (or nil) map as an empty object", func() {
+			filename := filepath.Join(GinkgoT().TempDir(), "output.yaml")
+			MustWriteSerializedFile(filename, map[string]interface{}{}, OutputFormatYaml)
+			Expect(*MustReadFile(filename)).To(Equal([]byte("{}\n")))
+			Expect(MustDeserializeFile(filename)).To(BeEmpty())
+
+			MustWriteSerializedFile(filename, nil, OutputFormatJSON)
+			Expect(*MustReadFile(filename)).To(Equal([]byte("{}")))
+		})
+	})
+
+	Describe("WriteToWriter", func() {
+		data := map[string]interface{}{"hello": "world"}
+
+		It("writes JSON", func() {
+			var buf bytes.Buffer
+			Expect(WriteToWriter(&buf, data, OutputFormatJSON)).To(Succeed())
+			Expect(buf.String()).To(MatchJSON(`{"hello":"world"}`))
+		})
+
+		It("writes YAML", func() {
+			var buf bytes.Buffer
+			Expect(WriteToWriter(&buf, data, OutputFormatYaml)).To(Succeed())
+			Expect(buf.String()).To(MatchYAML("hello: world"))
+		})
+
+		It("round-trips with ReadFromReader", func() {
+			var buf bytes.Buffer
+			Expect(WriteToWriter(&buf, data, OutputFormatYaml)).To(Succeed())
+			Expect(ReadFromReader(&buf, OutputFormatYaml)).To(Equal(data))
+		})
+
+		It("fails on an unknown format", func() {
+			var buf bytes.Buffer
+			Expect(WriteToWriter(&buf, data, "xml")).NotTo(Succeed())
+			Expect(buf.Len()).To(BeZero())
 		})
 	})
 
 	Describe("DeserializeFile", func() {
-		PIt("still to do", func() {
+		It("returns ErrEmptyInput on an empty stdin", func() {
+			stdin, err := os.Open(os.DevNull)
+			Expect(err).To(BeNil())
+			defer stdin.Close()
+			orgStdin := os.Stdin
+			os.Stdin = stdin
+			defer func() { os.Stdin = orgStdin }()
+
+			_, err = DeserializeFile("-")
+			Expect(err).To(MatchError(ErrEmptyInput))
+			Expect(err).To(MatchError("nothing to read from stdin; " + ErrEmptyInput.Error()))
+		})
+
+		It("returns ErrEmptyInput on an empty file", func() {
+			filename := filepath.Join(GinkgoT().TempDir(), "empty.yaml")
+			Expect(os.WriteFile(filename, []byte{}, 0o600)).To(Succeed())
+
+			_, err := DeserializeFile(filename)
+			Expect(err).To(MatchError(ErrEmptyInput))
 		})
 	})
 
@@ -49,4 +652,58 @@ var _ = Describe("filebasics", func() {
 		PIt("still to do", func() {
 		})
 	})
+
+	Describe("WriteManifest", func() {
+		It("lists the written files with their checksums and sizes", func() {
+			dir := GinkgoT().TempDir()
+			services := filepath.Join(dir, "parts", "services.yaml")
+			plugins := filepath.Join(dir, "parts", "plugins.yaml")
+			Expect(os.MkdirAll(filepath.Dir(services), 0o750)).To(Succeed())
+			MustWriteSerializedFile(services, map[string]interface{}{"services": []interface{}{}}, OutputFormatYaml)
+			MustWriteSerializedFile(plugins, map[string]interface{}{"plugins": []interface{}{}}, OutputFormatYaml)
+
+			manifestFile := filepath.Join(dir, "manifest.json")
+			Expect(WriteManifest(manifestFile, []string{services, plugins})).To(Succeed())
+
+			manifest := MustDeserializeFile(manifestFile)
+			Expect(manifest).To(HaveLen(2))
+			for name, filename := range map[string]string{
+				"parts/services.yaml": services,
+				"parts/plugins.yaml":  plugins,
+			} {
+				content := *MustReadFile(filename)
+				checksum := sha256.Sum256(content)
+				Expect(manifest).To(HaveKeyWithValue(name, map[string]interface{}{
+					"sha256": hex.EncodeToString(checksum[:]),
+					"size":   float64(len(content)),
+				}))
+			}
+		})
+
+		It("returns an error if a file cannot be read", func() {
+			dir := GinkgoT().TempDir()
+			err := WriteManifest(filepath.Join(dir, "manifest.json"), []string{filepath.Join(dir, "missing.yaml")})
+			Expect(err).To(MatchError(ContainSubstring("failed to read file")))
+		})
+	})
+
+	Describe("ApplyOverlayFile", func() {
+		It("merges the overlay onto the data", func() {
+			overlay := filepath.Join(GinkgoT().TempDir(), "overlay.yaml")
+			Expect(os.WriteFile(overlay, []byte("host: new.example.com\npath: /new\n"), 0o600)).To(Succeed())
+			data := map[string]interface{}{"name": "svc", "host": "example.com"}
+
+			Expect(ApplyOverlayFile(data, overlay, "replace")).To(Succeed())
+			Expect(data).To(Equal(map[string]interface{}{
+				"name": "svc",
+				"host": "new.example.com",
+				"path": "/new",
+			}))
+		})
+
+		It("fails on an unknown array strategy", func() {
+			Expect(ApplyOverlayFile(map[string]interface{}{}, "-", "merge")).To(MatchError(
+				"expected array strategy to be one of 'replace', or 'append', got: 'merge'"))
+		})
+	})
 })
@@ -0,0 +1,66 @@
+package filebasics
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ReadOptions defines the behaviour of ReadFile.
+type ReadOptions struct {
+	// If set, '${VAR}' and '${VAR:-default}' placeholders are replaced by the values of
+	// the environment variables, before the content is returned.
+	EnvInterpolate bool
+}
+
+var readOptions = struct {
+	sync.RWMutex
+	current ReadOptions
+}{}
+
+// SetReadOptions sets the behaviour of ReadFile. By default no interpolation is done. It is
+// safe for concurrent use.
+func SetReadOptions(opts ReadOptions) {
+	readOptions.Lock()
+	defer readOptions.Unlock()
+	readOptions.current = opts
+}
+
+// GetReadOptions returns the behaviour currently applied by ReadFile.
+func GetReadOptions() ReadOptions {
+	readOptions.RLock()
+	defer readOptions.RUnlock()
+	return readOptions.current
+}
+
+// envPlaceholder matches '${VAR}' and '${VAR:-default}'
+var envPlaceholder = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// InterpolateEnv replaces the '${VAR}' placeholders in the content by the values of the
+// environment variables. With '${VAR:-default}' the default is used if the variable is
+// unset or empty. Returns an error listing the variables that are undefined and have
+// no default.
+func InterpolateEnv(content []byte) ([]byte, error) {
+	undefined := make([]string, 0)
+	result := envPlaceholder.ReplaceAllFunc(content, func(placeholder []byte) []byte {
+		match := envPlaceholder.FindSubmatch(placeholder)
+		name := string(match[1])
+		value, found := os.LookupEnv(name)
+		if len(match[2]) > 0 && value == "" {
+			return match[3] // use the default
+		}
+		if !found {
+			undefined = append(undefined, name)
+			return placeholder
+		}
+		return []byte(value)
+	})
+
+	if len(undefined) > 0 {
+		return nil, fmt.Errorf("undefined environment variables without a default: %s",
+			strings.Join(undefined, ", "))
+	}
+	return result, nil
+}
@@ -0,0 +1,54 @@
+package filebasics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ManifestEntry describes a written file in a manifest.
+type ManifestEntry struct {
+	SHA256 string `json:"sha256"` // hex encoded SHA-256 checksum of the file content
+	Size   int64  `json:"size"`   // size of the file in bytes
+}
+
+// CreateManifest returns the manifest entries for the files, by filename. The filenames in
+// the result are relative to baseDir (using forward slashes), so the manifest is portable.
+func CreateManifest(baseDir string, filenames []string) (map[string]ManifestEntry, error) {
+	manifest := make(map[string]ManifestEntry, len(filenames))
+	for _, filename := range filenames {
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file '%s' for the manifest; %w", filename, err)
+		}
+
+		name, err := filepath.Rel(baseDir, filename)
+		if err != nil {
+			name = filename
+		}
+		checksum := sha256.Sum256(content)
+		manifest[filepath.ToSlash(name)] = ManifestEntry{
+			SHA256: hex.EncodeToString(checksum[:]),
+			Size:   int64(len(content)),
+		}
+	}
+	return manifest, nil
+}
+
+// WriteManifest writes a JSON manifest of the files, with their checksums and sizes. The
+// filenames in the manifest are relative to the directory of the manifest file. See
+// CreateManifest.
+func WriteManifest(manifestFilename string, filenames []string) error {
+	manifest, err := CreateManifest(filepath.Dir(manifestFilename), filenames)
+	if err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(manifest, "", defaultJSONIndent)
+	if err != nil {
+		return fmt.Errorf("failed to json-serialize the manifest; %w", err)
+	}
+	return WriteFile(manifestFilename, &content)
+}
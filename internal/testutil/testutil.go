@@ -0,0 +1,62 @@
+// Package testutil holds the assertions shared by the tests of the go-apiops packages.
+// They accept any TestingT, so both *testing.T and GinkgoT() can be used.
+package testutil
+
+import (
+	"bytes"
+
+	"github.com/kong/go-apiops/jsonbasics"
+	"gopkg.in/yaml.v3"
+)
+
+// TestingT is the part of *testing.T used by the assertions. GinkgoT() implements it too.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// parseValue parses JSON or YAML data into a value. Returns false if it cannot be parsed.
+func parseValue(data []byte) (interface{}, bool) {
+	var value interface{}
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// semanticEqual returns true if the 2 outputs are equal; byte for byte, or as JSON/YAML
+// values (see jsonbasics.EqualJSON).
+func semanticEqual(a []byte, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	valueA, okA := parseValue(a)
+	valueB, okB := parseValue(b)
+	return okA && okB && jsonbasics.EqualJSON(valueA, valueB)
+}
+
+// AssertSemanticEqual asserts that 2 JSON values are semantically equal (see
+// jsonbasics.EqualJSON); regardless of the key order of objects, and the types of numbers.
+// Returns true if they are equal.
+func AssertSemanticEqual(t TestingT, expected interface{}, actual interface{}) bool {
+	t.Helper()
+	if jsonbasics.EqualJSON(expected, actual) {
+		return true
+	}
+	t.Errorf("values are not semantically equal\nexpected: %#v\nactual  : %#v", expected, actual)
+	return false
+}
+
+// AssertIdempotent asserts that applying 'fn' to its own output does not change it any
+// further; fn(fn(input)) equals fn(input). The outputs are compared byte for byte, or if
+// that fails, as JSON/YAML values. Returns true if 'fn' is idempotent for the input.
+func AssertIdempotent(t TestingT, fn func([]byte) []byte, input []byte) bool {
+	t.Helper()
+	once := fn(input)
+	twice := fn(once)
+	if semanticEqual(once, twice) {
+		return true
+	}
+	t.Errorf("not idempotent, applying it again changed the output\nonce : %s\ntwice: %s", once, twice)
+	return false
+}
@@ -0,0 +1,61 @@
+package testutil
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder is a TestingT recording the errors, to test the failing assertions.
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func Test_AssertSemanticEqual(t *testing.T) {
+	r := &recorder{}
+	assert.True(t, AssertSemanticEqual(r, map[string]interface{}{"a": 1, "b": []string{"x"}},
+		map[string]interface{}{"b": []interface{}{"x"}, "a": 1.0}))
+	assert.Empty(t, r.errors)
+
+	assert.False(t, AssertSemanticEqual(r, map[string]interface{}{"a": 1}, map[string]interface{}{"a": 2}))
+	assert.Len(t, r.errors, 1)
+	assert.Contains(t, r.errors[0], "values are not semantically equal")
+}
+
+func Test_AssertIdempotent(t *testing.T) {
+	trim := func(data []byte) []byte { return []byte(strings.TrimSpace(string(data))) }
+	appendX := func(data []byte) []byte { return append(data, 'x') }
+
+	r := &recorder{}
+	assert.True(t, AssertIdempotent(r, trim, []byte("  value  ")))
+	assert.Empty(t, r.errors)
+
+	assert.False(t, AssertIdempotent(r, appendX, []byte("value")))
+	assert.Equal(t, []string{"not idempotent, applying it again changed the output\n" +
+		"once : valuex\ntwice: valuexx"}, r.errors)
+}
+
+func Test_AssertIdempotentSemantic(t *testing.T) {
+	// the key order changes on every call, but the content does not
+	calls := 0
+	reorder := func(_ []byte) []byte {
+		calls++
+		if calls%2 == 0 {
+			return []byte(`{"b": 2, "a": 1}`)
+		}
+		return []byte(`{"a": 1, "b": 2}`)
+	}
+
+	r := &recorder{}
+	assert.True(t, AssertIdempotent(r, reorder, []byte(`{}`)))
+	assert.Empty(t, r.errors)
+	assert.True(t, AssertIdempotent(t, reorder, []byte(`{}`))) // *testing.T is a TestingT
+}
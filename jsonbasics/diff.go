@@ -0,0 +1,87 @@
+package jsonbasics
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	PathAdded   = "added"   // the path only exists in the second value
+	PathRemoved = "removed" // the path only exists in the first value
+	PathChanged = "changed" // the path exists in both, with different values
+)
+
+// PathChange describes a difference between 2 JSON values, see DiffPaths.
+type PathChange struct {
+	Path string      // JSONPointer (RFC 6901) of the changed value, "" for the root
+	Type string      // PathAdded, PathRemoved, or PathChanged
+	Old  interface{} // the value in the first JSON value, nil if added
+	New  interface{} // the value in the second JSON value, nil if removed
+}
+
+// jsonPointerEscaper escapes a key for use in a JSONPointer
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// DiffPaths returns the paths that differ between 2 JSON values. Objects and arrays are
+// compared recursively (array entries by index), so only the changed leaves are returned,
+// and the added and removed entries as a whole. Leaves are compared using EqualJSON. The
+// changes are sorted by path, objects in sorted key order, arrays by index.
+func DiffPaths(a interface{}, b interface{}) []PathChange {
+	return diffPaths("", derefPointer(a), derefPointer(b), make([]PathChange, 0))
+}
+
+func diffPaths(path string, a interface{}, b interface{}, changes []PathChange) []PathChange {
+	switch nodeA := a.(type) {
+	case map[string]interface{}:
+		if objB, ok := b.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(nodeA)+len(objB))
+			for key := range nodeA {
+				keys = append(keys, key)
+			}
+			for key := range objB {
+				if _, found := nodeA[key]; !found {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				keyPath := path + "/" + jsonPointerEscaper.Replace(key)
+				valueA, foundA := nodeA[key]
+				valueB, foundB := objB[key]
+				switch {
+				case !foundA:
+					changes = append(changes, PathChange{Path: keyPath, Type: PathAdded, New: valueB})
+				case !foundB:
+					changes = append(changes, PathChange{Path: keyPath, Type: PathRemoved, Old: valueA})
+				default:
+					changes = diffPaths(keyPath, derefPointer(valueA), derefPointer(valueB), changes)
+				}
+			}
+			return changes
+		}
+
+	case []interface{}:
+		if arrB, ok := b.([]interface{}); ok {
+			for i := 0; i < len(nodeA) || i < len(arrB); i++ {
+				indexPath := path + "/" + strconv.Itoa(i)
+				switch {
+				case i >= len(nodeA):
+					changes = append(changes, PathChange{Path: indexPath, Type: PathAdded, New: arrB[i]})
+				case i >= len(arrB):
+					changes = append(changes, PathChange{Path: indexPath, Type: PathRemoved, Old: nodeA[i]})
+				default:
+					changes = diffPaths(indexPath, derefPointer(nodeA[i]), derefPointer(arrB[i]), changes)
+				}
+			}
+			return changes
+		}
+	}
+
+	// leaf values, or values of different types
+	if !EqualJSON(a, b) {
+		changes = append(changes, PathChange{Path: path, Type: PathChanged, Old: a, New: b})
+	}
+	return changes
+}
@@ -2,7 +2,13 @@ package jsonbasics
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -13,7 +19,7 @@ func ToObject(obj interface{}) (map[string]interface{}, error) {
 	case map[string]interface{}:
 		return result, nil
 	default:
-		return nil, fmt.Errorf("not an object, but %t", obj)
+		return nil, fmt.Errorf("expected an object, found %s", typeName(obj))
 	}
 }
 
@@ -23,7 +29,7 @@ func ToArray(arr interface{}) ([]interface{}, error) {
 	case []interface{}:
 		return result, nil
 	}
-	return nil, fmt.Errorf("not an array, but %t", arr)
+	return nil, fmt.Errorf("expected an array, found %s", typeName(arr))
 }
 
 // GetObjectArrayField returns a new slice containing all objects from the array referenced by fieldName.
@@ -94,6 +100,40 @@ func GetStringArrayField(object map[string]interface{}, fieldName string) ([]str
 	return result, nil
 }
 
+// GetArrayField returns the array referenced by fieldName.
+// If the field is not an array, it returns an error.
+// If the field doesn't exist it returns an empty array.
+func GetArrayField(object map[string]interface{}, fieldName string) ([]interface{}, error) {
+	target := object[fieldName]
+	if target == nil {
+		return make([]interface{}, 0), nil
+	}
+
+	return ToArray(target)
+}
+
+// SetArrayField sets an array in a parsed json object.
+// If 'arr' is nil, then the field is deleted from the object.
+func SetArrayField(object map[string]interface{}, fieldName string, arr []interface{}) {
+	if arr == nil {
+		delete(object, fieldName)
+		return
+	}
+	object[fieldName] = arr
+}
+
+// AppendToArrayField appends the values to the array referenced by fieldName. If the
+// field doesn't exist, the array will be created. If the field is not an array, it
+// returns an error and the object remains unchanged.
+func AppendToArrayField(object map[string]interface{}, fieldName string, values ...interface{}) error {
+	arr, err := GetArrayField(object, fieldName)
+	if err != nil {
+		return err
+	}
+	object[fieldName] = append(arr, values...)
+	return nil
+}
+
 // RemoveObjectFromArrayByFieldValue returns a slice in which objects that
 // match the field value are removed. Returns; new slice, # of removals, err.
 // occurrences determines the maximum number of items to remove, use -1 for unlimited.
@@ -150,22 +190,46 @@ func RemoveObjectFromArrayByFieldValue(inArr interface{},
 	return arr[:targetIdx], count, nil
 }
 
+// typeName returns the JSON type name of a value, for use in error messages; "null",
+// "string", "number", "boolean", "object", or "array". Other types return their Go type.
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "number"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// GetStringField returns a string from an object field. Returns an error if the field
+// is not a string, or is not found.
 func GetStringField(object map[string]interface{}, fieldName string) (string, error) {
 	value := object[fieldName]
 	switch result := value.(type) {
 	case string:
 		return result, nil
 	}
-	return "", fmt.Errorf("expected key '%s' to be a string, got %t", fieldName, value)
+	return "", fmt.Errorf("expected key '%s' to be a string, found %s", fieldName, typeName(value))
 }
 
+// GetStringIndex returns a string from an array entry. Returns an error if the entry
+// is not a string.
 func GetStringIndex(arr []interface{}, index int) (string, error) {
 	value := arr[index]
 	switch result := value.(type) {
 	case string:
 		return result, nil
 	}
-	return "", fmt.Errorf("expected index '%d' to be a string, got %t", index, value)
+	return "", fmt.Errorf("expected index '%d' to be a string, found %s", index, typeName(value))
 }
 
 // GetBoolField returns a boolean from an object field. Returns an error if the field
@@ -176,16 +240,18 @@ func GetBoolField(object map[string]interface{}, fieldName string) (bool, error)
 	case bool:
 		return result, nil
 	}
-	return false, fmt.Errorf("expected key '%s' to be a boolean", fieldName)
+	return false, fmt.Errorf("expected key '%s' to be a boolean, found %s", fieldName, typeName(value))
 }
 
+// GetBoolIndex returns a boolean from an array entry. Returns an error if the entry
+// is not a boolean.
 func GetBoolIndex(arr []interface{}, index int) (bool, error) {
 	value := arr[index]
 	switch result := value.(type) {
 	case bool:
 		return result, nil
 	}
-	return false, fmt.Errorf("expected index '%d' to be a boolean", index)
+	return false, fmt.Errorf("expected index '%d' to be a boolean, found %s", index, typeName(value))
 }
 
 // DeepCopyObject implements a poor man's deepcopy by jsonify/de-jsonify
@@ -204,6 +270,308 @@ func DeepCopyArray(data *[]interface{}) *[]interface{} {
 	return &dataCopy
 }
 
+// DeleteFieldByPath removes the field at 'path' (a list of object keys, from the root down to
+// the field). Returns whether the field existed. Deleting a non-existing field returns false,
+// and no error. Returns an error if the path is empty, or if an intermediate node is not an
+// object.
+func DeleteFieldByPath(data map[string]interface{}, path []string) (bool, error) {
+	if len(path) == 0 {
+		return false, errors.New("expected 'path' to have at least 1 element")
+	}
+
+	object := data
+	for i, key := range path[:len(path)-1] {
+		value, found := object[key]
+		if !found {
+			return false, nil
+		}
+		next, ok := value.(map[string]interface{})
+		if !ok {
+			return false, fmt.Errorf("expected '%s' to be an object", strings.Join(path[:i+1], "."))
+		}
+		object = next
+	}
+
+	key := path[len(path)-1]
+	if _, found := object[key]; !found {
+		return false, nil
+	}
+	delete(object, key)
+	return true, nil
+}
+
+// RemoveNulls removes all object fields with a null value, recursively, in place. Null
+// entries in arrays are kept, since removing them would change the indices.
+func RemoveNulls(data interface{}) {
+	switch d := data.(type) {
+	case map[string]interface{}:
+		for key, value := range d {
+			if value == nil {
+				delete(d, key)
+			} else {
+				RemoveNulls(value)
+			}
+		}
+	case []interface{}:
+		for _, value := range d {
+			RemoveNulls(value)
+		}
+	}
+}
+
+// RemoveEmpty returns a copy of the data, with all object fields removed that have an
+// empty array or empty object as value, recursively. Objects that only become empty by
+// removing their fields are removed as well. If removeEmptyStrings is set, fields with an
+// empty string are removed too. Fields named in 'keep' are never removed (eg. fields the
+// format requires, even when empty). Array entries are kept, since removing them would
+// change the indices. The input is not modified.
+func RemoveEmpty(data interface{}, removeEmptyStrings bool, keep ...string) interface{} {
+	keepFields := make(map[string]bool, len(keep))
+	for _, name := range keep {
+		keepFields[name] = true
+	}
+	return removeEmpty(data, removeEmptyStrings, keepFields)
+}
+
+func removeEmpty(data interface{}, removeEmptyStrings bool, keep map[string]bool) interface{} {
+	switch d := data.(type) {
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(d))
+		for key, value := range d {
+			cleaned := removeEmpty(value, removeEmptyStrings, keep)
+			if keep[key] || !isEmpty(cleaned, removeEmptyStrings) {
+				object[key] = cleaned
+			}
+		}
+		return object
+	case []interface{}:
+		array := make([]interface{}, len(d))
+		for i, value := range d {
+			array[i] = removeEmpty(value, removeEmptyStrings, keep)
+		}
+		return array
+	}
+	return data
+}
+
+// isEmpty returns true for an empty array or object, and optionally an empty string.
+func isEmpty(value interface{}, emptyStrings bool) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	case string:
+		return emptyStrings && v == ""
+	}
+	return false
+}
+
+// NormalizeYAMLMaps recursively converts any map[interface{}]interface{} (as produced by
+// some YAML parsers for non-string keys, eg. integers or booleans) to a
+// map[string]interface{}, with the keys formatted as strings. Objects and arrays are
+// updated in place, the (possibly new) value is returned.
+func NormalizeYAMLMaps(v interface{}) interface{} {
+	switch d := v.(type) {
+	case map[interface{}]interface{}:
+		object := make(map[string]interface{}, len(d))
+		for key, value := range d {
+			object[fmt.Sprintf("%v", key)] = NormalizeYAMLMaps(value)
+		}
+		return object
+	case map[string]interface{}:
+		for key, value := range d {
+			d[key] = NormalizeYAMLMaps(value)
+		}
+	case []interface{}:
+		for i, value := range d {
+			d[i] = NormalizeYAMLMaps(value)
+		}
+	}
+	return v
+}
+
+// Walk traverses the data depth-first, and calls 'visit' for every node (including 'root'
+// itself). The path passed is the list of keys from the root to the node, where array
+// indices are formatted as "[index]". Objects are traversed in their sorted key order.
+// A node is visited before its children. If 'visit' returns an error, the walk is aborted
+// and the error is returned.
+func Walk(root interface{}, visit func(path []string, value interface{}) error) error {
+	return walk(make([]string, 0), root, visit)
+}
+
+func walk(path []string, node interface{}, visit func(path []string, value interface{}) error) error {
+	// pass a copy, so 'visit' cannot tamper with our path
+	if err := visit(append([]string{}, path...), node); err != nil {
+		return err
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(n))
+		for key := range n {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := walk(append(path, key), n[key], visit); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, value := range n {
+			if err := walk(append(path, "["+strconv.Itoa(i)+"]"), value, visit); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// toFloat returns the numeric value as a float64, and whether it was a number.
+func toFloat(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() { //nolint:exhaustive // only numeric kinds are of interest
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// CoerceScalar converts a scalar value to the target type; "string", "bool", "int" (returned
+// as an int), or "number" (returned as a float64). Strings are parsed (eg. "true", or "42"),
+// and numbers and booleans are formatted as strings. Values of the target type are returned
+// as is. Returns an error if the value cannot be coerced, eg. "abc" to "int", 1.5 to "int",
+// or an object or array.
+func CoerceScalar(value interface{}, target string) (interface{}, error) {
+	str, isString := value.(string)
+	num, isNumber := toFloat(value)
+	boolean, isBool := value.(bool)
+
+	switch target {
+	case "string":
+		switch {
+		case isString:
+			return str, nil
+		case isBool:
+			return strconv.FormatBool(boolean), nil
+		case isNumber:
+			return strconv.FormatFloat(num, 'f', -1, 64), nil
+		}
+	case "bool":
+		switch {
+		case isBool:
+			return boolean, nil
+		case isString:
+			if b, err := strconv.ParseBool(strings.TrimSpace(str)); err == nil {
+				return b, nil
+			}
+		}
+	case "int":
+		if isString {
+			num, isNumber = parseNumber(str)
+		}
+		if isNumber && num == float64(int(num)) {
+			return int(num), nil
+		}
+	case "number":
+		if isString {
+			num, isNumber = parseNumber(str)
+		}
+		if isNumber {
+			return num, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown target type '%s', expected 'string', 'bool', 'int', or 'number'", target)
+	}
+	return nil, fmt.Errorf("cannot coerce %s '%v' to %s", typeName(value), value, target)
+}
+
+// parseNumber parses a string as a finite number, and returns whether it was one.
+func parseNumber(str string) (float64, bool) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+// derefPointer returns the value pointed to, or nil for a nil-pointer.
+func derefPointer(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// EqualJSON compares 2 JSON values for semantic equality. Objects are compared regardless
+// of key order, and numbers are compared by value regardless of their type (eg. 1 and 1.0 are
+// equal). Typed slices and maps (eg. []string) are compared by their contents, and pointers
+// are dereferenced.
+func EqualJSON(a interface{}, b interface{}) bool {
+	a = derefPointer(a)
+	b = derefPointer(b)
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+
+	va := reflect.ValueOf(a)
+	vb := reflect.ValueOf(b)
+	switch va.Kind() { //nolint:exhaustive // other kinds are compared as scalars
+	case reflect.Slice, reflect.Array:
+		if vb.Kind() != reflect.Slice && vb.Kind() != reflect.Array {
+			return false
+		}
+		if va.Len() != vb.Len() {
+			return false
+		}
+		for i := 0; i < va.Len(); i++ {
+			if !EqualJSON(va.Index(i).Interface(), vb.Index(i).Interface()) {
+				return false
+			}
+		}
+		return true
+
+	case reflect.Map:
+		if vb.Kind() != reflect.Map || va.Len() != vb.Len() {
+			return false
+		}
+		if va.Type().Key().Kind() != reflect.String || vb.Type().Key().Kind() != reflect.String {
+			return reflect.DeepEqual(a, b)
+		}
+		for _, key := range va.MapKeys() {
+			valueB := vb.MapIndex(reflect.ValueOf(key.String()).Convert(vb.Type().Key()))
+			if !valueB.IsValid() || !EqualJSON(va.MapIndex(key).Interface(), valueB.Interface()) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
 //
 //
 //  Start of workaround code
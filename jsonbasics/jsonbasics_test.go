@@ -1,6 +1,10 @@
 package jsonbasics_test
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
 	. "github.com/kong/go-apiops/filebasics"
 	. "github.com/kong/go-apiops/jsonbasics"
 	. "github.com/onsi/ginkgo/v2"
@@ -20,14 +24,14 @@ var _ = Describe("jsonbasics", func() {
 		It("returns an error if nil", func() {
 			val, err := ToObject(nil)
 
-			Expect(err).To(MatchError("not an object, but %!t(<nil>)"))
+			Expect(err).To(MatchError("expected an object, found null"))
 			Expect(val).To(BeNil())
 		})
 
 		It("returns an error if string", func() {
 			val, err := ToObject("123")
 
-			Expect(err).To(MatchError("not an object, but %!t(string=123)"))
+			Expect(err).To(MatchError("expected an object, found string"))
 			Expect(val).To(BeNil())
 		})
 	})
@@ -44,14 +48,14 @@ var _ = Describe("jsonbasics", func() {
 		It("returns an error if nil", func() {
 			val, err := ToArray(nil)
 
-			Expect(err).To(MatchError("not an array, but %!t(<nil>)"))
+			Expect(err).To(MatchError("expected an array, found null"))
 			Expect(val).To(BeNil())
 		})
 
 		It("returns an error if string", func() {
 			val, err := ToArray("123")
 
-			Expect(err).To(MatchError("not an array, but %!t(string=123)"))
+			Expect(err).To(MatchError("expected an array, found string"))
 			Expect(val).To(BeNil())
 		})
 	})
@@ -128,7 +132,7 @@ var _ = Describe("jsonbasics", func() {
 			}`)
 			objArr, err := GetObjectArrayField(MustDeserialize(&data), "myArray")
 
-			Expect(err).To(MatchError("not an array, but %!t(string=it's a string)"))
+			Expect(err).To(MatchError("expected an array, found string"))
 			Expect(objArr).To(BeNil())
 		})
 	})
@@ -153,7 +157,7 @@ var _ = Describe("jsonbasics", func() {
 			// since the myArray field is now []map[string]interface{} instead of
 			// []interface{} it will no longer be recognized as an array
 			objArr2, err := GetObjectArrayField(obj, "myArray")
-			Expect(err.Error()).To(ContainSubstring("not an array"))
+			Expect(err).To(MatchError("expected an array, found []map[string]interface {}"))
 			Expect(objArr2).To(BeNil())
 
 			// Do it again, but use the SetObjectArrayField
@@ -166,28 +170,452 @@ var _ = Describe("jsonbasics", func() {
 		})
 	})
 
+	Describe("GetArrayField", func() {
+		It("returns the array", func() {
+			data := []byte(`{
+				"myArray": [ 1, "two", true ]
+			}`)
+			arr, err := GetArrayField(MustDeserialize(&data), "myArray")
+
+			Expect(err).To(BeNil())
+			Expect(arr).To(BeEquivalentTo([]interface{}{1.0, "two", true}))
+		})
+
+		It("returns an empty array if the field doesn't exist", func() {
+			data := []byte(`{}`)
+			arr, err := GetArrayField(MustDeserialize(&data), "myArray")
+
+			Expect(err).To(BeNil())
+			Expect(arr).To(BeEquivalentTo([]interface{}{}))
+		})
+
+		It("returns an error if the field is not an array", func() {
+			data := []byte(`{
+				"myArray": "it's a string"
+			}`)
+			arr, err := GetArrayField(MustDeserialize(&data), "myArray")
+
+			Expect(err).To(MatchError("expected an array, found string"))
+			Expect(arr).To(BeNil())
+		})
+	})
+
+	Describe("SetArrayField", func() {
+		It("sets the array", func() {
+			obj := map[string]interface{}{}
+			SetArrayField(obj, "myArray", []interface{}{"one"})
+
+			Expect(obj).To(BeEquivalentTo(map[string]interface{}{
+				"myArray": []interface{}{"one"},
+			}))
+		})
+
+		It("deletes the field if the array is nil", func() {
+			obj := map[string]interface{}{
+				"myArray": []interface{}{"one"},
+			}
+			SetArrayField(obj, "myArray", nil)
+
+			Expect(obj).To(BeEquivalentTo(map[string]interface{}{}))
+		})
+	})
+
+	Describe("AppendToArrayField", func() {
+		It("appends to an existing array", func() {
+			data := []byte(`{
+				"myArray": [ "one" ]
+			}`)
+			obj := MustDeserialize(&data)
+			err := AppendToArrayField(obj, "myArray", "two", "three")
+
+			Expect(err).To(BeNil())
+			Expect(obj["myArray"]).To(BeEquivalentTo([]interface{}{"one", "two", "three"}))
+		})
+
+		It("creates the array if the field doesn't exist", func() {
+			obj := map[string]interface{}{}
+			err := AppendToArrayField(obj, "myArray", "one")
+
+			Expect(err).To(BeNil())
+			Expect(obj["myArray"]).To(BeEquivalentTo([]interface{}{"one"}))
+		})
+
+		It("returns an error if the field is not an array", func() {
+			obj := map[string]interface{}{
+				"myArray": "it's a string",
+			}
+			err := AppendToArrayField(obj, "myArray", "one")
+
+			Expect(err).To(MatchError("expected an array, found string"))
+			Expect(obj["myArray"]).To(Equal("it's a string"))
+		})
+	})
+
 	Describe("RemoveObjectFromArrayByFieldValue", func() {
 		PIt("still to do", func() {
 		})
 	})
 
 	Describe("GetStringField", func() {
-		PIt("still to do", func() {
+		data := map[string]interface{}{
+			"string": "hello",
+			"number": float64(1),
+			"bool":   true,
+			"object": map[string]interface{}{},
+			"array":  []interface{}{},
+			"null":   nil,
+		}
+
+		It("returns the string", func() {
+			Expect(GetStringField(data, "string")).To(Equal("hello"))
+		})
+
+		It("reports the type found", func() {
+			for name, found := range map[string]string{
+				"number":  "number",
+				"bool":    "boolean",
+				"object":  "object",
+				"array":   "array",
+				"null":    "null",
+				"missing": "null",
+			} {
+				_, err := GetStringField(data, name)
+				Expect(err).To(MatchError(fmt.Sprintf("expected key '%s' to be a string, found %s", name, found)))
+			}
 		})
 	})
 
 	Describe("GetStringIndex", func() {
-		PIt("still to do", func() {
+		It("reports the type found", func() {
+			_, err := GetStringIndex([]interface{}{"hello", 42}, 1)
+			Expect(err).To(MatchError("expected index '1' to be a string, found number"))
 		})
 	})
 
 	Describe("GetBoolField", func() {
-		PIt("still to do", func() {
+		data := map[string]interface{}{
+			"bool":   false,
+			"string": "true",
+			"number": 1,
+		}
+
+		It("returns the boolean", func() {
+			Expect(GetBoolField(data, "bool")).To(BeFalse())
+		})
+
+		It("reports the type found", func() {
+			_, err := GetBoolField(data, "string")
+			Expect(err).To(MatchError("expected key 'string' to be a boolean, found string"))
+			_, err = GetBoolField(data, "number")
+			Expect(err).To(MatchError("expected key 'number' to be a boolean, found number"))
 		})
 	})
 
 	Describe("GetBoolIndex", func() {
-		PIt("still to do", func() {
+		It("reports the type found", func() {
+			_, err := GetBoolIndex([]interface{}{true, map[string]interface{}{}}, 1)
+			Expect(err).To(MatchError("expected index '1' to be a boolean, found object"))
+		})
+	})
+
+	Describe("DeleteFieldByPath", func() {
+		data := []byte(`{ "a": { "b": { "c": 1, "d": 2 } }, "e": "scalar" }`)
+
+		It("deletes an existing field", func() {
+			obj := MustDeserialize(&data)
+			existed, err := DeleteFieldByPath(obj, []string{"a", "b", "c"})
+			Expect(err).To(BeNil())
+			Expect(existed).To(BeTrue())
+			Expect(obj["a"].(map[string]interface{})["b"]).To(Equal(map[string]interface{}{"d": float64(2)}))
+		})
+
+		It("returns false for a missing field", func() {
+			obj := MustDeserialize(&data)
+			existed, err := DeleteFieldByPath(obj, []string{"a", "b", "x"})
+			Expect(err).To(BeNil())
+			Expect(existed).To(BeFalse())
+
+			existed, err = DeleteFieldByPath(obj, []string{"x", "y", "z"})
+			Expect(err).To(BeNil())
+			Expect(existed).To(BeFalse())
+			Expect(obj).To(Equal(MustDeserialize(&data)))
+		})
+
+		It("returns an error if an intermediate node is not an object", func() {
+			obj := MustDeserialize(&data)
+			existed, err := DeleteFieldByPath(obj, []string{"e", "f"})
+			Expect(err).To(MatchError("expected 'e' to be an object"))
+			Expect(existed).To(BeFalse())
+		})
+
+		It("returns an error on an empty path", func() {
+			_, err := DeleteFieldByPath(MustDeserialize(&data), []string{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("RemoveNulls", func() {
+		It("removes null fields recursively, but keeps null array entries", func() {
+			data := []byte(`{
+				"a": null,
+				"b": { "c": null, "d": 1 },
+				"e": [ null, { "f": null, "g": "x" } ]
+			}`)
+			obj := MustDeserialize(&data)
+			RemoveNulls(obj)
+
+			expected := []byte(`{
+				"b": { "d": 1 },
+				"e": [ null, { "g": "x" } ]
+			}`)
+			Expect(*MustSerialize(obj, OutputFormatJSON)).To(MatchJSON(expected))
+		})
+	})
+
+	Describe("RemoveEmpty", func() {
+		data := []byte(`{
+			"tags": [],
+			"name": "",
+			"config": { "a": {}, "b": { "c": [] }, "d": 0 },
+			"empty": { "nested": { "deeper": {} } },
+			"list": [ {}, { "e": [] }, "" ],
+			"required": {}
+		}`)
+
+		It("removes empty arrays and objects recursively, but keeps array entries", func() {
+			obj := MustDeserialize(&data)
+			result := RemoveEmpty(obj, false)
+
+			expected := []byte(`{
+				"name": "",
+				"config": { "d": 0 },
+				"list": [ {}, {}, "" ]
+			}`)
+			Expect(*MustSerialize(result.(map[string]interface{}), OutputFormatJSON)).To(MatchJSON(expected))
+		})
+
+		It("removes empty strings if requested", func() {
+			obj := MustDeserialize(&data)
+			result := RemoveEmpty(obj, true)
+
+			expected := []byte(`{
+				"config": { "d": 0 },
+				"list": [ {}, {}, "" ]
+			}`)
+			Expect(*MustSerialize(result.(map[string]interface{}), OutputFormatJSON)).To(MatchJSON(expected))
+		})
+
+		It("keeps the given fields, and does not modify the input", func() {
+			obj := MustDeserialize(&data)
+			result := RemoveEmpty(obj, false, "required")
+
+			expected := []byte(`{
+				"name": "",
+				"config": { "d": 0 },
+				"list": [ {}, {}, "" ],
+				"required": {}
+			}`)
+			Expect(*MustSerialize(result.(map[string]interface{}), OutputFormatJSON)).To(MatchJSON(expected))
+			Expect(*MustSerialize(obj, OutputFormatJSON)).To(MatchJSON(data))
+		})
+	})
+
+	Describe("CoerceScalar", func() {
+		It("coerces a string to a bool", func() {
+			Expect(CoerceScalar("true", "bool")).To(BeTrue())
+			Expect(CoerceScalar(" False ", "bool")).To(BeFalse())
+			Expect(CoerceScalar(true, "bool")).To(BeTrue())
+		})
+
+		It("coerces a string to an int", func() {
+			Expect(CoerceScalar("42", "int")).To(Equal(42))
+			Expect(CoerceScalar("1e3", "int")).To(Equal(1000))
+			Expect(CoerceScalar(float64(7), "int")).To(Equal(7))
+		})
+
+		It("coerces to a number and a string", func() {
+			Expect(CoerceScalar("1.5", "number")).To(Equal(1.5))
+			Expect(CoerceScalar(3, "number")).To(Equal(float64(3)))
+			Expect(CoerceScalar(float64(10), "string")).To(Equal("10"))
+			Expect(CoerceScalar(false, "string")).To(Equal("false"))
+		})
+
+		It("fails on impossible coercions", func() {
+			_, err := CoerceScalar("abc", "int")
+			Expect(err).To(MatchError("cannot coerce string 'abc' to int"))
+			_, err = CoerceScalar(1.5, "int")
+			Expect(err).To(MatchError("cannot coerce number '1.5' to int"))
+			_, err = CoerceScalar(1, "bool")
+			Expect(err).To(HaveOccurred())
+			_, err = CoerceScalar("NaN", "number")
+			Expect(err).To(HaveOccurred())
+			_, err = CoerceScalar([]interface{}{}, "string")
+			Expect(err).To(HaveOccurred())
+			_, err = CoerceScalar("x", "date")
+			Expect(err).To(MatchError(
+				"unknown target type 'date', expected 'string', 'bool', 'int', or 'number'"))
+		})
+	})
+
+	Describe("NormalizeYAMLMaps", func() {
+		It("converts non-string keys to strings, recursively", func() {
+			data := map[interface{}]interface{}{
+				1:    "one",
+				true: "yes",
+				"nested": []interface{}{
+					map[interface{}]interface{}{
+						2:     map[interface{}]interface{}{false: 0},
+						"str": "value",
+					},
+				},
+			}
+
+			Expect(NormalizeYAMLMaps(data)).To(Equal(map[string]interface{}{
+				"1":    "one",
+				"true": "yes",
+				"nested": []interface{}{
+					map[string]interface{}{
+						"2":   map[string]interface{}{"false": 0},
+						"str": "value",
+					},
+				},
+			}))
+		})
+		It("returns other values as is", func() {
+			Expect(NormalizeYAMLMaps("value")).To(Equal("value"))
+			Expect(NormalizeYAMLMaps(nil)).To(BeNil())
+		})
+	})
+
+	Describe("Walk", func() {
+		It("visits all nodes depth-first with their paths", func() {
+			data := []byte(`{
+				"b": [ "x", { "c": true } ],
+				"a": 1
+			}`)
+			visited := make([]string, 0)
+			err := Walk(MustDeserialize(&data), func(path []string, value interface{}) error {
+				visited = append(visited, fmt.Sprintf("%v", path))
+				return nil
+			})
+
+			Expect(err).To(BeNil())
+			Expect(visited).To(Equal([]string{
+				"[]",
+				"[a]",
+				"[b]",
+				"[b [0]]",
+				"[b [1]]",
+				"[b [1] c]",
+			}))
+		})
+
+		It("aborts the walk if visit returns an error", func() {
+			data := []byte(`{
+				"a": { "b": 1 },
+				"c": 2
+			}`)
+			visited := make([]string, 0)
+			err := Walk(MustDeserialize(&data), func(path []string, value interface{}) error {
+				visited = append(visited, fmt.Sprintf("%v", path))
+				if len(path) == 2 {
+					return errors.New("stop here")
+				}
+				return nil
+			})
+
+			Expect(err).To(MatchError("stop here"))
+			Expect(visited).To(Equal([]string{"[]", "[a]", "[a b]"}))
+		})
+	})
+
+	Describe("EqualJSON", func() {
+		It("ignores key order", func() {
+			data1 := []byte(`{ "a": 1, "b": { "c": [ 1, 2 ], "d": "x" } }`)
+			data2 := []byte(`{ "b": { "d": "x", "c": [ 1, 2 ] }, "a": 1 }`)
+
+			Expect(EqualJSON(MustDeserialize(&data1), MustDeserialize(&data2))).To(BeTrue())
+		})
+
+		It("normalizes number types", func() {
+			Expect(EqualJSON(1, 1.0)).To(BeTrue())
+			Expect(EqualJSON(int64(1), float32(1))).To(BeTrue())
+			Expect(EqualJSON(json.Number("1.0"), uint8(1))).To(BeTrue())
+			Expect(EqualJSON(1, 1.5)).To(BeFalse())
+			Expect(EqualJSON(1, "1")).To(BeFalse())
+		})
+
+		It("compares nested values", func() {
+			data := []byte(`{ "a": [ { "b": 1 } ], "c": [ "x", "y" ] }`)
+			parsed := MustDeserialize(&data)
+			typed := map[string]interface{}{
+				"a": []map[string]interface{}{{"b": 1}},
+				"c": []string{"x", "y"},
+			}
+
+			Expect(EqualJSON(parsed, typed)).To(BeTrue())
+			Expect(EqualJSON(parsed, &typed)).To(BeTrue())
+
+			typed["c"] = []string{"y", "x"}
+			Expect(EqualJSON(parsed, typed)).To(BeFalse())
+		})
+
+		It("detects differences in structure", func() {
+			data1 := []byte(`{ "a": [ 1 ] }`)
+			data2 := []byte(`{ "a": [ 1, 2 ] }`)
+			data3 := []byte(`{ "a": [ 1 ], "b": null }`)
+
+			Expect(EqualJSON(MustDeserialize(&data1), MustDeserialize(&data2))).To(BeFalse())
+			Expect(EqualJSON(MustDeserialize(&data1), MustDeserialize(&data3))).To(BeFalse())
+			Expect(EqualJSON(nil, nil)).To(BeTrue())
+			Expect(EqualJSON(nil, false)).To(BeFalse())
+		})
+	})
+
+	Describe("DiffPaths", func() {
+		It("returns nothing for equal values", func() {
+			a := map[string]interface{}{"a": 1, "b": []interface{}{"x"}}
+			b := map[string]interface{}{"a": 1.0, "b": []string{"x"}}
+			Expect(DiffPaths(a, b)).To(BeEmpty())
+		})
+
+		It("returns added and removed keys and entries", func() {
+			a := map[string]interface{}{
+				"removed": "gone",
+				"list":    []interface{}{"a", "b", "c"},
+			}
+			b := map[string]interface{}{
+				"added": map[string]interface{}{"new": true},
+				"list":  []interface{}{"a", "b"},
+			}
+			Expect(DiffPaths(a, b)).To(Equal([]PathChange{
+				{Path: "/added", Type: PathAdded, New: map[string]interface{}{"new": true}},
+				{Path: "/list/2", Type: PathRemoved, Old: "c"},
+				{Path: "/removed", Type: PathRemoved, Old: "gone"},
+			}))
+		})
+
+		It("returns nested scalar changes, with escaped keys", func() {
+			a := map[string]interface{}{
+				"services": []interface{}{
+					map[string]interface{}{"name": "svc1", "a/b~c": 1, "port": 80},
+				},
+			}
+			b := map[string]interface{}{
+				"services": []interface{}{
+					map[string]interface{}{"name": "svc2", "a/b~c": 2, "port": 80},
+				},
+			}
+			Expect(DiffPaths(a, b)).To(Equal([]PathChange{
+				{Path: "/services/0/a~1b~0c", Type: PathChanged, Old: 1, New: 2},
+				{Path: "/services/0/name", Type: PathChanged, Old: "svc1", New: "svc2"},
+			}))
+		})
+
+		It("returns a change for values of different types", func() {
+			Expect(DiffPaths(map[string]interface{}{"a": "1"}, []interface{}{"1"})).To(Equal([]PathChange{
+				{Path: "", Type: PathChanged, Old: map[string]interface{}{"a": "1"}, New: []interface{}{"1"}},
+			}))
 		})
 	})
 
@@ -200,4 +628,142 @@ var _ = Describe("jsonbasics", func() {
 		PIt("still to do", func() {
 		})
 	})
+
+	Describe("OrderedMap", func() {
+		It("retains the order of the keys", func() {
+			m := NewOrderedMap()
+			m.Set("z", 1)
+			m.Set("a", 2)
+			m.Set("m", 3)
+			m.Set("z", 4) // existing key retains its position
+			Expect(m.Keys()).To(Equal([]string{"z", "a", "m"}))
+			value, found := m.Get("z")
+			Expect(found).To(BeTrue())
+			Expect(value).To(Equal(4))
+
+			m.Delete("a")
+			m.Delete("does-not-exist")
+			Expect(m.Keys()).To(Equal([]string{"z", "m"}))
+			_, found = m.Get("a")
+			Expect(found).To(BeFalse())
+		})
+
+		It("round-trips JSON, in order", func() {
+			data := `{"z":{"b":1,"a":[{"y":true,"x":null}]},"a":"hello"}`
+			m := NewOrderedMap()
+			Expect(json.Unmarshal([]byte(data), m)).To(Succeed())
+			Expect(m.Keys()).To(Equal([]string{"z", "a"}))
+			result, err := json.Marshal(m)
+			Expect(err).To(BeNil())
+			Expect(string(result)).To(Equal(data))
+		})
+
+		It("fails unmarshaling JSON that is not an object", func() {
+			m := NewOrderedMap()
+			Expect(json.Unmarshal([]byte(`[1, 2]`), m)).To(MatchError("expected the data to be an Object"))
+		})
+	})
+
+	Describe("JSONPointer", func() {
+		data := []byte(`{
+			"services": [ { "name": "svc1" } ],
+			"a/b": { "c~d": "escaped" }
+		}`)
+
+		It("parses pointers, and decodes the escapes", func() {
+			path, err := ParsePointer("/a~1b/c~0d")
+			Expect(err).To(BeNil())
+			Expect(path).To(Equal([]string{"a/b", "c~d"}))
+
+			path, err = ParsePointer("")
+			Expect(err).To(BeNil())
+			Expect(path).To(BeEmpty())
+
+			_, err = ParsePointer("services")
+			Expect(err).To(MatchError("expected JSONPointer 'services' to start with '/'"))
+			_, err = ParsePointer("/a~2b")
+			Expect(err).To(MatchError("invalid escape in JSONPointer '/a~2b', expected '~0' or '~1'"))
+		})
+
+		It("gets values by pointer", func() {
+			obj := MustDeserialize(&data)
+			Expect(GetByPointer(obj, "/services/0/name")).To(Equal("svc1"))
+			Expect(GetByPointer(obj, "/a~1b/c~0d")).To(Equal("escaped"))
+
+			_, err := GetByPointer(obj, "/services/1")
+			Expect(err).To(MatchError("'services.1' not found, the array has 1 entries"))
+			_, err = GetByPointer(obj, "/services/01")
+			Expect(err).To(MatchError("failed to get 'services.01'; expected '01' to be an array index"))
+			_, err = GetByPointer(obj, "/services/-")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("sets values by pointer, and appends to arrays", func() {
+			obj := MustDeserialize(&data)
+			Expect(SetByPointer(obj, "/services/-", map[string]interface{}{"name": "svc2"})).To(Succeed())
+			Expect(SetByPointer(obj, "/services/2", map[string]interface{}{"name": "svc3"})).To(Succeed())
+			Expect(SetByPointer(obj, "/services/0/name", "renamed")).To(Succeed())
+			Expect(SetByPointer(obj, "/a~1b/new~1key", true)).To(Succeed())
+
+			expected := []byte(`{
+				"services": [ { "name": "renamed" }, { "name": "svc2" }, { "name": "svc3" } ],
+				"a/b": { "c~d": "escaped", "new/key": true }
+			}`)
+			Expect(*MustSerialize(obj, OutputFormatJSON)).To(MatchJSON(expected))
+		})
+
+		It("fails setting a value below a missing node", func() {
+			obj := MustDeserialize(&data)
+			Expect(SetByPointer(obj, "/routes/0/name", "x")).To(MatchError("'routes' not found"))
+			Expect(SetByPointer(obj, "/services/5", "x")).To(
+				MatchError("'services.5' not found, the array has 1 entries"))
+			Expect(SetByPointer(obj, "", "x")).To(MatchError("expected 'path' to have at least 1 element"))
+			Expect(SetByPointer(obj, "/services/0/name/x", "x")).To(
+				MatchError("expected 'services.0.name' to be an object or array"))
+		})
+	})
+
+	Describe("Merge", func() {
+		base := func() map[string]interface{} {
+			return map[string]interface{}{
+				"name": "svc",
+				"port": 80,
+				"tags": []interface{}{"a"},
+				"obj":  map[string]interface{}{"keep": true, "drop": "me"},
+			}
+		}
+		overlay := map[string]interface{}{
+			"port": 8080,
+			"path": "/new",
+			"tags": []interface{}{"b"},
+			"obj":  map[string]interface{}{"drop": nil, "added": 1},
+		}
+
+		It("adds and overrides fields, and replaces arrays", func() {
+			Expect(Merge(base(), overlay, ArrayStrategyReplace)).To(Equal(map[string]interface{}{
+				"name": "svc",
+				"port": 8080,
+				"path": "/new",
+				"tags": []interface{}{"b"},
+				"obj":  map[string]interface{}{"keep": true, "added": 1},
+			}))
+		})
+
+		It("appends arrays", func() {
+			result := Merge(base(), overlay, ArrayStrategyAppend).(map[string]interface{})
+			Expect(result["tags"]).To(Equal([]interface{}{"a", "b"}))
+		})
+
+		It("replaces values of a different type", func() {
+			Expect(Merge(base(), map[string]interface{}{"tags": "none"}, ArrayStrategyAppend)).
+				To(HaveKeyWithValue("tags", "none"))
+			Expect(Merge("scalar", overlay, ArrayStrategyReplace)).To(HaveKeyWithValue("port", 8080))
+		})
+
+		It("validates the array strategy", func() {
+			Expect(ValidateArrayStrategy(ArrayStrategyAppend)).To(Succeed())
+			Expect(ValidateArrayStrategy("merge")).To(MatchError(
+				"expected array strategy to be one of 'replace', or 'append', got: 'merge'"))
+		})
+	})
 })
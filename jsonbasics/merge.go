@@ -0,0 +1,48 @@
+package jsonbasics
+
+import "fmt"
+
+const (
+	ArrayStrategyReplace = "replace" // an array in the overlay replaces the array in the base
+	ArrayStrategyAppend  = "append"  // an array in the overlay is appended to the array in the base
+)
+
+// ValidateArrayStrategy returns an error if the array strategy for Merge is unknown.
+func ValidateArrayStrategy(strategy string) error {
+	switch strategy {
+	case ArrayStrategyReplace, ArrayStrategyAppend:
+		return nil
+	}
+	return fmt.Errorf("expected array strategy to be one of '%s', or '%s', got: '%s'",
+		ArrayStrategyReplace, ArrayStrategyAppend, strategy)
+}
+
+// Merge deep-merges the overlay onto the base, and returns the result. Objects are merged
+// field by field (recursively), where a null field in the overlay removes the field from the
+// base. Arrays are merged by the array strategy (ArrayStrategyReplace or ArrayStrategyAppend),
+// and any other value in the overlay replaces the base value. Objects in the base are updated
+// in place, values from the overlay are used as is (not copied).
+func Merge(base interface{}, overlay interface{}, arrayStrategy string) interface{} {
+	switch overlayValue := overlay.(type) {
+	case map[string]interface{}:
+		baseObject, ok := base.(map[string]interface{})
+		if !ok {
+			baseObject = make(map[string]interface{})
+		}
+		for key, value := range overlayValue {
+			if value == nil {
+				delete(baseObject, key)
+				continue
+			}
+			baseObject[key] = Merge(baseObject[key], value, arrayStrategy)
+		}
+		return baseObject
+
+	case []interface{}:
+		if baseArray, ok := base.([]interface{}); ok && arrayStrategy == ArrayStrategyAppend {
+			return append(baseArray, overlayValue...)
+		}
+		return overlayValue
+	}
+	return overlay
+}
@@ -0,0 +1,216 @@
+package jsonbasics
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OrderedMap is a JSON object that retains the order of its keys, so the original order
+// survives a read/write cycle. Nested objects are *OrderedMap as well, arrays are
+// []interface{}. The zero value is not usable, use NewOrderedMap.
+type OrderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// NewOrderedMap returns a new, empty, OrderedMap.
+func NewOrderedMap() *OrderedMap {
+	return &OrderedMap{
+		keys:   make([]string, 0),
+		values: make(map[string]interface{}),
+	}
+}
+
+// Keys returns the keys, in order.
+func (m *OrderedMap) Keys() []string {
+	return append(make([]string, 0, len(m.keys)), m.keys...)
+}
+
+// Get returns the value of a key, and whether it was found.
+func (m *OrderedMap) Get(key string) (interface{}, bool) {
+	value, found := m.values[key]
+	return value, found
+}
+
+// Set sets the value of a key. A new key is added at the end, an existing key retains
+// its position.
+func (m *OrderedMap) Set(key string, value interface{}) {
+	if _, found := m.values[key]; !found {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Delete removes a key, if it exists.
+func (m *OrderedMap) Delete(key string) {
+	if _, found := m.values[key]; !found {
+		return
+	}
+	delete(m.values, key)
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// MarshalJSON implements json.Marshaler, writing the keys in order.
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		keyData, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		valueData, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(keyData)
+		buf.WriteByte(':')
+		buf.Write(valueData)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler, retaining the order of the keys.
+func (m *OrderedMap) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	value, err := decodeJSONValue(decoder)
+	if err != nil {
+		return err
+	}
+	object, ok := value.(*OrderedMap)
+	if !ok {
+		return errors.New("expected the data to be an Object")
+	}
+	*m = *object
+	return nil
+}
+
+// decodeJSONValue decodes the next JSON value from the decoder, objects are returned
+// as *OrderedMap.
+func decodeJSONValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		object := NewOrderedMap()
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object.Set(keyToken.(string), value)
+		}
+		_, err = decoder.Token() // consume the closing '}'
+		return object, err
+
+	case json.Delim('['):
+		array := make([]interface{}, 0)
+		for decoder.More() {
+			value, err := decodeJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err = decoder.Token() // consume the closing ']'
+		return array, err
+	}
+
+	return token, nil
+}
+
+// MarshalYAML implements yaml.Marshaler, writing the keys in order.
+func (m *OrderedMap) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, key := range m.keys {
+		var valueNode yaml.Node
+		if err := valueNode.Encode(m.values[key]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+			&valueNode)
+	}
+	return node, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler, retaining the order of the keys. Non-string
+// keys are converted to strings, like NormalizeYAMLMaps does.
+func (m *OrderedMap) UnmarshalYAML(node *yaml.Node) error {
+	value, err := decodeYAMLNode(node)
+	if err != nil {
+		return err
+	}
+	object, ok := value.(*OrderedMap)
+	if !ok {
+		return errors.New("expected the data to be an Object")
+	}
+	*m = *object
+	return nil
+}
+
+// decodeYAMLNode decodes a YAML node, mappings are returned as *OrderedMap.
+func decodeYAMLNode(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return decodeYAMLNode(node.Content[0])
+
+	case yaml.AliasNode:
+		return decodeYAMLNode(node.Alias)
+
+	case yaml.MappingNode:
+		object := NewOrderedMap()
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			var key interface{}
+			if err := node.Content[i].Decode(&key); err != nil {
+				return nil, err
+			}
+			value, err := decodeYAMLNode(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			object.Set(fmt.Sprintf("%v", key), value)
+		}
+		return object, nil
+
+	case yaml.SequenceNode:
+		array := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := decodeYAMLNode(item)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+	}
+
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
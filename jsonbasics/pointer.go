@@ -0,0 +1,155 @@
+package jsonbasics
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParsePointer parses an RFC 6901 JSONPointer (eg. "/services/0/name") into a path of
+// object keys and array indices, as used by GetByPath and SetByPath. The escapes "~1" and
+// "~0" are decoded into "/" and "~". The empty pointer "" refers to the whole document, and
+// returns an empty path.
+func ParsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("expected JSONPointer '%s' to start with '/'", pointer)
+	}
+
+	path := strings.Split(pointer[1:], "/")
+	for i, token := range path {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j == len(token)-1 || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, fmt.Errorf("invalid escape in JSONPointer '%s', expected '~0' or '~1'", pointer)
+			}
+		}
+		path[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return path, nil
+}
+
+// parseIndex returns the array index for a path element. The element "-" (the position
+// after the last entry) returns the length of the array. Leading zeroes are not allowed.
+func parseIndex(arr []interface{}, key string) (int, error) {
+	if key == "-" {
+		return len(arr), nil
+	}
+	index, err := strconv.Atoi(key)
+	if err != nil || index < 0 || (len(key) > 1 && key[0] == '0') || key[0] == '+' {
+		return 0, fmt.Errorf("expected '%s' to be an array index", key)
+	}
+	return index, nil
+}
+
+// GetByPath returns the value at 'path' (a list of object keys and array indices, from the
+// root down). An empty path returns the data itself. Returns an error if the value does not
+// exist, or if an intermediate node is a scalar.
+func GetByPath(data interface{}, path []string) (interface{}, error) {
+	node := data
+	for i, key := range path {
+		location := strings.Join(path[:i+1], ".")
+		switch current := node.(type) {
+		case map[string]interface{}:
+			value, found := current[key]
+			if !found {
+				return nil, fmt.Errorf("'%s' not found", location)
+			}
+			node = value
+		case []interface{}:
+			index, err := parseIndex(current, key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get '%s'; %w", location, err)
+			}
+			if index >= len(current) {
+				return nil, fmt.Errorf("'%s' not found, the array has %d entries", location, len(current))
+			}
+			node = current[index]
+		default:
+			return nil, fmt.Errorf("expected '%s' to be an object or array", strings.Join(path[:i], "."))
+		}
+	}
+	return node, nil
+}
+
+// setByPath sets the value in 'node', and returns the updated node (an array can grow).
+func setByPath(node interface{}, path []string, depth int, value interface{}) (interface{}, error) {
+	key := path[depth]
+	location := strings.Join(path[:depth+1], ".")
+	last := depth == len(path)-1
+
+	switch current := node.(type) {
+	case map[string]interface{}:
+		if last {
+			current[key] = value
+			return current, nil
+		}
+		child, found := current[key]
+		if !found {
+			return nil, fmt.Errorf("'%s' not found", location)
+		}
+		updated, err := setByPath(child, path, depth+1, value)
+		if err != nil {
+			return nil, err
+		}
+		current[key] = updated
+		return current, nil
+
+	case []interface{}:
+		index, err := parseIndex(current, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set '%s'; %w", location, err)
+		}
+		if last && index == len(current) {
+			return append(current, value), nil
+		}
+		if index >= len(current) {
+			return nil, fmt.Errorf("'%s' not found, the array has %d entries", location, len(current))
+		}
+		if last {
+			current[index] = value
+			return current, nil
+		}
+		updated, err := setByPath(current[index], path, depth+1, value)
+		if err != nil {
+			return nil, err
+		}
+		current[index] = updated
+		return current, nil
+	}
+	return nil, fmt.Errorf("expected '%s' to be an object or array", strings.Join(path[:depth], "."))
+}
+
+// SetByPath sets the value at 'path' (a list of object keys and array indices, from the root
+// down), in place. An object field is added or replaced, an array entry is replaced, and an
+// index equal to the length of the array (or "-") appends. Returns an error if the path is
+// empty, or if an intermediate node does not exist, or is a scalar.
+func SetByPath(data map[string]interface{}, path []string, value interface{}) error {
+	if len(path) == 0 {
+		return errors.New("expected 'path' to have at least 1 element")
+	}
+	_, err := setByPath(data, path, 0, value)
+	return err
+}
+
+// GetByPointer is the same as GetByPath, with the path given as an RFC 6901 JSONPointer (see
+// ParsePointer).
+func GetByPointer(data interface{}, pointer string) (interface{}, error) {
+	path, err := ParsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	return GetByPath(data, path)
+}
+
+// SetByPointer is the same as SetByPath, with the path given as an RFC 6901 JSONPointer (see
+// ParsePointer). The pointer "/services/-" appends to the 'services' array.
+func SetByPointer(data map[string]interface{}, pointer string, value interface{}) error {
+	path, err := ParsePointer(pointer)
+	if err != nil {
+		return err
+	}
+	return SetByPath(data, path, value)
+}
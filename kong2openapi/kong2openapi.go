@@ -0,0 +1,284 @@
+package kong2openapi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kong/go-apiops/jsonbasics"
+	"github.com/kong/go-apiops/logbasics"
+)
+
+// This is a best-effort, lossy, conversion of a decK file into an OpenAPI 3 skeleton. It is
+// meant for documentation purposes. Limitations:
+//
+//   - only routes nested in services are converted, top-level routes are ignored
+//   - regex paths are only converted if the regex parts are named captures, which become
+//     path parameters (eg. "~/users/(?<id>[^/]+)$" becomes "/users/{id}")
+//   - routes without 'methods' generate an operation for every HTTP method, and routes
+//     without 'paths' are converted as path "/"
+//   - hosts, headers, snis, etc. of routes are not represented
+//   - the operations have no request bodies, and a single 'default' response
+//   - plugins are added as "x-kong-plugin-<name>" extensions, with their 'id' and
+//     foreign keys removed. With a single service, its plugins are set at the document
+//     level, with multiple services they are set on every path of the service.
+
+// allMethods are the methods used for routes that do not specify any.
+var allMethods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+// namedCaptureRegex matches a named regex capture in a Kong path, eg. "(?<id>[^/]+)"
+var namedCaptureRegex = regexp.MustCompile(`\(\?<([a-zA-Z_][a-zA-Z0-9_]*)>[^()]*\)`)
+
+// regexCharsRegex matches any regex characters remaining after converting path parameters.
+var regexCharsRegex = regexp.MustCompile(`[\\^$.|?*+()\[\]]`)
+
+// K2oOptions defines the options for a K2O conversion operation
+type K2oOptions struct {
+	Title   string // Title of the generated spec, defaults to the service name, if only 1 service
+	Version string // Version of the generated spec, defaults to "1.0.0"
+}
+
+// convertPath converts a Kong route path into an OpenAPI path, and returns the names of the
+// path parameters. Returns an empty path if it cannot be converted.
+func convertPath(kongPath string) (string, []string) {
+	if !strings.HasPrefix(kongPath, "~") {
+		return kongPath, nil
+	}
+
+	path := strings.TrimPrefix(kongPath, "~")
+	path = strings.TrimSuffix(path, "$")
+	path = strings.TrimSuffix(path, "/?") // optional trailing slash as generated by openapi2kong
+	params := make([]string, 0)
+	path = namedCaptureRegex.ReplaceAllStringFunc(path, func(capture string) string {
+		name := namedCaptureRegex.FindStringSubmatch(capture)[1]
+		params = append(params, name)
+		return "{" + name + "}"
+	})
+	if regexCharsRegex.MatchString(path) {
+		return "", nil
+	}
+	return path, params
+}
+
+// getServer returns the OpenAPI server url for a service.
+func getServer(service map[string]interface{}) (string, error) {
+	if url, err := jsonbasics.GetStringField(service, "url"); err == nil {
+		return url, nil
+	}
+
+	host, err := jsonbasics.GetStringField(service, "host")
+	if err != nil {
+		return "", fmt.Errorf("expected service to have a 'url' or 'host'")
+	}
+	protocol, err := jsonbasics.GetStringField(service, "protocol")
+	if err != nil {
+		protocol = "http"
+	}
+	server := protocol + "://" + host
+	if port, ok := service["port"].(float64); ok {
+		server = fmt.Sprintf("%s:%d", server, int(port))
+	}
+	if path, err := jsonbasics.GetStringField(service, "path"); err == nil {
+		server = server + path
+	}
+	return server, nil
+}
+
+// getPluginExtensions returns the plugins of an entity as "x-kong-plugin-<name>" extensions.
+func getPluginExtensions(entity map[string]interface{}) (map[string]interface{}, error) {
+	plugins, err := jsonbasics.GetObjectArrayField(entity, "plugins")
+	if err != nil {
+		return nil, fmt.Errorf("expected 'plugins' to be an array; %w", err)
+	}
+
+	extensions := make(map[string]interface{})
+	for _, plugin := range plugins {
+		name, err := jsonbasics.GetStringField(plugin, "name")
+		if err != nil {
+			return nil, fmt.Errorf("expected plugin to have a 'name'")
+		}
+		config := *jsonbasics.DeepCopyObject(&plugin)
+		for _, key := range []string{"name", "id", "service", "route", "consumer"} {
+			delete(config, key)
+		}
+		extensions["x-kong-plugin-"+name] = config
+	}
+	return extensions, nil
+}
+
+// createOperation returns the operation for a route.
+func createOperation(
+	route map[string]interface{},
+	operationID string,
+	params []string,
+) (map[string]interface{}, error) {
+	operation, err := getPluginExtensions(route)
+	if err != nil {
+		return nil, err
+	}
+	operation["operationId"] = operationID
+	operation["responses"] = map[string]interface{}{
+		"default": map[string]interface{}{
+			"description": "default response",
+		},
+	}
+	if len(params) > 0 {
+		parameters := make([]interface{}, len(params))
+		for i, param := range params {
+			parameters[i] = map[string]interface{}{
+				"name":     param,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			}
+		}
+		operation["parameters"] = parameters
+	}
+	if tags, err := jsonbasics.GetStringArrayField(route, "tags"); err == nil && len(tags) > 0 {
+		operation["x-kong-tags"] = tags
+	}
+	return operation, nil
+}
+
+// getMethods returns the (lowercase) methods of a route, all methods if none are specified.
+func getMethods(route map[string]interface{}) ([]string, error) {
+	methods, err := jsonbasics.GetStringArrayField(route, "methods")
+	if err != nil {
+		return nil, fmt.Errorf("expected 'methods' to be an array of strings; %w", err)
+	}
+	if len(methods) == 0 {
+		return allMethods, nil
+	}
+	for i, method := range methods {
+		methods[i] = strings.ToLower(method)
+	}
+	return methods, nil
+}
+
+// Convert converts a decK file into an OpenAPI 3 skeleton. See the package documentation
+// for the limitations.
+func Convert(deckfile map[string]interface{}, opts K2oOptions) (map[string]interface{}, error) {
+	if deckfile == nil {
+		return nil, fmt.Errorf("expected a non-nil deck file")
+	}
+
+	services, err := jsonbasics.GetObjectArrayField(deckfile, "services")
+	if err != nil {
+		return nil, fmt.Errorf("expected 'services' to be an array; %w", err)
+	}
+
+	info := map[string]interface{}{
+		"title":   opts.Title,
+		"version": opts.Version,
+	}
+	if opts.Version == "" {
+		info["version"] = "1.0.0"
+	}
+	spec := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    info,
+	}
+	paths := make(map[string]interface{})
+
+	singleService := len(services) == 1
+	for i, service := range services {
+		serviceName, _ := jsonbasics.GetStringField(service, "name")
+		if serviceName == "" {
+			serviceName = fmt.Sprintf("service-%d", i)
+		}
+		logbasics.Info("processing service", "service", serviceName)
+
+		server, err := getServer(service)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert service '%s'; %w", serviceName, err)
+		}
+		servers := []interface{}{map[string]interface{}{"url": server}}
+		servicePlugins, err := getPluginExtensions(service)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert service '%s'; %w", serviceName, err)
+		}
+		if singleService {
+			spec["servers"] = servers
+			spec["x-kong-name"] = serviceName
+			for name, plugin := range servicePlugins {
+				spec[name] = plugin
+			}
+			if info["title"] == "" {
+				info["title"] = serviceName
+			}
+		}
+
+		routes, err := jsonbasics.GetObjectArrayField(service, "routes")
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert service '%s'; expected 'routes' to be an array; %w",
+				serviceName, err)
+		}
+		for j, route := range routes {
+			routeName, _ := jsonbasics.GetStringField(route, "name")
+			if routeName == "" {
+				routeName = fmt.Sprintf("%s-route-%d", serviceName, j)
+			}
+
+			methods, err := getMethods(route)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert route '%s'; %w", routeName, err)
+			}
+			kongPaths, err := jsonbasics.GetStringArrayField(route, "paths")
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert route '%s'; expected 'paths' to be an array of strings; %w",
+					routeName, err)
+			}
+
+			if len(kongPaths) == 0 {
+				kongPaths = []string{"/"} // Kong matches all paths
+			}
+
+			for k, kongPath := range kongPaths {
+				path, params := convertPath(kongPath)
+				if path == "" {
+					logbasics.Warn("skipping route path, it cannot be converted", "route", routeName, "path", kongPath)
+					continue
+				}
+
+				pathItem, found := paths[path].(map[string]interface{})
+				if !found {
+					pathItem = make(map[string]interface{})
+					if !singleService {
+						pathItem["servers"] = servers
+						for name, plugin := range servicePlugins {
+							pathItem[name] = plugin
+						}
+					}
+					paths[path] = pathItem
+				}
+
+				for _, method := range methods {
+					if pathItem[method] != nil {
+						logbasics.Warn("skipping route, the operation already exists", "route", routeName,
+							"path", path, "method", method)
+						continue
+					}
+					// operation ids must be unique
+					operationID := routeName
+					if len(kongPaths) > 1 {
+						operationID = fmt.Sprintf("%s_%d", operationID, k)
+					}
+					if len(methods) > 1 {
+						operationID = operationID + "_" + method
+					}
+					operation, err := createOperation(route, operationID, params)
+					if err != nil {
+						return nil, fmt.Errorf("failed to convert route '%s'; %w", routeName, err)
+					}
+					pathItem[method] = operation
+				}
+			}
+		}
+	}
+
+	if info["title"] == "" {
+		info["title"] = "Kong"
+	}
+	spec["paths"] = paths
+	return spec, nil
+}
@@ -0,0 +1,13 @@
+package kong2openapi_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestKong2openapi(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Kong2OpenAPI Suite")
+}
@@ -0,0 +1,133 @@
+package kong2openapi_test
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	. "github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/kong2openapi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("kong2openapi", func() {
+	Describe("Convert", func() {
+		It("converts a service with routes into a spec skeleton", func() {
+			deckfile := MustDeserializeFile("./kong2openapi_testfiles/deck.yaml")
+			spec, err := kong2openapi.Convert(deckfile, kong2openapi.K2oOptions{})
+			Expect(err).To(BeNil())
+
+			result := MustSerialize(spec, OutputFormatJSON)
+			Expect(*result).To(MatchJSON(`{
+				"openapi": "3.0.3",
+				"info": {
+					"title": "users-api",
+					"version": "1.0.0"
+				},
+				"servers": [
+					{ "url": "https://users.example.com:443/v1" }
+				],
+				"x-kong-name": "users-api",
+				"x-kong-plugin-rate-limiting": {
+					"config": { "minute": 10 }
+				},
+				"paths": {
+					"/users": {
+						"get": {
+							"operationId": "list-users",
+							"x-kong-tags": [ "users" ],
+							"responses": {
+								"default": { "description": "default response" }
+							}
+						}
+					},
+					"/users/{id}": {
+						"get": {
+							"operationId": "get-user_get",
+							"parameters": [
+								{ "name": "id", "in": "path", "required": true, "schema": { "type": "string" } }
+							],
+							"x-kong-plugin-key-auth": {
+								"config": { "key_names": [ "apikey" ] }
+							},
+							"responses": {
+								"default": { "description": "default response" }
+							}
+						},
+						"delete": {
+							"operationId": "get-user_delete",
+							"parameters": [
+								{ "name": "id", "in": "path", "required": true, "schema": { "type": "string" } }
+							],
+							"x-kong-plugin-key-auth": {
+								"config": { "key_names": [ "apikey" ] }
+							},
+							"responses": {
+								"default": { "description": "default response" }
+							}
+						}
+					}
+				}
+			}`))
+
+			// the result must be a valid spec
+			doc, err := openapi3.NewLoader().LoadFromData(*result)
+			Expect(err).To(BeNil())
+			Expect(doc.Validate(context.Background())).To(Succeed())
+		})
+
+		It("sets the servers on the paths with multiple services", func() {
+			deckfile := map[string]interface{}{
+				"services": []interface{}{
+					map[string]interface{}{
+						"name": "one",
+						"url":  "http://one.example.com",
+						"routes": []interface{}{
+							map[string]interface{}{"name": "r1", "paths": []interface{}{"/one"}, "methods": []interface{}{"GET"}},
+						},
+					},
+					map[string]interface{}{
+						"name": "two",
+						"url":  "http://two.example.com",
+						"routes": []interface{}{
+							map[string]interface{}{"name": "r2", "paths": []interface{}{"/two"}, "methods": []interface{}{"GET"}},
+						},
+					},
+				},
+			}
+			spec, err := kong2openapi.Convert(deckfile, kong2openapi.K2oOptions{Title: "my api", Version: "2.0"})
+			Expect(err).To(BeNil())
+			Expect(spec).ToNot(HaveKey("servers"))
+			Expect(spec["info"]).To(Equal(map[string]interface{}{"title": "my api", "version": "2.0"}))
+
+			encoded, _ := json.Marshal(spec["paths"])
+			Expect(encoded).To(MatchJSON(`{
+				"/one": {
+					"servers": [ { "url": "http://one.example.com" } ],
+					"get": { "operationId": "r1", "responses": { "default": { "description": "default response" } } }
+				},
+				"/two": {
+					"servers": [ { "url": "http://two.example.com" } ],
+					"get": { "operationId": "r2", "responses": { "default": { "description": "default response" } } }
+				}
+			}`))
+		})
+
+		It("skips paths that cannot be converted", func() {
+			deckfile := map[string]interface{}{
+				"services": []interface{}{
+					map[string]interface{}{
+						"host": "example.com",
+						"routes": []interface{}{
+							map[string]interface{}{"name": "r1", "paths": []interface{}{"~/files/.*$"}},
+						},
+					},
+				},
+			}
+			spec, err := kong2openapi.Convert(deckfile, kong2openapi.K2oOptions{})
+			Expect(err).To(BeNil())
+			Expect(spec["paths"]).To(BeEmpty())
+		})
+	})
+})
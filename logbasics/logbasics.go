@@ -4,7 +4,7 @@
 //
 // General behaviour;
 // * Errors will not be logged, but returned instead. Logging those is up to the caller.
-// * The library does not use verbosity level 0
+// * level 0 is used for warnings (when calling `Warn`), such that they are always logged
 // * level 1 is used for informational messages (when calling `Info`)
 // * level 2 is used for debug messages (when calling `Debug`)
 package logbasics
@@ -12,6 +12,7 @@ package logbasics
 import (
 	"log"
 	"os"
+	"sync/atomic"
 
 	"github.com/go-logr/logr"
 	"github.com/go-logr/stdr"
@@ -20,6 +21,7 @@ import (
 var (
 	globalLogger  logr.Logger
 	defaultLogger *logr.Logger
+	warningCount  int64 // the number of warnings logged, see WarningCount
 )
 
 // Info logs an informational message ("info" at verbosity level 1).
@@ -27,6 +29,19 @@ func Info(msg string, keysAndValues ...interface{}) {
 	globalLogger.V(1).Info(msg, keysAndValues...)
 }
 
+// Warn logs a warning message ("info" at verbosity level 0, with a "WARNING: " prefix).
+// Warnings are logged regardless of the verbosity.
+func Warn(msg string, keysAndValues ...interface{}) {
+	atomic.AddInt64(&warningCount, 1)
+	globalLogger.V(0).Info("WARNING: "+msg, keysAndValues...)
+}
+
+// WarningCount returns the number of warnings passed to Warn. Take the difference of 2 calls
+// to count the warnings of an operation.
+func WarningCount() int {
+	return int(atomic.LoadInt64(&warningCount))
+}
+
 // Debug logs a debug message ("info" at verbosity level 2).
 func Debug(msg string, keysAndValues ...interface{}) {
 	globalLogger.V(2).Info(msg, keysAndValues...)
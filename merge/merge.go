@@ -2,13 +2,37 @@ package merge
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/kong/go-apiops/deckformat"
 	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/jsonbasics"
 	"github.com/kong/go-apiops/logbasics"
 )
 
-func merge2Files(data1 map[string]interface{}, data2 map[string]interface{}) map[string]interface{} {
+const (
+	StrategyError          = "error"           // conflicting entities fail the merge
+	StrategyLastWins       = "last-wins"       // the entity from the later file replaces the earlier one
+	StrategyFirstWins      = "first-wins"      // the entity from the earlier file is kept
+	StrategySkipDuplicates = "skip-duplicates" // conflicting entities are dropped altogether
+)
+
+// ValidateStrategy returns an error if the merge strategy is unknown.
+func ValidateStrategy(strategy string) error {
+	switch strategy {
+	case StrategyError, StrategyLastWins, StrategyFirstWins, StrategySkipDuplicates:
+		return nil
+	}
+	return fmt.Errorf("expected merge strategy to be one of '%s', '%s', '%s', or '%s', got: '%s'",
+		StrategyError, StrategyLastWins, StrategyFirstWins, StrategySkipDuplicates, strategy)
+}
+
+// merge2Files merges data2 into data1. Top-level arrays are concatenated, or if a strategy
+// is given, the entities in the non-meta arrays are merged by name (see mergeEntities).
+func merge2Files(data1 map[string]interface{}, data2 map[string]interface{}, strategy string,
+	skipped map[string]bool,
+) (map[string]interface{}, error) {
 	mergedData := make(map[string]interface{})
 
 	for key, value := range data1 {
@@ -21,8 +45,16 @@ func merge2Files(data1 map[string]interface{}, data2 map[string]interface{}) map
 			if a, ok := existingValue.([]interface{}); ok {
 				// we currently have an array
 				if b, ok := value.([]interface{}); ok {
-					// the new value also is an array, so append it
-					mergedData[key] = append(a, b...)
+					if strategy == "" || strings.HasPrefix(key, "_") {
+						// the new value also is an array, so append it
+						mergedData[key] = append(a, b...)
+					} else {
+						merged, err := mergeEntities(key, a, b, strategy, skipped)
+						if err != nil {
+							return nil, err
+						}
+						mergedData[key] = merged
+					}
 				} else {
 					// the new value is not an array, overwrite the existing array
 					mergedData[key] = value
@@ -37,7 +69,7 @@ func merge2Files(data1 map[string]interface{}, data2 map[string]interface{}) map
 		}
 	}
 
-	return mergedData
+	return mergedData, nil
 }
 
 // MustFiles is identical to `Files` except that it will panic instead of returning
@@ -56,9 +88,108 @@ func MustFiles(filenames []string) (result map[string]interface{}, history []int
 // in order provided. An error will be returned if files are incompatible.
 // There are no checks on duplicates, etc... garbage-in-garbage-out.
 func Files(filenames []string) (result map[string]interface{}, history []interface{}, err error) {
+	return files(filenames, "", false)
+}
+
+// FilesWithStrategy is identical to `Files`, except that the entities in the top-level
+// arrays are merged by their 'name' field. Identical entities are only included once,
+// differing ones are resolved by the strategy (eg. StrategyError).
+func FilesWithStrategy(filenames []string, strategy string,
+) (result map[string]interface{}, history []interface{}, err error) {
+	if err := ValidateStrategy(strategy); err != nil {
+		return nil, nil, err
+	}
+	return files(filenames, strategy, false)
+}
+
+// Options are the options for FilesWithOptions.
+type Options struct {
+	// Strategy to merge the entities in the top-level arrays by name (see FilesWithStrategy).
+	// If "", the arrays are concatenated (see Files).
+	Strategy string
+	// ReconcileVersion upgrades the '_format_version' of all files to the highest one, before
+	// merging. Only minor upgrades are done, differing major versions still fail the merge.
+	// The upgrades are recorded in the history, as 'upgraded_from' of the file.
+	ReconcileVersion bool
+}
+
+// FilesWithOptions is identical to `Files`, with the merge behaviour set by the options.
+func FilesWithOptions(filenames []string, opts Options,
+) (result map[string]interface{}, history []interface{}, err error) {
+	if opts.Strategy != "" {
+		if err := ValidateStrategy(opts.Strategy); err != nil {
+			return nil, nil, err
+		}
+	}
+	return files(filenames, opts.Strategy, opts.ReconcileVersion)
+}
+
+// highestVersion returns the highest '_format_version' of the files, or "" if none has one.
+func highestVersion(datas []map[string]interface{}) (string, error) {
+	highest := ""
+	highestMajor, highestMinor := 0, 0
+	for _, data := range datas {
+		if data[deckformat.ConfigGet().VersionKey] == nil {
+			continue
+		}
+		major, minor, err := deckformat.ParseFormatVersion(data)
+		if err != nil {
+			return "", err
+		}
+		if highest == "" || major > highestMajor || (major == highestMajor && minor > highestMinor) {
+			highest = fmt.Sprint(major, ".", minor)
+			highestMajor, highestMinor = major, minor
+		}
+	}
+	return highest, nil
+}
+
+// reconcileVersion upgrades the '_format_version' of the file to the given version. Returns
+// the original version if it was upgraded, or "" if not. Returns an error if it requires a
+// major upgrade.
+func reconcileVersion(data map[string]interface{}, version string) (string, error) {
+	if data[deckformat.ConfigGet().VersionKey] == nil {
+		return "", nil // no version, so compatible with any version
+	}
+	major, minor, _ := deckformat.ParseFormatVersion(data) // validated by highestVersion
+	from := fmt.Sprint(major, ".", minor)
+	upgrade, err := deckformat.VersionUpgradePath(from, version)
+	if err != nil {
+		return "", err
+	}
+	if upgrade.MajorBump {
+		return "", fmt.Errorf("%w; cannot reconcile the versions, %s", deckformat.ErrIncompatible, upgrade.Description())
+	}
+	if upgrade.FromMinor == upgrade.ToMinor {
+		return "", nil
+	}
+	logbasics.Info("reconciling version", "description", upgrade.Description())
+	if err := deckformat.SetFormatVersion(data, version); err != nil {
+		return "", err
+	}
+	return from, nil
+}
+
+func files(filenames []string, strategy string, reconcile bool,
+) (result map[string]interface{}, history []interface{}, err error) {
 	if len(filenames) == 0 {
 		panic("no filenames provided")
 	}
+	skipped := make(map[string]bool)
+
+	// read all files, such that the versions can be reconciled upfront
+	datas := make([]map[string]interface{}, len(filenames))
+	for i, filename := range filenames {
+		if datas[i], err = filebasics.DeserializeFile(filename); err != nil {
+			return nil, nil, err
+		}
+	}
+	reconcileTo := ""
+	if reconcile {
+		if reconcileTo, err = highestVersion(datas); err != nil {
+			return nil, nil, err
+		}
+	}
 
 	historyArray := make([]interface{}, len(filenames))
 	minorVersion := 0
@@ -66,12 +197,7 @@ func Files(filenames []string) (result map[string]interface{}, history []interfa
 	// traverse all files
 	for i, filename := range filenames {
 		logbasics.Info("merging file", "filename", filename)
-
-		// read the file
-		data, err := filebasics.DeserializeFile(filename)
-		if err != nil {
-			return nil, nil, err
-		}
+		data := datas[i]
 
 		newInfo := make(map[string]interface{})
 		newInfo["filename"] = filename
@@ -81,16 +207,26 @@ func Files(filenames []string) (result map[string]interface{}, history []interfa
 		}
 		historyArray[i] = newInfo
 
+		if reconcileTo != "" {
+			from, err := reconcileVersion(data, reconcileTo)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to merge %s: %w", filename, err)
+			}
+			if from != "" {
+				newInfo["upgraded_from"] = from
+			}
+		}
+
 		if result == nil {
 			// set up initial map, ensure it is "compatible" with first entry
 			result = make(map[string]interface{})
-			if data[deckformat.TransformKey] != nil {
-				logbasics.Debug("setting transform meta-field", "value", data[deckformat.TransformKey])
-				result[deckformat.TransformKey] = data[deckformat.TransformKey]
+			if data[deckformat.ConfigGet().TransformKey] != nil {
+				logbasics.Debug("setting transform meta-field", "value", data[deckformat.ConfigGet().TransformKey])
+				result[deckformat.ConfigGet().TransformKey] = data[deckformat.ConfigGet().TransformKey]
 			}
-			if data[deckformat.VersionKey] != nil {
-				logbasics.Debug("setting version meta-field", "value", data[deckformat.VersionKey])
-				result[deckformat.VersionKey] = data[deckformat.VersionKey]
+			if data[deckformat.ConfigGet().VersionKey] != nil {
+				logbasics.Debug("setting version meta-field", "value", data[deckformat.ConfigGet().VersionKey])
+				result[deckformat.ConfigGet().VersionKey] = data[deckformat.ConfigGet().VersionKey]
 			}
 		}
 
@@ -108,18 +244,138 @@ func Files(filenames []string) (result map[string]interface{}, history []interfa
 			minorVersion = m
 		}
 
-		result = merge2Files(result, data)
+		if result, err = merge2Files(result, data, strategy, skipped); err != nil {
+			return nil, nil, fmt.Errorf("failed to merge %s: %w", filename, err)
+		}
 	}
 
 	// set final resulting format version
-	if result[deckformat.VersionKey] != nil {
+	if result[deckformat.ConfigGet().VersionKey] != nil {
 		ma, _, _ := deckformat.ParseFormatVersion(result)
 		if ma == 0 {
-			delete(result, deckformat.VersionKey)
+			delete(result, deckformat.ConfigGet().VersionKey)
 		} else {
-			result[deckformat.VersionKey] = fmt.Sprint(ma, ".", minorVersion)
+			result[deckformat.ConfigGet().VersionKey] = fmt.Sprint(ma, ".", minorVersion)
 		}
 	}
 
 	return result, historyArray, nil
 }
+
+// entityName returns the name of an entity, or "" if it is not an object with a name.
+func entityName(entity interface{}) string {
+	obj, err := jsonbasics.ToObject(entity)
+	if err != nil {
+		return ""
+	}
+	name, _ := jsonbasics.GetStringField(obj, "name")
+	return name
+}
+
+// mergeEntities merges the entities in 'newEntities' into 'entities'. Entities are identified
+// by their 'name' field. Identical entities are only included once, differing ones are resolved
+// by the strategy. 'skipped' tracks the names dropped by StrategySkipDuplicates, such that
+// later entities by that name are dropped as well.
+func mergeEntities(key string, entities []interface{}, newEntities []interface{}, strategy string,
+	skipped map[string]bool,
+) ([]interface{}, error) {
+	result := append(make([]interface{}, 0, len(entities)+len(newEntities)), entities...)
+
+	for _, newEntity := range newEntities {
+		name := entityName(newEntity)
+		if name != "" && skipped[key+"."+name] {
+			logbasics.Debug("skipping entity with conflicting definitions", "type", key, "name", name)
+			continue
+		}
+
+		found := false
+		if name != "" {
+			for i, entity := range result {
+				if entityName(entity) != name {
+					continue
+				}
+				found = true
+				if jsonbasics.EqualJSON(entity, newEntity) {
+					logbasics.Debug("skipping duplicate entity", "type", key, "name", name)
+					break
+				}
+				switch strategy {
+				case StrategyLastWins:
+					logbasics.Info("overwriting entity", "type", key, "name", name)
+					result[i] = newEntity
+				case StrategyFirstWins:
+					logbasics.Info("keeping the first entity", "type", key, "name", name)
+				case StrategySkipDuplicates:
+					logbasics.Warn("skipping entity with conflicting definitions", "type", key, "name", name)
+					skipped[key+"."+name] = true
+					result = append(result[:i], result[i+1:]...)
+				default:
+					return nil, fmt.Errorf("conflicting entities in '%s' with name '%s'", key, name)
+				}
+				break
+			}
+		}
+		if !found {
+			result = append(result, newEntity)
+		}
+	}
+	return result, nil
+}
+
+// Into merges the entities of 'data' into 'target', and returns the merged result. Neither
+// input is modified. Unlike `Files`, entities in top-level arrays are deduplicated by
+// their 'name' field. If an entity with the same name exists, but with a different
+// definition, an error is returned, unless 'overwrite' is set, in which case the entity
+// from 'data' replaces the existing one. The same goes for any other top-level
+// non-meta field. The history of 'data' is not copied.
+func Into(target map[string]interface{}, data map[string]interface{}, overwrite bool,
+) (map[string]interface{}, error) {
+	if err := deckformat.CompatibleFile(target, data); err != nil {
+		return nil, err
+	}
+
+	// copy to have pure JSON types, so the comparisons work
+	result := *jsonbasics.DeepCopyObject(&target)
+	data = *jsonbasics.DeepCopyObject(&data)
+
+	for key, value := range data {
+		existingValue, found := result[key]
+		switch {
+		case key == deckformat.ConfigGet().HistoryKey:
+			continue
+
+		case key == deckformat.ConfigGet().VersionKey && found:
+			// the majors are equal (we're compatible) so take the highest minor
+			_, minor1, _ := deckformat.ParseFormatVersion(result)
+			major2, minor2, _ := deckformat.ParseFormatVersion(data)
+			if minor2 > minor1 {
+				result[key] = fmt.Sprint(major2, ".", minor2)
+			}
+
+		case !found:
+			result[key] = value
+
+		default:
+			existingArray, err1 := jsonbasics.ToArray(existingValue)
+			newArray, err2 := jsonbasics.ToArray(value)
+			if err1 == nil && err2 == nil {
+				strategy := StrategyError
+				if overwrite {
+					strategy = StrategyLastWins
+				}
+				merged, err := mergeEntities(key, existingArray, newArray, strategy, make(map[string]bool))
+				if err != nil {
+					return nil, err
+				}
+				result[key] = merged
+			} else if !reflect.DeepEqual(existingValue, value) {
+				if !overwrite {
+					return nil, fmt.Errorf("conflicting values for key '%s'", key)
+				}
+				result[key] = value
+			}
+		}
+	}
+
+	return result, nil
+}
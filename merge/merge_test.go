@@ -118,6 +118,162 @@ var _ = Describe("Merge", func() {
 		})
 	})
 
+	Describe("into an existing file", func() {
+		It("adds generated entities to the existing ones", func() {
+			existing := MustDeserializeFile("./merge_testfiles/into_existing.yml")
+			converted := MustDeserializeFile("./merge_testfiles/into_converted.yml")
+
+			res, err := merge.Into(existing, converted, false)
+			Expect(err).To(BeNil())
+			MustWriteSerializedFile("./merge_testfiles/into_generated.json", res, OutputFormatJSON)
+
+			result := MustSerialize(res, OutputFormatJSON)
+			expected := MustReadFile("./merge_testfiles/into_expected.json")
+			Expect(*result).To(MatchJSON(*expected))
+		})
+
+		It("includes identical entities only once", func() {
+			existing := MustDeserializeFile("./merge_testfiles/into_existing.yml")
+
+			res, err := merge.Into(existing, existing, false)
+			Expect(err).To(BeNil())
+			Expect(res).To(BeEquivalentTo(existing))
+		})
+
+		It("errors on conflicting entities", func() {
+			existing := MustDeserializeFile("./merge_testfiles/into_existing.yml")
+			conflict := MustDeserializeFile("./merge_testfiles/into_conflict.yml")
+
+			res, err := merge.Into(existing, conflict, false)
+			Expect(err).To(MatchError("conflicting entities in 'services' with name 'hand-maintained-service'"))
+			Expect(res).To(BeNil())
+		})
+
+		It("overwrites conflicting entities if requested", func() {
+			existing := MustDeserializeFile("./merge_testfiles/into_existing.yml")
+			conflict := MustDeserializeFile("./merge_testfiles/into_conflict.yml")
+
+			res, err := merge.Into(existing, conflict, true)
+			Expect(err).To(BeNil())
+			Expect(res["services"]).To(BeEquivalentTo(conflict["services"]))
+		})
+
+		It("errors on incompatible files", func() {
+			existing := MustDeserializeFile("./merge_testfiles/into_existing.yml")
+			badVersion := MustDeserializeFile("./merge_testfiles/badversion.yml")
+
+			_, err := merge.Into(existing, badVersion, false)
+			Expect(err).To(MatchError("files are incompatible; major versions are incompatible; 3.0 and 1.0"))
+		})
+	})
+
+	Describe("FilesWithStrategy", func() {
+		fileList := []string{
+			"./merge_testfiles/strategy1.yml",
+			"./merge_testfiles/strategy2.yml",
+		}
+		serviceURLs := func(res map[string]interface{}) map[string]string {
+			urls := make(map[string]string)
+			for _, s := range res["services"].([]interface{}) {
+				service := s.(map[string]interface{})
+				urls[service["name"].(string)] = service["url"].(string)
+			}
+			Expect(res["services"]).To(HaveLen(len(urls)), "expected no duplicate services")
+			return urls
+		}
+
+		It("fails on conflicting entities with 'error'", func() {
+			_, _, err := merge.FilesWithStrategy(fileList, merge.StrategyError)
+			Expect(err).To(MatchError("failed to merge ./merge_testfiles/strategy2.yml: " +
+				"conflicting entities in 'services' with name 'shared-service'"))
+		})
+
+		It("uses the later entity with 'last-wins'", func() {
+			res, _, err := merge.FilesWithStrategy(fileList, merge.StrategyLastWins)
+			Expect(err).To(BeNil())
+			Expect(serviceURLs(res)).To(Equal(map[string]string{
+				"shared-service":    "http://second.example.com",
+				"identical-service": "http://example.com",
+				"first-service":     "http://example.com",
+				"second-service":    "http://example.com",
+			}))
+		})
+
+		It("keeps the earlier entity with 'first-wins'", func() {
+			res, _, err := merge.FilesWithStrategy(fileList, merge.StrategyFirstWins)
+			Expect(err).To(BeNil())
+			Expect(serviceURLs(res)).To(Equal(map[string]string{
+				"shared-service":    "http://first.example.com",
+				"identical-service": "http://example.com",
+				"first-service":     "http://example.com",
+				"second-service":    "http://example.com",
+			}))
+		})
+
+		It("drops the conflicting entities with 'skip-duplicates'", func() {
+			res, _, err := merge.FilesWithStrategy(fileList, merge.StrategySkipDuplicates)
+			Expect(err).To(BeNil())
+			Expect(serviceURLs(res)).To(Equal(map[string]string{
+				"identical-service": "http://example.com",
+				"first-service":     "http://example.com",
+				"second-service":    "http://example.com",
+			}))
+		})
+
+		It("fails on an unknown strategy", func() {
+			_, _, err := merge.FilesWithStrategy(fileList, "random")
+			Expect(err).To(MatchError("expected merge strategy to be one of 'error', 'last-wins', " +
+				"'first-wins', or 'skip-duplicates', got: 'random'"))
+		})
+	})
+
+	Describe("FilesWithOptions", func() {
+		It("upgrades the files to the highest version with 'ReconcileVersion'", func() {
+			fileList := []string{
+				"./merge_testfiles/file1.yml", // 3.0
+				"./merge_testfiles/file2.yml", // 3.1
+				"./merge_testfiles/file3.yml", // no version
+			}
+			res, hist, err := merge.FilesWithOptions(fileList, merge.Options{ReconcileVersion: true})
+			Expect(err).To(BeNil())
+			Expect(res["_format_version"]).To(Equal("3.1"))
+
+			// the upgrade is recorded in the history, for the upgraded file only
+			Expect(hist).To(HaveLen(3))
+			Expect(hist[0]).To(HaveKeyWithValue("upgraded_from", "3.0"))
+			Expect(hist[1]).NotTo(HaveKey("upgraded_from"))
+			Expect(hist[2]).NotTo(HaveKey("upgraded_from"))
+		})
+
+		It("records nothing without 'ReconcileVersion'", func() {
+			fileList := []string{
+				"./merge_testfiles/file1.yml",
+				"./merge_testfiles/file2.yml",
+			}
+			res, hist, err := merge.FilesWithOptions(fileList, merge.Options{})
+			Expect(err).To(BeNil())
+			Expect(res["_format_version"]).To(Equal("3.1"))
+			Expect(hist[0]).NotTo(HaveKey("upgraded_from"))
+		})
+
+		It("fails on differing major versions with 'ReconcileVersion'", func() {
+			fileList := []string{
+				"./merge_testfiles/file1.yml",
+				"./merge_testfiles/badversion.yml",
+			}
+			_, _, err := merge.FilesWithOptions(fileList, merge.Options{ReconcileVersion: true})
+			Expect(err).To(MatchError("failed to merge ./merge_testfiles/badversion.yml: files are " +
+				"incompatible; cannot reconcile the versions, major upgrade from 1.0 to 3.0; the file " +
+				"must be transformed"))
+		})
+
+		It("fails on an unknown strategy", func() {
+			_, _, err := merge.FilesWithOptions([]string{"./merge_testfiles/file1.yml"},
+				merge.Options{Strategy: "random"})
+			Expect(err).To(MatchError(ContainSubstring("expected merge strategy to be one of")))
+		})
+	})
+
 	Describe("MustMerge", func() {
 		It("succeeds on proper files", func() {
 			// This tests the order of the resulting file, but also the version of the
@@ -0,0 +1,107 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/logbasics"
+)
+
+const aclExtension = "x-kong-acl"
+
+// getACLAllowList returns the groups to allow, of the 'x-kong-acl' extension, without
+// duplicates. Returns nil if the extension is not set.
+func getACLAllowList(props openapi3.ExtensionProps) ([]string, error) {
+	if props.Extensions == nil || props.Extensions[aclExtension] == nil {
+		return nil, nil
+	}
+	var groups []string
+	err := json.Unmarshal(props.Extensions[aclExtension].(json.RawMessage), &groups)
+	if err != nil {
+		return nil, fmt.Errorf("expected '%s' to be an array of strings; %w", aclExtension, err)
+	}
+	return appendUniqueGroups(make([]string, 0, len(groups)), groups), nil
+}
+
+// appendUniqueGroups appends the groups to the list, skipping the ones already in it.
+func appendUniqueGroups(list []string, groups []string) []string {
+	for _, group := range groups {
+		found := false
+		for _, existing := range list {
+			if existing == group {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, group)
+		}
+	}
+	return list
+}
+
+// convertACLExtension replaces the 'x-kong-acl' extension by an 'x-kong-plugin-acl'
+// extension, with the groups as its 'allow' list. Such that it follows the same rules as
+// any other plugin. If the operation already has an 'acl' plugin, the groups are merged
+// into its 'allow' list. An empty list is skipped with a warning.
+func convertACLExtension(props *openapi3.ExtensionProps) error {
+	groups, err := getACLAllowList(*props)
+	if err != nil || groups == nil {
+		return err
+	}
+	delete(props.Extensions, aclExtension)
+	if len(groups) == 0 {
+		logbasics.Warn("'" + aclExtension + "' is an empty list, skipping it")
+		return nil
+	}
+
+	plugin := make(map[string]interface{})
+	if raw, ok := props.Extensions["x-kong-plugin-acl"].(json.RawMessage); ok {
+		if err := json.Unmarshal(raw, &plugin); err != nil {
+			return fmt.Errorf("cannot use '%s' together with 'x-kong-plugin-acl', unless it is an object; %w",
+				aclExtension, err)
+		}
+	}
+	config, _ := plugin["config"].(map[string]interface{})
+	if config == nil {
+		config = make(map[string]interface{})
+	}
+	if config["deny"] != nil {
+		return fmt.Errorf("cannot use '%s' together with an 'x-kong-plugin-acl' with a 'deny' list", aclExtension)
+	}
+
+	allow := make([]string, 0)
+	if existing, ok := config["allow"].([]interface{}); ok {
+		for _, group := range existing {
+			if name, ok := group.(string); ok {
+				allow = append(allow, name)
+			}
+		}
+	}
+	config["allow"] = appendUniqueGroups(allow, groups)
+	plugin["config"] = config
+
+	pluginJSON, _ := json.Marshal(plugin)
+	props.Extensions["x-kong-plugin-acl"] = json.RawMessage(pluginJSON)
+	return nil
+}
+
+// convertAllACLExtensions converts the 'x-kong-acl' extensions on the operations.
+func convertAllACLExtensions(doc *openapi3.T) error {
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		for method, operation := range doc.Paths[path].Operations() {
+			if err := convertACLExtension(&operation.ExtensionProps); err != nil {
+				return fmt.Errorf("failed to create acl plugin from operation '%s %s': %w", path, method, err)
+			}
+		}
+	}
+	return nil
+}
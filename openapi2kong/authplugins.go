@@ -0,0 +1,238 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/logbasics"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	basicAuthPluginName = "basic-auth"
+	hmacAuthPluginName  = "hmac-auth"
+	keyAuthPluginName   = "key-auth"
+	oauth2PluginName    = "oauth2"
+	// openIDConnectPluginName is the (Enterprise) 'openid-connect' plugin
+	openIDConnectPluginName = "openid-connect"
+	// hmacExtension marks a security scheme as implemented by the 'hmac-auth' plugin. It is
+	// either 'true', or an object with the plugin config.
+	hmacExtension = "x-kong-hmac"
+)
+
+// getHmacConfig returns the 'hmac-auth' plugin config from the 'x-kong-hmac' extension of
+// the security scheme, or nil if it is absent or 'false'.
+func getHmacConfig(scheme *openapi3.SecurityScheme, schemeName string) (map[string]interface{}, error) {
+	if scheme.Extensions == nil || scheme.Extensions[hmacExtension] == nil {
+		return nil, nil
+	}
+	var hint interface{}
+	_ = json.Unmarshal(scheme.Extensions[hmacExtension].(json.RawMessage), &hint)
+	switch value := hint.(type) {
+	case bool:
+		if !value {
+			return nil, nil
+		}
+		return make(map[string]interface{}), nil
+	case map[string]interface{}:
+		return value, nil
+	}
+	return nil, fmt.Errorf("expected '%s' of security scheme '%s' to be a boolean or an object",
+		hmacExtension, schemeName)
+}
+
+// DefaultSecurityMapping is the security scheme to plugin mapping used by GenerateAuthPlugins,
+// see O2kOptions.SecurityMapping.
+var DefaultSecurityMapping = map[string]string{
+	"http:basic": basicAuthPluginName,
+	"oauth2":     oauth2PluginName,
+}
+
+// getMappedPlugin returns the plugin name for a security scheme; mapped by the scheme name,
+// "http:<scheme>" for 'http' schemes, or the scheme type, in that order. Returns whether a
+// mapping was found; an empty plugin name means no plugin.
+func getMappedPlugin(mapping map[string]string, scheme *openapi3.SecurityScheme, schemeName string,
+) (string, bool) {
+	keys := []string{schemeName}
+	if scheme.Type == "http" {
+		keys = append(keys, "http:"+strings.ToLower(scheme.Scheme))
+	}
+	keys = append(keys, scheme.Type)
+	for _, key := range keys {
+		if pluginName, found := mapping[key]; found {
+			return pluginName, true
+		}
+	}
+	return "", false
+}
+
+// getSecurityMapping returns the mapping to use; the defaults, overridden by 'custom'.
+func getSecurityMapping(custom map[string]string) map[string]string {
+	mapping := make(map[string]string, len(DefaultSecurityMapping)+len(custom))
+	for key, pluginName := range DefaultSecurityMapping {
+		mapping[key] = pluginName
+	}
+	for key, pluginName := range custom {
+		mapping[key] = pluginName
+	}
+	return mapping
+}
+
+// getMappedPluginConfig returns the config of a plugin implementing a security scheme. The
+// 'oauth2' plugin gets the grants of the flows, and the required scopes, 'key-auth' the key
+// name, and 'openid-connect' the issuer. Any other plugin gets an empty config.
+func getMappedPluginConfig(pluginName string, scheme *openapi3.SecurityScheme, scopes []string,
+) map[string]interface{} {
+	config := make(map[string]interface{})
+	switch pluginName {
+	case oauth2PluginName:
+		if flows := scheme.Flows; flows != nil {
+			if flows.AuthorizationCode != nil {
+				config["enable_authorization_code"] = true
+			}
+			if flows.ClientCredentials != nil {
+				config["enable_client_credentials"] = true
+			}
+			if flows.Implicit != nil {
+				config["enable_implicit_grant"] = true
+			}
+			if flows.Password != nil {
+				config["enable_password_grant"] = true
+			}
+		}
+		if len(scopes) > 0 {
+			sorted := append(make([]string, 0, len(scopes)), scopes...)
+			sort.Strings(sorted)
+			config["scopes"] = sorted
+			config["mandatory_scope"] = true
+		}
+	case keyAuthPluginName:
+		if scheme.Type == "apiKey" && scheme.Name != "" {
+			config["key_names"] = []string{scheme.Name}
+			config["key_in_header"] = scheme.In == "header"
+			config["key_in_query"] = scheme.In == "query"
+		}
+	case openIDConnectPluginName:
+		if scheme.OpenIdConnectUrl != "" {
+			config["issuer"] = scheme.OpenIdConnectUrl
+		}
+	}
+	return config
+}
+
+// getAuthPluginConfigs returns the configs of the auth plugins, by plugin name, implementing
+// the security schemes referenced by the security requirements. Schemes with an
+// 'x-kong-hmac' hint get 'hmac-auth', others the plugin from the mapping (see
+// getMappedPlugin). Unmapped schemes are skipped with a warning, except for 'apiKey'
+// schemes, which are implemented by an 'x-kong-plugin-key-auth' (see fillKeyAuthPlugin).
+func getAuthPluginConfigs(security *openapi3.SecurityRequirements, schemes openapi3.SecuritySchemes,
+	mapping map[string]string, operationName string,
+) (map[string]map[string]interface{}, error) {
+	configs := make(map[string]map[string]interface{})
+	if security == nil {
+		return configs, nil
+	}
+
+	// addConfig adds the config, the first one wins if multiple schemes map to the same plugin
+	addConfig := func(pluginName string, config map[string]interface{}, schemeName string) {
+		if configs[pluginName] == nil {
+			configs[pluginName] = config
+		} else if !reflect.DeepEqual(configs[pluginName], config) {
+			logbasics.Warn("multiple '"+pluginName+"' security schemes with different configs, using the first",
+				"operation", operationName, "securityScheme", schemeName)
+		}
+	}
+
+	requirementsWithPlugins := 0
+	for _, requirement := range *security {
+		schemeNames := make([]string, 0, len(requirement))
+		for schemeName := range requirement {
+			schemeNames = append(schemeNames, schemeName)
+		}
+		sort.Strings(schemeNames)
+
+		hasPlugin := false
+		for _, schemeName := range schemeNames {
+			schemeRef := schemes[schemeName]
+			if schemeRef == nil || schemeRef.Value == nil {
+				continue
+			}
+			scheme := schemeRef.Value
+
+			hmacConfig, err := getHmacConfig(scheme, schemeName)
+			if err != nil {
+				return nil, err
+			}
+			if hmacConfig != nil {
+				addConfig(hmacAuthPluginName, hmacConfig, schemeName)
+				hasPlugin = true
+				continue
+			}
+
+			pluginName, mapped := getMappedPlugin(mapping, scheme, schemeName)
+			switch {
+			case pluginName != "":
+				addConfig(pluginName, getMappedPluginConfig(pluginName, scheme, requirement[schemeName]), schemeName)
+				hasPlugin = true
+			case mapped:
+				// explicitly mapped to no plugin
+			case scheme.Type == "http":
+				logbasics.Warn("the http security scheme '"+scheme.Scheme+"' is not supported, ignoring it",
+					"operation", operationName, "securityScheme", schemeName)
+			case scheme.Type != "apiKey":
+				logbasics.Warn("no plugin is mapped for the security scheme type '"+scheme.Type+"', ignoring it",
+					"operation", operationName, "securityScheme", schemeName)
+			}
+		}
+		if hasPlugin {
+			requirementsWithPlugins++
+		}
+	}
+
+	if requirementsWithPlugins > 1 {
+		logbasics.Warn("alternative security requirements are not supported, all generated "+
+			"auth plugins are required", "operation", operationName)
+	}
+	return configs, nil
+}
+
+// insertAuthPlugins inserts the auth plugins for the security requirements, see
+// getAuthPluginConfigs. Plugins already in any of the 'existing' lists (eg. from an
+// 'x-kong-plugin-basic-auth' extension) are left as they are.
+func insertAuthPlugins(
+	list *[]*map[string]interface{},
+	existing []*[]*map[string]interface{},
+	security *openapi3.SecurityRequirements,
+	schemes openapi3.SecuritySchemes,
+	mapping map[string]string,
+	uuidNamespace uuid.UUID,
+	baseName string,
+	tags []string,
+) (*[]*map[string]interface{}, error) {
+	configs, err := getAuthPluginConfigs(security, schemes, mapping, baseName)
+	if err != nil {
+		return nil, err
+	}
+	pluginNames := make([]string, 0, len(configs))
+	for pluginName := range configs {
+		pluginNames = append(pluginNames, pluginName)
+	}
+	sort.Strings(pluginNames)
+	for _, pluginName := range pluginNames {
+		if findPlugin(pluginName, append([]*[]*map[string]interface{}{list}, existing...)...) != nil {
+			continue
+		}
+		plugin := map[string]interface{}{
+			"name":   pluginName,
+			"config": configs[pluginName],
+			"tags":   tags,
+		}
+		plugin["id"] = createPluginID(uuidNamespace, baseName, plugin)
+		list = insertPlugin(list, &plugin)
+	}
+	return list, nil
+}
@@ -0,0 +1,137 @@
+package openapi2kong
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/logbasics"
+)
+
+// CallbackTag is added to the tags of the routes generated from callbacks, see IncludeCallbacks.
+const CallbackTag = "callback"
+
+// splitRuntimeExpression splits a string at the first occurrence of one of the characters,
+// ignoring those inside runtime expressions ('{$...}'). Returns the parts before and after
+// (including) the separator.
+func splitRuntimeExpression(value string, separators string) (string, string) {
+	depth := 0
+	for i, char := range value {
+		switch {
+		case char == '{':
+			depth++
+		case char == '}' && depth > 0:
+			depth--
+		case depth == 0 && strings.ContainsRune(separators, char):
+			return value[:i], value[i:]
+		}
+	}
+	return value, ""
+}
+
+// replaceRuntimeExpressions replaces the runtime expressions in a path ('{$request.body#/id}')
+// by path parameters ('{request_body_id}').
+func replaceRuntimeExpressions(path string) string {
+	result := ""
+	for {
+		start := strings.Index(path, "{$")
+		if start == -1 {
+			return result + path
+		}
+		end := strings.Index(path[start:], "}")
+		if end == -1 {
+			return result + path
+		}
+		expression := path[start+2 : start+end]
+		result += path[:start] + "{" + sanitizeRegexCapture(expression) + "}"
+		path = path[start+end+1:]
+	}
+}
+
+// getCallbackPath returns the path to generate the routes for, from a callback expression
+// (eg. "https://{$request.body#/host}/events?id={$request.body#/id}"). The host, and query,
+// are dropped. A templated host is skipped with a warning. Returns "" if there is no
+// static path.
+func getCallbackPath(expression string) string {
+	rest := expression
+	host := ""
+	if scheme := strings.Index(rest, "://"); scheme != -1 && !strings.Contains(rest[:scheme], "{") {
+		host, rest = splitRuntimeExpression(rest[scheme+3:], "/?")
+	} else if strings.HasPrefix(rest, "{$") {
+		// the base url is a runtime expression, eg. "{$request.body#/callbackUrl}/events"
+		host, rest = splitRuntimeExpression(rest, "/?")
+	}
+	if strings.Contains(host, "{$") {
+		logbasics.Warn("skipping the templated host of callback", "expression", expression)
+	}
+
+	path, _ := splitRuntimeExpression(rest, "?")
+	if !strings.HasPrefix(path, "/") {
+		return ""
+	}
+	return replaceRuntimeExpressions(path)
+}
+
+// addCallbackPaths adds the path items of the callbacks of all operations to the document
+// paths, so routes get generated for them. Callback paths that already exist are skipped
+// with a warning. Returns the path items added.
+func addCallbackPaths(doc *openapi3.T) map[*openapi3.PathItem]bool {
+	added := make(map[*openapi3.PathItem]bool)
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	newPaths := make(openapi3.Paths)
+	for _, path := range paths {
+		operations := doc.Paths[path].Operations()
+		methods := make([]string, 0, len(operations))
+		for method := range operations {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+
+		for _, method := range methods {
+			callbacks := operations[method].Callbacks
+			names := make([]string, 0, len(callbacks))
+			for name := range callbacks {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				if callbacks[name] == nil || callbacks[name].Value == nil {
+					continue
+				}
+				callback := *callbacks[name].Value
+				expressions := make([]string, 0, len(callback))
+				for expression := range callback {
+					expressions = append(expressions, expression)
+				}
+				sort.Strings(expressions)
+
+				for _, expression := range expressions {
+					callbackPath := getCallbackPath(expression)
+					switch {
+					case callbackPath == "":
+						logbasics.Warn("skipping callback without a static path", "operation", method+" "+path,
+							"callback", name, "expression", expression)
+					case doc.Paths[callbackPath] != nil || newPaths[callbackPath] != nil:
+						logbasics.Warn("skipping callback, its path is already in use", "operation", method+" "+path,
+							"callback", name, "path", callbackPath)
+					default:
+						newPaths[callbackPath] = callback[expression]
+						added[callback[expression]] = true
+					}
+				}
+			}
+		}
+	}
+
+	for path, pathItem := range newPaths {
+		doc.Paths[path] = pathItem
+	}
+	return added
+}
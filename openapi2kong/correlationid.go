@@ -0,0 +1,42 @@
+package openapi2kong
+
+import (
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	correlationIDPluginName = "correlation-id"
+	// DefaultCorrelationIDHeader is the header used by the EnsureCorrelationID option, if no
+	// CorrelationIDHeader is specified. It is the plugin default.
+	DefaultCorrelationIDHeader = "Kong-Request-ID"
+	// DefaultCorrelationIDGenerator is the generator used by the EnsureCorrelationID option, if
+	// no CorrelationIDGenerator is specified.
+	DefaultCorrelationIDGenerator = "uuid"
+)
+
+// insertCorrelationIDPlugin inserts a 'correlation-id' plugin, unless the list already has
+// one (eg. from an 'x-kong-plugin-correlation-id' extension, or inherited).
+func insertCorrelationIDPlugin(
+	list *[]*map[string]interface{},
+	headerName string,
+	generator string,
+	uuidNamespace uuid.UUID,
+	baseName string,
+	tags []string,
+) *[]*map[string]interface{} {
+	if findPlugin(correlationIDPluginName, list) != nil {
+		return list
+	}
+
+	plugin := map[string]interface{}{
+		"name": correlationIDPluginName,
+		"config": map[string]interface{}{
+			"header_name": headerName,
+			"generator":   generator,
+		},
+	}
+	plugin["id"] = createPluginID(uuidNamespace, baseName, plugin)
+	plugin["tags"] = tags
+
+	return insertPlugin(list, &plugin)
+}
@@ -0,0 +1,52 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/kong/go-apiops/logbasics"
+)
+
+// serviceOnlyFields are the fields of a Kong service, that do not exist on a route. They
+// are dropped from 'x-kong-route-defaults' with a warning.
+var serviceOnlyFields = []string{
+	"ca_certificates", "client_certificate", "connect_timeout", "enabled", "host", "path", "port",
+	"protocol", "read_timeout", "retries", "tls_verify", "tls_verify_depth", "url", "write_timeout",
+}
+
+// routeOnlyFields are the fields of a Kong route, that do not exist on a service. They
+// are dropped from 'x-kong-service-defaults' with a warning.
+var routeOnlyFields = []string{
+	"destinations", "headers", "hosts", "https_redirect_status_code", "methods", "path_handling",
+	"paths", "preserve_host", "protocols", "regex_priority", "request_buffering",
+	"response_buffering", "snis", "sources", "strip_path",
+}
+
+// dropMisplacedFields removes the 'misplaced' fields from the JSON encoded defaults of the
+// extension, with a warning for each, pointing to the extension they belong in ('correct').
+// Returns the defaults unchanged if none are found.
+func dropMisplacedFields(defaults []byte, extension string, misplaced []string, correct string) []byte {
+	if defaults == nil {
+		return nil
+	}
+	var object map[string]interface{}
+	_ = json.Unmarshal(defaults, &object)
+
+	found := make([]string, 0)
+	for _, field := range misplaced {
+		if _, ok := object[field]; ok {
+			found = append(found, field)
+			delete(object, field)
+		}
+	}
+	if len(found) == 0 {
+		return defaults
+	}
+	sort.Strings(found)
+	for _, field := range found {
+		logbasics.Warn("the field is not supported in '"+extension+"', use '"+correct+"' instead; ignoring it",
+			"field", field)
+	}
+	result, _ := json.Marshal(object)
+	return result
+}
@@ -0,0 +1,52 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/logbasics"
+)
+
+const (
+	responseTransformerPluginName = "response-transformer"
+	// sunsetExtension holds the date an operation will be removed, for the 'Sunset' header
+	sunsetExtension = "x-sunset"
+)
+
+// sunsetLayouts are the accepted formats of the 'x-sunset' date
+var sunsetLayouts = []string{"2006-01-02", time.RFC3339, http.TimeFormat}
+
+// getSunsetHeader returns the value of the 'Sunset' header (an HTTP-date) from the 'x-sunset'
+// extension of the operation. Returns "" if absent, or invalid (with a warning).
+func getSunsetHeader(props openapi3.ExtensionProps, operationName string) string {
+	if props.Extensions == nil || props.Extensions[sunsetExtension] == nil {
+		return ""
+	}
+	var value interface{}
+	_ = json.Unmarshal(props.Extensions[sunsetExtension].(json.RawMessage), &value)
+	if date, ok := value.(string); ok {
+		for _, layout := range sunsetLayouts {
+			if sunset, err := time.Parse(layout, date); err == nil {
+				return sunset.UTC().Format(http.TimeFormat)
+			}
+		}
+	}
+	logbasics.Warn("invalid '"+sunsetExtension+"' date, omitting the Sunset header; expected a date "+
+		"('2006-01-02'), a date-time (RFC3339), or an HTTP-date", "operation", operationName, "value", value)
+	return ""
+}
+
+// getDeprecationHeaders returns the 'Deprecation' and 'Sunset' headers (in "name:value"
+// format) for a deprecated operation, or nil if the operation is not deprecated.
+func getDeprecationHeaders(operation *openapi3.Operation, operationName string) []string {
+	if !operation.Deprecated {
+		return nil
+	}
+	headers := []string{"Deprecation:true"}
+	if sunset := getSunsetHeader(operation.ExtensionProps, operationName); sunset != "" {
+		headers = append(headers, "Sunset:"+sunset)
+	}
+	return headers
+}
@@ -0,0 +1,105 @@
+package openapi2kong
+
+import (
+	"errors"
+)
+
+// isEmitted returns the value of an EmitNames/EmitIDs option, which defaults to true.
+func isEmitted(option *bool) bool {
+	return option == nil || *option
+}
+
+// validateEmitKeys checks that at least one of the EmitNames and EmitIDs options is enabled.
+func validateEmitKeys(emitNames *bool, emitIDs *bool) error {
+	if !isEmitted(emitNames) && !isEmitted(emitIDs) {
+		return errors.New("at least one of the options EmitNames and EmitIDs must be enabled")
+	}
+	return nil
+}
+
+// getPluginLists returns the plugin lists of the services, and their routes.
+func getPluginLists(services []interface{}) []*[]*map[string]interface{} {
+	lists := make([]*[]*map[string]interface{}, 0)
+	for _, s := range services {
+		service := s.(map[string]interface{})
+		if plugins, ok := service["plugins"].(*[]*map[string]interface{}); ok && plugins != nil {
+			lists = append(lists, plugins)
+		}
+		routes, _ := service["routes"].([]interface{})
+		for _, r := range routes {
+			if plugins, ok := r.(map[string]interface{})["plugins"].(*[]*map[string]interface{}); ok && plugins != nil {
+				lists = append(lists, plugins)
+			}
+		}
+	}
+	return lists
+}
+
+// applyEmitKeys removes the generated names or ids, as selected by the EmitNames and EmitIDs
+// options. Names are only removed from the services and routes, since the names of plugins,
+// upstreams, snis, and vaults are required. Without names, the top-level plugins refer to the
+// services and routes by id. The snis always refer to the certificate placeholder by id,
+// since certificates have no name.
+func applyEmitKeys(result map[string]interface{}, services []interface{}, emitNames bool, emitIDs bool) {
+	if emitNames && emitIDs {
+		return
+	}
+
+	// the ids of the services and routes, by name, for the references to them
+	ids := map[string]map[string]string{
+		"service": make(map[string]string),
+		"route":   make(map[string]string),
+	}
+	entities := make([]map[string]interface{}, 0)
+	for _, s := range services {
+		service := s.(map[string]interface{})
+		ids["service"][service["name"].(string)] = service["id"].(string)
+		entities = append(entities, service)
+		routes, _ := service["routes"].([]interface{})
+		for _, r := range routes {
+			route := r.(map[string]interface{})
+			ids["route"][route["name"].(string)] = route["id"].(string)
+			entities = append(entities, route)
+		}
+	}
+
+	topLevelPlugins, _ := result["plugins"].(*[]*map[string]interface{})
+	if !emitNames && topLevelPlugins != nil {
+		for _, plugin := range *topLevelPlugins {
+			for foreignKey, idsByName := range ids {
+				if name, ok := (*plugin)[foreignKey].(string); ok && idsByName[name] != "" {
+					(*plugin)[foreignKey] = idsByName[name]
+				}
+			}
+		}
+	}
+
+	for _, entity := range entities {
+		if !emitNames {
+			delete(entity, "name")
+		}
+		if !emitIDs {
+			delete(entity, "id")
+		}
+	}
+	if emitIDs {
+		return
+	}
+
+	// without ids, also remove them from the other generated entities
+	pluginLists := getPluginLists(services)
+	if topLevelPlugins != nil {
+		pluginLists = append(pluginLists, topLevelPlugins)
+	}
+	for _, list := range pluginLists {
+		for _, plugin := range *list {
+			delete(*plugin, "id")
+		}
+	}
+	for _, section := range []string{"upstreams", "snis", "vaults"} {
+		entities, _ := result[section].([]interface{})
+		for _, entity := range entities {
+			delete(entity.(map[string]interface{}), "id")
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/logbasics"
+)
+
+const (
+	// examplesKey is the route field the PreserveExamples option stores the examples in.
+	// The '_ignore' metadata field holds opaque data, which is ignored by Kong.
+	examplesKey = "_ignore"
+	// maxExampleSize is the maximum size (serialized as JSON) of a preserved example. Larger
+	// examples are skipped with a warning.
+	maxExampleSize = 4096
+)
+
+// getMediaTypeExamples returns the examples of the media types by content type, in the same
+// format as the spec ('example', or 'examples' by name). Examples exceeding maxExampleSize
+// are skipped with a warning. Returns nil if there are none.
+func getMediaTypeExamples(content openapi3.Content, operationName string) map[string]interface{} {
+	contentTypes := make([]string, 0, len(content))
+	for contentType := range content {
+		contentTypes = append(contentTypes, contentType)
+	}
+	sort.Strings(contentTypes)
+
+	fitsSize := func(example interface{}, contentType string, name string) bool {
+		serialized, _ := json.Marshal(example)
+		if len(serialized) <= maxExampleSize {
+			return true
+		}
+		logbasics.Warn("skipping example exceeding the maximum size", "operation", operationName,
+			"content-type", contentType, "example", name, "size", len(serialized), "max", maxExampleSize)
+		return false
+	}
+
+	result := make(map[string]interface{})
+	for _, contentType := range contentTypes {
+		mediaType := content[contentType]
+		if mediaType == nil {
+			continue
+		}
+		if mediaType.Example != nil {
+			if fitsSize(mediaType.Example, contentType, "example") {
+				result[contentType] = map[string]interface{}{"example": mediaType.Example}
+			}
+			continue
+		}
+		named := make(map[string]interface{})
+		for name, exampleRef := range mediaType.Examples {
+			if exampleRef == nil || exampleRef.Value == nil || exampleRef.Value.Value == nil {
+				continue
+			}
+			if fitsSize(exampleRef.Value.Value, contentType, name) {
+				named[name] = exampleRef.Value.Value
+			}
+		}
+		if len(named) > 0 {
+			result[contentType] = map[string]interface{}{"examples": named}
+		}
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// getOperationExamples returns the request body and response examples of the operation,
+// as an object with a 'request' and a 'responses' (by status code) entry. Returns nil if
+// the operation has no examples.
+func getOperationExamples(operation *openapi3.Operation, operationName string) map[string]interface{} {
+	examples := make(map[string]interface{})
+
+	if operation.RequestBody != nil && operation.RequestBody.Value != nil {
+		if request := getMediaTypeExamples(operation.RequestBody.Value.Content, operationName); request != nil {
+			examples["request"] = request
+		}
+	}
+
+	responses := make(map[string]interface{})
+	for statusCode, responseRef := range operation.Responses {
+		if responseRef == nil || responseRef.Value == nil {
+			continue
+		}
+		if response := getMediaTypeExamples(responseRef.Value.Content, operationName); response != nil {
+			responses[statusCode] = response
+		}
+	}
+	if len(responses) > 0 {
+		examples["responses"] = responses
+	}
+
+	if len(examples) == 0 {
+		return nil
+	}
+	return examples
+}
@@ -0,0 +1,126 @@
+package openapi2kong
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// the levels on which an 'x-kong-...' extension can be used
+const (
+	scopeDocument = 1 << iota
+	scopePath
+	scopeOperation
+
+	scopeAll = scopeDocument | scopePath | scopeOperation
+)
+
+// scopeNames are the names of the levels, for use in messages
+var scopeNames = map[int]string{
+	scopeDocument:  "document",
+	scopePath:      "path",
+	scopeOperation: "operation",
+}
+
+// knownExtensions are the 'x-kong-...' extensions consumed by the converter, with the
+// levels they can be used on. Plugins ('x-kong-plugin-<name>') can be used on all levels.
+var knownExtensions = map[string]int{
+	"x-kong-name":                    scopeAll,
+	serviceNameExtension:             scopeAll,
+	"x-kong-service-defaults":        scopeAll,
+	"x-kong-upstream-defaults":       scopeAll,
+	"x-kong-route-defaults":          scopeAll,
+	"x-kong-pre-function":            scopeAll,
+	"x-kong-post-function":           scopeAll,
+	ipRestrictionExtension:           scopeAll,
+	protocolExtension:                scopeAll,
+	protocolsExtension:               scopeAll,
+	serviceKeepaliveExtension:        scopeAll,
+	rateLimitingExtension:            scopeAll,
+	connectTimeoutExtension:          scopeAll,
+	readTimeoutExtension:             scopeAll,
+	writeTimeoutExtension:            scopeAll,
+	retriesExtension:                 scopeAll,
+	preserveHostExtension:            scopeAll,
+	httpsRedirectStatusCodeExtension: scopeAll,
+	pluginRefExtension:               scopeAll,
+	"x-kong-tags":                    scopeDocument,
+	tagsRemoveExtension:              scopeOperation,
+	"x-kong-name-prefix":             scopeDocument,
+	vaultsExtension:                  scopeDocument,
+	grpcGatewayExtension:             scopeDocument,
+	"x-kong-upstream":                scopeDocument | scopeOperation,
+	serviceURLExtension:              scopeDocument | scopeOperation,
+	"x-kong-strip-path":              scopePath | scopeOperation,
+	pathHandlingExtension:            scopePath | scopeOperation,
+	headersExtension:                 scopePath | scopeOperation,
+	mockStatusExtension:              scopeOperation,
+	terminationExtension:             scopeOperation,
+	aclExtension:                     scopeOperation,
+	streamingExtension:               scopeOperation,
+	uploadExtension:                  scopeOperation,
+}
+
+// getExtensionProblems returns the 'x-kong-...' extensions in props that are unknown, or
+// not supported on the level ('scope') they are used on. 'location' is used in the messages.
+func getExtensionProblems(props openapi3.ExtensionProps, scope int, location string) []string {
+	names := make([]string, 0)
+	for name := range props.Extensions {
+		if strings.HasPrefix(name, "x-kong-") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	problems := make([]string, 0)
+	for _, name := range names {
+		scopes, known := knownExtensions[name]
+		if !known && strings.HasPrefix(name, "x-kong-plugin-") {
+			scopes, known = scopeAll, true
+		}
+		if !known {
+			problems = append(problems, fmt.Sprintf("unknown extension '%s' on %s", name, location))
+		} else if scopes&scope == 0 {
+			problems = append(problems, fmt.Sprintf("extension '%s' is not supported on %s level, found on %s",
+				name, scopeNames[scope], location))
+		}
+	}
+	return problems
+}
+
+// validateExtensions returns an error listing all 'x-kong-...' extensions in the document
+// that the converter would not consume; unknown ones (eg. typos), and ones used on a level
+// they are not supported on.
+func validateExtensions(doc *openapi3.T) error {
+	problems := getExtensionProblems(doc.ExtensionProps, scopeDocument, "document root")
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		pathitem := doc.Paths[path]
+		problems = append(problems,
+			getExtensionProblems(pathitem.ExtensionProps, scopePath, fmt.Sprintf("path '%s'", path))...)
+
+		operations := pathitem.Operations()
+		methods := make([]string, 0, len(operations))
+		for method := range operations {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			problems = append(problems, getExtensionProblems(operations[method].ExtensionProps, scopeOperation,
+				fmt.Sprintf("operation '%s %s'", path, method))...)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("unrecognized 'x-kong-...' extensions found; %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
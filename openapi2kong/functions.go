@@ -0,0 +1,117 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/logbasics"
+)
+
+// functionExtensions maps the function extensions to the serverless plugins they generate.
+var functionExtensions = map[string]string{
+	"x-kong-pre-function":  "pre-function",
+	"x-kong-post-function": "post-function",
+}
+
+// getFunctionCode returns the Lua code from a function extension. Entries are either
+// strings with the Lua code, or objects with a 'file' key referencing a file with the code.
+// File references are resolved relative to baseDir.
+func getFunctionCode(extensionName string, value json.RawMessage, baseDir string) ([]string, error) {
+	var entries []interface{}
+	if err := json.Unmarshal(value, &entries); err != nil {
+		return nil, fmt.Errorf("expected '%s' to be an array", extensionName)
+	}
+
+	code := make([]string, len(entries))
+	for i, entry := range entries {
+		switch e := entry.(type) {
+		case string:
+			code[i] = e
+
+		case map[string]interface{}:
+			filename, ok := e["file"].(string)
+			if !ok || filename == "" {
+				return nil, fmt.Errorf("expected entries in '%s' to be strings, or objects with a 'file' key",
+					extensionName)
+			}
+			if !filepath.IsAbs(filename) {
+				filename = filepath.Join(baseDir, filename)
+			}
+			logbasics.Debug("reading function file", "extension", extensionName, "filename", filename)
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file '%s' for '%s'; %w", filename, extensionName, err)
+			}
+			code[i] = string(content)
+
+		default:
+			return nil, fmt.Errorf("expected entries in '%s' to be strings, or objects with a 'file' key",
+				extensionName)
+		}
+	}
+	return code, nil
+}
+
+// convertFunctionExtensions replaces the 'x-kong-pre-function' and 'x-kong-post-function'
+// extensions by the equivalent 'x-kong-plugin-pre-function' and 'x-kong-plugin-post-function'
+// extensions. Such that they follow the same rules as any other plugin.
+func convertFunctionExtensions(props *openapi3.ExtensionProps, baseDir string) error {
+	if props.Extensions == nil {
+		return nil
+	}
+
+	for extensionName, pluginName := range functionExtensions {
+		value, found := props.Extensions[extensionName]
+		if !found {
+			continue
+		}
+		if props.Extensions["x-kong-plugin-"+pluginName] != nil {
+			return fmt.Errorf("cannot use both '%s' and 'x-kong-plugin-%s'", extensionName, pluginName)
+		}
+
+		code, err := getFunctionCode(extensionName, value.(json.RawMessage), baseDir)
+		if err != nil {
+			return err
+		}
+
+		plugin, _ := json.Marshal(map[string]interface{}{
+			"config": map[string]interface{}{
+				"access": code,
+			},
+		})
+		props.Extensions["x-kong-plugin-"+pluginName] = json.RawMessage(plugin)
+		delete(props.Extensions, extensionName)
+	}
+	return nil
+}
+
+// convertAllFunctionExtensions converts the function extensions on document, path, and
+// operation level. See convertFunctionExtensions.
+func convertAllFunctionExtensions(doc *openapi3.T, baseDir string) error {
+	if err := convertFunctionExtensions(&doc.ExtensionProps, baseDir); err != nil {
+		return fmt.Errorf("failed to convert functions on document root: %w", err)
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		pathitem := doc.Paths[path]
+		if err := convertFunctionExtensions(&pathitem.ExtensionProps, baseDir); err != nil {
+			return fmt.Errorf("failed to convert functions on path '%s': %w", path, err)
+		}
+		for method, operation := range pathitem.Operations() {
+			if err := convertFunctionExtensions(&operation.ExtensionProps, baseDir); err != nil {
+				return fmt.Errorf("failed to convert functions on operation '%s %s': %w", path, method, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/logbasics"
+)
+
+const grpcGatewayExtension = "x-kong-grpc-gateway"
+
+// convertGrpcGatewayExtension replaces the 'x-kong-grpc-gateway' extension by the equivalent
+// 'x-kong-plugin-grpc-gateway' extension, with the 'proto' file set. Such that it follows the
+// same rules as any other plugin. The proto file is resolved relative to baseDir and must exist.
+func convertGrpcGatewayExtension(props *openapi3.ExtensionProps, baseDir string) error {
+	if props.Extensions == nil || props.Extensions[grpcGatewayExtension] == nil {
+		return nil
+	}
+	if props.Extensions["x-kong-plugin-grpc-gateway"] != nil {
+		return fmt.Errorf("cannot use both '%s' and 'x-kong-plugin-grpc-gateway'", grpcGatewayExtension)
+	}
+
+	var filename string
+	err := json.Unmarshal(props.Extensions[grpcGatewayExtension].(json.RawMessage), &filename)
+	if err != nil || filename == "" {
+		return fmt.Errorf("expected '%s' to be a non-empty string (the proto filename)", grpcGatewayExtension)
+	}
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(baseDir, filename)
+	}
+	logbasics.Debug("checking proto file", "extension", grpcGatewayExtension, "filename", filename)
+	if _, err := os.Stat(filename); err != nil {
+		return fmt.Errorf("failed to find proto file '%s' for '%s'; %w", filename, grpcGatewayExtension, err)
+	}
+
+	plugin, _ := json.Marshal(map[string]interface{}{
+		"config": map[string]interface{}{
+			"proto": filename,
+		},
+	})
+	props.Extensions["x-kong-plugin-grpc-gateway"] = json.RawMessage(plugin)
+	delete(props.Extensions, grpcGatewayExtension)
+	return nil
+}
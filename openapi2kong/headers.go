@@ -0,0 +1,68 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const headersExtension = "x-kong-headers"
+
+// parseRouteHeaders parses the value of an 'x-kong-headers' extension; an object with the
+// header names, and their values to match, as an array of strings, or a single string.
+func parseRouteHeaders(raw json.RawMessage) (map[string][]string, error) {
+	var headers map[string]interface{}
+	if err := json.Unmarshal(raw, &headers); err != nil {
+		return nil, fmt.Errorf("expected '%s' to be an object with header names and values", headersExtension)
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make(map[string][]string, len(headers))
+	for _, name := range names {
+		if strings.EqualFold(name, "host") {
+			return nil, fmt.Errorf("header 'host' cannot be used in '%s', route on the 'hosts' instead", headersExtension)
+		}
+
+		var values []string
+		switch value := headers[name].(type) {
+		case string:
+			values = []string{value} // a single value
+		case []interface{}:
+			for _, entry := range value {
+				if s, ok := entry.(string); ok {
+					values = append(values, s)
+				} else {
+					values = nil
+					break
+				}
+			}
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("expected the value of header '%s' in '%s' to be a string, or a "+
+				"non-empty array of strings", name, headersExtension)
+		}
+		result[name] = values
+	}
+	return result, nil
+}
+
+// getRouteHeaders returns the 'headers' to match for a route. Precedence is; 'x-kong-headers'
+// on the operation -> on the path. Returns nil if not set.
+func getRouteHeaders(operationProps openapi3.ExtensionProps, pathProps openapi3.ExtensionProps,
+) (map[string][]string, error) {
+	for _, props := range []openapi3.ExtensionProps{operationProps, pathProps} {
+		if props.Extensions == nil || props.Extensions[headersExtension] == nil {
+			continue
+		}
+		return parseRouteHeaders(props.Extensions[headersExtension].(json.RawMessage))
+	}
+	return nil, nil
+}
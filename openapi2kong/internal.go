@@ -0,0 +1,108 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	internalExtension    = "x-internal"
+	terminationExtension = "x-kong-termination"
+
+	// DefaultTerminationStatus is the status of the response blocking internal operations
+	DefaultTerminationStatus = 403
+)
+
+// termination is the response of a blocked operation, see 'x-kong-termination'
+type termination struct {
+	Status      int    `json:"status"`
+	Message     string `json:"message"`
+	ContentType string `json:"content_type"`
+	Body        string `json:"body"`
+}
+
+// isInternal returns true if the operation is flagged 'x-internal: true', on the operation
+// itself, or on its path. The operation level takes precedence.
+func isInternal(pathProps openapi3.ExtensionProps, operationProps openapi3.ExtensionProps) (bool, error) {
+	for _, props := range []openapi3.ExtensionProps{operationProps, pathProps} {
+		if props.Extensions == nil || props.Extensions[internalExtension] == nil {
+			continue
+		}
+		var internal bool
+		if err := json.Unmarshal(props.Extensions[internalExtension].(json.RawMessage), &internal); err != nil {
+			return false, fmt.Errorf("expected '%s' to be a boolean", internalExtension)
+		}
+		return internal, nil
+	}
+	return false, nil
+}
+
+// getTermination returns the 'x-kong-termination' extension of the operation, with the
+// status, message, content_type, and body of the blocking response. Returns nil if not set.
+func getTermination(props openapi3.ExtensionProps) (*termination, error) {
+	if props.Extensions == nil || props.Extensions[terminationExtension] == nil {
+		return nil, nil
+	}
+	var t termination
+	if err := json.Unmarshal(props.Extensions[terminationExtension].(json.RawMessage), &t); err != nil {
+		return nil, fmt.Errorf("expected '%s' to be an object with 'status', 'message', "+
+			"'content_type', and 'body'; %w", terminationExtension, err)
+	}
+	if t.Status != 0 {
+		if err := validateTerminationStatus(t.Status); err != nil {
+			return nil, fmt.Errorf("invalid '%s'; %w", terminationExtension, err)
+		}
+	}
+	if t.Message != "" && t.Body != "" {
+		return nil, fmt.Errorf("expected '%s' to have either a 'message' or a 'body', not both",
+			terminationExtension)
+	}
+	return &t, nil
+}
+
+// validateTerminationStatus returns an error if the status is not a valid HTTP status code.
+func validateTerminationStatus(status int) error {
+	if status < 100 || status > 599 {
+		return fmt.Errorf("expected the termination status to be an HTTP status code (100-599), got %d", status)
+	}
+	return nil
+}
+
+// generateBlockingPlugin returns a 'request-termination' plugin that blocks all requests
+// with the given status (the message defaults to the status text), for operations that are
+// internal-only. The optional termination overrides the response.
+func generateBlockingPlugin(status int, t *termination, uuidNamespace uuid.UUID, baseName string,
+	tags []string,
+) *map[string]interface{} {
+	config := make(map[string]interface{})
+	if t != nil {
+		if t.Status != 0 {
+			status = t.Status
+		}
+		if t.Message != "" {
+			config["message"] = t.Message
+		}
+		if t.ContentType != "" {
+			config["content_type"] = t.ContentType
+		}
+		if t.Body != "" {
+			config["body"] = t.Body
+		}
+	}
+	config["status_code"] = status
+	if config["message"] == nil && config["body"] == nil {
+		config["message"] = http.StatusText(status)
+	}
+
+	plugin := map[string]interface{}{
+		"name":   "request-termination",
+		"config": config,
+		"tags":   tags,
+	}
+	plugin["id"] = createPluginID(uuidNamespace, baseName, plugin)
+	return &plugin
+}
@@ -0,0 +1,159 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/jsonbasics"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	ipRestrictionExtension  = "x-kong-ip-restriction"
+	ipRestrictionPluginName = "ip-restriction"
+)
+
+// ipRestriction holds the allow and deny lists from the 'x-kong-ip-restriction' extension.
+type ipRestriction struct {
+	allow   []string
+	deny    []string
+	enabled *bool // nil if not specified
+}
+
+// getIPList returns the validated list of IP addresses/CIDRs in 'field' of the object.
+func getIPList(obj map[string]interface{}, field string) ([]string, error) {
+	list, err := jsonbasics.GetArrayField(obj, field)
+	if err != nil {
+		return nil, fmt.Errorf("expected '%s.%s' to be an array of strings", ipRestrictionExtension, field)
+	}
+
+	result := make([]string, len(list))
+	for i, entry := range list {
+		value, ok := entry.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected '%s.%s' to be an array of strings", ipRestrictionExtension, field)
+		}
+		if _, _, err := net.ParseCIDR(value); err != nil && net.ParseIP(value) == nil {
+			return nil, fmt.Errorf("invalid IP address or CIDR '%s' in '%s.%s'", value, ipRestrictionExtension, field)
+		}
+		result[i] = value
+	}
+	return result, nil
+}
+
+// getIPRestriction returns the parsed 'x-kong-ip-restriction' extension, or nil if
+// it isn't present.
+func getIPRestriction(props openapi3.ExtensionProps, components *map[string]interface{}) (*ipRestriction, error) {
+	jsonstr, err := getXKongObject(props, ipRestrictionExtension, components)
+	if err != nil || jsonstr == nil {
+		return nil, err
+	}
+
+	var obj map[string]interface{}
+	_ = json.Unmarshal(jsonstr, &obj)
+
+	var restriction ipRestriction
+	if restriction.allow, err = getIPList(obj, "allow"); err != nil {
+		return nil, err
+	}
+	if restriction.deny, err = getIPList(obj, "deny"); err != nil {
+		return nil, err
+	}
+	if obj["enabled"] != nil {
+		enabled, err := jsonbasics.GetBoolField(obj, "enabled")
+		if err != nil {
+			return nil, fmt.Errorf("expected '%s.enabled' to be a boolean", ipRestrictionExtension)
+		}
+		restriction.enabled = &enabled
+	}
+	return &restriction, nil
+}
+
+// appendUnique appends the entries that are not in the list yet.
+func appendUnique(list []string, entries []string) []string {
+	for _, entry := range entries {
+		found := false
+		for _, existing := range list {
+			if existing == entry {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// merge returns a new ipRestriction with the lists of both combined. Either can be nil.
+// The 'enabled' setting of 'other' takes precedence, if set.
+func (r *ipRestriction) merge(other *ipRestriction) *ipRestriction {
+	if r == nil {
+		return other
+	}
+	if other == nil {
+		return r
+	}
+	enabled := r.enabled
+	if other.enabled != nil {
+		enabled = other.enabled
+	}
+	return &ipRestriction{
+		allow:   appendUnique(appendUnique([]string{}, r.allow), other.allow),
+		deny:    appendUnique(appendUnique([]string{}, r.deny), other.deny),
+		enabled: enabled,
+	}
+}
+
+// insertIPRestrictionPlugin inserts an 'ip-restriction' plugin with the lists into the
+// plugin list. If the list already has an 'ip-restriction' plugin, then the lists will be
+// added to its configuration.
+func insertIPRestrictionPlugin(
+	list *[]*map[string]interface{},
+	restriction *ipRestriction,
+	uuidNamespace uuid.UUID,
+	baseName string,
+	tags []string,
+) *[]*map[string]interface{} {
+	if restriction == nil {
+		return list
+	}
+
+	plugin := map[string]interface{}{
+		"name": ipRestrictionPluginName,
+	}
+	config := make(map[string]interface{})
+	for _, existing := range *list {
+		if (*existing)["name"] == ipRestrictionPluginName {
+			plugin = *(jsonbasics.DeepCopyObject(existing))
+			config, _ = jsonbasics.ToObject(plugin["config"])
+			if config == nil {
+				config = make(map[string]interface{})
+			}
+			break
+		}
+	}
+
+	allow, _ := jsonbasics.GetStringArrayField(config, "allow")
+	deny, _ := jsonbasics.GetStringArrayField(config, "deny")
+	allow = appendUnique(allow, restriction.allow)
+	deny = appendUnique(deny, restriction.deny)
+	if len(allow) > 0 {
+		config["allow"] = allow
+	}
+	if len(deny) > 0 {
+		config["deny"] = deny
+	}
+
+	plugin["config"] = config
+	if restriction.enabled != nil {
+		plugin["enabled"] = *restriction.enabled
+	}
+	plugin["id"] = createPluginID(uuidNamespace, baseName, plugin)
+	plugin["tags"] = tags
+
+	return insertPlugin(list, &plugin)
+}
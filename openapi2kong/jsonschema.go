@@ -71,3 +71,180 @@ func extractSchema(s *openapi3.SchemaRef) string {
 	// update the $ref values; this is safe because plain " (double-quotes) would be escaped if in actual values
 	return strings.ReplaceAll(string(result), "\"$ref\":\"#/components/schemas/", "\"$ref\":\"#/definitions/")
 }
+
+// isObjectSchema returns true if the schema describes an object.
+func isObjectSchema(schema map[string]interface{}) bool {
+	return schema["type"] == "object" || schema["properties"] != nil
+}
+
+// allOfReferences collects the names of the definitions that are referenced as a member
+// of an 'allOf', recursively.
+func allOfReferences(node interface{}, names map[string]bool) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if members, ok := n["allOf"].([]interface{}); ok {
+			for _, member := range members {
+				if m, ok := member.(map[string]interface{}); ok {
+					if ref, ok := m["$ref"].(string); ok {
+						names[strings.TrimPrefix(ref, "#/definitions/")] = true
+					}
+				}
+			}
+		}
+		for _, value := range n {
+			allOfReferences(value, names)
+		}
+	case []interface{}:
+		for _, value := range n {
+			allOfReferences(value, names)
+		}
+	}
+}
+
+// disallowAdditionalProperties sets 'additionalProperties: false' on all object schemas
+// that do not specify it, recursively. Members of 'allOf' are not updated themselves
+// (only their sub-schemas), since that would reject the properties of the other members.
+// 'skip' holds the names of the definitions used as 'allOf' members.
+func disallowAdditionalProperties(node interface{}, inject bool, skip map[string]bool) {
+	schema, ok := node.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if inject && isObjectSchema(schema) && schema["additionalProperties"] == nil {
+		schema["additionalProperties"] = false
+	}
+
+	if subSchemas, ok := schema["properties"].(map[string]interface{}); ok {
+		for _, subSchema := range subSchemas {
+			disallowAdditionalProperties(subSchema, true, skip)
+		}
+	}
+	if subSchemas, ok := schema["definitions"].(map[string]interface{}); ok {
+		for name, subSchema := range subSchemas {
+			disallowAdditionalProperties(subSchema, !skip[name], skip)
+		}
+	}
+	for _, key := range []string{"items", "not", "additionalProperties"} {
+		disallowAdditionalProperties(schema[key], true, skip)
+	}
+	for _, key := range []string{"allOf", "anyOf", "oneOf"} {
+		if subSchemas, ok := schema[key].([]interface{}); ok {
+			for _, subSchema := range subSchemas {
+				disallowAdditionalProperties(subSchema, key != "allOf", skip)
+			}
+		}
+	}
+}
+
+// strictSchema returns the JSONschema string with 'additionalProperties: false' injected
+// in all object schemas that do not specify 'additionalProperties'.
+func strictSchema(schema string) string {
+	var finalSchema map[string]interface{}
+	if err := json.Unmarshal([]byte(schema), &finalSchema); err != nil {
+		return schema
+	}
+	skip := make(map[string]bool)
+	allOfReferences(finalSchema, skip)
+	disallowAdditionalProperties(finalSchema, true, skip)
+	result, _ := json.Marshal(finalSchema)
+	return string(result)
+}
+
+// requiredSchema returns the JSONschema string extended to reject a missing (null) body.
+// The plugin has no setting to require a body, so it is enforced through the schema.
+func requiredSchema(schema string) string {
+	var finalSchema map[string]interface{}
+	if err := json.Unmarshal([]byte(schema), &finalSchema); err != nil {
+		return schema
+	}
+	notNull := map[string]interface{}{"type": "null"}
+	if finalSchema["not"] == nil {
+		finalSchema["not"] = notNull
+	} else {
+		// already has a 'not', so wrap it. Definitions stay at the root for the $refs to resolve
+		wrapper := map[string]interface{}{"not": notNull}
+		if definitions, ok := finalSchema["definitions"]; ok {
+			wrapper["definitions"] = definitions
+			delete(finalSchema, "definitions")
+		}
+		wrapper["allOf"] = []interface{}{finalSchema}
+		finalSchema = wrapper
+	}
+	result, _ := json.Marshal(finalSchema)
+	return string(result)
+}
+
+// isReadOnlySchema returns true if the schema is 'readOnly', directly or through its '$ref'
+// to one of the definitions.
+func isReadOnlySchema(schema map[string]interface{}, definitions map[string]interface{}) bool {
+	if schema["readOnly"] == true {
+		return true
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		if definition, ok := definitions[strings.TrimPrefix(ref, "#/definitions/")].(map[string]interface{}); ok {
+			return definition["readOnly"] == true
+		}
+	}
+	return false
+}
+
+// unrequireReadOnly removes the 'readOnly' properties from the 'required' lists of all object
+// schemas, recursively. 'definitions' are the definitions of the root schema, to resolve
+// the '$ref's.
+func unrequireReadOnly(node interface{}, definitions map[string]interface{}) {
+	schema, ok := node.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	if required, ok := schema["required"].([]interface{}); ok && properties != nil {
+		kept := make([]interface{}, 0, len(required))
+		for _, name := range required {
+			property, _ := properties[name.(string)].(map[string]interface{})
+			if property == nil || !isReadOnlySchema(property, definitions) {
+				kept = append(kept, name)
+			}
+		}
+		if len(kept) > 0 {
+			schema["required"] = kept
+		} else {
+			delete(schema, "required")
+		}
+	}
+
+	for _, subSchema := range properties {
+		unrequireReadOnly(subSchema, definitions)
+	}
+	if subSchemas, ok := schema["definitions"].(map[string]interface{}); ok {
+		for _, subSchema := range subSchemas {
+			unrequireReadOnly(subSchema, definitions)
+		}
+	}
+	for _, key := range []string{"items", "not", "additionalProperties"} {
+		unrequireReadOnly(schema[key], definitions)
+	}
+	for _, key := range []string{"allOf", "anyOf", "oneOf"} {
+		if subSchemas, ok := schema[key].([]interface{}); ok {
+			for _, subSchema := range subSchemas {
+				unrequireReadOnly(subSchema, definitions)
+			}
+		}
+	}
+}
+
+// requestSchema returns the JSONschema string for validating requests; 'readOnly' properties
+// are server generated, so they are removed from the 'required' lists. The properties
+// themselves are kept, such that a client echoing them is not rejected (eg. with
+// 'additionalProperties: false'). 'writeOnly' properties are valid in requests, and are
+// left alone.
+func requestSchema(schema string) string {
+	var finalSchema map[string]interface{}
+	if err := json.Unmarshal([]byte(schema), &finalSchema); err != nil {
+		return schema
+	}
+	definitions, _ := finalSchema["definitions"].(map[string]interface{})
+	unrequireReadOnly(finalSchema, definitions)
+	result, _ := json.Marshal(finalSchema)
+	return string(result)
+}
@@ -0,0 +1,87 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/logbasics"
+)
+
+const serviceKeepaliveExtension = "x-kong-service-keepalive"
+
+// keepaliveRange is the range of valid values for a Kong service field.
+type keepaliveRange struct {
+	min int64
+	max int64
+}
+
+// keepaliveFields are the Kong service fields that can be set by the extension, with
+// the ranges as accepted by Kong.
+var keepaliveFields = map[string]keepaliveRange{
+	"retries":         {0, 32767},
+	"connect_timeout": {1, 2147483646},
+	"read_timeout":    {1, 2147483646},
+	"write_timeout":   {1, 2147483646},
+}
+
+// getServiceKeepalive returns the validated service fields from the 'x-kong-service-keepalive'
+// extension, or nil if not present. Values out of range are dropped with a warning, since Kong
+// would reject them.
+func getServiceKeepalive(props openapi3.ExtensionProps, components *map[string]interface{},
+) (map[string]interface{}, error) {
+	jsonBytes, err := getXKongObject(props, serviceKeepaliveExtension, components)
+	if err != nil || jsonBytes == nil {
+		return nil, err
+	}
+	var keepalive map[string]interface{}
+	_ = json.Unmarshal(jsonBytes, &keepalive)
+
+	fieldNames := make([]string, 0, len(keepalive))
+	for fieldName := range keepalive {
+		fieldNames = append(fieldNames, fieldName)
+	}
+	sort.Strings(fieldNames)
+
+	result := make(map[string]interface{})
+	for _, fieldName := range fieldNames {
+		valid, found := keepaliveFields[fieldName]
+		if !found {
+			return nil, fmt.Errorf("unknown field '%s' in '%s'", fieldName, serviceKeepaliveExtension)
+		}
+		value, ok := keepalive[fieldName].(float64)
+		if !ok || value != math.Trunc(value) {
+			return nil, fmt.Errorf("expected '%s.%s' to be an integer", serviceKeepaliveExtension, fieldName)
+		}
+		if value < float64(valid.min) || value > float64(valid.max) {
+			logbasics.Warn("value out of range, ignoring it", "field", serviceKeepaliveExtension+"."+fieldName,
+				"value", value, "min", valid.min, "max", valid.max)
+			continue
+		}
+		result[fieldName] = int64(value)
+	}
+	return result, nil
+}
+
+// applyServiceKeepalive returns the service defaults (JSON string) with the fields from the
+// 'x-kong-service-keepalive' extension added. The extension fields take precedence. If neither
+// is given it returns nil.
+func applyServiceKeepalive(props openapi3.ExtensionProps, serviceDefaults []byte,
+	components *map[string]interface{},
+) ([]byte, error) {
+	keepalive, err := getServiceKeepalive(props, components)
+	if err != nil || keepalive == nil {
+		return serviceDefaults, err
+	}
+
+	defaults := make(map[string]interface{})
+	if serviceDefaults != nil {
+		_ = json.Unmarshal(serviceDefaults, &defaults)
+	}
+	for fieldName, value := range keepalive {
+		defaults[fieldName] = value
+	}
+	return json.Marshal(defaults)
+}
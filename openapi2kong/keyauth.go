@@ -0,0 +1,85 @@
+package openapi2kong
+
+import (
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/jsonbasics"
+	"github.com/kong/go-apiops/logbasics"
+)
+
+// getAPIKeyNames returns the sorted key names of the 'apiKey' security schemes referenced by
+// the security requirements, and whether they are passed in headers and/or query parameters.
+func getAPIKeyNames(security *openapi3.SecurityRequirements, schemes openapi3.SecuritySchemes,
+) (names []string, inHeader bool, inQuery bool) {
+	if security == nil {
+		return nil, false, false
+	}
+
+	seen := make(map[string]bool)
+	for _, requirement := range *security {
+		for schemeName := range requirement {
+			schemeRef := schemes[schemeName]
+			if schemeRef == nil || schemeRef.Value == nil || schemeRef.Value.Type != "apiKey" {
+				continue
+			}
+			scheme := schemeRef.Value
+			switch scheme.In {
+			case "header":
+				inHeader = true
+			case "query":
+				inQuery = true
+			default:
+				logbasics.Warn("the key-auth plugin does not support api keys in '"+scheme.In+"', ignoring it",
+					"securityScheme", schemeName)
+				continue
+			}
+			if !seen[scheme.Name] {
+				seen[scheme.Name] = true
+				names = append(names, scheme.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, inHeader, inQuery
+}
+
+// fillKeyAuthPlugin completes the config of the 'key-auth' plugin in the list (if any), based
+// on the 'apiKey' security schemes in effect. The 'key_names' are only set if not given in
+// the plugin config, and similarly 'key_in_header' and 'key_in_query'.
+func fillKeyAuthPlugin(
+	pluginList *[]*map[string]interface{},
+	security *openapi3.SecurityRequirements,
+	schemes openapi3.SecuritySchemes,
+) {
+	if pluginList == nil {
+		return
+	}
+	for _, plugin := range *pluginList {
+		if (*plugin)["name"] != "key-auth" {
+			continue
+		}
+
+		config, _ := jsonbasics.ToObject((*plugin)["config"])
+		if config != nil && config["key_names"] != nil {
+			return // explicitly configured, nothing to do
+		}
+		names, inHeader, inQuery := getAPIKeyNames(security, schemes)
+		if len(names) == 0 {
+			return
+		}
+
+		if config == nil {
+			config = make(map[string]interface{})
+			(*plugin)["config"] = config
+		}
+		config["key_names"] = names
+		if config["key_in_header"] == nil {
+			config["key_in_header"] = inHeader
+		}
+		if config["key_in_query"] == nil {
+			config["key_in_query"] = inQuery
+		}
+		return
+	}
+}
@@ -0,0 +1,41 @@
+package openapi2kong
+
+import (
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// getLinkTags returns the 'link:<name>' tags for the links of the operation responses,
+// sorted and deduplicated. Returns nil if there are none.
+func getLinkTags(operation *openapi3.Operation) []string {
+	names := make(map[string]bool)
+	for _, responseRef := range operation.Responses {
+		if responseRef == nil || responseRef.Value == nil {
+			continue
+		}
+		for name := range responseRef.Value.Links {
+			names[name] = true
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	tags := make([]string, 0, len(names))
+	for name := range names {
+		tags = append(tags, "link:"+docsTagReplacer.Replace(name))
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// addLinkTags returns the tags with the 'link:<name>' tags of the operation added. The
+// original tags array is not modified.
+func addLinkTags(tags []string, operation *openapi3.Operation) []string {
+	linkTags := getLinkTags(operation)
+	if linkTags == nil {
+		return tags
+	}
+	return append(append(make([]string, 0, len(tags)+len(linkTags)), tags...), linkTags...)
+}
@@ -0,0 +1,146 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/logbasics"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	mockStatusExtension = "x-kong-mock-status"
+	mockPluginName      = "request-termination"
+)
+
+// getMockStatus returns the status code of the response to mock for the operation. This is
+// the 'x-kong-mock-status' extension if given (it must exist in the responses), or the lowest
+// 2xx response. Returns "" if there is none.
+func getMockStatus(operation *openapi3.Operation) (string, error) {
+	if operation.Extensions != nil && operation.Extensions[mockStatusExtension] != nil {
+		var status interface{}
+		_ = json.Unmarshal(operation.Extensions[mockStatusExtension].(json.RawMessage), &status)
+		var statusCode string
+		switch s := status.(type) {
+		case float64:
+			statusCode = strconv.Itoa(int(s))
+		case string:
+			statusCode = s
+		default:
+			return "", fmt.Errorf("expected '%s' to be a status code", mockStatusExtension)
+		}
+		if operation.Responses[statusCode] == nil {
+			return "", fmt.Errorf("'%s' response '%s' not found in the operation responses",
+				mockStatusExtension, statusCode)
+		}
+		return statusCode, nil
+	}
+
+	successCodes := make([]string, 0)
+	for statusCode := range operation.Responses {
+		if code, err := strconv.Atoi(statusCode); err == nil && code >= 200 && code <= 299 {
+			successCodes = append(successCodes, statusCode)
+		}
+	}
+	if len(successCodes) == 0 {
+		return "", nil
+	}
+	sort.Strings(successCodes) // all 3 digits, so sorting as strings is fine
+	return successCodes[0], nil
+}
+
+// getMockBody returns the content-type and body of the example for the response, or "" if
+// there is no example. JSON content types are preferred.
+func getMockBody(response *openapi3.Response) (string, string) {
+	contentTypes := make([]string, 0, len(response.Content))
+	for contentType := range response.Content {
+		contentTypes = append(contentTypes, contentType)
+	}
+	sort.Slice(contentTypes, func(i, j int) bool {
+		iJSON := strings.Contains(strings.ToLower(contentTypes[i]), "json")
+		jJSON := strings.Contains(strings.ToLower(contentTypes[j]), "json")
+		if iJSON != jJSON {
+			return iJSON
+		}
+		return contentTypes[i] < contentTypes[j]
+	})
+
+	for _, contentType := range contentTypes {
+		mediaType := response.Content[contentType]
+		example := mediaType.Example
+		if example == nil && len(mediaType.Examples) > 0 {
+			names := make([]string, 0, len(mediaType.Examples))
+			for name := range mediaType.Examples {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if exampleRef := mediaType.Examples[names[0]]; exampleRef != nil && exampleRef.Value != nil {
+				example = exampleRef.Value.Value
+			}
+		}
+		if example == nil {
+			continue
+		}
+		if body, ok := example.(string); ok && !strings.Contains(strings.ToLower(contentType), "json") {
+			return contentType, body
+		}
+		body, _ := json.Marshal(example)
+		return contentType, string(body)
+	}
+	return "", ""
+}
+
+// isNoContentStatus returns true for the status codes of responses without a body;
+// 204 (No Content), 205 (Reset Content), and 304 (Not Modified).
+func isNoContentStatus(code int) bool {
+	return code == 204 || code == 205 || code == 304
+}
+
+// generateMockPlugin returns a 'request-termination' plugin that returns the mocked response
+// of the operation, or nil if there is no response to mock. Responses without a body (eg.
+// 204) get no 'body' and 'content_type', even if the spec has an example for them.
+func generateMockPlugin(
+	operation *openapi3.Operation,
+	uuidNamespace uuid.UUID,
+	baseName string,
+	tags []string,
+) (*map[string]interface{}, error) {
+	statusCode, err := getMockStatus(operation)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode == "" {
+		logbasics.Info("no success response to mock", "operation", baseName)
+		return nil, nil
+	}
+	logbasics.Debug("generating mock plugin", "operation", baseName, "status", statusCode)
+
+	code, _ := strconv.Atoi(statusCode)
+	config := map[string]interface{}{
+		"status_code": code,
+	}
+	if response := operation.Responses[statusCode].Value; response != nil {
+		if contentType, body := getMockBody(response); body != "" {
+			if isNoContentStatus(code) {
+				// a body would break clients, the 'request-termination' plugin sends none by default
+				logbasics.Warn("skipping the example body for a mocked '"+statusCode+"' response",
+					"operation", baseName)
+			} else {
+				config["content_type"] = contentType
+				config["body"] = body
+			}
+		}
+	}
+
+	plugin := map[string]interface{}{
+		"name":   mockPluginName,
+		"config": config,
+		"tags":   tags,
+	}
+	plugin["id"] = createPluginID(uuidNamespace, baseName, plugin)
+	return &plugin, nil
+}
@@ -0,0 +1,70 @@
+package openapi2kong
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const (
+	// MultiBackendSplit generates a service for each operation with its own servers, with
+	// the routes of the path split over those services (default).
+	MultiBackendSplit = "split"
+	// MultiBackendError returns an error if operations on the same path have different
+	// servers, for setups expecting a single backend per path.
+	MultiBackendError = "error"
+)
+
+// validateMultiBackendPolicy returns an error if the policy is unknown.
+func validateMultiBackendPolicy(policy string) error {
+	switch policy {
+	case "", MultiBackendSplit, MultiBackendError:
+		return nil
+	}
+	return fmt.Errorf("expected multi-backend policy to be one of '%s', or '%s', got: '%s'",
+		MultiBackendSplit, MultiBackendError, policy)
+}
+
+// serversKey returns the urls of the servers, as a string to compare server blocks.
+func serversKey(servers *openapi3.Servers) string {
+	urls := make([]string, 0, len(*servers))
+	for _, server := range *servers {
+		urls = append(urls, server.URL)
+	}
+	sort.Strings(urls)
+	return strings.Join(urls, "', '")
+}
+
+// checkMultiBackend returns an error if the policy is MultiBackendError, and the operations
+// of the path have different servers in effect (their own, or the 'pathServers').
+func checkMultiBackend(path string, pathitem *openapi3.PathItem, pathServers *openapi3.Servers,
+	policy string,
+) error {
+	if policy != MultiBackendError {
+		return nil
+	}
+
+	backends := make(map[string][]string) // servers key -> methods using them
+	for method, operation := range pathitem.Operations() {
+		servers := operation.Servers
+		if servers == nil || len(*servers) == 0 {
+			servers = pathServers
+		}
+		key := serversKey(servers)
+		backends[key] = append(backends[key], method)
+	}
+	if len(backends) < 2 {
+		return nil
+	}
+
+	groups := make([]string, 0, len(backends))
+	for key, methods := range backends {
+		sort.Strings(methods)
+		groups = append(groups, fmt.Sprintf("%s ('%s')", strings.Join(methods, ", "), key))
+	}
+	sort.Strings(groups)
+	return fmt.Errorf("the operations on path '%s' have different servers: %s", path,
+		strings.Join(groups, "; "))
+}
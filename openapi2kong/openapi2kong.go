@@ -1,30 +1,240 @@
 package openapi2kong
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/url"
+	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/deckformat"
 	"github.com/kong/go-apiops/jsonbasics"
 	"github.com/kong/go-apiops/logbasics"
+	"github.com/kong/go-apiops/refresolver"
 	"github.com/mozillazg/go-slugify"
 	uuid "github.com/satori/go.uuid"
 )
 
-const (
-	formatVersionKey   = "_format_version"
-	formatVersionValue = "3.0"
-)
+// defaultFormatVersion is the '_format_version' of the output, if not set in the options
+const defaultFormatVersion = "3.0"
+
+// serviceNameExtension sets the name of the generated service, independent of the uuid-base
+const serviceNameExtension = "x-kong-service-name"
+
+// tagsRemoveExtension removes inherited tags from the entities generated for an operation
+const tagsRemoveExtension = "x-kong-tags-remove"
+
+// emittableSections are the top-level sections that can be selected using O2kOptions.EmitSections
+var emittableSections = []string{"consumers", "plugins", "services", "snis", "upstreams", "vaults"}
+
+// validateEmitSections returns an error if any of the sections is unknown.
+func validateEmitSections(sections []string) error {
+	for _, section := range sections {
+		found := false
+		for _, known := range emittableSections {
+			if section == known {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown section '%s' requested, expected one of: '%s'", section,
+				strings.Join(emittableSections, "', '"))
+		}
+	}
+	return nil
+}
+
+// filterSections removes all non-requested top-level sections from the result.
+// Meta fields (starting with '_') are always retained.
+func filterSections(result map[string]interface{}, sections []string) {
+	if len(sections) == 0 {
+		return
+	}
+	keep := make(map[string]bool)
+	for _, section := range sections {
+		keep[section] = true
+	}
+	for key := range result {
+		if !keep[key] && !strings.HasPrefix(key, "_") {
+			delete(result, key)
+		}
+	}
+}
 
 // O2KOptions defines the options for an O2K conversion operation
 type O2kOptions struct {
 	Tags          *[]string // Array of tags to mark all generated entities with, taken from 'x-kong-tags' if omitted.
 	DocName       string    // Base document name, will be taken from x-kong-name, or info.title (for UUID generation!)
 	UUIDNamespace uuid.UUID // Namespace for UUID generation, defaults to DNS namespace for UUID v5
+	SpecFilename  string    // Filename of the spec, used as DocName if there is no x-kong-name nor info.title
+	// Return an error instead of generating a random DocName, if none of the above is available.
+	// A random name results in different IDs on every run.
+	RequireDocName bool
+	// Add a 'request-termination' plugin to every route, returning the response selected by
+	// 'x-kong-mock-status' (or the lowest 2xx response), with its example as body
+	GenerateMock bool
+	// Default 'strip_path' value for the generated routes, if unset it is false. Can be
+	// overridden by 'x-kong-strip-path' on paths and operations.
+	StripPath *bool
+	// Block operations flagged 'x-internal: true' (on the operation or path) with a
+	// 'request-termination' plugin (HTTP 403). The route is generated, but blocked at the edge.
+	// The response can be set per operation with 'x-kong-termination'.
+	BlockInternal bool
+	// The HTTP status of the response blocking the internal operations (see BlockInternal),
+	// defaults to DefaultTerminationStatus (403).
+	TerminationStatus int
+	// Skip generating routes for operations that do not have a 2xx response defined
+	RequireSuccessResponse bool
+	// How a plugin on a lower level (path, operation) relates to the same plugin on a higher
+	// level; PluginMergeReplace (default) uses the lower one as is, PluginMergeMerge
+	// deep-merges it into the higher one, so it only overrides the fields it sets
+	PluginMergeStrategy string
+	// Add a 'request-transformer' plugin to every generated service, adding the TracingHeaders.
+	// If a 'request-transformer' is already configured, the headers are added to it.
+	AddTracingHeaders bool
+	// Headers to add (if absent) in "name:value" format, defaults to DefaultTracingHeaders
+	TracingHeaders []string
+	// Add a 'correlation-id' plugin to every generated service, unless it already has one
+	// (eg. from 'x-kong-plugin-correlation-id').
+	EnsureCorrelationID bool
+	// The 'header_name' of the added 'correlation-id' plugin, defaults to DefaultCorrelationIDHeader
+	CorrelationIDHeader string
+	// The 'generator' of the added 'correlation-id' plugin, defaults to DefaultCorrelationIDGenerator
+	CorrelationIDGenerator string
+	// Add a 'request-transformer' plugin to the routes, adding the 'schema.default' values of
+	// query and header parameters, if absent in the request
+	ParameterDefaults bool
+	// Policy for paths that differ only by a trailing slash; TrailingSlashStrict (default),
+	// TrailingSlashMerge, or TrailingSlashStrip
+	TrailingSlash string
+	// How the path of the servers combines with the operation paths; PathStrategyOperation
+	// (default) routes on the operation path and proxies to the server path + operation path.
+	// PathStrategyPrefix routes on, and proxies to, the server path + operation path.
+	PathStrategy string
+	// How operations on the same path, with different servers in effect, are handled;
+	// MultiBackendSplit (default) generates a service for each operation with its own
+	// servers. MultiBackendError returns an error instead.
+	MultiBackendPolicy string
+	// Validate the configuration of known plugins against their schemas. Vault references
+	// (eg. "{vault://env/my-secret}") are accepted for any field, and passed through as is.
+	ValidatePluginConfig bool
+	// Top-level sections to output (eg. "plugins"), if empty then all sections are included
+	EmitSections []string
+	// Populate the route 'hosts' from the hostnames of the servers in effect. Routes will then
+	// match on both host and path. Note: the service 'host' is where Kong proxies to, the route
+	// 'hosts' are matched against incoming requests, so clients must use the server hostnames
+	// when calling Kong. Hosts from 'x-kong-route-defaults' take precedence.
+	RouteByHost bool
+	// Generate top-level 'snis' from the hostnames of the servers in effect for the routes
+	// (wildcards are retained). They refer to a certificate placeholder id, the certificate
+	// itself is not generated.
+	GenerateSNIs bool
+	// Prefix for all generated entity names, and the uuid generation. Taken from
+	// 'x-kong-name-prefix' if omitted.
+	NamePrefix string
+	// Return an error if no routes are generated, eg. when the spec has no paths
+	RequireRoutes bool
+	// Directory to resolve file references against (eg. in 'x-kong-pre-function'), defaults
+	// to the current directory
+	BaseDir string
+	// Preserve the 'externalDocs' url of the spec and operations, as a 'docs:<url>' tag
+	// on the generated services and routes
+	PreserveDescriptions bool
+	// Add an 'apiversion:<version>' tag with the 'info.version' of the spec to all generated
+	// entities, for release tracking. Skipped with a warning if there is no version.
+	TagSpecVersion bool
+	// Together with PreserveDescriptions, record the names of the 'links' of the operation
+	// responses as 'link:<name>' tags on the generated routes
+	PreserveLinks bool
+	// Reject unknown fields in generated request-validator body schemas, by setting
+	// 'additionalProperties: false' on object schemas that do not specify it
+	StrictValidation bool
+	// Return an error listing the 'x-kong-...' extensions that are not consumed by the
+	// converter; unknown ones (eg. typos), and ones used on an unsupported level
+	StrictExtensions bool
+	// Only output the top-level 'plugins', referring to the services and routes by the names
+	// the full conversion generates. For use with services and routes managed separately.
+	PluginOverlayOnly bool
+	// Group the operations by their first OpenAPI tag, into a service per tag (named
+	// "<doc name>_<tag>"), instead of the single document level service. Operations without
+	// tags remain on the document level service. Path and operation level services are
+	// not affected.
+	ServicePerTag bool
+	// The '_format_version' of the output, in 'x.y' format, to match the targeted decK
+	// version. Defaults to "3.0".
+	FormatVersion string
+	// Generate routes for the operations of the callbacks ('callbacks' on the operations),
+	// tagged "callback". The host of a callback url is dropped (a templated one with a
+	// warning), the path is matched, with runtime expressions as path parameters.
+	IncludeCallbacks bool
+	// Tighten the regex captures of path parameters to the values allowed by their schema;
+	// an 'enum' becomes an alternation of its values (eg. "(?<status>open|closed)").
+	UseParamPatterns bool
+	// The 'path_handling' of the generated routes; PathHandlingV0 or PathHandlingV1. If
+	// empty it is not set (Kong defaults to v0). Can be overridden by 'x-kong-route-defaults',
+	// and by 'x-kong-path-handling' on paths and operations.
+	PathHandling string
+	// Preserve the request body and response examples of the operations on the generated
+	// routes, for contract-test tooling. They are added to the '_ignore' field of the route
+	// (opaque data ignored by Kong) as an '{ "examples": ... }' entry. Examples larger than
+	// 4kb (serialized) are skipped with a warning.
+	PreserveExamples bool
+	// Add auth plugins to the routes, for the security schemes in the 'security' requirements
+	// in effect (of the operation, or the document); 'basic-auth' for 'http' schemes with
+	// scheme 'basic', 'oauth2' for 'oauth2' schemes, and 'hmac-auth' for schemes with an
+	// 'x-kong-hmac' hint. Plugins that are already configured are not added again.
+	GenerateAuthPlugins bool
+	// Overrides the security scheme to plugin mapping of GenerateAuthPlugins (see
+	// DefaultSecurityMapping). The keys are a scheme name (in 'components.securitySchemes'),
+	// "http:<scheme>" (eg. "http:bearer"), or a scheme type (eg. "oauth2"), in order of
+	// precedence. The value is the plugin name, or "" for no plugin.
+	SecurityMapping map[string]string
+	// Add a 'response-transformer' plugin to the routes of 'deprecated' operations, adding a
+	// 'Deprecation' header, and a 'Sunset' header if the operation has an 'x-sunset' date.
+	MarkDeprecated bool
+	// Emit the generated names on the services and routes, defaults to true. Without names,
+	// the top-level plugins refer to the services and routes by id.
+	EmitNames *bool
+	// Emit the generated ids on the entities, defaults to true. At least one of EmitNames
+	// and EmitIDs must be enabled.
+	EmitIDs *bool
+	// The protocol for server urls without a scheme (eg. "//example.com/api"); "http" or
+	// "https", defaults to "https". A port 80 or 443 still implies "http" or "https".
+	DefaultProtocol string
+	// Set 'request_buffering' and 'response_buffering' to false on all generated routes, for
+	// streaming APIs. Can be overridden by 'x-kong-streaming' on operations.
+	DisableBuffering bool
+	// The url the spec is served from, to resolve relative server urls (eg. "/api/v1")
+	// against, before deriving the service host and path. Must be an absolute url. If
+	// omitted, relative server urls default to host 'localhost', with a warning.
+	SpecBaseURL string
+	// Check that all '$ref's resolve before converting, defaults to true. Internal and
+	// external (file) references are checked, and all unresolvable ones are reported, with
+	// their location. Remote (url) references are only checked to be allowed (see AllowRemoteRefs).
+	ValidateRefs *bool
+	// Allow '$ref's to local files, which must be within the BaseDir (or the directory of the
+	// SpecFilename, see BaseDir). Off by default, since the spec could read any file otherwise.
+	AllowExternalRefs bool
+	// Allow '$ref's to remote documents (http and https urls), which are then fetched. Off by
+	// default, since the spec could have requests made to any url otherwise.
+	AllowRemoteRefs bool
+}
+
+// O2kInfo contains information about a completed O2K conversion operation
+type O2kInfo struct {
+	DocName string // The resolved (slugified) base document name used for UUID generation
+	// The generated services and routes, with the operations the routes were generated from,
+	// eg. for a documentation portal
+	Summary []ServiceSummary
+	// The number of generated entities, and of skipped operations
+	Stats O2kStats
 }
 
 // setDefaults sets the defaults for the OpenAPI2Kong operation.
@@ -33,6 +243,24 @@ func (opts *O2kOptions) setDefaults() {
 	if uuid.Equal(emptyUUID, opts.UUIDNamespace) {
 		opts.UUIDNamespace = uuid.NamespaceDNS
 	}
+	if opts.AddTracingHeaders && len(opts.TracingHeaders) == 0 {
+		opts.TracingHeaders = DefaultTracingHeaders
+	}
+	if opts.EnsureCorrelationID && opts.CorrelationIDHeader == "" {
+		opts.CorrelationIDHeader = DefaultCorrelationIDHeader
+	}
+	if opts.EnsureCorrelationID && opts.CorrelationIDGenerator == "" {
+		opts.CorrelationIDGenerator = DefaultCorrelationIDGenerator
+	}
+	if opts.FormatVersion == "" {
+		opts.FormatVersion = defaultFormatVersion
+	}
+	if opts.TerminationStatus == 0 {
+		opts.TerminationStatus = DefaultTerminationStatus
+	}
+	if opts.DefaultProtocol == "" {
+		opts.DefaultProtocol = httpsScheme
+	}
 }
 
 // Slugify converts a name to a valid Kong name by removing and replacing unallowed characters
@@ -51,14 +279,90 @@ func Slugify(name ...string) string {
 func sanitizeRegexCapture(varName string) string {
 	varName = slugify.Slugify(varName)
 	varName = strings.ReplaceAll(varName, "-", "_")
-	if strings.HasPrefix(varName, "_") {
+	if varName == "" || varName[0] == '_' || (varName[0] >= '0' && varName[0] <= '9') {
 		varName = "a" + varName
 	}
 	return varName
 }
 
+// uniqueRegexCapture returns the capture name, with a "_<n>" suffix added if it was already
+// used (eg. "{user-id}" and "{user_id}" sanitize to the same name). PCRE does not allow
+// duplicate capture names. The name is added to the used ones.
+func uniqueRegexCapture(captureName string, used map[string]bool) string {
+	uniqueName := captureName
+	for i := 2; used[uniqueName]; i++ {
+		uniqueName = captureName + "_" + strconv.Itoa(i)
+	}
+	used[uniqueName] = true
+	return uniqueName
+}
+
+// docsTagReplacer encodes the characters that Kong doesn't allow in tags.
+var docsTagReplacer = strings.NewReplacer("%", "%25", ",", "%2C", "/", "%2F")
+
+// getDocsTag returns the 'docs:<url>' tag for the externalDocs, or "" if there is none.
+// Kong doesn't allow ',' and '/' in tags, so they are encoded. Urls with characters
+// that cannot be encoded are skipped with a warning.
+func getDocsTag(externalDocs *openapi3.ExternalDocs) string {
+	if externalDocs == nil || externalDocs.URL == "" {
+		return ""
+	}
+	for _, char := range externalDocs.URL {
+		if char < 0x20 || char == 0x7f {
+			logbasics.Warn("skipping 'externalDocs.url' with characters invalid for tags", "url", externalDocs.URL)
+			return ""
+		}
+	}
+	return "docs:" + docsTagReplacer.Replace(externalDocs.URL)
+}
+
+// getVersionTag returns the 'apiversion:<version>' tag for the 'info.version' of the spec,
+// encoded like the docs tag. Returns "" with a warning if there is no (valid) version.
+func getVersionTag(info *openapi3.Info) string {
+	if info == nil || info.Version == "" {
+		logbasics.Warn("no 'info.version' in the spec, skipping the version tag")
+		return ""
+	}
+	for _, char := range info.Version {
+		if char < 0x20 || char == 0x7f {
+			logbasics.Warn("skipping 'info.version' with characters invalid for tags", "version", info.Version)
+			return ""
+		}
+	}
+	return "apiversion:" + docsTagReplacer.Replace(info.Version)
+}
+
+// addDocsTag returns the tags with the 'docs:<url>' tag for the externalDocs added. The
+// original tags array is not modified.
+func addDocsTag(tags []string, externalDocs *openapi3.ExternalDocs) []string {
+	docsTag := getDocsTag(externalDocs)
+	if docsTag == "" {
+		return tags
+	}
+	return append(append(make([]string, 0, len(tags)+1), tags...), docsTag)
+}
+
+// getSpecLocation returns the location of the spec, to resolve external references against.
+// Based on BaseDir and SpecFilename, defaults to the current directory.
+func getSpecLocation(opts O2kOptions) *url.URL {
+	name := "-"
+	dir := opts.BaseDir
+	if opts.SpecFilename != "" && opts.SpecFilename != "-" {
+		name = filepath.Base(opts.SpecFilename)
+		if dir == "" {
+			dir = filepath.Dir(opts.SpecFilename)
+		}
+	}
+	location, err := filepath.Abs(filepath.Join(dir, name))
+	if err != nil {
+		location = filepath.Join(dir, name)
+	}
+	return &url.URL{Path: filepath.ToSlash(location)}
+}
+
 // getKongTags returns the provided tags or if nil, then the `x-kong-tags` property,
-// validated to be a string array. If there is no error, then there will always be
+// validated to be a string array. The provided tags (eg. the CLI '--select-tag') replace
+// the `x-kong-tags`, they are not merged. If there is no error, then there will always be
 // an array returned for safe access later in the process.
 func getKongTags(doc *openapi3.T, tagsProvided *[]string) ([]string, error) {
 	if tagsProvided != nil {
@@ -97,6 +401,57 @@ func getKongTags(doc *openapi3.T, tagsProvided *[]string) ([]string, error) {
 	return resultArray, nil
 }
 
+// getKongTagsRemove returns the `x-kong-tags-remove` property, validated to be a string
+// array. Returns nil if not set.
+func getKongTagsRemove(props openapi3.ExtensionProps) ([]string, error) {
+	if props.Extensions == nil || props.Extensions[tagsRemoveExtension] == nil {
+		return nil, nil
+	}
+	var tags []string
+	err := json.Unmarshal(props.Extensions[tagsRemoveExtension].(json.RawMessage), &tags)
+	if err != nil {
+		return nil, fmt.Errorf("expected '%s' to be an array of strings: %w", tagsRemoveExtension, err)
+	}
+	return tags, nil
+}
+
+// removeTags returns a copy of the inherited tags, without the ones listed in the
+// `x-kong-tags-remove` property. Tags to remove that are not inherited are logged as a
+// warning. Returns the inherited tags as is, if the property is not set.
+func removeTags(inherited []string, props openapi3.ExtensionProps) ([]string, error) {
+	remove, err := getKongTagsRemove(props)
+	if err != nil || remove == nil {
+		return inherited, err
+	}
+
+	removed := make(map[string]bool, len(remove))
+	for _, tag := range remove {
+		removed[tag] = false
+	}
+	result := make([]string, 0, len(inherited))
+	for _, tag := range inherited {
+		if _, found := removed[tag]; found {
+			removed[tag] = true
+		} else {
+			result = append(result, tag)
+		}
+	}
+	for _, tag := range remove {
+		if !removed[tag] {
+			logbasics.Warn("tag in '"+tagsRemoveExtension+"' is not inherited, ignoring it", "tag", tag)
+		}
+	}
+	return result, nil
+}
+
+// sortedUniqueTags returns a sorted copy of the tags, without duplicates, such that the
+// output is deterministic.
+func sortedUniqueTags(tags []string) []string {
+	result := appendUnique(make([]string, 0, len(tags)), tags)
+	sort.Strings(result)
+	return result
+}
+
 // getKongName returns the `x-kong-name` property, validated to be a string
 func getKongName(props openapi3.ExtensionProps) (string, error) {
 	if props.Extensions != nil && props.Extensions["x-kong-name"] != nil {
@@ -110,6 +465,21 @@ func getKongName(props openapi3.ExtensionProps) (string, error) {
 	return "", nil
 }
 
+// getServiceName returns the slugified 'x-kong-service-name' extension, the explicit name of
+// the generated service. Unlike 'x-kong-name' it does not affect the uuid generation, nor the
+// names of the other entities. Returns "" if not set.
+func getServiceName(props openapi3.ExtensionProps) (string, error) {
+	if props.Extensions != nil && props.Extensions[serviceNameExtension] != nil {
+		var name string
+		err := json.Unmarshal(props.Extensions[serviceNameExtension].(json.RawMessage), &name)
+		if err != nil {
+			return "", fmt.Errorf("expected '%s' to be a string: %w", serviceNameExtension, err)
+		}
+		return Slugify(name), nil
+	}
+	return "", nil
+}
+
 func dereferenceJSONObject(
 	value map[string]interface{},
 	components *map[string]interface{},
@@ -201,9 +571,20 @@ func getXKongComponents(doc *openapi3.T) (*map[string]interface{}, error) {
 	return &components, nil
 }
 
-// getServiceDefaults returns a JSON string containing the defaults
+// getServiceDefaults returns a JSON string containing the defaults, including the
+// fields from 'x-kong-service-keepalive' and the shorthands (eg. 'x-kong-read-timeout').
+// Route fields are dropped with a warning.
 func getServiceDefaults(props openapi3.ExtensionProps, components *map[string]interface{}) ([]byte, error) {
-	return getXKongObject(props, "x-kong-service-defaults", components)
+	serviceDefaults, err := getXKongObject(props, "x-kong-service-defaults", components)
+	if err != nil {
+		return nil, err
+	}
+	serviceDefaults = dropMisplacedFields(serviceDefaults, "x-kong-service-defaults", routeOnlyFields,
+		"x-kong-route-defaults")
+	if serviceDefaults, err = applyServiceKeepalive(props, serviceDefaults, components); err != nil {
+		return nil, err
+	}
+	return applyShorthands(props, serviceDefaults, serviceShorthands)
 }
 
 // getUpstreamDefaults returns a JSON string containing the defaults
@@ -211,9 +592,17 @@ func getUpstreamDefaults(props openapi3.ExtensionProps, components *map[string]i
 	return getXKongObject(props, "x-kong-upstream-defaults", components)
 }
 
-// getRouteDefaults returns a JSON string containing the defaults
+// getRouteDefaults returns a JSON string containing the defaults, including the fields from
+// the shorthands (eg. 'x-kong-preserve-host'). Service fields (eg. the timeouts and retries)
+// are dropped with a warning.
 func getRouteDefaults(props openapi3.ExtensionProps, components *map[string]interface{}) ([]byte, error) {
-	return getXKongObject(props, "x-kong-route-defaults", components)
+	routeDefaults, err := getXKongObject(props, "x-kong-route-defaults", components)
+	if err != nil {
+		return nil, err
+	}
+	routeDefaults = dropMisplacedFields(routeDefaults, "x-kong-route-defaults", serviceOnlyFields,
+		"x-kong-service-defaults")
+	return applyShorthands(props, routeDefaults, routeShorthands)
 }
 
 // create plugin id
@@ -223,9 +612,57 @@ func createPluginID(uuidNamespace uuid.UUID, baseName string, config map[string]
 	return uuid.NewV5(uuidNamespace, baseName+".plugin."+pluginName).String()
 }
 
+// createPluginInstanceID creates the id for an instance of a plugin, when there are
+// multiple by the same name. The first instance gets the same id as a single plugin.
+func createPluginInstanceID(uuidNamespace uuid.UUID, baseName string, config map[string]interface{},
+	instance int,
+) string {
+	if instance == 0 {
+		return createPluginID(uuidNamespace, baseName, config)
+	}
+	pluginName := config["name"].(string) // safe because it was previously parsed
+
+	return uuid.NewV5(uuidNamespace, baseName+".plugin."+pluginName+"."+strconv.Itoa(instance)).String()
+}
+
+// getXKongPluginObjects returns the JSON strings of the plugin instances in a
+// 'x-kong-plugin-<name>' extension. The extension is either a single object, or an
+// array of objects for multiple instances of the plugin.
+func getXKongPluginObjects(props openapi3.ExtensionProps, key string,
+	components *map[string]interface{},
+) ([][]byte, error) {
+	var jsonBlob interface{}
+	_ = json.Unmarshal(props.Extensions[key].(json.RawMessage), &jsonBlob)
+	values, isArray := jsonBlob.([]interface{})
+	if !isArray {
+		jsonstr, err := getXKongObject(props, key, components)
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{jsonstr}, nil
+	}
+
+	instances := make([][]byte, 0, len(values))
+	for i, value := range values {
+		jsonObject, err := jsonbasics.ToObject(value)
+		if err != nil {
+			return nil, fmt.Errorf("expected entry %d of '%s' to be a JSON object", i+1, key)
+		}
+		object, err := dereferenceJSONObject(jsonObject, components)
+		if err != nil {
+			return nil, err
+		}
+		jsonstr, _ := json.Marshal(object)
+		instances = append(instances, jsonstr)
+	}
+	return instances, nil
+}
+
 // getPluginsList returns a list of plugins retrieved from the extension properties
 // (the 'x-kong-plugin<pluginname>' extensions). Applied on top of the optional
-// pluginsToInclude list. The result will be sorted by plugin name.
+// pluginsToInclude list. An extension replaces all inherited instances of the plugin, or
+// removes them if set to 'false'.
+// The result will be sorted by plugin name, and then by instance.
 func getPluginsList(
 	props openapi3.ExtensionProps,
 	pluginsToInclude *[]*map[string]interface{},
@@ -234,7 +671,7 @@ func getPluginsList(
 	components *map[string]interface{},
 	tags []string,
 ) (*[]*map[string]interface{}, error) {
-	plugins := make(map[string]*map[string]interface{})
+	plugins := make(map[string][]*map[string]interface{})
 
 	// copy inherited list of plugins
 	if pluginsToInclude != nil {
@@ -243,11 +680,11 @@ func getPluginsList(
 			configCopy := *(jsonbasics.DeepCopyObject(config))
 
 			// generate a new ID, for a new plugin, based on new basename
-			configCopy["id"] = createPluginID(uuidNamespace, baseName, configCopy)
+			configCopy["id"] = createPluginInstanceID(uuidNamespace, baseName, configCopy, len(plugins[pluginName]))
 
 			configCopy["tags"] = tags
 
-			plugins[pluginName] = &configCopy
+			plugins[pluginName] = append(plugins[pluginName], &configCopy)
 		}
 	}
 
@@ -256,43 +693,57 @@ func getPluginsList(
 		for extensionName := range props.Extensions {
 			if strings.HasPrefix(extensionName, "x-kong-plugin-") {
 				pluginName := strings.TrimPrefix(extensionName, "x-kong-plugin-")
+				if isPluginRemoved(props, pluginName) {
+					// 'false' removes the inherited instances
+					delete(plugins, pluginName)
+					continue
+				}
 
-				jsonstr, err := getXKongObject(props, extensionName, components)
+				instances, err := getXKongPluginObjects(props, extensionName, components)
 				if err != nil {
 					return nil, err
 				}
 
-				var pluginConfig map[string]interface{}
-				err = json.Unmarshal(jsonstr, &pluginConfig)
-				if err != nil {
-					return nil, fmt.Errorf(fmt.Sprintf("failed to parse JSON object for '%s': %%w", extensionName), err)
-				}
+				pluginConfigs := make([]*map[string]interface{}, 0, len(instances))
+				for i, jsonstr := range instances {
+					var pluginConfig map[string]interface{}
+					err = json.Unmarshal(jsonstr, &pluginConfig)
+					if err != nil {
+						return nil, fmt.Errorf(fmt.Sprintf("failed to parse JSON object for '%s': %%w", extensionName), err)
+					}
+
+					if enabled, found := pluginConfig["enabled"]; found {
+						// plugins are enabled by default, but can be disabled for staged rollouts
+						if _, ok := enabled.(bool); !ok {
+							return nil, fmt.Errorf("expected 'enabled' in '%s' to be a boolean", extensionName)
+						}
+					}
 
-				pluginConfig["name"] = pluginName
-				pluginConfig["id"] = createPluginID(uuidNamespace, baseName, pluginConfig)
-				pluginConfig["tags"] = tags
+					pluginConfig["name"] = pluginName
+					pluginConfig["id"] = createPluginInstanceID(uuidNamespace, baseName, pluginConfig, i)
+					pluginConfig["tags"] = tags
 
-				// foreign keys to service+route are not allowed (consumer is allowed)
-				delete(pluginConfig, "service")
-				delete(pluginConfig, "route")
+					// foreign keys to service+route are not allowed (consumer is allowed)
+					delete(pluginConfig, "service")
+					delete(pluginConfig, "route")
 
-				plugins[pluginName] = &pluginConfig
+					pluginConfigs = append(pluginConfigs, &pluginConfig)
+				}
+				plugins[pluginName] = pluginConfigs
 			}
 		}
 	}
 
 	// the list is complete, sort to be deterministic in the output
-	sortedNames := make([]string, len(plugins))
-	i := 0
+	sortedNames := make([]string, 0, len(plugins))
 	for pluginName := range plugins {
-		sortedNames[i] = pluginName
-		i++
+		sortedNames = append(sortedNames, pluginName)
 	}
 	sort.Strings(sortedNames)
 
-	sorted := make([]*map[string]interface{}, len(plugins))
-	for i, pluginName := range sortedNames {
-		sorted[i] = plugins[pluginName]
+	sorted := make([]*map[string]interface{}, 0, len(plugins))
+	for _, pluginName := range sortedNames {
+		sorted = append(sorted, plugins[pluginName]...)
 	}
 	return &sorted, nil
 }
@@ -315,7 +766,8 @@ func getValidatorPlugin(list *[]*map[string]interface{}, currentConfig []byte) (
 	return currentConfig, list
 }
 
-// insertPlugin will insert a plugin in the list array, in a sorted manner.
+// insertPlugin will insert a plugin in the list array, in a sorted manner. If the list
+// has the plugin already, its first instance is replaced.
 // List must already be sorted by plugin-name.
 func insertPlugin(list *[]*map[string]interface{}, newPlugin *map[string]interface{}) *[]*map[string]interface{} {
 	if newPlugin == nil {
@@ -327,7 +779,8 @@ func insertPlugin(list *[]*map[string]interface{}, newPlugin *map[string]interfa
 
 	for _, plugin := range *list {
 		pluginName := (*plugin)["name"].(string) // safe because it was previously parsed
-		if pluginName == newPluginName {
+		if pluginName == newPluginName && newPlugin != nil {
+			// replaces the first instance, any other instances are kept
 			l = append(l, newPlugin)
 			newPlugin = nil
 		} else {
@@ -382,6 +835,89 @@ func getForeignKeyPlugins(
 	return &genericPlugins, &newPluginList
 }
 
+// getDocBaseName returns the slugified base name for the document. Precedence is;
+// specified in options -> x-kong-name -> Info.Title -> spec filename -> random (or an
+// error if RequireDocName is set)
+func getDocBaseName(doc *openapi3.T, opts O2kOptions) (string, error) {
+	docBaseName := opts.DocName
+	if docBaseName == "" {
+		logbasics.Debug("no document name specified, trying x-kong-name")
+		var err error
+		if docBaseName, err = getKongName(doc.ExtensionProps); err != nil {
+			return "", err
+		}
+	}
+	if docBaseName == "" {
+		logbasics.Debug("no x-kong-name specified, trying Info.Title")
+		if doc.Info != nil {
+			docBaseName = strings.TrimSpace(doc.Info.Title)
+		}
+	}
+	if docBaseName == "" && opts.SpecFilename != "" && opts.SpecFilename != "-" {
+		logbasics.Debug("no Info.Title specified, trying the spec filename")
+		base := filepath.Base(opts.SpecFilename)
+		docBaseName = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	if docBaseName == "" && opts.RequireDocName {
+		return "", fmt.Errorf("no document name available for uuid generation; set 'x-kong-name' or " +
+			"a non-empty 'info.title' in the spec, or specify a uuid-base")
+	}
+	if docBaseName == "" {
+		logbasics.Info("no document name, x-kong-name, Info.Title, nor filename specified, generating random name")
+		docBaseName = uuid.NewV4().String()
+	}
+	return Slugify(docBaseName), nil
+}
+
+// getNamePrefix returns the slugified name prefix for the document. Precedence is;
+// specified in options -> x-kong-name-prefix. Returns "" if there is none.
+func getNamePrefix(doc *openapi3.T, opts O2kOptions) (string, error) {
+	prefix := opts.NamePrefix
+	if prefix == "" && doc.ExtensionProps.Extensions != nil &&
+		doc.ExtensionProps.Extensions["x-kong-name-prefix"] != nil {
+		err := json.Unmarshal(doc.ExtensionProps.Extensions["x-kong-name-prefix"].(json.RawMessage), &prefix)
+		if err != nil {
+			return "", fmt.Errorf("expected 'x-kong-name-prefix' to be a string: %w", err)
+		}
+	}
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return "", nil
+	}
+	return Slugify(prefix), nil
+}
+
+// getStripPath returns the 'strip_path' value for a route. Precedence is; 'x-kong-strip-path'
+// on the operation -> on the path -> the StripPath option -> false.
+func getStripPath(operationProps openapi3.ExtensionProps, pathProps openapi3.ExtensionProps,
+	defaultStripPath *bool,
+) (bool, error) {
+	for _, props := range []openapi3.ExtensionProps{operationProps, pathProps} {
+		if props.Extensions == nil || props.Extensions["x-kong-strip-path"] == nil {
+			continue
+		}
+		var stripPath bool
+		if err := json.Unmarshal(props.Extensions["x-kong-strip-path"].(json.RawMessage), &stripPath); err != nil {
+			return false, fmt.Errorf("expected 'x-kong-strip-path' to be a boolean")
+		}
+		return stripPath, nil
+	}
+	if defaultStripPath != nil {
+		return *defaultStripPath, nil
+	}
+	return false, nil
+}
+
+// hasSuccessResponse returns true if the operation has at least 1 response in the 2xx range.
+func hasSuccessResponse(operation *openapi3.Operation) bool {
+	for statusCode := range operation.Responses {
+		if strings.HasPrefix(statusCode, "2") {
+			return true
+		}
+	}
+	return false
+}
+
 // MustConvert is the same as Convert, but will panic if an error is returned.
 func MustConvert(content *[]byte, opts O2kOptions) map[string]interface{} {
 	result, err := Convert(content, opts)
@@ -393,12 +929,67 @@ func MustConvert(content *[]byte, opts O2kOptions) map[string]interface{} {
 
 // Convert converts an OpenAPI spec to a Kong declarative file.
 func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
+	result, _, err := ConvertWithInfo(content, opts)
+	return result, err
+}
+
+// ConvertWithInfo is the same as Convert, but also returns information about the conversion.
+func ConvertWithInfo(content *[]byte, opts O2kOptions) (map[string]interface{}, O2kInfo, error) {
+	return ConvertWithInfoContext(context.Background(), content, opts)
+}
+
+// ConvertContext is the same as Convert, but can be cancelled through the context. On
+// cancellation it returns the error of the context (ctx.Err()).
+func ConvertContext(ctx context.Context, content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
+	result, _, err := ConvertWithInfoContext(ctx, content, opts)
+	return result, err
+}
+
+// ConvertWithInfoContext is the same as ConvertWithInfo, but can be cancelled through the
+// context. The context is checked while resolving external references, and for every path
+// and operation. On cancellation it returns the error of the context (ctx.Err()).
+func ConvertWithInfoContext(ctx context.Context, content *[]byte, opts O2kOptions,
+) (map[string]interface{}, O2kInfo, error) {
+	var info O2kInfo
+	if err := ctx.Err(); err != nil {
+		return nil, info, err
+	}
 	opts.setDefaults()
 	logbasics.Debug("received OpenAPI2Kong options", "options", opts)
 
+	if err := validateEmitSections(opts.EmitSections); err != nil {
+		return nil, info, err
+	}
+	if err := validatePluginMergeStrategy(opts.PluginMergeStrategy); err != nil {
+		return nil, info, err
+	}
+	if err := validatePathStrategy(opts.PathStrategy); err != nil {
+		return nil, info, err
+	}
+	if err := validateMultiBackendPolicy(opts.MultiBackendPolicy); err != nil {
+		return nil, info, err
+	}
+	if err := validatePathHandling(opts.PathHandling); err != nil {
+		return nil, info, err
+	}
+	if err := validateTerminationStatus(opts.TerminationStatus); err != nil {
+		return nil, info, err
+	}
+	if err := validateEmitKeys(opts.EmitNames, opts.EmitIDs); err != nil {
+		return nil, info, err
+	}
+	if err := validateDefaultProtocol(opts.DefaultProtocol); err != nil {
+		return nil, info, err
+	}
+	if _, err := parseSpecBaseURL(opts.SpecBaseURL); err != nil {
+		return nil, info, err
+	}
+
 	// set up output document
 	result := make(map[string]interface{})
-	result[formatVersionKey] = formatVersionValue
+	if err := deckformat.SetFormatVersion(result, opts.FormatVersion); err != nil {
+		return nil, info, fmt.Errorf("invalid format version; %w", err)
+	}
 	services := make([]interface{}, 0)
 	upstreams := make([]interface{}, 0)
 
@@ -414,10 +1005,15 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 		docService          map[string]interface{}     // service entity in use on document level
 		docUpstreamDefaults []byte                     // JSON string representation of upstream-defaults on document level
 		docUpstream         map[string]interface{}     // upstream entity in use on document level
+		docUpstreamRef      string                     // name of an existing upstream to use on document level
+		docServiceURL       string                     // url of the service on document level, overriding the servers
 		docRouteDefaults    []byte                     // JSON string representation of route-defaults on document level
 		docPluginList       *[]*map[string]interface{} // array of plugin configs, sorted by plugin name
 		docValidatorConfig  []byte                     // JSON string representation of validator config to generate
+		docIPRestriction    *ipRestriction             // ip-restriction lists on document level
 		foreignKeyPlugins   *[]*map[string]interface{} // top-level array of plugin configs, sorted by plugin name+id
+		sniHosts            []string                   // hostnames to generate snis for, in order of appearance
+		vaults              []interface{}              // vaults declared by 'x-kong-vaults'
 
 		pathBaseName         string                     // the slugified basename for the path
 		pathServers          *openapi3.Servers          // servers block on current path level
@@ -425,9 +1021,12 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 		pathService          map[string]interface{}     // service entity in use on path level
 		pathUpstreamDefaults []byte                     // JSON string representation of upstream-defaults on path level
 		pathUpstream         map[string]interface{}     // upstream entity in use on path level
+		pathUpstreamRef      string                     // name of an existing upstream to use on path level
+		pathServiceURL       string                     // url of the service on path level, overriding the servers
 		pathRouteDefaults    []byte                     // JSON string representation of route-defaults on path level
 		pathPluginList       *[]*map[string]interface{} // array of plugin configs, sorted by plugin name
 		pathValidatorConfig  []byte                     // JSON string representation of validator config to generate
+		pathIPRestriction    *ipRestriction             // ip-restriction lists on path level, including doc level
 
 		operationBaseName         string                     // the slugified basename for the operation
 		operationServers          *openapi3.Servers          // servers block on current operation level
@@ -435,16 +1034,56 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 		operationService          map[string]interface{}     // service entity in use on operation level
 		operationUpstreamDefaults []byte                     // JSON string representation of upstream-defaults on ops level
 		operationUpstream         map[string]interface{}     // upstream entity in use on operation level
+		operationUpstreamRef      string                     // name of an existing upstream to use on ops level
+		operationServiceURL       string                     // url of the service on ops level, overriding the servers
 		operationRouteDefaults    []byte                     // JSON string representation of route-defaults on ops level
 		operationPluginList       *[]*map[string]interface{} // array of plugin configs, sorted by plugin name
 		operationValidatorConfig  []byte                     // JSON string representation of validator config to generate
+		operationIPRestriction    *ipRestriction             // ip-restriction lists on ops level, including path level
 	)
 
-	// Load and parse the OAS file
+	// Load and parse the OAS file, external references are resolved relative to the spec, and
+	// only loaded if allowed (the resolver returns an error for the others)
+	specLocation := getSpecLocation(opts)
+	resolver := refresolver.NewWithOptions(refresolver.Options{
+		AllowFiles:  opts.AllowExternalRefs,
+		AllowRemote: opts.AllowRemoteRefs,
+		BaseDir:     filepath.Dir(filepath.FromSlash(specLocation.Path)),
+		Context:     ctx,
+	})
 	loader := openapi3.NewLoader()
-	doc, err = loader.LoadFromData(*content)
+	loader.Context = ctx
+	loader.IsExternalRefsAllowed = true
+	loader.ReadFromURIFunc = func(_ *openapi3.Loader, location *url.URL) ([]byte, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if location.Scheme == "" {
+			return resolver.LoadJSON(filepath.FromSlash(location.Path))
+		}
+		return resolver.LoadJSON(location.String())
+	}
+	if opts.ValidateRefs == nil || *opts.ValidateRefs {
+		if err = validateRefs(content, filepath.FromSlash(specLocation.Path), resolver); err != nil {
+			return nil, info, fmt.Errorf("error parsing OAS3 file: [%w]", err)
+		}
+	}
+	if content, err = expandPathsRef(content, filepath.FromSlash(specLocation.Path), resolver); err != nil {
+		return nil, info, fmt.Errorf("error parsing OAS3 file: [%w]", err)
+	}
+	doc, err = loader.LoadFromDataWithPath(*content, specLocation)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, info, ctxErr
+	}
 	if err != nil {
-		return nil, fmt.Errorf("error parsing OAS3 file: [%w]", err)
+		return nil, info, fmt.Errorf("error parsing OAS3 file: [%w]", err)
+	}
+
+	// check the extensions before any of them are converted
+	if opts.StrictExtensions {
+		if err = validateExtensions(doc); err != nil {
+			return nil, info, err
+		}
 	}
 
 	//
@@ -455,53 +1094,116 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 
 	// collect tags to use
 	if kongTags, err = getKongTags(doc, opts.Tags); err != nil {
-		return nil, err
+		return nil, info, err
 	}
 	logbasics.Info("tags after parsing x-kong-tags", "tags", kongTags)
+	if opts.TagSpecVersion {
+		if versionTag := getVersionTag(doc.Info); versionTag != "" {
+			kongTags = append(append(make([]string, 0, len(kongTags)+1), kongTags...), versionTag)
+		}
+	}
+	kongTags = sortedUniqueTags(kongTags)
 
 	// set document level elements
 	docServers = &doc.Servers // this one is always set, but can be empty
 
-	// determine document name, precedence: specified -> x-kong-name -> Info.Title -> random
-	docBaseName = opts.DocName
-	if docBaseName == "" {
-		logbasics.Debug("no document name specified, trying x-kong-name")
-		if docBaseName, err = getKongName(doc.ExtensionProps); err != nil {
-			return nil, err
-		}
-		if docBaseName == "" {
-			logbasics.Debug("no x-kong-name specified, trying Info.Title")
-			if doc.Info != nil && doc.Info.Title != "" {
-				docBaseName = doc.Info.Title
-			} else {
-				logbasics.Info("no document name, x-kong-name, nor Info.Title specified, generating random name")
-				docBaseName = uuid.NewV4().String()
-			}
-		}
+	// determine document name, precedence: specified -> x-kong-name -> Info.Title -> filename -> random
+	if docBaseName, err = getDocBaseName(doc, opts); err != nil {
+		return nil, info, err
+	}
+	if namePrefix, err := getNamePrefix(doc, opts); err != nil {
+		return nil, info, err
+	} else if namePrefix != "" {
+		// prefix the base name, since it is the base for all entity names and uuids
+		docBaseName = Slugify(namePrefix, docBaseName)
 	}
-	docBaseName = Slugify(docBaseName)
+	info.DocName = docBaseName
 	logbasics.Info("document name (namespace for UUID generation)", "name", docBaseName)
 
 	if kongComponents, err = getXKongComponents(doc); err != nil {
-		return nil, err
+		return nil, info, err
+	}
+
+	callbackPaths := make(map[*openapi3.PathItem]bool) // the path items added from callbacks
+	if opts.IncludeCallbacks {
+		callbackPaths = addCallbackPaths(doc)
+	}
+
+	if err = convertAllFunctionExtensions(doc, opts.BaseDir); err != nil {
+		return nil, info, err
+	}
+	if err = convertGrpcGatewayExtension(&doc.ExtensionProps, opts.BaseDir); err != nil {
+		return nil, info, fmt.Errorf("failed to create grpc-gateway plugin from document root: %w", err)
+	}
+	if err = convertAllRateLimitingExtensions(doc); err != nil {
+		return nil, info, err
+	}
+	if err = convertAllPluginRefExtensions(doc); err != nil {
+		return nil, info, err
+	}
+	if err = convertAllACLExtensions(doc); err != nil {
+		return nil, info, err
+	}
+	if err = convertAllUploadExtensions(doc); err != nil {
+		return nil, info, err
+	}
+	mergeAllPathParameters(doc)
+	specBaseURL, _ := parseSpecBaseURL(opts.SpecBaseURL) // validated above
+	if err = resolveAllServerURLs(doc, specBaseURL); err != nil {
+		return nil, info, err
+	}
+
+	if opts.ValidatePluginConfig {
+		if err = validateAllPluginConfigs(doc, kongComponents); err != nil {
+			return nil, info, err
+		}
 	}
 
 	// for defaults we keep strings, so deserializing them provides a copy right away
 	if docServiceDefaults, err = getServiceDefaults(doc.ExtensionProps, kongComponents); err != nil {
-		return nil, err
+		return nil, info, err
 	}
 	if docUpstreamDefaults, err = getUpstreamDefaults(doc.ExtensionProps, kongComponents); err != nil {
-		return nil, err
+		return nil, info, err
 	}
 	if docRouteDefaults, err = getRouteDefaults(doc.ExtensionProps, kongComponents); err != nil {
-		return nil, err
+		return nil, info, err
+	}
+
+	if vaults, err = createKongVaults(doc.ExtensionProps, opts.UUIDNamespace, docBaseName, kongTags); err != nil {
+		return nil, info, err
+	}
+
+	if docUpstreamRef, err = getUpstreamRef(doc.ExtensionProps); err != nil {
+		return nil, info, fmt.Errorf("failed to create service/upstream from document root: %w", err)
+	}
+
+	if docServiceURL, err = getServiceURL(doc.ExtensionProps); err != nil {
+		return nil, info, fmt.Errorf("failed to create service/upstream from document root: %w", err)
+	}
+	if err = checkServiceURL(docServiceURL, docUpstreamRef, docUpstreamDefaults); err != nil {
+		return nil, info, fmt.Errorf("failed to create service/upstream from document root: %w", err)
 	}
 
 	// create the top-level docService and (optional) docUpstream
-	docService, docUpstream, err = CreateKongService(docBaseName, docServers, docServiceDefaults,
-		docUpstreamDefaults, kongTags, opts.UUIDNamespace)
+	docService, docUpstream, err = createKongService(docBaseName, serviceServers(docServers, docServiceURL),
+		docServiceDefaults, docUpstreamDefaults, kongTags, opts.UUIDNamespace, opts.DefaultProtocol)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create service/upstream from document root: %w", err)
+		return nil, info, fmt.Errorf("failed to create service/upstream from document root: %w", err)
+	}
+	applyServiceURL(docService, docServiceURL)
+	if docUpstreamRef != "" {
+		// use the existing upstream, instead of generating one
+		docService["host"] = docUpstreamRef
+		docUpstream = nil
+	}
+	if serviceName, err := getServiceName(doc.ExtensionProps); err != nil {
+		return nil, info, err
+	} else if serviceName != "" {
+		docService["name"] = serviceName
+	}
+	if opts.PreserveDescriptions {
+		docService["tags"] = addDocsTag(kongTags, doc.ExternalDocs)
 	}
 	services = append(services, docService)
 	if docUpstream != nil {
@@ -511,15 +1213,36 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 	// attach plugins
 	docPluginList, err = getPluginsList(doc.ExtensionProps, nil, opts.UUIDNamespace, docBaseName, kongComponents, kongTags)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create plugins list from document root: %w", err)
+		return nil, info, fmt.Errorf("failed to create plugins list from document root: %w", err)
 	}
+	fillKeyAuthPlugin(docPluginList, &doc.Security, doc.Components.SecuritySchemes)
 
 	// Extract the request-validator config from the plugin list
 	docValidatorConfig, docPluginList = getValidatorPlugin(docPluginList, docValidatorConfig)
 
+	// add the ip-restriction plugin
+	if docIPRestriction, err = getIPRestriction(doc.ExtensionProps, kongComponents); err != nil {
+		return nil, info, fmt.Errorf("failed to create ip-restriction plugin from document root: %w", err)
+	}
+	docPluginList = insertIPRestrictionPlugin(docPluginList, docIPRestriction, opts.UUIDNamespace,
+		docBaseName, kongTags)
+	if opts.AddTracingHeaders {
+		docPluginList = insertTracingHeadersPlugin(docPluginList, opts.TracingHeaders, opts.UUIDNamespace,
+			docBaseName, kongTags)
+	}
+	if opts.EnsureCorrelationID {
+		docPluginList = insertCorrelationIDPlugin(docPluginList, opts.CorrelationIDHeader,
+			opts.CorrelationIDGenerator, opts.UUIDNamespace, docBaseName, kongTags)
+	}
+
 	// move consumer bound plugins to doc level plugins list (multiple foreign keys)
+	docPluginsWithConsumers := docPluginList // to copy to the services by tag
 	foreignKeyPlugins, docPluginList = getForeignKeyPlugins(
 		foreignKeyPlugins, docPluginList, "service", docService["name"].(string))
+	tagServices := make(map[string]map[string]interface{}) // the services by tag, for ServicePerTag
+	removedPlugins := make(map[string]map[string]bool)     // plugins removed on routes, by route name
+	routeSources := make(map[string]RouteSummary)          // the operations of the routes, by route name
+	securityMapping := getSecurityMapping(opts.SecurityMapping)
 
 	docService["plugins"] = docPluginList
 
@@ -529,22 +1252,39 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 	//
 	//
 
+	// deal with paths that only differ by a trailing slash
+	paths, optionalSlash, err := applyTrailingSlashPolicy(doc.Paths, opts.TrailingSlash)
+	if err != nil {
+		return nil, info, err
+	}
+	if len(paths) == 0 {
+		// a missing 'paths' key is parsed as empty
+		if opts.RequireRoutes {
+			return nil, info, fmt.Errorf("no routes to generate; the 'paths' object is empty or missing")
+		}
+		logbasics.Info("the 'paths' object is empty or missing, no routes will be generated")
+	}
+	routeCount := 0
+
 	// create a sorted array of paths, to be deterministic in our output order
-	sortedPaths := make([]string, len(doc.Paths))
+	sortedPaths := make([]string, len(paths))
 	i := 0
-	for path := range doc.Paths {
+	for path := range paths {
 		sortedPaths[i] = path
 		i++
 	}
 	sort.Strings(sortedPaths)
 
 	for _, path := range sortedPaths {
+		if err := ctx.Err(); err != nil {
+			return nil, info, err
+		}
 		logbasics.Info("processing path", "path", path)
-		pathitem := doc.Paths[path]
+		pathitem := paths[path]
 
 		// determine path name, precedence: specified -> x-kong-name -> actual-path
 		if pathBaseName, err = getKongName(pathitem.ExtensionProps); err != nil {
-			return nil, err
+			return nil, info, err
 		}
 		if pathBaseName == "" {
 			pathBaseName = Slugify(path)
@@ -563,7 +1303,7 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 		// Set up the defaults on the Path level
 		newPathService := false
 		if pathServiceDefaults, err = getServiceDefaults(pathitem.ExtensionProps, kongComponents); err != nil {
-			return nil, err
+			return nil, info, err
 		}
 		if pathServiceDefaults == nil {
 			pathServiceDefaults = docServiceDefaults
@@ -573,7 +1313,7 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 
 		newUpstream := false
 		if pathUpstreamDefaults, err = getUpstreamDefaults(pathitem.ExtensionProps, kongComponents); err != nil {
-			return nil, err
+			return nil, info, err
 		}
 		if pathUpstreamDefaults == nil {
 			pathUpstreamDefaults = docUpstreamDefaults
@@ -583,7 +1323,7 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 		}
 
 		if pathRouteDefaults, err = getRouteDefaults(pathitem.ExtensionProps, kongComponents); err != nil {
-			return nil, err
+			return nil, info, err
 		}
 		if pathRouteDefaults == nil {
 			pathRouteDefaults = docRouteDefaults
@@ -591,37 +1331,84 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 
 		// if there is no path level servers block, use the document one
 		pathServers = &pathitem.Servers
+		pathServiceURL = docServiceURL
 		if len(*pathServers) == 0 { // it's always set, so we ignore it if empty
 			pathServers = docServers
 		} else {
 			newUpstream = true
 			newPathService = true
+			pathServiceURL = "" // the path servers replace the inherited url
+		}
+		if err := checkMultiBackend(path, pathitem, pathServers, opts.MultiBackendPolicy); err != nil {
+			return nil, info, err
 		}
 
+		// an existing upstream is only used if the path doesn't need its own
+		pathUpstreamRef = ""
+		if !newUpstream {
+			pathUpstreamRef = docUpstreamRef
+		}
+
+		// collect the ip-restriction lists for this path
+		var ipRestrictionOnPath *ipRestriction
+		if ipRestrictionOnPath, err = getIPRestriction(pathitem.ExtensionProps, kongComponents); err != nil {
+			return nil, info, fmt.Errorf("failed to create ip-restriction plugin from path '%s': %w", path, err)
+		}
+		pathIPRestriction = docIPRestriction.merge(ipRestrictionOnPath)
+
 		// create a new service if we need to do so
 		if newPathService {
 			// create the path-level service and (optional) upstream
 			logbasics.Debug("creating path-level service/upstream")
-			pathService, pathUpstream, err = CreateKongService(
+			if err = checkServiceURL(pathServiceURL, pathUpstreamRef, pathUpstreamDefaults); err != nil {
+				return nil, info, fmt.Errorf("failed to create service/updstream from path '%s': %w", path, err)
+			}
+			pathService, pathUpstream, err = createKongService(
 				pathBaseName,
-				pathServers,
+				serviceServers(pathServers, pathServiceURL),
 				pathServiceDefaults,
 				pathUpstreamDefaults,
 				kongTags,
-				opts.UUIDNamespace)
+				opts.UUIDNamespace,
+				opts.DefaultProtocol)
 			if err != nil {
-				return nil, fmt.Errorf("failed to create service/updstream from path '%s': %w", path, err)
+				return nil, info, fmt.Errorf("failed to create service/updstream from path '%s': %w", path, err)
+			}
+			applyServiceURL(pathService, pathServiceURL)
+			if pathUpstreamRef != "" {
+				pathService["host"] = pathUpstreamRef
+				pathUpstream = nil
+			}
+			if serviceName, err := getServiceName(pathitem.ExtensionProps); err != nil {
+				return nil, info, err
+			} else if serviceName != "" {
+				pathService["name"] = serviceName
 			}
 
 			// collect path plugins, including the doc-level plugins since we have a new service entity
 			pathPluginList, err = getPluginsList(pathitem.ExtensionProps, docPluginList,
 				opts.UUIDNamespace, pathBaseName, kongComponents, kongTags)
 			if err != nil {
-				return nil, fmt.Errorf("failed to create plugins list from path item: %w", err)
+				return nil, info, fmt.Errorf("failed to create plugins list from path item: %w", err)
 			}
+			mergePluginConfigs(opts.PluginMergeStrategy, pathPluginList, docPluginList)
+			fillKeyAuthPlugin(pathPluginList, &doc.Security, doc.Components.SecuritySchemes)
 
 			// Extract the request-validator config from the plugin list
-			pathValidatorConfig, pathPluginList = getValidatorPlugin(pathPluginList, docValidatorConfig)
+			pathValidatorConfig, pathPluginList = getValidatorPlugin(pathPluginList,
+				inheritedValidatorConfig(pathitem.ExtensionProps, docValidatorConfig))
+
+			// add the ip-restriction plugin
+			pathPluginList = insertIPRestrictionPlugin(pathPluginList, pathIPRestriction, opts.UUIDNamespace,
+				pathBaseName, kongTags)
+			if opts.AddTracingHeaders {
+				pathPluginList = insertTracingHeadersPlugin(pathPluginList, opts.TracingHeaders, opts.UUIDNamespace,
+					pathBaseName, kongTags)
+			}
+			if opts.EnsureCorrelationID {
+				pathPluginList = insertCorrelationIDPlugin(pathPluginList, opts.CorrelationIDHeader,
+					opts.CorrelationIDGenerator, opts.UUIDNamespace, pathBaseName, kongTags)
+			}
 
 			// move consumer bound plugins to doc level plugins list (multiple foreign keys)
 			foreignKeyPlugins, pathPluginList = getForeignKeyPlugins(
@@ -648,11 +1435,20 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 			pathPluginList, err = getPluginsList(pathitem.ExtensionProps, nil,
 				opts.UUIDNamespace, pathBaseName, kongComponents, kongTags)
 			if err != nil {
-				return nil, fmt.Errorf("failed to create plugins list from path item: %w", err)
+				return nil, info, fmt.Errorf("failed to create plugins list from path item: %w", err)
 			}
+			mergePluginConfigs(opts.PluginMergeStrategy, pathPluginList, docPluginList)
+			fillKeyAuthPlugin(pathPluginList, &doc.Security, doc.Components.SecuritySchemes)
 
 			// Extract the request-validator config from the plugin list
-			pathValidatorConfig, pathPluginList = getValidatorPlugin(pathPluginList, docValidatorConfig)
+			pathValidatorConfig, pathPluginList = getValidatorPlugin(pathPluginList,
+				inheritedValidatorConfig(pathitem.ExtensionProps, docValidatorConfig))
+
+			// add the ip-restriction plugin, only if set on this level
+			if ipRestrictionOnPath != nil {
+				pathPluginList = insertIPRestrictionPlugin(pathPluginList, pathIPRestriction, opts.UUIDNamespace,
+					pathBaseName, kongTags)
+			}
 		}
 
 		//
@@ -673,14 +1469,23 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 
 		// traverse all operations
 		for _, method := range sortedMethods {
+			if err := ctx.Err(); err != nil {
+				return nil, info, err
+			}
 			operation := operations[method]
 			logbasics.Info("processing operation", "method", method, "path", path, "id", operation.OperationID)
 
+			if opts.RequireSuccessResponse && !hasSuccessResponse(operation) {
+				logbasics.Info("skipping operation without a success response", "method", method, "path", path)
+				info.Stats.SkippedOperations++
+				continue
+			}
+
 			var operationRoutes []interface{} // the routes array we need to add to
 
 			// determine operation name, precedence: specified -> operation-ID -> method-name
 			if operationBaseName, err = getKongName(operation.ExtensionProps); err != nil {
-				return nil, err
+				return nil, info, err
 			}
 			if operationBaseName != "" {
 				// an x-kong-name was provided, so build as "doc-path-name"
@@ -697,10 +1502,16 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 			}
 			logbasics.Debug("operation base name (namespace for UUID generation)", "name", operationBaseName)
 
+			// the inherited tags, minus the ones removed on the operation
+			operationTags, err := removeTags(kongTags, operation.ExtensionProps)
+			if err != nil {
+				return nil, info, fmt.Errorf("failed to get tags for operation '%s %s': %w", path, method, err)
+			}
+
 			// Set up the defaults on the Operation level
 			newOperationService := false
 			if operationServiceDefaults, err = getServiceDefaults(operation.ExtensionProps, kongComponents); err != nil {
-				return nil, err
+				return nil, info, err
 			}
 			if operationServiceDefaults == nil {
 				operationServiceDefaults = pathServiceDefaults
@@ -710,7 +1521,7 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 
 			newUpstream := false
 			if operationUpstreamDefaults, err = getUpstreamDefaults(operation.ExtensionProps, kongComponents); err != nil {
-				return nil, err
+				return nil, info, err
 			}
 			if operationUpstreamDefaults == nil {
 				operationUpstreamDefaults = pathUpstreamDefaults
@@ -720,7 +1531,7 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 			}
 
 			if operationRouteDefaults, err = getRouteDefaults(operation.ExtensionProps, kongComponents); err != nil {
-				return nil, err
+				return nil, info, err
 			}
 			if operationRouteDefaults == nil {
 				operationRouteDefaults = pathRouteDefaults
@@ -735,19 +1546,55 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 				newOperationService = true
 			}
 
+			if operationUpstreamRef, err = getUpstreamRef(operation.ExtensionProps); err != nil {
+				return nil, info, fmt.Errorf("failed to create service/upstream from operation '%s %s': %w",
+					path, method, err)
+			}
+			if operationUpstreamRef != "" {
+				newOperationService = true
+			} else if !newUpstream {
+				operationUpstreamRef = pathUpstreamRef
+			}
+
+			// the url of the service; its own, or else the path one, unless it has its own servers
+			if operationServiceURL, err = getServiceURL(operation.ExtensionProps); err != nil {
+				return nil, info, fmt.Errorf("failed to create service/upstream from operation '%s %s': %w",
+					path, method, err)
+			}
+			if operationServiceURL != "" {
+				newOperationService = true
+			} else if !newUpstream && operationUpstreamRef == "" {
+				operationServiceURL = pathServiceURL
+			}
+
 			// create a new service if we need to do so
 			if newOperationService {
 				// create the operation-level service and (optional) upstream
 				logbasics.Debug("creating operation-level service/upstream")
-				operationService, operationUpstream, err = CreateKongService(
+				if err = checkServiceURL(operationServiceURL, operationUpstreamRef, operationUpstreamDefaults); err != nil {
+					return nil, info, fmt.Errorf("failed to create service/updstream from operation '%s %s': %w",
+						path, method, err)
+				}
+				operationService, operationUpstream, err = createKongService(
 					operationBaseName,
-					operationServers,
+					serviceServers(operationServers, operationServiceURL),
 					operationServiceDefaults,
 					operationUpstreamDefaults,
-					kongTags,
-					opts.UUIDNamespace)
+					operationTags,
+					opts.UUIDNamespace,
+					opts.DefaultProtocol)
 				if err != nil {
-					return nil, fmt.Errorf("failed to create service/updstream from operation '%s %s': %w", path, method, err)
+					return nil, info, fmt.Errorf("failed to create service/updstream from operation '%s %s': %w", path, method, err)
+				}
+				applyServiceURL(operationService, operationServiceURL)
+				if operationUpstreamRef != "" {
+					operationService["host"] = operationUpstreamRef
+					operationUpstream = nil
+				}
+				if serviceName, err := getServiceName(operation.ExtensionProps); err != nil {
+					return nil, info, err
+				} else if serviceName != "" {
+					operationService["name"] = serviceName
 				}
 				services = append(services, operationService)
 				if operationUpstream != nil {
@@ -763,6 +1610,17 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 				operationRoutes = operationService["routes"].([]interface{})
 			} else {
 				operationService = pathService
+				if opts.ServicePerTag && !newPathService && len(operation.Tags) > 0 {
+					// use the service for the tag, instead of the doc level one
+					tag := operation.Tags[0]
+					if tagServices[tag] == nil {
+						logbasics.Debug("creating service for tag", "tag", tag)
+						tagServices[tag], foreignKeyPlugins = createTagService(docService, docPluginsWithConsumers,
+							tag, foreignKeyPlugins, opts.UUIDNamespace, kongComponents, operationTags)
+						services = append(services, tagServices[tag])
+					}
+					operationService = tagServices[tag]
+				}
 				operationRoutes = operationService["routes"].([]interface{})
 			}
 
@@ -771,32 +1629,113 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 				// we're operating on the doc-level service entity, so we need the plugins
 				// from the path and operation
 				operationPluginList, err = getPluginsList(operation.ExtensionProps, pathPluginList,
-					opts.UUIDNamespace, operationBaseName, kongComponents, kongTags)
+					opts.UUIDNamespace, operationBaseName, kongComponents, operationTags)
 			} else if newOperationService {
 				// we're operating on an operation-level service entity, so we need the plugins
 				// from the document, path, and operation.
 				operationPluginList, _ = getPluginsList(doc.ExtensionProps, nil, opts.UUIDNamespace,
-					operationBaseName, kongComponents, kongTags)
+					operationBaseName, kongComponents, operationTags)
 				operationPluginList, _ = getPluginsList(pathitem.ExtensionProps, operationPluginList, opts.UUIDNamespace,
-					operationBaseName, kongComponents, kongTags)
+					operationBaseName, kongComponents, operationTags)
 				operationPluginList, err = getPluginsList(operation.ExtensionProps, operationPluginList, opts.UUIDNamespace,
-					operationBaseName, kongComponents, kongTags)
+					operationBaseName, kongComponents, operationTags)
 			} else if newPathService {
 				// we're operating on a path-level service entity, so we only need the plugins
 				// from the operation.
 				operationPluginList, err = getPluginsList(operation.ExtensionProps, nil, opts.UUIDNamespace,
-					operationBaseName, kongComponents, kongTags)
+					operationBaseName, kongComponents, operationTags)
 			}
 			if err != nil {
-				return nil, fmt.Errorf("failed to create plugins list from operation item: %w", err)
+				return nil, info, fmt.Errorf("failed to create plugins list from operation item: %w", err)
+			}
+			// the doc-level plugins removed on the path are not inherited by the operation
+			inheritedDocPlugins := withoutPlugins(docPluginList, getRemovedPlugins(pathitem.ExtensionProps))
+			mergePluginConfigs(opts.PluginMergeStrategy, operationPluginList, pathPluginList, inheritedDocPlugins)
+			operationSecurity := operation.Security
+			if operationSecurity == nil {
+				operationSecurity = &doc.Security
+			}
+			fillKeyAuthPlugin(operationPluginList, operationSecurity, doc.Components.SecuritySchemes)
+			if opts.GenerateAuthPlugins {
+				operationPluginList, err = insertAuthPlugins(operationPluginList,
+					[]*[]*map[string]interface{}{pathPluginList, inheritedDocPlugins}, operationSecurity,
+					doc.Components.SecuritySchemes, securityMapping, opts.UUIDNamespace, operationBaseName, operationTags)
+				if err != nil {
+					return nil, info, fmt.Errorf("failed to create auth plugins for operation '%s %s': %w",
+						path, method, err)
+				}
+			}
+
+			// add the ip-restriction plugin, if set on this level, or if we have a new service entity
+			var ipRestrictionOnOperation *ipRestriction
+			if ipRestrictionOnOperation, err = getIPRestriction(operation.ExtensionProps, kongComponents); err != nil {
+				return nil, info, fmt.Errorf("failed to create ip-restriction plugin from operation '%s %s': %w",
+					path, method, err)
+			}
+			operationIPRestriction = pathIPRestriction.merge(ipRestrictionOnOperation)
+			if ipRestrictionOnOperation != nil || newOperationService {
+				operationPluginList = insertIPRestrictionPlugin(operationPluginList, operationIPRestriction,
+					opts.UUIDNamespace, operationBaseName, operationTags)
+			}
+			if opts.AddTracingHeaders && newOperationService {
+				operationPluginList = insertTracingHeadersPlugin(operationPluginList, opts.TracingHeaders,
+					opts.UUIDNamespace, operationBaseName, operationTags)
+			}
+			if opts.EnsureCorrelationID && newOperationService {
+				operationPluginList = insertCorrelationIDPlugin(operationPluginList, opts.CorrelationIDHeader,
+					opts.CorrelationIDGenerator, opts.UUIDNamespace, operationBaseName, operationTags)
 			}
 
 			// Extract the request-validator config from the plugin list, generate it and reinsert
-			operationValidatorConfig, operationPluginList = getValidatorPlugin(operationPluginList, pathValidatorConfig)
+			operationValidatorConfig, operationPluginList = getValidatorPlugin(operationPluginList,
+				inheritedValidatorConfig(operation.ExtensionProps, pathValidatorConfig))
 			validatorPlugin := generateValidatorPlugin(operationValidatorConfig, operation, opts.UUIDNamespace,
-				operationBaseName)
+				operationBaseName, opts.StrictValidation)
 			operationPluginList = insertPlugin(operationPluginList, validatorPlugin)
 
+			if opts.GenerateMock {
+				mockPlugin, err := generateMockPlugin(operation, opts.UUIDNamespace, operationBaseName, operationTags)
+				if err != nil {
+					return nil, info, fmt.Errorf("failed to create mock plugin from operation '%s %s': %w",
+						path, method, err)
+				}
+				operationPluginList = insertPlugin(operationPluginList, mockPlugin)
+			}
+
+			if opts.BlockInternal {
+				internal, err := isInternal(pathitem.ExtensionProps, operation.ExtensionProps)
+				if err != nil {
+					return nil, info, fmt.Errorf("failed to check operation '%s %s': %w", path, method, err)
+				}
+				if internal {
+					logbasics.Info("blocking internal operation", "method", method, "path", path)
+					termination, err := getTermination(operation.ExtensionProps)
+					if err != nil {
+						return nil, info, fmt.Errorf("failed to create request-termination plugin from "+
+							"operation '%s %s': %w", path, method, err)
+					}
+					operationPluginList = insertPlugin(operationPluginList, generateBlockingPlugin(
+						opts.TerminationStatus, termination, opts.UUIDNamespace, operationBaseName, operationTags))
+				}
+			}
+
+			if opts.ParameterDefaults {
+				if defaults := getParameterDefaults(pathitem.Parameters, operation.Parameters); len(defaults) > 0 {
+					// base it on the one in effect, since a route plugin replaces the service one
+					base := findPlugin(requestTransformerPluginName, operationPluginList, pathPluginList, inheritedDocPlugins)
+					operationPluginList = insertRequestTransformerPlugin(operationPluginList, base, defaults,
+						opts.UUIDNamespace, operationBaseName, operationTags)
+				}
+			}
+
+			if opts.MarkDeprecated {
+				if headers := getDeprecationHeaders(operation, operationBaseName); headers != nil {
+					base := findPlugin(responseTransformerPluginName, operationPluginList, pathPluginList, inheritedDocPlugins)
+					operationPluginList = insertTransformerPlugin(responseTransformerPluginName, operationPluginList,
+						base, map[string][]string{"headers": headers}, opts.UUIDNamespace, operationBaseName, operationTags)
+				}
+			}
+
 			// construct the route
 			var route map[string]interface{}
 			if operationRouteDefaults != nil {
@@ -806,6 +1745,13 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 				route = make(map[string]interface{})
 			}
 
+			if !newOperationService {
+				// removed plugins inherited from the service are handled after all routes are created
+				if removed := getRemovedPlugins(pathitem.ExtensionProps, operation.ExtensionProps); len(removed) > 0 {
+					removedPlugins[operationBaseName] = removed
+				}
+			}
+
 			// move consumer bound plugins to doc level plugins list (multiple foreign keys)
 			foreignKeyPlugins, operationPluginList = getForeignKeyPlugins(
 				foreignKeyPlugins, operationPluginList, "route", operationBaseName)
@@ -814,43 +1760,141 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 			route["plugins"] = operationPluginList
 
 			// Escape path contents for regex creation
-			convertedPath := path
-			charsToEscape := []string{"(", ")", ".", "+", "?", "*", "["}
-			for _, char := range charsToEscape {
-				convertedPath = strings.ReplaceAll(convertedPath, char, "\\"+char)
-			}
+			convertedPath := escapePathRegex(path)
 
 			// convert path parameters to regex captures
 			re, _ := regexp.Compile("{([^}]+)}")
 			regexPriority := 200 // non-regexed (no params) paths have higher precedence in OAS
 			if matches := re.FindAllStringSubmatch(convertedPath, -1); matches != nil {
 				regexPriority = 100
+				captureNames := make(map[string]bool)
 				for _, match := range matches {
 					varName := match[1]
+					captureName := uniqueRegexCapture(sanitizeRegexCapture(varName), captureNames)
 					// match single segment; '/', '?', and '#' can mark the end of a segment
 					// see https://github.com/OAI/OpenAPI-Specification/issues/291#issuecomment-316593913
-					regexMatch := "(?<" + sanitizeRegexCapture(varName) + ">[^#?/]+)"
+					regexMatch := "(?<" + captureName + ">[^#?/]+)"
+					if opts.UseParamPatterns {
+						if values := getPathParameterEnum(pathitem.Parameters, operation.Parameters, varName); values != nil {
+							regexMatch = enumRegexCapture(captureName, values)
+						}
+					}
 					placeHolder := "{" + varName + "}"
 					logbasics.Debug("replacing path parameter", "parameter", placeHolder, "regex", regexMatch)
 					convertedPath = strings.Replace(convertedPath, placeHolder, regexMatch, 1)
 				}
 			}
+			if optionalSlash[path] {
+				// match both with and without the trailing slash
+				convertedPath = convertedPath + "/?"
+			}
 			route["paths"] = []string{"~" + convertedPath + "$"}
 			route["id"] = uuid.NewV5(opts.UUIDNamespace, operationBaseName+".route").String()
 			route["name"] = operationBaseName
 			route["methods"] = []string{method}
-			route["tags"] = kongTags
+			routeSources[operationBaseName] = RouteSummary{
+				Path:        path,
+				Method:      method,
+				OperationID: operation.OperationID,
+			}
+			route["tags"] = operationTags
+			if opts.PreserveDescriptions {
+				route["tags"] = addDocsTag(operationTags, operation.ExternalDocs)
+				if opts.PreserveLinks {
+					route["tags"] = addLinkTags(route["tags"].([]string), operation)
+				}
+			}
+			if callbackPaths[pathitem] {
+				tags, _ := route["tags"].([]string)
+				route["tags"] = append(append(make([]string, 0, len(tags)+1), tags...), CallbackTag)
+			}
+			if opts.PreserveExamples {
+				if examples := getOperationExamples(operation, operationBaseName); examples != nil {
+					existing, _ := route[examplesKey].([]interface{})
+					route[examplesKey] = append(existing, map[string]interface{}{"examples": examples})
+				}
+			}
+			if opts.GenerateSNIs {
+				if sniHosts, err = collectSNIHosts(sniHosts, operationServers); err != nil {
+					return nil, info, fmt.Errorf("failed to create snis for operation '%s %s': %w", path, method, err)
+				}
+			}
+			if opts.RouteByHost && route["hosts"] == nil {
+				hosts, err := getServerHosts(operationServers)
+				if err != nil {
+					return nil, info, fmt.Errorf("failed to create route hosts for operation '%s %s': %w", path, method, err)
+				}
+				if len(hosts) > 0 {
+					route["hosts"] = hosts
+				}
+			}
+			protocols, err := getRouteProtocols(
+				[]openapi3.ExtensionProps{operation.ExtensionProps, pathitem.ExtensionProps, doc.ExtensionProps},
+				operation, operationBaseName)
+			if err != nil {
+				return nil, info, fmt.Errorf("failed to create route protocols for operation '%s %s': %w", path, method, err)
+			}
+			if protocols != nil {
+				route["protocols"] = protocols
+			}
 			route["regex_priority"] = regexPriority
-			route["strip_path"] = false // TODO: there should be some logic around defaults etc iirc
+			stripPath, err := getStripPath(operation.ExtensionProps, pathitem.ExtensionProps, opts.StripPath)
+			if err != nil {
+				return nil, info, fmt.Errorf("failed to get strip_path for operation '%s %s': %w", path, method, err)
+			}
+			route["strip_path"] = stripPath
+			defaultPathHandling := opts.PathHandling
+			if route["path_handling"] != nil {
+				defaultPathHandling = "" // keep the one from the route defaults
+			}
+			pathHandling, err := getPathHandling(operation.ExtensionProps, pathitem.ExtensionProps, defaultPathHandling)
+			if err != nil {
+				return nil, info, fmt.Errorf("failed to get path_handling for operation '%s %s': %w", path, method, err)
+			}
+			if pathHandling != "" {
+				route["path_handling"] = pathHandling
+			}
+			headers, err := getRouteHeaders(operation.ExtensionProps, pathitem.ExtensionProps)
+			if err != nil {
+				return nil, info, fmt.Errorf("failed to get headers for operation '%s %s': %w", path, method, err)
+			}
+			if headers != nil {
+				route["headers"] = headers // replaces any headers from the route defaults
+			}
+			streaming, err := isStreaming(operation.ExtensionProps, opts.DisableBuffering)
+			if err != nil {
+				return nil, info, fmt.Errorf("failed to get buffering for operation '%s %s': %w", path, method, err)
+			}
+			if streaming {
+				applyStreaming(route)
+			}
+			if isUpload(operation.ExtensionProps) {
+				applyUpload(route)
+			}
 
 			operationRoutes = append(operationRoutes, route)
+			routeCount++
 			operationService["routes"] = operationRoutes
 		}
 	}
 
+	if routeCount == 0 && len(paths) > 0 && opts.RequireRoutes {
+		return nil, info, fmt.Errorf("no routes to generate; all operations were skipped")
+	}
+
+	moveRemovedServicePlugins(services, removedPlugins, opts.UUIDNamespace)
+	if len(tagServices) > 0 {
+		services, foreignKeyPlugins = removeUnusedDocService(services, docService, foreignKeyPlugins)
+	}
+	applyPathStrategy(services, opts.PathStrategy)
+
 	// export arrays with services, upstreams, and plugins to the final object
-	result["services"] = services
-	result["upstreams"] = upstreams
+	if opts.PluginOverlayOnly {
+		foreignKeyPlugins = createPluginOverlay(services, foreignKeyPlugins)
+	} else {
+		result["services"] = services
+		result["upstreams"] = upstreams
+	}
 	if len(*foreignKeyPlugins) > 0 {
 		sort.Slice(*foreignKeyPlugins,
 			func(i, j int) bool {
@@ -862,8 +1906,23 @@ func Convert(content *[]byte, opts O2kOptions) (map[string]interface{}, error) {
 			})
 		result["plugins"] = foreignKeyPlugins
 	}
+	if len(sniHosts) > 0 && !opts.PluginOverlayOnly {
+		result["snis"] = createKongSNIs(sniHosts, opts.UUIDNamespace, docBaseName, kongTags)
+	}
+	if len(vaults) > 0 && !opts.PluginOverlayOnly {
+		result["vaults"] = vaults
+	}
+	info.Summary = getSummary(services, routeSources)
+	if opts.PluginOverlayOnly {
+		// only the top-level plugins are generated, they include the ones of the services and routes
+		info.Stats = getStats(nil, foreignKeyPlugins, info.Stats.SkippedOperations)
+	} else {
+		info.Stats = getStats(services, foreignKeyPlugins, info.Stats.SkippedOperations)
+	}
+	applyEmitKeys(result, services, isEmitted(opts.EmitNames), isEmitted(opts.EmitIDs))
+	filterSections(result, opts.EmitSections)
 
 	// we're done!
 	logbasics.Debug("finished processing document")
-	return result, nil
+	return result, info, nil
 }
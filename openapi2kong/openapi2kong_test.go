@@ -1,12 +1,18 @@
 package openapi2kong
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/go-logr/logr/funcr"
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+	uuid "github.com/satori/go.uuid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -39,3 +45,1933 @@ func Test_Openapi2kong(t *testing.T) {
 		}
 	}
 }
+
+// loadFixture returns the content of a file in the fixture directory.
+func loadFixture(t *testing.T, filename string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(fixturePath + filename)
+	if err != nil {
+		t.Fatalf("failed reading fixture '%s': %v", filename, err)
+	}
+	return data
+}
+
+// getServices returns the services of a conversion result.
+func getServices(result map[string]interface{}) []map[string]interface{} {
+	list, _ := result["services"].([]interface{})
+	services := make([]map[string]interface{}, 0, len(list))
+	for _, service := range list {
+		services = append(services, service.(map[string]interface{}))
+	}
+	return services
+}
+
+// getServiceRoutes returns the routes of a service.
+func getServiceRoutes(service map[string]interface{}) []map[string]interface{} {
+	list, _ := service["routes"].([]interface{})
+	routes := make([]map[string]interface{}, 0, len(list))
+	for _, route := range list {
+		routes = append(routes, route.(map[string]interface{}))
+	}
+	return routes
+}
+
+// getRouteNames returns the names of the routes of all services, in the order generated.
+func getRouteNames(result map[string]interface{}) []string {
+	names := make([]string, 0)
+	for _, service := range getServices(result) {
+		for _, route := range getServiceRoutes(service) {
+			names = append(names, route["name"].(string))
+		}
+	}
+	return names
+}
+
+// getRouteNamesByService returns the names of the routes, in the order generated, by
+// service name.
+func getRouteNamesByService(result map[string]interface{}) map[string][]string {
+	names := make(map[string][]string)
+	for _, service := range getServices(result) {
+		routes := make([]string, 0)
+		for _, route := range getServiceRoutes(service) {
+			routes = append(routes, route["name"].(string))
+		}
+		names[service["name"].(string)] = routes
+	}
+	return names
+}
+
+// getRoute returns the route by name, from any of the services. Nil if not found.
+func getRoute(result map[string]interface{}, name string) map[string]interface{} {
+	for _, service := range getServices(result) {
+		for _, route := range getServiceRoutes(service) {
+			if route["name"] == name {
+				return route
+			}
+		}
+	}
+	return nil
+}
+
+// getRoutePlugins returns the configs of the plugins of the routes of all services, by
+// plugin name, by route name.
+func getRoutePlugins(result map[string]interface{}) map[string]map[string]interface{} {
+	plugins := make(map[string]map[string]interface{})
+	for _, service := range getServices(result) {
+		for _, route := range getServiceRoutes(service) {
+			plugins[route["name"].(string)] = getPluginConfigs(route)
+		}
+	}
+	return plugins
+}
+
+// getRoutePluginConfigs returns the config of a plugin on the routes of all services, by
+// route name. The routes without the plugin are omitted.
+func getRoutePluginConfigs(result map[string]interface{}, pluginName string) map[string]interface{} {
+	configs := make(map[string]interface{})
+	for _, service := range getServices(result) {
+		for _, route := range getServiceRoutes(service) {
+			if plugin, found := getPlugins(route)[pluginName]; found {
+				configs[route["name"].(string)] = plugin["config"]
+			}
+		}
+	}
+	return configs
+}
+
+// getRouteValues returns a field of the routes of all services, by route name. Nil for the
+// routes without the field.
+func getRouteValues(result map[string]interface{}, field string) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, service := range getServices(result) {
+		for _, route := range getServiceRoutes(service) {
+			values[route["name"].(string)] = route[field]
+		}
+	}
+	return values
+}
+
+// getPlugins returns the plugins of an entity (eg. a service or route), by plugin name.
+func getPlugins(entity map[string]interface{}) map[string]map[string]interface{} {
+	plugins := make(map[string]map[string]interface{})
+	if list, _ := entity["plugins"].(*[]*map[string]interface{}); list != nil {
+		for _, plugin := range *list {
+			plugins[(*plugin)["name"].(string)] = *plugin
+		}
+	}
+	return plugins
+}
+
+// getEntityPluginNames returns the names of the plugins of an entity, in the order generated
+// (including duplicates, eg. for multiple instances).
+func getEntityPluginNames(entity map[string]interface{}) []string {
+	names := make([]string, 0)
+	if list, _ := entity["plugins"].(*[]*map[string]interface{}); list != nil {
+		for _, plugin := range *list {
+			names = append(names, (*plugin)["name"].(string))
+		}
+	}
+	return names
+}
+
+// getPluginConfigs returns the configs of the plugins of an entity, by plugin name.
+func getPluginConfigs(entity map[string]interface{}) map[string]interface{} {
+	configs := make(map[string]interface{})
+	for name, plugin := range getPlugins(entity) {
+		configs[name] = plugin["config"]
+	}
+	return configs
+}
+
+func Test_getDocBaseName(t *testing.T) {
+	docWithName := []byte(`openapi: 3.0.0
+x-kong-name: kong name
+info:
+  title: the title
+paths: {}
+`)
+	docWithTitle := []byte(`openapi: 3.0.0
+info:
+  title: the title
+paths: {}
+`)
+	docEmptyTitle := []byte(`openapi: 3.0.0
+info:
+  title: ""
+paths: {}
+`)
+
+	tests := []struct {
+		name     string
+		spec     []byte
+		opts     O2kOptions
+		expected string
+	}{
+		{"specified", docWithName, O2kOptions{DocName: "my name", SpecFilename: "file.yaml"}, "my-name"},
+		{"x-kong-name", docWithName, O2kOptions{SpecFilename: "file.yaml"}, "kong-name"},
+		{"info.title", docWithTitle, O2kOptions{SpecFilename: "file.yaml"}, "the-title"},
+		{"filename", docEmptyTitle, O2kOptions{SpecFilename: "/some/path/my-spec.yaml"}, "my-spec"},
+	}
+
+	for _, tst := range tests {
+		_, info, err := ConvertWithInfo(&tst.spec, tst.opts)
+		if err != nil {
+			t.Errorf("'%s' didn't expect error: %v", tst.name, err)
+		}
+		assert.Equal(t, tst.expected, info.DocName, "'%s': resolved document name", tst.name)
+	}
+
+	// random name if nothing else is available, stdin is not a filename
+	_, info, err := ConvertWithInfo(&docEmptyTitle, O2kOptions{SpecFilename: "-"})
+	if err != nil {
+		t.Errorf("'random' didn't expect error: %v", err)
+	}
+	_, err = uuid.FromString(info.DocName)
+	assert.Nil(t, err, "expected a random uuid as document name, got '%s'", info.DocName)
+}
+
+func Test_getDocBaseNameRequired(t *testing.T) {
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: ""
+paths: {}
+`)
+	_, err := Convert(&spec, O2kOptions{SpecFilename: "-", RequireDocName: true})
+	assert.EqualError(t, err, "no document name available for uuid generation; set 'x-kong-name' or "+
+		"a non-empty 'info.title' in the spec, or specify a uuid-base")
+
+	result, err := Convert(&spec, O2kOptions{SpecFilename: "-", DocName: "given", RequireDocName: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "given", getServices(result)[0]["name"])
+}
+
+func Test_getDocBaseNameLogged(t *testing.T) {
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 1})
+	logbasics.SetLogger(&logger)
+	defer logbasics.SetLogger(nil)
+
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: the title
+paths: {}
+`)
+	_, err := Convert(&spec, O2kOptions{})
+	if err != nil {
+		t.Errorf("didn't expect error: %v", err)
+	}
+	assert.Contains(t, logs,
+		`"level"=1 "msg"="document name (namespace for UUID generation)" "name"="the-title"`)
+}
+
+func Test_RequireSuccessResponse(t *testing.T) {
+	spec := loadFixture(t, "17-require-success-response.yaml")
+
+	result, err := Convert(&spec, O2kOptions{RequireSuccessResponse: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"stubs_working_get"}, getRouteNames(result))
+}
+
+func Test_IPRestrictionInvalidCIDR(t *testing.T) {
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: bad cidr
+paths:
+  /internal:
+    get:
+      x-kong-ip-restriction:
+        allow:
+          - 10.0.0.0/8
+          - 10.0.0.0/33
+      responses:
+        "200":
+          description: OK
+`)
+	_, err := Convert(&spec, O2kOptions{})
+	assert.EqualError(t, err, "failed to create ip-restriction plugin from operation '/internal GET': "+
+		"invalid IP address or CIDR '10.0.0.0/33' in 'x-kong-ip-restriction.allow'")
+}
+
+func Test_TrailingSlash(t *testing.T) {
+	spec := loadFixture(t, "18-trailing-slash.yaml")
+
+	result, err := Convert(&spec, O2kOptions{TrailingSlash: TrailingSlashStrict})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"slashes_other~_get":  []string{"~/other/$"},
+		"slashes_users_get":   []string{"~/users$"},
+		"slashes_users~_get":  []string{"~/users/$"},
+		"slashes_users~_post": []string{"~/users/$"},
+	}, getRouteValues(result, "paths"), "strict")
+
+	result, err = Convert(&spec, O2kOptions{TrailingSlash: TrailingSlashMerge})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"slashes_other~_get": []string{"~/other/$"},
+		"slashes_users_get":  []string{"~/users/?$"},
+		"slashes_users_post": []string{"~/users/?$"},
+	}, getRouteValues(result, "paths"), "merge")
+
+	result, err = Convert(&spec, O2kOptions{TrailingSlash: TrailingSlashStrip})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"slashes_other_get":  []string{"~/other$"},
+		"slashes_users_get":  []string{"~/users$"},
+		"slashes_users_post": []string{"~/users$"},
+	}, getRouteValues(result, "paths"), "strip")
+
+	_, err = Convert(&spec, O2kOptions{TrailingSlash: "bad"})
+	assert.EqualError(t, err, "expected trailing slash policy to be one of 'strict', 'merge', or 'strip', got: 'bad'")
+}
+
+func Test_TrailingSlashMergeWarnsWhenLossy(t *testing.T) {
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 1})
+	logbasics.SetLogger(&logger)
+	defer logbasics.SetLogger(nil)
+
+	spec := loadFixture(t, "18-trailing-slash.yaml")
+	_, err := Convert(&spec, O2kOptions{TrailingSlash: TrailingSlashMerge})
+	assert.Nil(t, err)
+	assert.Contains(t, logs, `"level"=0 "msg"="WARNING: merging paths, operation is defined on both, the second `+
+		`is dropped" "method"="GET" "path"="/users" "dropped"="/users/"`)
+}
+
+func Test_ValidatePluginConfig(t *testing.T) {
+	specTemplate := `openapi: 3.0.0
+info:
+  title: plugin validation
+paths:
+  /limited:
+    get:
+      x-kong-plugin-rate-limiting:
+        config:
+          %s
+      x-kong-plugin-my-custom-plugin:
+        config:
+          anything: goes
+      responses:
+        "200":
+          description: OK
+`
+	valid := []byte(fmt.Sprintf(specTemplate, "minute: 10\n          policy: local"))
+	invalid := []byte(fmt.Sprintf(specTemplate, "minutes: 10\n          policy: somewhere"))
+
+	_, err := Convert(&valid, O2kOptions{ValidatePluginConfig: true})
+	assert.Nil(t, err)
+
+	_, err = Convert(&invalid, O2kOptions{ValidatePluginConfig: true})
+	assert.EqualError(t, err, "invalid config for plugin 'rate-limiting' on operation 'GET /limited'; "+
+		"config.policy: value is not one of the allowed values; config: property \"minutes\" is unsupported")
+
+	// validation is off by default
+	_, err = Convert(&invalid, O2kOptions{})
+	assert.Nil(t, err)
+}
+
+func Test_EmitSections(t *testing.T) {
+	dataIn := loadFixture(t, "09a-plugins-with-consumers.yaml")
+
+	result, err := Convert(&dataIn, O2kOptions{EmitSections: []string{"plugins"}})
+	assert.Nil(t, err)
+	keys := make([]string, 0)
+	for key := range result {
+		keys = append(keys, key)
+	}
+	assert.ElementsMatch(t, []string{"_format_version", "plugins"}, keys)
+	assert.NotEmpty(t, result["plugins"])
+
+	_, err = Convert(&dataIn, O2kOptions{EmitSections: []string{"plugins", "routes"}})
+	assert.EqualError(t, err, "unknown section 'routes' requested, expected one of: "+
+		"'consumers', 'plugins', 'services', 'snis', 'upstreams', 'vaults'")
+}
+
+func Test_PreserveDescriptions(t *testing.T) {
+	spec := loadFixture(t, "19-preserve-descriptions.yaml")
+
+	result, err := Convert(&spec, O2kOptions{PreserveDescriptions: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"team-a", "docs:https:%2F%2Fexample.com%2Fdocs"}, getServices(result)[0]["tags"])
+	assert.Equal(t, map[string]interface{}{
+		"documented_documented_get":   []string{"team-a", "docs:https:%2F%2Fexample.com%2Fdocs%2Flist?a=1%2C2"},
+		"documented_undocumented_get": []string{"team-a"},
+	}, getRouteValues(result, "tags"))
+}
+
+func Test_FunctionExtensions(t *testing.T) {
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: functions
+x-kong-post-function:
+  - file: missing.lua
+paths: {}
+`)
+	_, err := Convert(&spec, O2kOptions{BaseDir: "scripts"})
+	assert.ErrorContains(t, err, "failed to convert functions on document root: "+
+		"failed to read file 'scripts/missing.lua' for 'x-kong-post-function'")
+}
+
+func Test_GrpcGateway(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "hello.proto"), []byte(`syntax = "proto3";`), 0o600))
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: grpc
+x-kong-grpc-gateway: hello.proto
+paths:
+  /hello:
+    get:
+      responses:
+        "200":
+          description: OK
+`)
+	result, err := Convert(&spec, O2kOptions{BaseDir: dir})
+	assert.Nil(t, err)
+	service := getServices(result)[0]
+	plugins := getPlugins(service)
+	assert.Len(t, plugins, 1)
+	assert.Equal(t, map[string]interface{}{
+		"proto": filepath.Join(dir, "hello.proto"),
+	}, plugins["grpc-gateway"]["config"])
+	assert.Empty(t, getPlugins(getServiceRoutes(service)[0]))
+
+	_, err = Convert(&spec, O2kOptions{BaseDir: "protos"})
+	assert.ErrorContains(t, err, "failed to create grpc-gateway plugin from document root: "+
+		"failed to find proto file 'protos/hello.proto' for 'x-kong-grpc-gateway'")
+}
+
+func Test_RouteByHost(t *testing.T) {
+	spec := loadFixture(t, "21-route-by-host.yaml")
+
+	result, err := Convert(&spec, O2kOptions{RouteByHost: true})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"hosts_users_get": []string{"api.example.com", "backup.example.com"},
+		"hosts_admin_get": []string{"admin.example.com"},
+	}, getRouteValues(result, "hosts"))
+}
+
+func Test_GenerateSNIs(t *testing.T) {
+	spec := loadFixture(t, "22-generate-snis.yaml")
+
+	result, err := Convert(&spec, O2kOptions{GenerateSNIs: true})
+	assert.Nil(t, err)
+	certificateID := uuid.NewV5(uuid.NamespaceDNS, "snis.certificate").String()
+	names := make([]string, 0)
+	for _, sni := range result["snis"].([]interface{}) {
+		s := sni.(map[string]interface{})
+		names = append(names, s["name"].(string))
+		assert.Equal(t, map[string]interface{}{"id": certificateID}, s["certificate"])
+		assert.Equal(t, uuid.NewV5(uuid.NamespaceDNS, "snis.sni."+s["name"].(string)).String(), s["id"])
+	}
+	assert.Equal(t, []string{"admin.example.com", "api.example.com", "*.example.org"}, names)
+}
+
+func Test_ExternalReferences(t *testing.T) {
+	dir := t.TempDir()
+	responses := []byte(`ok:
+  description: OK
+`)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "responses.yaml"), responses, 0o600))
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: external
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          $ref: "./responses.yaml#/ok"
+`)
+
+	result, err := Convert(&spec, O2kOptions{BaseDir: dir, AllowExternalRefs: true})
+	assert.Nil(t, err)
+	assert.Len(t, result["services"], 1)
+
+	result, err = Convert(&spec, O2kOptions{SpecFilename: filepath.Join(dir, "spec.yaml"), AllowExternalRefs: true})
+	assert.Nil(t, err)
+	assert.Len(t, result["services"], 1)
+
+	_, err = Convert(&spec, O2kOptions{BaseDir: t.TempDir(), AllowExternalRefs: true})
+	assert.ErrorContains(t, err, "responses.yaml")
+
+	// not loaded by default
+	_, err = Convert(&spec, O2kOptions{BaseDir: dir})
+	assert.ErrorContains(t, err, "external reference not allowed: local file '"+
+		filepath.Join(dir, "responses.yaml")+"'")
+	validateRefs := false
+	_, err = Convert(&spec, O2kOptions{BaseDir: dir, ValidateRefs: &validateRefs})
+	assert.ErrorContains(t, err, "external reference not allowed: local file")
+}
+
+func Test_ExternalReferencesConfined(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "spec"), 0o700))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "responses.yaml"), []byte(`ok:
+  description: OK
+`), 0o600))
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: external
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          $ref: "../responses.yaml#/ok"
+        "201":
+          $ref: "` + filepath.ToSlash(filepath.Join(dir, "responses.yaml")) + `#/ok"
+`)
+
+	_, err := Convert(&spec, O2kOptions{BaseDir: filepath.Join(dir, "spec"), AllowExternalRefs: true})
+	assert.ErrorContains(t, err, "found 2 unresolvable references")
+	assert.ErrorContains(t, err, "is outside of '"+filepath.Join(dir, "spec")+"'")
+
+	_, err = Convert(&spec, O2kOptions{BaseDir: dir, AllowExternalRefs: true})
+	assert.ErrorContains(t, err, "found 1 unresolvable references: '../responses.yaml#/ok'")
+
+	spec = []byte(`openapi: 3.0.0
+info:
+  title: remote
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          $ref: "http://127.0.0.1:1/responses.yaml#/ok"
+`)
+	_, err = Convert(&spec, O2kOptions{BaseDir: dir, AllowExternalRefs: true})
+	assert.ErrorContains(t, err, "external reference not allowed: remote document 'http://127.0.0.1:1/responses.yaml'")
+}
+
+func Test_ExternalPaths(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "api"), 0o700))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "api", "paths.yaml"), []byte(`/users:
+  get:
+    responses:
+      "200":
+        $ref: "./responses.yaml#/ok"
+/users/{id}:
+  $ref: "./user.yaml"
+`), 0o600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "api", "user.yaml"), []byte(`get:
+  responses:
+    "200":
+      $ref: "./responses.yaml#/ok"
+`), 0o600))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "api", "responses.yaml"), []byte(`ok:
+  description: OK
+`), 0o600))
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: fragments
+paths:
+  $ref: "./api/paths.yaml"
+`)
+
+	result, err := Convert(&spec, O2kOptions{BaseDir: dir, AllowExternalRefs: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"fragments_users_get", "fragments_users-id_get"}, getRouteNames(result))
+
+	emptyDir := t.TempDir()
+	_, err = Convert(&spec, O2kOptions{BaseDir: emptyDir, AllowExternalRefs: true})
+	assert.ErrorContains(t, err, "found 1 unresolvable references: './api/paths.yaml' at '#/paths/$ref'")
+
+	validateRefs := false
+	_, err = Convert(&spec, O2kOptions{BaseDir: emptyDir, ValidateRefs: &validateRefs, AllowExternalRefs: true})
+	assert.ErrorContains(t, err, "failed to resolve 'paths'")
+}
+
+func Test_RouteProtocols(t *testing.T) {
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 1})
+	logbasics.SetLogger(&logger)
+	defer logbasics.SetLogger(nil)
+
+	spec := loadFixture(t, "23-route-protocols.yaml")
+	_, err := Convert(&spec, O2kOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, logs, `"level"=0 "msg"="WARNING: route mixes gRPC and non-gRPC protocols" `+
+		`"route"="protocols_mixed_post" "protocols"=["https","grpcs"]`)
+}
+
+func Test_EmptyPaths(t *testing.T) {
+	specs := map[string][]byte{
+		"missing": []byte(`openapi: 3.0.0
+info:
+  title: no paths
+`),
+		"empty": []byte(`openapi: 3.0.0
+info:
+  title: no paths
+paths: {}
+`),
+	}
+
+	for name, spec := range specs {
+		spec := spec
+		result, err := Convert(&spec, O2kOptions{})
+		assert.Nil(t, err, name)
+		services := getServices(result)
+		assert.Len(t, services, 1, name)
+		assert.Empty(t, getServiceRoutes(services[0]), name)
+
+		_, err = Convert(&spec, O2kOptions{RequireRoutes: true})
+		assert.EqualError(t, err, "no routes to generate; the 'paths' object is empty or missing", name)
+	}
+
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: stubs only
+paths:
+  /stub:
+    get:
+      responses:
+        "500":
+          description: not implemented yet
+`)
+	_, err := Convert(&spec, O2kOptions{RequireRoutes: true, RequireSuccessResponse: true})
+	assert.EqualError(t, err, "no routes to generate; all operations were skipped")
+}
+
+func Test_NamePrefix(t *testing.T) {
+	spec := loadFixture(t, "24-name-prefix.yaml")
+
+	_, info, err := ConvertWithInfo(&spec, O2kOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "team-a_my-api", info.DocName)
+
+	// the option takes precedence over the extension
+	result, err := Convert(&spec, O2kOptions{NamePrefix: "team b"})
+	assert.Nil(t, err)
+	service := getServices(result)[0]
+	route := getServiceRoutes(service)[0]
+	assert.Equal(t, "team-b_my-api", service["name"])
+	assert.Equal(t, "team-b_my-api_users_get", route["name"])
+	assert.Equal(t, uuid.NewV5(uuid.NamespaceDNS, "team-b_my-api.service").String(), service["id"])
+	assert.Equal(t, uuid.NewV5(uuid.NamespaceDNS, "team-b_my-api_users_get.route").String(), route["id"])
+}
+
+func Test_PluginMergeStrategy(t *testing.T) {
+	spec := loadFixture(t, "25-plugin-merge-strategy.yaml")
+
+	result, err := Convert(&spec, O2kOptions{PluginMergeStrategy: PluginMergeMerge})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"cors": map[string]interface{}{
+			"origins":     []interface{}{"c.example.com"}, // arrays are replaced, not merged
+			"credentials": true,
+		},
+		"rate-limiting": map[string]interface{}{
+			"minute": float64(5),
+			"policy": "local",
+		},
+	}, getPluginConfigs(getServiceRoutes(getServices(result)[0])[0]))
+
+	_, err = Convert(&spec, O2kOptions{PluginMergeStrategy: "append"})
+	assert.EqualError(t, err, "expected plugin merge strategy to be one of 'replace', or 'merge', got: 'append'")
+}
+
+func Test_AddTracingHeaders(t *testing.T) {
+	spec := loadFixture(t, "26-tracing-headers.yaml")
+
+	result, err := Convert(&spec, O2kOptions{
+		AddTracingHeaders: true,
+		TracingHeaders:    []string{"X-Request-ID:generated", "X-Trace:$(headers.traceparent)"},
+	})
+	assert.Nil(t, err)
+	services := getServices(result)
+	assert.Len(t, services, 2)
+	for _, service := range services {
+		assert.Equal(t, []string{"request-transformer"}, getEntityPluginNames(service))
+		assert.Equal(t, map[string]interface{}{
+			"add": map[string]interface{}{
+				"headers": []string{"X-Source:kong", "x-request-id:keep-me", "X-Trace:$(headers.traceparent)"},
+			},
+		}, getPluginConfigs(service)["request-transformer"])
+	}
+
+	// defaults
+	spec = []byte(`openapi: 3.0.0
+info:
+  title: tracing
+paths: {}
+`)
+	result, err = Convert(&spec, O2kOptions{AddTracingHeaders: true})
+	assert.Nil(t, err)
+	service := getServices(result)[0]
+	assert.Equal(t, []string{"request-transformer"}, getEntityPluginNames(service))
+	assert.Equal(t, map[string]interface{}{
+		"add": map[string]interface{}{"headers": DefaultTracingHeaders},
+	}, getPluginConfigs(service)["request-transformer"])
+}
+
+func Test_EnsureCorrelationID(t *testing.T) {
+	spec := loadFixture(t, "27-correlation-id.yaml")
+
+	result, err := Convert(&spec, O2kOptions{EnsureCorrelationID: true})
+	assert.Nil(t, err)
+	services := getServices(result)
+	assert.Len(t, services, 2)
+	configs := make(map[string]interface{})
+	for _, service := range services {
+		assert.Equal(t, []string{"correlation-id"}, getEntityPluginNames(service))
+		configs[service["name"].(string)] = getPluginConfigs(service)["correlation-id"]
+		for _, route := range getServiceRoutes(service) {
+			assert.NotContains(t, getEntityPluginNames(route), "correlation-id")
+		}
+	}
+	assert.Equal(t, map[string]interface{}{
+		"correlation":       map[string]interface{}{"header_name": "Kong-Request-ID", "generator": "uuid"},
+		"correlation_admin": map[string]interface{}{"header_name": "X-Admin-ID"},
+	}, configs)
+
+	// configured header and generator
+	spec = []byte(`openapi: 3.0.0
+info:
+  title: correlation
+paths: {}
+`)
+	result, err = Convert(&spec, O2kOptions{
+		EnsureCorrelationID:    true,
+		CorrelationIDHeader:    "X-Correlation-ID",
+		CorrelationIDGenerator: "tracker",
+	})
+	assert.Nil(t, err)
+	service := getServices(result)[0]
+	assert.Equal(t, []string{"correlation-id"}, getEntityPluginNames(service))
+	assert.Equal(t, map[string]interface{}{"header_name": "X-Correlation-ID", "generator": "tracker"},
+		getPluginConfigs(service)["correlation-id"])
+}
+
+func Test_ParameterDefaults(t *testing.T) {
+	spec := loadFixture(t, "28-parameter-defaults.yaml")
+
+	result, err := Convert(&spec, O2kOptions{ParameterDefaults: true})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"add": map[string]interface{}{
+			"headers":     []string{"X-Version:v1"},
+			"querystring": []string{"limit:10"},
+		},
+	}, getPluginConfigs(getRoute(result, "defaults_users-id_get"))["request-transformer"])
+
+	// the route plugin replaces the service one, so it must retain the tracing headers
+	result, err = Convert(&spec, O2kOptions{ParameterDefaults: true, AddTracingHeaders: true})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"add": map[string]interface{}{
+			"headers":     []string{DefaultTracingHeaders[0], "X-Version:v1"},
+			"querystring": []string{"limit:10"},
+		},
+	}, getPluginConfigs(getRoute(result, "defaults_users-id_get"))["request-transformer"])
+}
+
+func Test_DisabledPlugins(t *testing.T) {
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: disabled
+x-kong-plugin-correlation-id:
+  enabled: "no"
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: OK
+`)
+	_, err := Convert(&spec, O2kOptions{})
+	assert.ErrorContains(t, err, "expected 'enabled' in 'x-kong-plugin-correlation-id' to be a boolean")
+}
+
+func Test_ServiceKeepalive(t *testing.T) {
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: keepalive
+x-kong-service-keepalive:
+  retries: 1.5
+paths: {}
+`)
+	_, err := Convert(&spec, O2kOptions{})
+	assert.EqualError(t, err, "expected 'x-kong-service-keepalive.retries' to be an integer")
+}
+
+func Test_StrictValidation(t *testing.T) {
+	spec := loadFixture(t, "31-strict-validation.yaml")
+
+	result, err := Convert(&spec, O2kOptions{StrictValidation: true})
+	assert.Nil(t, err)
+	for name, expected := range map[string]string{
+		"strict_closed_post": `{"additionalProperties":false,"properties":{"name":{"type":"string"}},` +
+			`"type":"object"}`,
+		"strict_open_post": `{"additionalProperties":true,"properties":{"name":{"type":"string"}},` +
+			`"type":"object"}`,
+		"strict_omitted_post": `{"additionalProperties":false,"properties":{"address":{"additionalProperties":false,` +
+			`"properties":{"street":{"type":"string"}},"type":"object"}},"type":"object"}`,
+	} {
+		config := getPluginConfigs(getRoute(result, name))["request-validator"].(map[string]interface{})
+		assert.JSONEq(t, expected, config["body_schema"].(string), name)
+	}
+}
+
+func Test_strictSchemaAllOf(t *testing.T) {
+	// members of an allOf must remain open, otherwise they reject each others properties
+	schema := strictSchema(`{"allOf":[{"$ref":"#/definitions/a"},{"type":"object"}],` +
+		`"definitions":{"a":{"type":"object"},"b":{"type":"object"}}}`)
+	assert.JSONEq(t, `{"allOf":[{"$ref":"#/definitions/a"},{"type":"object"}],`+
+		`"definitions":{"a":{"type":"object"},"b":{"type":"object","additionalProperties":false}}}`, schema)
+}
+
+func Test_GenerateMock(t *testing.T) {
+	spec := loadFixture(t, "33-generate-mock.yaml")
+
+	result, err := Convert(&spec, O2kOptions{GenerateMock: true})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"mock_users_post": map[string]interface{}{
+			"status_code":  201,
+			"content_type": "application/json",
+			"body":         `{"id":123}`,
+		},
+		"mock_users_get": map[string]interface{}{
+			"status_code":  200,
+			"content_type": "text/plain",
+			"body":         "hello",
+		},
+	}, getRoutePluginConfigs(result, "request-termination"))
+
+	spec = []byte(`openapi: 3.0.0
+info:
+  title: mock
+paths:
+  /users:
+    get:
+      x-kong-mock-status: 202
+      responses:
+        "200":
+          description: OK
+`)
+	_, err = Convert(&spec, O2kOptions{GenerateMock: true})
+	assert.EqualError(t, err, "failed to create mock plugin from operation '/users GET': "+
+		"'x-kong-mock-status' response '202' not found in the operation responses")
+}
+
+func Test_BlockInternal(t *testing.T) {
+	spec := loadFixture(t, "34-block-internal.yaml")
+
+	result, err := Convert(&spec, O2kOptions{BlockInternal: true})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"internal_admin_get": map[string]interface{}{
+			"status_code": 403,
+			"message":     "Forbidden",
+		},
+	}, getRoutePluginConfigs(result, "request-termination"))
+}
+
+func Test_StripPath(t *testing.T) {
+	spec := loadFixture(t, "35-strip-path.yaml")
+
+	stripPath := true
+	result, err := Convert(&spec, O2kOptions{StripPath: &stripPath})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"strip_users_get":  true,
+		"strip_users_post": false,
+		"strip_admin_get":  true,
+	}, getRouteValues(result, "strip_path"))
+}
+
+func Test_PluginOverlayOnly(t *testing.T) {
+	spec := loadFixture(t, "36-plugin-overlay-only.yaml")
+	full, err := Convert(&spec, O2kOptions{})
+	assert.Nil(t, err)
+	route := getRoute(full, "overlay_users_get")
+
+	result, err := Convert(&spec, O2kOptions{PluginOverlayOnly: true})
+	assert.Nil(t, err)
+	assert.Nil(t, result["services"])
+	assert.Nil(t, result["upstreams"])
+	assert.Equal(t, "3.0", result["_format_version"])
+
+	refs := make(map[string]interface{})
+	for name, plugin := range getPlugins(result) {
+		if plugin["route"] != nil {
+			refs[name] = "route:" + plugin["route"].(string)
+			assert.Equal(t, getPlugins(route)[name]["id"], plugin["id"])
+		} else {
+			refs[name] = "service:" + plugin["service"].(string)
+		}
+	}
+	assert.Equal(t, map[string]interface{}{
+		"cors":          "service:overlay",
+		"rate-limiting": "route:overlay_users_get",
+	}, refs)
+}
+
+func Test_UpstreamRef(t *testing.T) {
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: upstreamref
+x-kong-upstream: my-upstream
+x-kong-upstream-defaults:
+  slots: 100
+paths: {}
+`)
+	_, err := Convert(&spec, O2kOptions{})
+	assert.EqualError(t, err, "failed to create service/upstream from document root: "+
+		"'x-kong-upstream' and 'x-kong-upstream-defaults' cannot be used together")
+}
+
+func Test_PathStrategy(t *testing.T) {
+	tests := []struct {
+		name         string
+		serverURL    string
+		strategy     string
+		wantPaths    []string
+		wantSvcPaths string
+	}{
+		{
+			name:         "operation strategy keeps the base path on the service",
+			serverURL:    "https://example.com/v1",
+			strategy:     PathStrategyOperation,
+			wantPaths:    []string{"~/users$", "~/users/(?<id>[^#?/]+)$"},
+			wantSvcPaths: "/v1",
+		},
+		{
+			name:         "prefix strategy, base path without trailing slash",
+			serverURL:    "https://example.com/v1",
+			strategy:     PathStrategyPrefix,
+			wantPaths:    []string{"~/v1/users$", "~/v1/users/(?<id>[^#?/]+)$"},
+			wantSvcPaths: "/",
+		},
+		{
+			name:         "prefix strategy, base path with trailing slash",
+			serverURL:    "https://example.com/v1/",
+			strategy:     PathStrategyPrefix,
+			wantPaths:    []string{"~/v1/users$", "~/v1/users/(?<id>[^#?/]+)$"},
+			wantSvcPaths: "/",
+		},
+		{
+			name:         "prefix strategy, nested base path with regex characters",
+			serverURL:    "https://example.com/api/v1.0/",
+			strategy:     PathStrategyPrefix,
+			wantPaths:    []string{"~/api/v1\\.0/users$", "~/api/v1\\.0/users/(?<id>[^#?/]+)$"},
+			wantSvcPaths: "/",
+		},
+		{
+			name:         "prefix strategy, root base path",
+			serverURL:    "https://example.com/",
+			strategy:     PathStrategyPrefix,
+			wantPaths:    []string{"~/users$", "~/users/(?<id>[^#?/]+)$"},
+			wantSvcPaths: "/",
+		},
+		{
+			name:         "prefix strategy, no base path",
+			serverURL:    "https://example.com",
+			strategy:     PathStrategyPrefix,
+			wantPaths:    []string{"~/users$", "~/users/(?<id>[^#?/]+)$"},
+			wantSvcPaths: "/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := []byte(`openapi: 3.0.0
+info:
+  title: paths
+servers:
+  - url: ` + tt.serverURL + `
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: OK
+  /users/{id}:
+    get:
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: OK
+`)
+			result, err := Convert(&spec, O2kOptions{PathStrategy: tt.strategy})
+			assert.Nil(t, err)
+			service := getServices(result)[0]
+			assert.Equal(t, tt.wantSvcPaths, service["path"])
+			paths := make([]string, 0)
+			for _, route := range getServiceRoutes(service) {
+				paths = append(paths, route["paths"].([]string)...)
+			}
+			assert.Equal(t, tt.wantPaths, paths)
+		})
+	}
+
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: paths
+paths: {}
+`)
+	_, err := Convert(&spec, O2kOptions{PathStrategy: "bad"})
+	assert.EqualError(t, err, "expected path strategy to be one of 'operation', or 'prefix', got: 'bad'")
+}
+
+func Test_RateLimiting(t *testing.T) {
+	convert := func(rateLimiting string) error {
+		spec := []byte(`openapi: 3.0.0
+info:
+  title: ratelimit
+paths:
+  /users:
+    get:
+      x-kong-rate-limiting: ` + rateLimiting + `
+      responses:
+        "200":
+          description: OK
+`)
+		_, err := Convert(&spec, O2kOptions{})
+		return err
+	}
+
+	assert.EqualError(t, convert(`{ type: sliding-window, limits: { minute: 10 } }`),
+		"failed to create rate limiting plugin from operation '/users GET': "+
+			"expected 'x-kong-rate-limiting.type' to be one of 'rate-limiting', 'rate-limiting-advanced', "+
+			"or 'response-ratelimiting', got: 'sliding-window'")
+	assert.ErrorContains(t, convert(`{ limits: { week: 10 } }`), "unknown window 'week' in 'x-kong-rate-limiting.limits'")
+}
+
+func Test_StrictExtensions(t *testing.T) {
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: strict
+x-kong-plguin-cors:
+  config:
+    origins: ["*"]
+x-kong-strip-path: true
+paths:
+  /users:
+    x-kong-mock-status: 200
+    get:
+      x-kong-plugin-cors: {}
+      x-kong-name: list-users
+      responses:
+        "200":
+          description: OK
+`)
+	_, err := Convert(&spec, O2kOptions{})
+	assert.Nil(t, err, "extensions are not checked by default")
+
+	_, err = Convert(&spec, O2kOptions{StrictExtensions: true})
+	assert.EqualError(t, err, "unrecognized 'x-kong-...' extensions found; "+
+		"unknown extension 'x-kong-plguin-cors' on document root; "+
+		"extension 'x-kong-strip-path' is not supported on document level, found on document root; "+
+		"extension 'x-kong-mock-status' is not supported on path level, found on path '/users'")
+}
+
+func Test_PluginInstances(t *testing.T) {
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: instances
+x-kong-plugin-cors: [ "not an object" ]
+paths: {}
+`)
+	_, err := Convert(&spec, O2kOptions{})
+	assert.ErrorContains(t, err, "expected entry 1 of 'x-kong-plugin-cors' to be a JSON object")
+}
+
+func Test_ValidatorCookieParameters(t *testing.T) {
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 1})
+	logbasics.SetLogger(&logger)
+	defer logbasics.SetLogger(nil)
+
+	spec := loadFixture(t, "41-validator-cookie-parameters.yaml")
+	_, err := Convert(&spec, O2kOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, logs, `"level"=0 "msg"="WARNING: the request-validator plugin does not support `+
+		`parameters in 'cookie', skipping it" "parameter"="session"`)
+}
+
+func Test_ServicePerTag(t *testing.T) {
+	spec := loadFixture(t, "42-service-per-tag.yaml")
+
+	result, err := Convert(&spec, O2kOptions{ServicePerTag: true})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]string{
+		"shop_orders": {"shop_orders_get"},
+		"shop_users":  {"shop_users_get", "shop_users_post"},
+	}, getRouteNamesByService(result))
+
+	// the services by tag are copies of the document level service, with their own plugins
+	for _, service := range getServices(result) {
+		assert.Equal(t, "shop.example.com", service["host"])
+		assert.Equal(t, uuid.NewV5(uuid.NamespaceDNS, service["name"].(string)+".service").String(), service["id"])
+		plugins := getPlugins(service)
+		assert.Len(t, plugins, 1)
+		assert.Equal(t, uuid.NewV5(uuid.NamespaceDNS, service["name"].(string)+".plugin.cors").String(),
+			plugins["cors"]["id"])
+	}
+
+	// operations without tags remain on the document level service
+	spec = []byte(strings.Replace(string(spec), "      tags: [ orders ]\n", "", 1))
+	result, err = Convert(&spec, O2kOptions{ServicePerTag: true})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]string{
+		"shop":       {"shop_orders_get"},
+		"shop_users": {"shop_users_get", "shop_users_post"},
+	}, getRouteNamesByService(result))
+}
+
+func Test_FormatVersion(t *testing.T) {
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: version
+paths: {}
+`)
+	result, err := Convert(&spec, O2kOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "3.0", result["_format_version"])
+
+	result, err = Convert(&spec, O2kOptions{FormatVersion: "3.1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "3.1", result["_format_version"])
+
+	_, err = Convert(&spec, O2kOptions{FormatVersion: "latest"})
+	assert.EqualError(t, err, "invalid format version; "+
+		"expected field '._format_version' to be a string in 'x.y' format")
+}
+
+func Test_Callbacks(t *testing.T) {
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 1})
+	logbasics.SetLogger(&logger)
+	defer logbasics.SetLogger(nil)
+
+	spec := loadFixture(t, "43-callbacks.yaml")
+	result, err := Convert(&spec, O2kOptions{IncludeCallbacks: true})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"hooks_subscriptions_post":          []string{},
+		"hooks_events-request-body-id_post": []string{"callback"},
+	}, getRouteValues(result, "tags"))
+	assert.Contains(t, logs, `"level"=0 "msg"="WARNING: skipping the templated host of callback" `+
+		`"expression"="{$request.body#/callbackHost}/events/{$request.body#/id}?source=api"`)
+	assert.Equal(t, []string{"~/events/(?<request_body_id>[^#?/]+)$"},
+		getRoute(result, "hooks_events-request-body-id_post")["paths"])
+}
+
+func Test_RequestBodyRequired(t *testing.T) {
+	// an existing 'not' is wrapped
+	assert.Equal(t, `{"allOf":[{"not":{"type":"string"}}],"definitions":{"id":{"type":"string"}},"not":{"type":"null"}}`,
+		requiredSchema(`{"not":{"type":"string"},"definitions":{"id":{"type":"string"}}}`))
+}
+
+func Test_ReplaceUUIDBase(t *testing.T) {
+	spec := loadFixture(t, "45-replace-uuid-base.yaml")
+	result, err := Convert(&spec, O2kOptions{DocName: "old", GenerateSNIs: true})
+	assert.Nil(t, err)
+	result = filebasics.MustDeserialize(filebasics.MustSerialize(result, filebasics.OutputFormatJSON))
+	expected, err := Convert(&spec, O2kOptions{DocName: "new", GenerateSNIs: true})
+	assert.Nil(t, err)
+
+	// re-seeding gives the same result as converting with the new uuid-base
+	assert.Nil(t, ReplaceUUIDBase(result, "old", "new", uuid.UUID{}))
+	assert.JSONEq(t, string(*filebasics.MustSerialize(expected, filebasics.OutputFormatJSON)),
+		string(*filebasics.MustSerialize(result, filebasics.OutputFormatJSON)))
+
+	// entities not derived from the uuid-base are left alone
+	deck := map[string]interface{}{
+		"services": []interface{}{
+			map[string]interface{}{"name": "other", "id": "fixed-id"},
+		},
+		"routes": []interface{}{
+			map[string]interface{}{"name": "r", "service": map[string]interface{}{"id": "fixed-id"}},
+		},
+	}
+	assert.Nil(t, ReplaceUUIDBase(deck, "old", "new", uuid.UUID{}))
+	assert.Equal(t, "fixed-id", getServices(deck)[0]["id"])
+
+	assert.EqualError(t, ReplaceUUIDBase(deck, "", "new", uuid.UUID{}),
+		"expected both the old and new uuid-base to be non-empty")
+}
+
+func Test_EnumParameters(t *testing.T) {
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 1})
+	logbasics.SetLogger(&logger)
+	defer logbasics.SetLogger(nil)
+
+	spec := loadFixture(t, "46-enum-parameters.yaml")
+	_, err := Convert(&spec, O2kOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, logs, `"level"=0 "msg"="WARNING: the enum of the parameter has values of mixed types" `+
+		`"parameter"="x-level" "in"="header"`)
+
+	// path parameters are tightened into the regex
+	result, err := Convert(&spec, O2kOptions{UseParamPatterns: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`~/orders/(?<status>open|closed\.v2)$`}, getRoute(result, "enums_orders-status_get")["paths"])
+}
+
+func Test_PluginConfigVaultReferences(t *testing.T) {
+	spec := loadFixture(t, "48-plugin-config-vault-references.yaml")
+	result, err := Convert(&spec, O2kOptions{ValidatePluginConfig: true})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"minute":         "{vault://env/rate-limit}",
+		"policy":         "redis",
+		"redis_password": "{vault://env/redis-password}",
+	}, getPluginConfigs(getRoute(result, "vaults_limited_get"))["rate-limiting"])
+
+	// other errors are still reported
+	spec = []byte(strings.Replace(string(spec), "policy: redis", "policy: somewhere", 1))
+	_, err = Convert(&spec, O2kOptions{ValidatePluginConfig: true})
+	assert.EqualError(t, err, "invalid config for plugin 'rate-limiting' on operation 'GET /limited'; "+
+		"config.policy: value is not one of the allowed values")
+}
+
+func Test_PathHandling(t *testing.T) {
+	spec := loadFixture(t, "49-path-handling.yaml")
+
+	result, err := Convert(&spec, O2kOptions{PathHandling: PathHandlingV0})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"handling_default_get":   "v0",
+		"handling_operation_get": "v1",
+	}, getRouteValues(result, "path_handling"))
+
+	_, err = Convert(&spec, O2kOptions{PathHandling: "v2"})
+	assert.EqualError(t, err, "expected path handling to be one of 'v0', or 'v1', got: 'v2'")
+
+	spec = []byte(strings.Replace(string(spec), "x-kong-path-handling: v1", "x-kong-path-handling: v3", 1))
+	_, err = Convert(&spec, O2kOptions{})
+	assert.EqualError(t, err, "failed to get path_handling for operation '/operation GET': "+
+		"invalid 'x-kong-path-handling'; expected path handling to be one of 'v0', or 'v1', got: 'v3'")
+}
+
+func Test_PreserveExamples(t *testing.T) {
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 1})
+	logbasics.SetLogger(&logger)
+	defer logbasics.SetLogger(nil)
+
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: examples
+paths:
+  /users:
+    post:
+      requestBody:
+        content:
+          application/json:
+            example:
+              name: john
+      responses:
+        "201":
+          description: Created
+          content:
+            application/json:
+              examples:
+                created:
+                  value:
+                    id: 1
+                    name: john
+                large:
+                  value: "` + strings.Repeat("x", 5000) + `"
+        "400":
+          description: Bad request
+    get:
+      responses:
+        "200":
+          description: OK
+`)
+	// not preserved by default
+	result, err := Convert(&spec, O2kOptions{})
+	assert.Nil(t, err)
+	assert.NotContains(t, getRoute(result, "examples_users_post"), "_ignore")
+
+	result, err = Convert(&spec, O2kOptions{PreserveExamples: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"examples": map[string]interface{}{
+				"request": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"example": map[string]interface{}{"name": "john"},
+					},
+				},
+				"responses": map[string]interface{}{
+					"201": map[string]interface{}{
+						"application/json": map[string]interface{}{
+							"examples": map[string]interface{}{
+								"created": map[string]interface{}{"id": float64(1), "name": "john"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, getRoute(result, "examples_users_post")["_ignore"])
+	assert.NotContains(t, getRoute(result, "examples_users_get"), "_ignore")
+
+	found := false
+	for _, log := range logs {
+		if strings.Contains(log, "skipping example exceeding the maximum size") &&
+			strings.Contains(log, `"example"="large"`) {
+			found = true
+		}
+	}
+	assert.True(t, found, "expected a warning for the large example")
+}
+
+func Test_GenerateAuthPlugins(t *testing.T) {
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 1})
+	logbasics.SetLogger(&logger)
+	defer logbasics.SetLogger(nil)
+
+	spec := loadFixture(t, "50-generate-auth-plugins.yaml")
+	result, err := Convert(&spec, O2kOptions{GenerateAuthPlugins: true})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"auth_basic_get": {
+			"basic-auth": map[string]interface{}{},
+		},
+		"auth_hmac_get": {
+			"hmac-auth": map[string]interface{}{"enforce_headers": []interface{}{"date"}},
+		},
+		"auth_public_get": {},
+		"auth_bearer_get": {},
+	}, getRoutePlugins(result))
+
+	found := false
+	for _, log := range logs {
+		if strings.Contains(log, "the http security scheme 'bearer' is not supported") {
+			found = true
+		}
+	}
+	assert.True(t, found, "expected a warning for the bearer scheme")
+
+	// an explicitly configured plugin is not replaced
+	explicit := []byte(strings.Replace(string(spec), "security:\n  - basic: []\n",
+		"security:\n  - basic: []\nx-kong-plugin-basic-auth:\n  config:\n    hide_credentials: true\n", 1))
+	result, err = Convert(&explicit, O2kOptions{GenerateAuthPlugins: true})
+	assert.Nil(t, err)
+	assert.Empty(t, getPluginConfigs(getRoute(result, "auth_basic_get")))
+
+	// invalid hint
+	invalid := []byte(strings.Replace(string(spec), "x-kong-hmac:\n        enforce_headers: [ date ]",
+		"x-kong-hmac: yes-please", 1))
+	_, err = Convert(&invalid, O2kOptions{GenerateAuthPlugins: true})
+	assert.EqualError(t, err, "failed to create auth plugins for operation '/hmac GET': "+
+		"expected 'x-kong-hmac' of security scheme 'signature' to be a boolean or an object")
+}
+
+func Test_MarkDeprecated(t *testing.T) {
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 1})
+	logbasics.SetLogger(&logger)
+	defer logbasics.SetLogger(nil)
+
+	spec := loadFixture(t, "51-mark-deprecated.yaml")
+	result, err := Convert(&spec, O2kOptions{MarkDeprecated: true})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"lifecycle_sunset_get": map[string]interface{}{"add": map[string]interface{}{
+			"headers": []string{"Deprecation:true", "Sunset:Sun, 30 Jun 2030 00:00:00 GMT"},
+		}},
+		"lifecycle_deprecated_get": map[string]interface{}{"add": map[string]interface{}{
+			"headers": []string{"Deprecation:true"},
+		}},
+		"lifecycle_invalid_get": map[string]interface{}{"add": map[string]interface{}{
+			"headers": []string{"Deprecation:true"},
+		}},
+	}, getRoutePluginConfigs(result, "response-transformer"))
+
+	found := false
+	for _, log := range logs {
+		if strings.Contains(log, "invalid 'x-sunset' date, omitting the Sunset header") &&
+			strings.Contains(log, `"value"="next year"`) {
+			found = true
+		}
+	}
+	assert.True(t, found, "expected a warning for the invalid date")
+}
+
+func Test_MisplacedDefaults(t *testing.T) {
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 1})
+	logbasics.SetLogger(&logger)
+	defer logbasics.SetLogger(nil)
+
+	spec := loadFixture(t, "52-misplaced-defaults.yaml")
+	_, err := Convert(&spec, O2kOptions{})
+	assert.Nil(t, err)
+
+	warnings := make([]string, 0)
+	for _, log := range logs {
+		if strings.Contains(log, "the field is not supported in") {
+			warnings = append(warnings, log)
+		}
+	}
+	assert.Len(t, warnings, 3)
+	assert.Contains(t, strings.Join(warnings, "\n"), `"msg"="WARNING: the field is not supported in `+
+		`'x-kong-route-defaults', use 'x-kong-service-defaults' instead; ignoring it" "field"="read_timeout"`)
+	assert.Contains(t, strings.Join(warnings, "\n"), `"msg"="WARNING: the field is not supported in `+
+		`'x-kong-service-defaults', use 'x-kong-route-defaults' instead; ignoring it" "field"="strip_path"`)
+}
+
+func Test_TerminationStatus(t *testing.T) {
+	spec := loadFixture(t, "55-termination-status.yaml")
+
+	result, err := Convert(&spec, O2kOptions{BlockInternal: true, TerminationStatus: 404})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"internal_admin_get": map[string]interface{}{
+			"status_code": 404,
+			"message":     "Not Found",
+		},
+		"internal_legal_get": map[string]interface{}{
+			"status_code": 451,
+			"message":     "Unavailable in your region",
+		},
+	}, getRoutePluginConfigs(result, "request-termination"))
+
+	_, err = Convert(&spec, O2kOptions{BlockInternal: true, TerminationStatus: 1000})
+	assert.EqualError(t, err, "expected the termination status to be an HTTP status code (100-599), got 1000")
+
+	invalid := []byte(strings.Replace(string(spec), "status: 451", "status: 42", 1))
+	_, err = Convert(&invalid, O2kOptions{BlockInternal: true})
+	assert.ErrorContains(t, err, "invalid 'x-kong-termination'; expected the termination status to be "+
+		"an HTTP status code (100-599), got 42")
+}
+
+func Test_PreserveLinks(t *testing.T) {
+	spec := loadFixture(t, "56-preserve-links.yaml")
+
+	// only together with PreserveDescriptions
+	result, err := Convert(&spec, O2kOptions{PreserveLinks: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, getRoute(result, "links_users_post")["tags"])
+
+	result, err = Convert(&spec, O2kOptions{PreserveDescriptions: true, PreserveLinks: true})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"links_users_post": []string{"link:DeleteUser", "link:GetUserById"},
+		"links_getuser":    []string{},
+	}, getRouteValues(result, "tags"))
+}
+
+func Test_SecurityMapping(t *testing.T) {
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 1})
+	logbasics.SetLogger(&logger)
+	defer logbasics.SetLogger(nil)
+
+	spec := loadFixture(t, "57-security-mapping.yaml")
+
+	// the default mapping
+	result, err := Convert(&spec, O2kOptions{GenerateAuthPlugins: true})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"auth_oauth_get": {
+			"oauth2": map[string]interface{}{
+				"enable_client_credentials": true,
+				"scopes":                    []string{"read"},
+				"mandatory_scope":           true,
+			},
+		},
+		"auth_bearer_get": {},
+		"auth_mtls_get":   {},
+	}, getRoutePlugins(result))
+	assert.Contains(t, strings.Join(logs, "\n"),
+		`"msg"="WARNING: no plugin is mapped for the security scheme type 'mutualTLS', ignoring it"`)
+
+	// a custom mapping overrides the defaults
+	result, err = Convert(&spec, O2kOptions{
+		GenerateAuthPlugins: true,
+		SecurityMapping: map[string]string{
+			"oauth2":      "openid-connect",
+			"http:bearer": "jwt",
+			"mtls":        "mtls-auth",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"auth_oauth_get":  {"openid-connect": map[string]interface{}{}},
+		"auth_bearer_get": {"jwt": map[string]interface{}{}},
+		"auth_mtls_get":   {"mtls-auth": map[string]interface{}{}},
+	}, getRoutePlugins(result))
+
+	// mapping to "" generates no plugin
+	result, err = Convert(&spec, O2kOptions{
+		GenerateAuthPlugins: true,
+		SecurityMapping:     map[string]string{"oauth": ""},
+	})
+	assert.Nil(t, err)
+	assert.Empty(t, getPluginConfigs(getRoute(result, "auth_oauth_get")))
+}
+
+func Test_TagSpecVersion(t *testing.T) {
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 1})
+	logbasics.SetLogger(&logger)
+	defer logbasics.SetLogger(nil)
+
+	spec := loadFixture(t, "58-tag-spec-version.yaml")
+	result, err := Convert(&spec, O2kOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"team-a"}, getServices(result)[0]["tags"])
+
+	result, err = Convert(&spec, O2kOptions{TagSpecVersion: true})
+	assert.Nil(t, err)
+	service := getServices(result)[0]
+	assert.Equal(t, []string{"apiversion:1.4.0%2Fbeta", "team-a"}, service["tags"])
+	assert.Equal(t, []string{"apiversion:1.4.0%2Fbeta", "team-a"}, getServiceRoutes(service)[0]["tags"])
+
+	// no version, skipped with a warning
+	unversioned := []byte(strings.Replace(string(spec), "  version: 1.4.0/beta\n", "", 1))
+	result, err = Convert(&unversioned, O2kOptions{TagSpecVersion: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"team-a"}, getServices(result)[0]["tags"])
+	assert.Contains(t, strings.Join(logs, "\n"), "no 'info.version' in the spec, skipping the version tag")
+}
+
+func Test_ShorthandExtensions(t *testing.T) {
+	spec := loadFixture(t, "59-shorthand-extensions.yaml")
+
+	invalid := []byte(strings.Replace(string(spec), "x-kong-read-timeout: 5000", "x-kong-read-timeout: fast", 1))
+	_, err := Convert(&invalid, O2kOptions{})
+	assert.EqualError(t, err, "expected 'x-kong-read-timeout' to be an integer")
+
+	invalid = []byte(strings.Replace(string(spec), "x-kong-https-redirect-status-code: 308",
+		"x-kong-https-redirect-status-code: 200", 1))
+	_, err = Convert(&invalid, O2kOptions{})
+	assert.EqualError(t, err, "expected 'x-kong-https-redirect-status-code' to be one of "+
+		"[301 302 307 308 426], got 200")
+}
+
+func Test_EmitNamesAndIDs(t *testing.T) {
+	spec := loadFixture(t, "60-emit-names-and-ids.yaml")
+	yes := true
+	no := false
+
+	// names only, the top-level plugin refers to the route by name
+	result, err := Convert(&spec, O2kOptions{EmitIDs: &no, GenerateSNIs: true})
+	assert.Nil(t, err)
+	service := getServices(result)[0]
+	route := getRoute(result, "keys_list-users")
+	plugin := getPlugins(result)["rate-limiting"]
+	assert.NotContains(t, service, "id")
+	assert.NotContains(t, route, "id")
+	assert.NotContains(t, getPlugins(service)["cors"], "id")
+	assert.NotContains(t, plugin, "id")
+	assert.Equal(t, "keys_list-users", plugin["route"])
+	sni := result["snis"].([]interface{})[0].(map[string]interface{})
+	assert.NotContains(t, sni, "id")
+	assert.NotEmpty(t, sni["certificate"].(map[string]interface{})["id"]) // certificates have no name
+
+	// ids only, the top-level plugin refers to the route by id
+	result, err = Convert(&spec, O2kOptions{EmitNames: &no, EmitIDs: &yes})
+	assert.Nil(t, err)
+	service = getServices(result)[0]
+	route = getServiceRoutes(service)[0]
+	plugin = getPlugins(result)["rate-limiting"]
+	assert.NotContains(t, service, "name")
+	assert.NotEmpty(t, service["id"])
+	assert.NotContains(t, route, "name")
+	assert.NotEmpty(t, route["id"])
+	assert.Equal(t, route["id"], plugin["route"])
+
+	// at least one is required
+	_, err = Convert(&spec, O2kOptions{EmitNames: &no, EmitIDs: &no})
+	assert.EqualError(t, err, "at least one of the options EmitNames and EmitIDs must be enabled")
+}
+
+func Test_Summary(t *testing.T) {
+	spec := loadFixture(t, "61-summary.yaml")
+	_, info, err := ConvertWithInfo(&spec, O2kOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []ServiceSummary{
+		{
+			Name:    "summary",
+			Tags:    []string{"team-a"},
+			Plugins: []string{"cors"},
+			Routes: []RouteSummary{
+				{
+					Name:        "summary_list-users",
+					Path:        "/users",
+					Method:      "GET",
+					OperationID: "list-users",
+					Tags:        []string{"team-a"},
+					Plugins:     []string{"rate-limiting"},
+				},
+				{
+					Name:    "summary_users_post",
+					Path:    "/users",
+					Method:  "POST",
+					Tags:    []string{"team-a"},
+					Plugins: []string{},
+				},
+			},
+		},
+	}, info.Summary)
+}
+
+func Test_ServiceNameExtension(t *testing.T) {
+	spec := loadFixture(t, "62-service-name-extension.yaml")
+	_, info, err := ConvertWithInfo(&spec, O2kOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "id-seed", info.DocName)
+
+	// must be a string
+	invalid := []byte(strings.Replace(string(spec), "x-kong-service-name: My Backend", "x-kong-service-name: 123", 1))
+	_, err = Convert(&invalid, O2kOptions{})
+	assert.ErrorContains(t, err, "expected 'x-kong-service-name' to be a string")
+}
+
+func Test_ACLExtension(t *testing.T) {
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 1})
+	logbasics.SetLogger(&logger)
+	defer logbasics.SetLogger(nil)
+
+	spec := loadFixture(t, "63-acl-extension.yaml")
+	_, err := Convert(&spec, O2kOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, strings.Join(logs, "\n"), "'x-kong-acl' is an empty list, skipping it")
+
+	// a deny list cannot be combined
+	denied := []byte(strings.Replace(string(spec), `allow: ["admins"]`, `deny: ["guests"]`, 1))
+	_, err = Convert(&denied, O2kOptions{})
+	assert.ErrorContains(t, err, "cannot use 'x-kong-acl' together with an 'x-kong-plugin-acl' with a 'deny' list")
+}
+
+func Test_DefaultProtocol(t *testing.T) {
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 1})
+	logbasics.SetLogger(&logger)
+	defer logbasics.SetLogger(nil)
+
+	spec := loadFixture(t, "65-default-protocol.yaml")
+	result, err := Convert(&spec, O2kOptions{DefaultProtocol: "http"})
+	assert.Nil(t, err)
+	services := getServices(result)
+	assert.Equal(t, "http", services[0]["protocol"])
+	assert.EqualValues(t, 80, services[0]["port"])
+	assert.Contains(t, strings.Join(logs, "\n"), "server url has no scheme, assuming 'http'")
+
+	// an explicit scheme always wins
+	assert.Equal(t, "https", services[1]["protocol"])
+	assert.EqualValues(t, 443, services[1]["port"])
+
+	_, err = Convert(&spec, O2kOptions{DefaultProtocol: "grpc"})
+	assert.EqualError(t, err, "expected default protocol to be one of 'http', or 'https', got: 'grpc'")
+}
+
+func Test_Vaults(t *testing.T) {
+	spec := loadFixture(t, "66-vaults.yaml")
+	duplicate := []byte(strings.Replace(string(spec), "x-kong-vaults:\n", `x-kong-vaults:
+  - name: hcv
+    prefix: my-env
+`, 1))
+	_, err := Convert(&duplicate, O2kOptions{})
+	assert.EqualError(t, err, "duplicate prefix 'my-env' in 'x-kong-vaults'")
+}
+
+func Test_Streaming(t *testing.T) {
+	spec := loadFixture(t, "67-streaming.yaml")
+
+	// the option disables buffering on all routes, unless opted out
+	result, err := Convert(&spec, O2kOptions{DisableBuffering: true})
+	assert.Nil(t, err)
+	assert.Equal(t, false, getRoute(result, "streaming_plain_get")["request_buffering"])
+	assert.Equal(t, false, getRoute(result, "streaming_plain_get")["response_buffering"])
+	assert.NotContains(t, getRoute(result, "streaming_unbuffered_get"), "request_buffering")
+
+	invalid := []byte(strings.Replace(string(spec), "x-kong-streaming: true", "x-kong-streaming: yes please", 1))
+	_, err = Convert(&invalid, O2kOptions{})
+	assert.EqualError(t, err, "failed to get buffering for operation '/events GET': "+
+		"expected 'x-kong-streaming' to be a boolean")
+}
+
+func Test_TagsDeduplicated(t *testing.T) {
+	spec := loadFixture(t, "68-tags-deduplicated.yaml")
+
+	// the spec tags are deduplicated and sorted
+	result, err := Convert(&spec, O2kOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"shared", "team-b"}, getServices(result)[0]["tags"])
+
+	// the provided tags (eg. '--select-tag') replace the spec tags, deduplicated and sorted
+	result, err = Convert(&spec, O2kOptions{Tags: &[]string{"team-a", "shared", "team-a"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"shared", "team-a"}, getServices(result)[0]["tags"])
+}
+
+func Test_ConvertContext(t *testing.T) {
+	var builder strings.Builder
+	builder.WriteString("openapi: 3.0.0\ninfo:\n  title: large\npaths:\n")
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&builder, "  /path%d:\n    get:\n      responses:\n        \"200\":\n          description: OK\n", i)
+	}
+	spec := []byte(builder.String())
+
+	// cancel the conversion while processing the first path
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	processed := 0
+	logger := funcr.New(func(prefix, args string) {
+		if strings.Contains(args, `"processing path"`) {
+			processed++
+			cancel()
+		}
+	}, funcr.Options{Verbosity: 1})
+	logbasics.SetLogger(&logger)
+	defer logbasics.SetLogger(nil)
+
+	result, err := ConvertContext(ctx, &spec, O2kOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, result)
+	assert.Equal(t, 1, processed)
+
+	// an already cancelled context returns right away
+	processed = 0
+	_, err = ConvertContext(ctx, &spec, O2kOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, processed)
+}
+
+func Test_MultiBackendPolicy(t *testing.T) {
+	spec := loadFixture(t, "70-multi-backend-policy.yaml")
+
+	// split is the default
+	result, err := Convert(&spec, O2kOptions{})
+	assert.Nil(t, err)
+	split, err := Convert(&spec, O2kOptions{MultiBackendPolicy: MultiBackendSplit})
+	assert.Nil(t, err)
+	assert.Equal(t, result, split)
+
+	// error
+	_, err = Convert(&spec, O2kOptions{MultiBackendPolicy: MultiBackendError})
+	assert.EqualError(t, err, "the operations on path '/x' have different servers: "+
+		"GET ('https://read.example.com'); POST ('https://write.example.com')")
+
+	// error, but all operations use the same servers
+	same := []byte(strings.ReplaceAll(string(spec), "write.example.com", "read.example.com"))
+	_, err = Convert(&same, O2kOptions{MultiBackendPolicy: MultiBackendError})
+	assert.Nil(t, err)
+
+	_, err = Convert(&spec, O2kOptions{MultiBackendPolicy: "bad"})
+	assert.EqualError(t, err, "expected multi-backend policy to be one of 'split', or 'error', got: 'bad'")
+}
+
+func Test_SpecBaseURL(t *testing.T) {
+	spec := loadFixture(t, "71-spec-base-url.yaml")
+	result, err := Convert(&spec, O2kOptions{SpecBaseURL: "http://example.com:8080/specs/openapi.yaml"})
+	assert.Nil(t, err)
+	service := getServices(result)[0]
+	assert.Equal(t, "example.com", service["host"])
+	assert.EqualValues(t, 8080, service["port"])
+	assert.Equal(t, "http", service["protocol"])
+	assert.Equal(t, "/api/v1", service["path"])
+
+	// without a base url, the host defaults to localhost, with a warning
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, prefix+args)
+	}, funcr.Options{Verbosity: 1})
+	logbasics.SetLogger(&logger)
+	defer logbasics.SetLogger(nil)
+	_, err = Convert(&spec, O2kOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, strings.Join(logs, "\n"), "server url is relative, and no spec base url is set")
+
+	_, err = Convert(&spec, O2kOptions{SpecBaseURL: "/specs/openapi.yaml"})
+	assert.EqualError(t, err, "expected the spec base url to be an absolute url, got: '/specs/openapi.yaml'")
+}
+
+func Test_PluginRefs(t *testing.T) {
+	spec := loadFixture(t, "72-plugin-refs.yaml")
+	unknown := []byte(strings.ReplaceAll(string(spec), "x-kong-plugin-ref: my-rate-limit",
+		"x-kong-plugin-ref: my-rate-limt"))
+	_, err := Convert(&unknown, O2kOptions{})
+	assert.EqualError(t, err, "failed to create plugins from operation '/one GET': unknown plugin "+
+		"'my-rate-limt' in 'x-kong-plugin-ref', expected one of 'my-rate-limit' (from '/components/x-kong-plugins')")
+
+	conflict := []byte(strings.ReplaceAll(string(spec), "x-kong-plugin-ref: my-rate-limit",
+		"x-kong-plugin-ref: my-rate-limit\n      x-kong-plugin-rate-limiting: {}"))
+	_, err = Convert(&conflict, O2kOptions{})
+	assert.EqualError(t, err, "failed to create plugins from operation '/one GET': plugin 'my-rate-limit' "+
+		"in 'x-kong-plugin-ref' cannot be used together with 'x-kong-plugin-rate-limiting'")
+}
+
+func Test_Stats(t *testing.T) {
+	spec := loadFixture(t, "73-stats.yaml")
+	_, info, err := ConvertWithInfo(&spec, O2kOptions{RequireSuccessResponse: true})
+	assert.Nil(t, err)
+	assert.Equal(t, O2kStats{Services: 2, Routes: 2, Plugins: 3, SkippedOperations: 1}, info.Stats)
+
+	_, info, err = ConvertWithInfo(&spec, O2kOptions{PluginOverlayOnly: true})
+	assert.Nil(t, err)
+	assert.Equal(t, O2kStats{Plugins: 3}, info.Stats)
+}
+
+func Test_ValidateRefs(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "schemas.yaml"), []byte(`User:
+  type: object
+  properties:
+    address:
+      $ref: "#/Address"
+`), 0o600))
+	spec := []byte(`openapi: 3.0.0
+info:
+  title: refs
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                $ref: "./schemas.yaml#/User"
+        "404":
+          $ref: "#/components/responses/NotFound"
+components:
+  responses: {}
+`)
+	_, err := Convert(&spec, O2kOptions{BaseDir: dir, AllowExternalRefs: true})
+	assert.EqualError(t, err, "error parsing OAS3 file: [found 2 unresolvable references: "+
+		"'#/components/responses/NotFound' at '#/paths/~1users/get/responses/404/$ref'; JSONpointer "+
+		"'/components/responses/NotFound' not found; "+
+		"'#/Address' at 'schemas.yaml#/User/properties/address/$ref'; JSONpointer '/Address' not found]")
+}
+
+func Test_GenerateMockNoContent(t *testing.T) {
+	spec := loadFixture(t, "74-generate-mock-no-content.yaml")
+	result, err := Convert(&spec, O2kOptions{GenerateMock: true})
+	assert.Nil(t, err)
+	// no body, nor content type, even if the spec has an example
+	assert.Equal(t, map[string]interface{}{
+		"mock_users_delete": map[string]interface{}{"status_code": 204},
+		"mock_users_get":    map[string]interface{}{"status_code": 204},
+	}, getRoutePluginConfigs(result, "request-termination"))
+}
+
+func Test_RouteHeaders(t *testing.T) {
+	for _, headers := range []string{`[ "X-Env" ]`, `{ X-Env: 123 }`, `{ X-Env: [] }`, `{ Host: example.com }`} {
+		spec := []byte(`openapi: 3.0.0
+info:
+  title: headers
+paths:
+  /users:
+    get:
+      x-kong-headers: ` + headers + `
+      responses:
+        "200":
+          description: OK
+`)
+		_, err := Convert(&spec, O2kOptions{})
+		assert.ErrorContains(t, err, "failed to get headers for operation '/users GET'", headers)
+	}
+}
+
+func Test_KongTagsRemove(t *testing.T) {
+	spec := loadFixture(t, "76-kong-tags-remove.yaml")
+	result, err := Convert(&spec, O2kOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"internal", "public", "team-a"}, getServices(result)[0]["tags"])
+
+	// the removed tag is dropped from the route and its plugins, the others are kept
+	route := getRoute(result, "tags_users_get")
+	assert.Equal(t, []string{"public", "team-a"}, route["tags"])
+	assert.Equal(t, []string{"public", "team-a"}, getPlugins(route)["cors"]["tags"])
+	assert.Equal(t, []string{"internal", "public", "team-a"}, getRoute(result, "tags_users_post")["tags"])
+}
+
+func Test_Upload(t *testing.T) {
+	for upload, message := range map[string]string{
+		"x-kong-upload: {}": "expected 'x-kong-upload.max_size' to be a positive integer",
+		"x-kong-upload: { max_size: 1, size_unit: gigabytes }": "expected 'x-kong-upload.size_unit' to be one of " +
+			"'bytes', 'kilobytes', or 'megabytes', got: 'gigabytes'",
+		"x-kong-upload: { max_size: 1 }\n      x-kong-streaming: false": "cannot use 'x-kong-upload' together " +
+			"with 'x-kong-streaming: false'",
+		"x-kong-upload: { max_size: 1 }\n      x-kong-plugin-request-size-limiting: {}": "cannot use both " +
+			"'x-kong-upload' and 'x-kong-plugin-request-size-limiting'",
+	} {
+		spec := []byte(`openapi: 3.0.0
+info:
+  title: upload
+paths:
+  /files:
+    post:
+      ` + upload + `
+      responses:
+        "200":
+          description: OK
+`)
+		_, err := Convert(&spec, O2kOptions{})
+		assert.ErrorContains(t, err, message, upload)
+	}
+}
+
+func Test_ServiceURL(t *testing.T) {
+	for extensions, message := range map[string]string{
+		"x-kong-service-url: /api": "expected 'x-kong-service-url' to be an absolute url, got: '/api'",
+		"x-kong-service-url: 123":  "expected 'x-kong-service-url' to be a string",
+		"x-kong-service-url: http://backend.internal\nx-kong-upstream-defaults: {}": "cannot use " +
+			"'x-kong-service-url' together with 'x-kong-upstream-defaults'",
+		"x-kong-service-url: http://backend.internal\nx-kong-upstream: my-upstream": "cannot use " +
+			"'x-kong-service-url' together with 'x-kong-upstream'",
+	} {
+		spec := []byte(`openapi: 3.0.0
+info:
+  title: service-url
+` + extensions + `
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: OK
+`)
+		_, err := Convert(&spec, O2kOptions{})
+		assert.ErrorContains(t, err, message, extensions)
+	}
+}
@@ -0,0 +1,36 @@
+package openapi2kong
+
+// createPluginOverlay returns the plugins of the services and their routes in a single
+// (top-level) plugin list, referring to their service or route by name. The plugins
+// already in foreignKeyPlugins are included as is.
+func createPluginOverlay(
+	services []interface{},
+	foreignKeyPlugins *[]*map[string]interface{},
+) *[]*map[string]interface{} {
+	overlay := append(make([]*map[string]interface{}, 0), *foreignKeyPlugins...)
+
+	addPlugins := func(entity map[string]interface{}, foreignKey string) {
+		pluginList, ok := entity["plugins"].(*[]*map[string]interface{})
+		if !ok {
+			return
+		}
+		for _, plugin := range *pluginList {
+			overlayPlugin := make(map[string]interface{})
+			for key, value := range *plugin {
+				overlayPlugin[key] = value
+			}
+			overlayPlugin[foreignKey] = entity["name"]
+			overlay = append(overlay, &overlayPlugin)
+		}
+	}
+
+	for _, service := range services {
+		service := service.(map[string]interface{})
+		addPlugins(service, "service")
+		routes, _ := service["routes"].([]interface{})
+		for _, route := range routes {
+			addPlugins(route.(map[string]interface{}), "route")
+		}
+	}
+	return &overlay
+}
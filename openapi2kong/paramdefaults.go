@@ -0,0 +1,73 @@
+package openapi2kong
+
+import (
+	"strconv"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/logbasics"
+)
+
+// parameterSections maps the parameter locations to the 'request-transformer' config sections.
+// Path parameters are always present, and cookies are not supported by the plugin.
+var parameterSections = map[string]string{
+	openapi3.ParameterInQuery:  "querystring",
+	openapi3.ParameterInHeader: "headers",
+}
+
+// formatDefault returns the default value of a parameter as a string. Returns false if it
+// is not a scalar value.
+func formatDefault(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+// getParameterDefaults returns the query and header parameters that have a 'schema.default'
+// value, as 'request-transformer' entries by section (eg. "querystring": ["name:value"]).
+// Operation parameters take precedence over path parameters with the same name and location.
+func getParameterDefaults(pathParameters openapi3.Parameters,
+	operationParameters openapi3.Parameters,
+) map[string][]string {
+	type paramKey struct{ in, name string }
+	defaults := make(map[paramKey]string)
+	order := make([]paramKey, 0)
+
+	for _, parameters := range []openapi3.Parameters{pathParameters, operationParameters} {
+		for _, parameterRef := range parameters {
+			param := parameterRef.Value
+			if param == nil || parameterSections[param.In] == "" {
+				continue
+			}
+			key := paramKey{param.In, param.Name}
+			if param.Schema == nil || param.Schema.Value == nil || param.Schema.Value.Default == nil {
+				delete(defaults, key) // an operation parameter without default overrides a path one
+				continue
+			}
+
+			value, ok := formatDefault(param.Schema.Value.Default)
+			if !ok {
+				logbasics.Warn("skipping non-scalar parameter default", "parameter", param.Name, "in", param.In)
+				continue
+			}
+			if _, found := defaults[key]; !found {
+				order = append(order, key)
+			}
+			defaults[key] = value
+		}
+	}
+
+	result := make(map[string][]string)
+	for _, key := range order {
+		if value, found := defaults[key]; found {
+			section := parameterSections[key.in]
+			result[section] = append(result[section], key.name+":"+value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,68 @@
+package openapi2kong
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/logbasics"
+)
+
+// mixedEnumTypes returns true if the enum values are not all of the same (JSON) type.
+func mixedEnumTypes(values []interface{}) bool {
+	for _, value := range values {
+		if fmt.Sprintf("%T", value) != fmt.Sprintf("%T", values[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// warnMixedEnumTypes logs a warning if the enum of the parameter schema has values of
+// mixed types. Returns true if it did.
+func warnMixedEnumTypes(param *openapi3.Parameter) bool {
+	if param.Schema == nil || param.Schema.Value == nil || !mixedEnumTypes(param.Schema.Value.Enum) {
+		return false
+	}
+	logbasics.Warn("the enum of the parameter has values of mixed types", "parameter", param.Name, "in", param.In)
+	return true
+}
+
+// getPathParameterEnum returns the enum values of the path parameter 'name' as strings.
+// Operation parameters take precedence over path parameters. Returns nil if there is no
+// enum, or if it has non-scalar or mixed-type values.
+func getPathParameterEnum(pathParameters openapi3.Parameters,
+	operationParameters openapi3.Parameters,
+	name string,
+) []string {
+	var param *openapi3.Parameter
+	for _, parameters := range []openapi3.Parameters{pathParameters, operationParameters} {
+		if p := parameters.GetByInAndName(openapi3.ParameterInPath, name); p != nil {
+			param = p
+		}
+	}
+	if param == nil || param.Schema == nil || param.Schema.Value == nil || len(param.Schema.Value.Enum) == 0 {
+		return nil
+	}
+	if warnMixedEnumTypes(param) {
+		return nil
+	}
+
+	values := make([]string, 0, len(param.Schema.Value.Enum))
+	for _, value := range param.Schema.Value.Enum {
+		formatted, ok := formatDefault(value)
+		if !ok {
+			logbasics.Warn("the enum of the parameter has non-scalar values", "parameter", name, "in", param.In)
+			return nil
+		}
+		values = append(values, regexp.QuoteMeta(formatted))
+	}
+	return values
+}
+
+// enumRegexCapture returns a regex capture matching only the enum values,
+// eg. "(?<status>open|closed)".
+func enumRegexCapture(captureName string, values []string) string {
+	return "(?<" + captureName + ">" + strings.Join(values, "|") + ")"
+}
@@ -0,0 +1,47 @@
+package openapi2kong
+
+import (
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// mergeParameters returns the path-item parameters with the operation parameters, where an
+// operation parameter overrides a path-item parameter with the same name and location (as
+// by the OpenAPI spec). The path-item parameters come first, in order.
+func mergeParameters(pathParameters openapi3.Parameters, operationParameters openapi3.Parameters,
+) openapi3.Parameters {
+	if len(pathParameters) == 0 {
+		return operationParameters
+	}
+
+	type paramKey struct{ in, name string }
+	overridden := make(map[paramKey]bool)
+	for _, parameterRef := range operationParameters {
+		if parameterRef != nil && parameterRef.Value != nil {
+			overridden[paramKey{parameterRef.Value.In, parameterRef.Value.Name}] = true
+		}
+	}
+
+	merged := make(openapi3.Parameters, 0, len(pathParameters)+len(operationParameters))
+	for _, parameterRef := range pathParameters {
+		if parameterRef == nil || parameterRef.Value == nil {
+			continue
+		}
+		key := paramKey{parameterRef.Value.In, parameterRef.Value.Name}
+		if !overridden[key] {
+			overridden[key] = true // skip duplicates within the path-item as well
+			merged = append(merged, parameterRef)
+		}
+	}
+	return append(merged, operationParameters...)
+}
+
+// mergeAllPathParameters merges the parameters of the path-items into their operations (see
+// mergeParameters), such that the operations have all parameters that apply to them (eg. for
+// the request-validator).
+func mergeAllPathParameters(doc *openapi3.T) {
+	for _, pathItem := range doc.Paths {
+		for _, operation := range pathItem.Operations() {
+			operation.Parameters = mergeParameters(pathItem.Parameters, operation.Parameters)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const (
+	// PathHandlingV0 sets 'path_handling: v0' on the routes, the Kong 2.x behaviour
+	PathHandlingV0 = "v0"
+	// PathHandlingV1 sets 'path_handling: v1' on the routes
+	PathHandlingV1 = "v1"
+)
+
+const pathHandlingExtension = "x-kong-path-handling"
+
+// validatePathHandling returns an error if the path handling is unknown.
+func validatePathHandling(pathHandling string) error {
+	switch pathHandling {
+	case "", PathHandlingV0, PathHandlingV1:
+		return nil
+	}
+	return fmt.Errorf("expected path handling to be one of '%s', or '%s', got: '%s'",
+		PathHandlingV0, PathHandlingV1, pathHandling)
+}
+
+// getPathHandling returns the 'path_handling' value for a route. Precedence is;
+// 'x-kong-path-handling' on the operation -> on the path -> defaultPathHandling.
+// Returns "" if not set.
+func getPathHandling(operationProps openapi3.ExtensionProps, pathProps openapi3.ExtensionProps,
+	defaultPathHandling string,
+) (string, error) {
+	for _, props := range []openapi3.ExtensionProps{operationProps, pathProps} {
+		if props.Extensions == nil || props.Extensions[pathHandlingExtension] == nil {
+			continue
+		}
+		var pathHandling string
+		if err := json.Unmarshal(props.Extensions[pathHandlingExtension].(json.RawMessage), &pathHandling); err != nil {
+			return "", fmt.Errorf("expected '%s' to be a string", pathHandlingExtension)
+		}
+		if err := validatePathHandling(pathHandling); err != nil {
+			return "", fmt.Errorf("invalid '%s'; %w", pathHandlingExtension, err)
+		}
+		return pathHandling, nil
+	}
+	return defaultPathHandling, nil
+}
@@ -0,0 +1,84 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/jsonbasics"
+	"github.com/kong/go-apiops/refresolver"
+)
+
+// absoluteRefs rewrites all '$ref' values in data (recursively, in place) to absolute
+// references, where relative ones are resolved against 'location'.
+func absoluteRefs(data interface{}, location string) error {
+	switch d := data.(type) {
+	case map[string]interface{}:
+		for key, value := range d {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				refLocation, fragment, err := refresolver.Location(ref, location)
+				if err != nil {
+					return err
+				}
+				if fragment != "" {
+					refLocation = refLocation + "#" + fragment
+				}
+				d[key] = refLocation
+				continue
+			}
+			if err := absoluteRefs(value, location); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, value := range d {
+			if err := absoluteRefs(value, location); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expandPathsRef handles a root level 'paths' object that is a reference to an external
+// document (eg. 'paths: { $ref: "./paths.yaml" }'), which the OAS parser does not support.
+// The referenced object is inlined, where the references within it are made absolute, such
+// that they still resolve relative to the external document. 'location' is the location
+// of the spec.
+// If 'paths' is not a reference, then the content is returned as is.
+func expandPathsRef(content *[]byte, location string, resolver *refresolver.Resolver) (*[]byte, error) {
+	data, err := filebasics.Deserialize(content)
+	if err != nil {
+		return content, nil // leave reporting to the OAS parser
+	}
+	paths, ok := data["paths"].(map[string]interface{})
+	if !ok || len(paths) != 1 {
+		return content, nil
+	}
+	ref, ok := paths["$ref"].(string)
+	if !ok {
+		return content, nil
+	}
+
+	target, targetLocation, err := resolver.Resolve(ref, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve 'paths'; %w", err)
+	}
+	targetPaths, ok := target.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected the 'paths' reference '%s' to be an object", ref)
+	}
+
+	// copy, since the resolver caches the document
+	expanded := *jsonbasics.DeepCopyObject(&targetPaths)
+	if err := absoluteRefs(expanded, targetLocation); err != nil {
+		return nil, fmt.Errorf("failed to resolve references in '%s'; %w", targetLocation, err)
+	}
+	data["paths"] = expanded
+
+	result, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize the expanded 'paths'; %w", err)
+	}
+	return &result, nil
+}
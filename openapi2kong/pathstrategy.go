@@ -0,0 +1,72 @@
+package openapi2kong
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// PathStrategyOperation matches the routes on the operation paths, the path of the servers
+	// is only used as the service 'path' (default). Eg. "/users" is proxied to "/v1/users".
+	PathStrategyOperation = "operation"
+	// PathStrategyPrefix matches the routes on the server path combined with the operation
+	// path, and proxies them as is. Eg. "/v1/users" is proxied to "/v1/users".
+	PathStrategyPrefix = "prefix"
+)
+
+// validatePathStrategy returns an error if the strategy is unknown.
+func validatePathStrategy(strategy string) error {
+	switch strategy {
+	case "", PathStrategyOperation, PathStrategyPrefix:
+		return nil
+	}
+	return fmt.Errorf("expected path strategy to be one of '%s', or '%s', got: '%s'",
+		PathStrategyOperation, PathStrategyPrefix, strategy)
+}
+
+// escapePathRegex escapes the characters in a path that have a special meaning in a regex.
+func escapePathRegex(path string) string {
+	charsToEscape := []string{"(", ")", ".", "+", "?", "*", "["}
+	for _, char := range charsToEscape {
+		path = strings.ReplaceAll(path, char, "\\"+char)
+	}
+	return path
+}
+
+// joinBasePath prefixes the (regex) route path "~/..." with the base path, without
+// doubling or dropping the slash in between.
+func joinBasePath(basePath string, routePath string) string {
+	basePath = strings.TrimSuffix(basePath, "/")
+	if basePath == "" {
+		return routePath
+	}
+	if !strings.HasPrefix(basePath, "/") {
+		basePath = "/" + basePath
+	}
+	return "~" + escapePathRegex(basePath) + strings.TrimPrefix(routePath, "~")
+}
+
+// applyPathStrategy updates the services and their routes according to the path strategy.
+func applyPathStrategy(services []interface{}, strategy string) {
+	if strategy != PathStrategyPrefix {
+		return
+	}
+
+	for _, service := range services {
+		service := service.(map[string]interface{})
+		if service["url"] != nil {
+			continue // the url is used verbatim, see 'x-kong-service-url'
+		}
+		basePath, _ := service["path"].(string)
+		routes, _ := service["routes"].([]interface{})
+		for _, route := range routes {
+			route := route.(map[string]interface{})
+			paths := route["paths"].([]string)
+			for i, path := range paths {
+				paths[i] = joinBasePath(basePath, path)
+			}
+		}
+		// the routes now match the full path, so proxy it as is
+		service["path"] = "/"
+	}
+}
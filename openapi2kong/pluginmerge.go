@@ -0,0 +1,92 @@
+package openapi2kong
+
+import (
+	"fmt"
+
+	"github.com/kong/go-apiops/jsonbasics"
+)
+
+const (
+	PluginMergeReplace = "replace" // a plugin on a lower level replaces the inherited one (default)
+	PluginMergeMerge   = "merge"   // a plugin on a lower level is deep-merged into the inherited one
+)
+
+// validatePluginMergeStrategy returns an error if the strategy is unknown.
+func validatePluginMergeStrategy(strategy string) error {
+	switch strategy {
+	case "", PluginMergeReplace, PluginMergeMerge:
+		return nil
+	}
+	return fmt.Errorf("expected plugin merge strategy to be one of '%s', or '%s', got: '%s'",
+		PluginMergeReplace, PluginMergeMerge, strategy)
+}
+
+// deepMerge merges 'overlay' into 'base' (in place). Objects are merged recursively, any
+// other value (including arrays) in 'overlay' replaces the one in 'base'.
+func deepMerge(base map[string]interface{}, overlay map[string]interface{}) map[string]interface{} {
+	for key, value := range overlay {
+		baseObject, baseIsObject := base[key].(map[string]interface{})
+		overlayObject, overlayIsObject := value.(map[string]interface{})
+		if baseIsObject && overlayIsObject {
+			base[key] = deepMerge(baseObject, overlayObject)
+		} else {
+			base[key] = value
+		}
+	}
+	return base
+}
+
+// findPlugin returns the plugin with the given name from the first list that has it, or nil.
+func findPlugin(name string, lists ...*[]*map[string]interface{}) *map[string]interface{} {
+	return findPluginInstance(name, 0, lists...)
+}
+
+// findPluginInstance returns the n-th (0 based) instance of the plugin with the given name,
+// from the first list that has the plugin, or nil.
+func findPluginInstance(name string, instance int, lists ...*[]*map[string]interface{}) *map[string]interface{} {
+	for _, list := range lists {
+		if list == nil {
+			continue
+		}
+		found := false
+		count := 0
+		for _, plugin := range *list {
+			if (*plugin)["name"] == name {
+				if count == instance {
+					return plugin
+				}
+				found = true
+				count++
+			}
+		}
+		if found {
+			return nil
+		}
+	}
+	return nil
+}
+
+// mergePluginConfigs implements the PluginMergeMerge strategy. Every plugin in 'list' is
+// deep-merged on top of the inherited plugin by the same name (and instance, if there are
+// multiple). The inherited lists are searched in order, the first one having the plugin is
+// used. The list is updated in place.
+func mergePluginConfigs(strategy string, list *[]*map[string]interface{}, inherited ...*[]*map[string]interface{}) {
+	if strategy != PluginMergeMerge {
+		return
+	}
+	instances := make(map[string]int)
+	for i, plugin := range *list {
+		name := (*plugin)["name"].(string) // safe because it was previously parsed
+		if name == "request-validator" {
+			// has its own inheritance rules, see getValidatorPlugin
+			continue
+		}
+		inheritedPlugin := findPluginInstance(name, instances[name], inherited...)
+		instances[name]++
+		if inheritedPlugin == nil || inheritedPlugin == plugin {
+			continue
+		}
+		merged := deepMerge(*jsonbasics.DeepCopyObject(inheritedPlugin), *jsonbasics.DeepCopyObject(plugin))
+		(*list)[i] = &merged
+	}
+}
@@ -0,0 +1,128 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const (
+	pluginRefExtension   = "x-kong-plugin-ref" // references named plugin configs, by name
+	namedPluginsSection  = "x-kong-plugins"    // the named plugin configs, on 'components'
+	namedPluginsLocation = "/components/" + namedPluginsSection
+)
+
+// getNamedPlugins returns the named plugin configs of the '/components/x-kong-plugins'
+// object, by name. Each config must be an object with the plugin 'name'. Returns an empty
+// map if there are none.
+func getNamedPlugins(doc *openapi3.T) (map[string]map[string]interface{}, error) {
+	namedPlugins := make(map[string]map[string]interface{})
+	raw, ok := doc.Components.ExtensionProps.Extensions[namedPluginsSection].(json.RawMessage)
+	if !ok {
+		return namedPlugins, nil
+	}
+
+	if err := json.Unmarshal(raw, &namedPlugins); err != nil {
+		return nil, fmt.Errorf("expected '%s' to be an object with plugin configs by name; %w",
+			namedPluginsLocation, err)
+	}
+	for refName, plugin := range namedPlugins {
+		if name, ok := plugin["name"].(string); !ok || name == "" {
+			return nil, fmt.Errorf("expected plugin '%s' in '%s' to have a 'name'", refName, namedPluginsLocation)
+		}
+	}
+	return namedPlugins, nil
+}
+
+// getPluginRefs returns the names of the 'x-kong-plugin-ref' extension, a string or an
+// array of strings. Returns nil if the extension is not set.
+func getPluginRefs(props openapi3.ExtensionProps) ([]string, error) {
+	raw, ok := props.Extensions[pluginRefExtension].(json.RawMessage)
+	if !ok {
+		return nil, nil
+	}
+	var refName string
+	if err := json.Unmarshal(raw, &refName); err == nil {
+		return []string{refName}, nil
+	}
+	var refNames []string
+	if err := json.Unmarshal(raw, &refNames); err != nil {
+		return nil, fmt.Errorf("expected '%s' to be a string, or an array of strings", pluginRefExtension)
+	}
+	return refNames, nil
+}
+
+// convertPluginRefExtension replaces the 'x-kong-plugin-ref' extension by the
+// 'x-kong-plugin-<name>' extensions of the referenced named plugin configs. Such that they
+// follow the same rules as any other plugin. Unknown references, and references to a plugin
+// that is already configured, return an error.
+func convertPluginRefExtension(props *openapi3.ExtensionProps, namedPlugins map[string]map[string]interface{}) error {
+	refNames, err := getPluginRefs(*props)
+	if err != nil || refNames == nil {
+		return err
+	}
+	delete(props.Extensions, pluginRefExtension)
+
+	for _, refName := range refNames {
+		plugin, found := namedPlugins[refName]
+		if !found {
+			known := make([]string, 0, len(namedPlugins))
+			for name := range namedPlugins {
+				known = append(known, name)
+			}
+			sort.Strings(known)
+			return fmt.Errorf("unknown plugin '%s' in '%s', expected one of '%s' (from '%s')", refName,
+				pluginRefExtension, strings.Join(known, "', '"), namedPluginsLocation)
+		}
+
+		extensionName := "x-kong-plugin-" + plugin["name"].(string)
+		if props.Extensions[extensionName] != nil {
+			return fmt.Errorf("plugin '%s' in '%s' cannot be used together with '%s'", refName,
+				pluginRefExtension, extensionName)
+		}
+
+		config := make(map[string]interface{}, len(plugin))
+		for key, value := range plugin {
+			if key != "name" {
+				config[key] = value
+			}
+		}
+		configJSON, _ := json.Marshal(config)
+		props.Extensions[extensionName] = json.RawMessage(configJSON)
+	}
+	return nil
+}
+
+// convertAllPluginRefExtensions converts the 'x-kong-plugin-ref' extensions on the
+// document, paths, and operations.
+func convertAllPluginRefExtensions(doc *openapi3.T) error {
+	namedPlugins, err := getNamedPlugins(doc)
+	if err != nil {
+		return err
+	}
+
+	if err := convertPluginRefExtension(&doc.ExtensionProps, namedPlugins); err != nil {
+		return fmt.Errorf("failed to create plugins from document root: %w", err)
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if err := convertPluginRefExtension(&doc.Paths[path].ExtensionProps, namedPlugins); err != nil {
+			return fmt.Errorf("failed to create plugins from path '%s': %w", path, err)
+		}
+		for method, operation := range doc.Paths[path].Operations() {
+			if err := convertPluginRefExtension(&operation.ExtensionProps, namedPlugins); err != nil {
+				return fmt.Errorf("failed to create plugins from operation '%s %s': %w", path, method, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/jsonbasics"
+	uuid "github.com/satori/go.uuid"
+)
+
+// isPluginRemoved returns true if the 'x-kong-plugin-<name>' extension is set to 'false',
+// which removes the inherited plugin on that level.
+func isPluginRemoved(props openapi3.ExtensionProps, pluginName string) bool {
+	raw, ok := props.Extensions["x-kong-plugin-"+pluginName].(json.RawMessage)
+	if !ok {
+		return false
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return false
+	}
+	return value == false
+}
+
+// getRemovedPlugins returns the names of the plugins removed on any of the given levels.
+func getRemovedPlugins(props ...openapi3.ExtensionProps) map[string]bool {
+	removed := make(map[string]bool)
+	for _, p := range props {
+		for extensionName := range p.Extensions {
+			if !strings.HasPrefix(extensionName, "x-kong-plugin-") {
+				continue
+			}
+			pluginName := strings.TrimPrefix(extensionName, "x-kong-plugin-")
+			if isPluginRemoved(p, pluginName) {
+				removed[pluginName] = true
+			}
+		}
+	}
+	return removed
+}
+
+// inheritedValidatorConfig returns the inherited request-validator config, or nil if the
+// validator is removed on this level.
+func inheritedValidatorConfig(props openapi3.ExtensionProps, inherited []byte) []byte {
+	if isPluginRemoved(props, "request-validator") {
+		return nil
+	}
+	return inherited
+}
+
+// withoutPlugins returns a copy of the list, without the named plugins. Returns the list
+// itself if there is nothing to remove.
+func withoutPlugins(list *[]*map[string]interface{}, names map[string]bool) *[]*map[string]interface{} {
+	if list == nil || len(names) == 0 {
+		return list
+	}
+	filtered := make([]*map[string]interface{}, 0, len(*list))
+	for _, plugin := range *list {
+		if !names[(*plugin)["name"].(string)] { // safe because it was previously parsed
+			filtered = append(filtered, plugin)
+		}
+	}
+	return &filtered
+}
+
+// moveRemovedServicePlugins handles the plugins removed on routes that they are inherited
+// by from their service. Kong cannot disable a service plugin for a single route, so
+// such a plugin is moved from the service to all its other routes (unless they have their
+// own instances already). 'removed' holds the removed plugin names by route name.
+func moveRemovedServicePlugins(services []interface{}, removed map[string]map[string]bool,
+	uuidNamespace uuid.UUID,
+) {
+	if len(removed) == 0 {
+		return
+	}
+	for _, s := range services {
+		service := s.(map[string]interface{})
+		servicePlugins, _ := service["plugins"].(*[]*map[string]interface{})
+		routes, _ := service["routes"].([]interface{})
+		if servicePlugins == nil || len(routes) == 0 {
+			continue
+		}
+
+		// the service plugins removed on any of the routes
+		moving := make(map[string]bool)
+		for _, r := range routes {
+			routeName := r.(map[string]interface{})["name"].(string)
+			for pluginName := range removed[routeName] {
+				if findPlugin(pluginName, servicePlugins) != nil {
+					moving[pluginName] = true
+				}
+			}
+		}
+		if len(moving) == 0 {
+			continue
+		}
+
+		for _, r := range routes {
+			route := r.(map[string]interface{})
+			routeName := route["name"].(string)
+			routePlugins, _ := route["plugins"].(*[]*map[string]interface{})
+			if routePlugins == nil {
+				routePlugins = &[]*map[string]interface{}{}
+			}
+			list := *routePlugins
+			for pluginName := range moving {
+				if removed[routeName][pluginName] || findPlugin(pluginName, routePlugins) != nil {
+					continue
+				}
+				instance := 0
+				for _, plugin := range *servicePlugins {
+					if (*plugin)["name"] != pluginName {
+						continue
+					}
+					pluginCopy := *jsonbasics.DeepCopyObject(plugin)
+					pluginCopy["id"] = createPluginInstanceID(uuidNamespace, routeName, pluginCopy, instance)
+					instance++
+					list = append(list, &pluginCopy)
+				}
+			}
+			sort.SliceStable(list, func(i, j int) bool {
+				return (*list[i])["name"].(string) < (*list[j])["name"].(string)
+			})
+			route["plugins"] = &list
+		}
+		service["plugins"] = withoutPlugins(servicePlugins, moving)
+	}
+}
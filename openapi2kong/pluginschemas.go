@@ -0,0 +1,211 @@
+package openapi2kong
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/logbasics"
+)
+
+// pluginSchemaFS holds the JSON schemas for the 'config' object of the common plugins.
+// The files are named '<plugin-name>.json'.
+//
+//go:embed plugin_schemas/*.json
+var pluginSchemaFS embed.FS
+
+// pluginSchemas is a cache of the parsed plugin schemas, by plugin name. Guarded by
+// pluginSchemasLock, since conversions can run concurrently.
+var (
+	pluginSchemas     = make(map[string]*openapi3.Schema)
+	pluginSchemasLock sync.Mutex
+)
+
+// PluginSchemaNames returns the names of the plugins with a bundled config schema, sorted.
+func PluginSchemaNames() []string {
+	entries, _ := pluginSchemaFS.ReadDir("plugin_schemas")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetPluginSchemaJSON returns the bundled JSON schema, as used for validating the 'config'
+// object of a plugin (see O2kOptions.ValidatePluginConfig). Returns an error if there is
+// no schema for the plugin.
+func GetPluginSchemaJSON(pluginName string) ([]byte, error) {
+	data, err := pluginSchemaFS.ReadFile("plugin_schemas/" + pluginName + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("no schema available for plugin '%s', expected one of '%s'", pluginName,
+			strings.Join(PluginSchemaNames(), "', '"))
+	}
+	return data, nil
+}
+
+// getPluginSchema returns the config schema for a plugin, or nil if there is none.
+func getPluginSchema(pluginName string) *openapi3.Schema {
+	pluginSchemasLock.Lock()
+	defer pluginSchemasLock.Unlock()
+	if schema, found := pluginSchemas[pluginName]; found {
+		return schema
+	}
+
+	var schema *openapi3.Schema
+	data, err := pluginSchemaFS.ReadFile("plugin_schemas/" + pluginName + ".json")
+	if err == nil {
+		schema = &openapi3.Schema{}
+		if err := json.Unmarshal(data, schema); err != nil {
+			panic(fmt.Sprintf("bad embedded schema for plugin '%s': %v", pluginName, err))
+		}
+	}
+	pluginSchemas[pluginName] = schema
+	return schema
+}
+
+// isVaultReference returns true if the value is a Kong vault reference (eg.
+// "{vault://env/my-secret}"), which is resolved by Kong at runtime.
+func isVaultReference(value interface{}) bool {
+	str, ok := value.(string)
+	return ok && strings.HasPrefix(str, "{vault://") && strings.HasSuffix(str, "}")
+}
+
+// getConfigValue returns the value at the path in the config, or nil if it does not exist.
+func getConfigValue(config interface{}, path []string) interface{} {
+	for _, key := range path {
+		switch node := config.(type) {
+		case map[string]interface{}:
+			config = node[key]
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil
+			}
+			config = node[index]
+		default:
+			return nil
+		}
+	}
+	return config
+}
+
+// validatePluginConfig validates the 'config' object of a plugin against its schema.
+// 'location' is used in the error message to identify the plugin. Vault references are
+// accepted for any field.
+func validatePluginConfig(pluginName string, plugin map[string]interface{}, location string) error {
+	schema := getPluginSchema(pluginName)
+	if schema == nil {
+		logbasics.Warn("no schema available, skipping plugin config validation", "plugin", pluginName,
+			"location", location)
+		return nil
+	}
+
+	config := plugin["config"]
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+
+	err := schema.VisitJSON(config, openapi3.MultiErrors())
+	if err == nil {
+		return nil
+	}
+
+	var errs openapi3.MultiError
+	if me, ok := err.(openapi3.MultiError); ok {
+		errs = me
+	} else {
+		errs = openapi3.MultiError{err}
+	}
+
+	messages := make([]string, 0, len(errs))
+	for _, e := range errs {
+		if se, ok := e.(*openapi3.SchemaError); ok {
+			if isVaultReference(getConfigValue(config, se.JSONPointer())) {
+				continue // the value is only known at runtime, so cannot be validated
+			}
+			field := strings.Join(append([]string{"config"}, se.JSONPointer()...), ".")
+			messages = append(messages, field+": "+se.Reason)
+		} else {
+			messages = append(messages, e.Error())
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	sort.Strings(messages)
+
+	return fmt.Errorf("invalid config for plugin '%s' on %s; %s", pluginName, location, strings.Join(messages, "; "))
+}
+
+// validatePluginConfigs validates the 'x-kong-plugin-<name>' extensions found.
+func validatePluginConfigs(props openapi3.ExtensionProps, components *map[string]interface{},
+	location string,
+) error {
+	extensionNames := make([]string, 0)
+	for extensionName := range props.Extensions {
+		pluginName := strings.TrimPrefix(extensionName, "x-kong-plugin-")
+		if strings.HasPrefix(extensionName, "x-kong-plugin-") && !isPluginRemoved(props, pluginName) {
+			extensionNames = append(extensionNames, extensionName)
+		}
+	}
+	sort.Strings(extensionNames)
+
+	for _, extensionName := range extensionNames {
+		instances, err := getXKongPluginObjects(props, extensionName, components)
+		if err != nil {
+			return err
+		}
+
+		pluginName := strings.TrimPrefix(extensionName, "x-kong-plugin-")
+		for _, jsonstr := range instances {
+			var plugin map[string]interface{}
+			_ = json.Unmarshal(jsonstr, &plugin)
+
+			if err := validatePluginConfig(pluginName, plugin, location); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateAllPluginConfigs validates the plugin configurations on all levels in the document.
+func validateAllPluginConfigs(doc *openapi3.T, components *map[string]interface{}) error {
+	if err := validatePluginConfigs(doc.ExtensionProps, components, "document"); err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		pathItem := doc.Paths[path]
+		if err := validatePluginConfigs(pathItem.ExtensionProps, components, "path '"+path+"'"); err != nil {
+			return err
+		}
+
+		operations := pathItem.Operations()
+		methods := make([]string, 0, len(operations))
+		for method := range operations {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+
+		for _, method := range methods {
+			location := "operation '" + method + " " + path + "'"
+			if err := validatePluginConfigs(operations[method].ExtensionProps, components, location); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
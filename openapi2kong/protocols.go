@@ -0,0 +1,112 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/logbasics"
+)
+
+const (
+	protocolExtension  = "x-kong-protocol"  // single protocol hint, eg. "grpc"
+	protocolsExtension = "x-kong-protocols" // explicit list of route protocols
+)
+
+// grpcProtocols are the route protocols used for gRPC operations without an explicit protocol.
+var grpcProtocols = []string{"grpc", "grpcs"}
+
+// getStringExtension returns the string value of an extension, or "" if not present.
+func getStringExtension(props openapi3.ExtensionProps, name string) (string, error) {
+	if props.Extensions == nil || props.Extensions[name] == nil {
+		return "", nil
+	}
+	var value string
+	if err := json.Unmarshal(props.Extensions[name].(json.RawMessage), &value); err != nil {
+		return "", fmt.Errorf("expected '%s' to be a string", name)
+	}
+	return value, nil
+}
+
+// getStringArrayExtension returns the string array value of an extension, or nil if not present.
+func getStringArrayExtension(props openapi3.ExtensionProps, name string) ([]string, error) {
+	if props.Extensions == nil || props.Extensions[name] == nil {
+		return nil, nil
+	}
+	var value []string
+	if err := json.Unmarshal(props.Extensions[name].(json.RawMessage), &value); err != nil {
+		return nil, fmt.Errorf("expected '%s' to be an array of strings", name)
+	}
+	return value, nil
+}
+
+// hasGrpcContent returns true if the operation has a request body with a gRPC content type.
+func hasGrpcContent(operation *openapi3.Operation) bool {
+	if operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return false
+	}
+	for contentType := range operation.RequestBody.Value.Content {
+		if strings.HasPrefix(contentType, "application/grpc") {
+			return true
+		}
+	}
+	return false
+}
+
+// isGrpcProtocol returns true for the gRPC protocols.
+func isGrpcProtocol(protocol string) bool {
+	return protocol == "grpc" || protocol == "grpcs"
+}
+
+// getRouteProtocols returns the protocols to set on the route generated for the operation,
+// or nil if there is no reason to set them. The extensions are looked up on the operation,
+// path, and document, in that order. Precedence: 'x-kong-protocols', 'x-kong-protocol',
+// gRPC content type.
+func getRouteProtocols(
+	propsList []openapi3.ExtensionProps, // operation, path, document level extensions
+	operation *openapi3.Operation,
+	routeName string,
+) ([]string, error) {
+	for _, props := range propsList {
+		protocols, err := getStringArrayExtension(props, protocolsExtension)
+		if err != nil {
+			return nil, err
+		}
+		if protocols == nil {
+			continue
+		}
+
+		hasGrpc := false
+		hasOther := false
+		for _, protocol := range protocols {
+			if isGrpcProtocol(protocol) {
+				hasGrpc = true
+			} else {
+				hasOther = true
+			}
+		}
+		if hasGrpc && hasOther {
+			logbasics.Warn("route mixes gRPC and non-gRPC protocols", "route", routeName, "protocols", protocols)
+		}
+		return protocols, nil
+	}
+
+	for _, props := range propsList {
+		protocol, err := getStringExtension(props, protocolExtension)
+		if err != nil {
+			return nil, err
+		}
+		if protocol != "" {
+			if isGrpcProtocol(protocol) && operation.RequestBody != nil && !hasGrpcContent(operation) {
+				logbasics.Warn("route marked as gRPC, but has a non-gRPC request body", "route", routeName)
+			}
+			return []string{protocol}, nil
+		}
+	}
+
+	if hasGrpcContent(operation) {
+		return grpcProtocols, nil
+	}
+	return nil, nil
+}
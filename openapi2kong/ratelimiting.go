@@ -0,0 +1,169 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const rateLimitingExtension = "x-kong-rate-limiting"
+
+const (
+	RateLimitingBasic    = "rate-limiting"          // the rate-limiting plugin (default)
+	RateLimitingAdvanced = "rate-limiting-advanced" // the rate-limiting-advanced plugin (Enterprise)
+	RateLimitingResponse = "response-ratelimiting"  // the response-ratelimiting plugin
+)
+
+// rateLimitingWindows are the windows supported in 'limits', with their size in seconds.
+var rateLimitingWindows = map[string]int{
+	"second": 1,
+	"minute": 60,
+	"hour":   3600,
+	"day":    86400,
+	"month":  2592000,
+	"year":   31536000,
+}
+
+// rateLimiting is the content of the 'x-kong-rate-limiting' extension.
+type rateLimiting struct {
+	Type   string                 `json:"type"`   // the plugin to generate, defaults to RateLimitingBasic
+	Limits map[string]int         `json:"limits"` // the limits by window, eg. "minute: 10"
+	Name   string                 `json:"name"`   // name of the limit, only for RateLimitingResponse
+	Config map[string]interface{} `json:"config"` // any additional plugin configuration
+}
+
+// getSortedWindows returns the windows of the limits, sorted by window size. Returns an
+// error if a window is unknown, or its limit is not positive.
+func getSortedWindows(limits map[string]int) ([]string, error) {
+	if len(limits) == 0 {
+		return nil, fmt.Errorf("expected '%s.limits' to have at least 1 entry", rateLimitingExtension)
+	}
+	windows := make([]string, 0, len(limits))
+	for window, limit := range limits {
+		if rateLimitingWindows[window] == 0 {
+			return nil, fmt.Errorf("unknown window '%s' in '%s.limits', expected one of: "+
+				"second, minute, hour, day, month, year", window, rateLimitingExtension)
+		}
+		if limit <= 0 {
+			return nil, fmt.Errorf("expected the '%s' limit in '%s.limits' to be positive", window,
+				rateLimitingExtension)
+		}
+		windows = append(windows, window)
+	}
+	sort.Slice(windows, func(i, j int) bool {
+		return rateLimitingWindows[windows[i]] < rateLimitingWindows[windows[j]]
+	})
+	return windows, nil
+}
+
+// createRateLimitingConfig returns the plugin config for the rate limiting type, in the
+// config shape of the selected plugin.
+func createRateLimitingConfig(rl rateLimiting) (map[string]interface{}, error) {
+	windows, err := getSortedWindows(rl.Limits)
+	if err != nil {
+		return nil, err
+	}
+
+	config := make(map[string]interface{})
+	switch rl.Type {
+	case RateLimitingBasic:
+		for _, window := range windows {
+			config[window] = rl.Limits[window]
+		}
+
+	case RateLimitingAdvanced:
+		limits := make([]int, len(windows))
+		windowSizes := make([]int, len(windows))
+		for i, window := range windows {
+			limits[i] = rl.Limits[window]
+			windowSizes[i] = rateLimitingWindows[window]
+		}
+		config["limit"] = limits
+		config["window_size"] = windowSizes
+
+	case RateLimitingResponse:
+		limit := make(map[string]interface{})
+		for _, window := range windows {
+			limit[window] = rl.Limits[window]
+		}
+		name := rl.Name
+		if name == "" {
+			name = "default"
+		}
+		config["limits"] = map[string]interface{}{name: limit}
+
+	default:
+		return nil, fmt.Errorf("expected '%s.type' to be one of '%s', '%s', or '%s', got: '%s'",
+			rateLimitingExtension, RateLimitingBasic, RateLimitingAdvanced, RateLimitingResponse, rl.Type)
+	}
+
+	if rl.Name != "" && rl.Type != RateLimitingResponse {
+		return nil, fmt.Errorf("'%s.name' is only supported for type '%s'", rateLimitingExtension,
+			RateLimitingResponse)
+	}
+	return deepMerge(config, rl.Config), nil
+}
+
+// convertRateLimitingExtension replaces the 'x-kong-rate-limiting' extension by the
+// 'x-kong-plugin-<type>' extension of the selected plugin. Such that it follows the same
+// rules as any other plugin.
+func convertRateLimitingExtension(props *openapi3.ExtensionProps) error {
+	if props.Extensions == nil || props.Extensions[rateLimitingExtension] == nil {
+		return nil
+	}
+
+	var rl rateLimiting
+	err := json.Unmarshal(props.Extensions[rateLimitingExtension].(json.RawMessage), &rl)
+	if err != nil {
+		return fmt.Errorf("expected '%s' to be an object with 'type' and 'limits'; %w", rateLimitingExtension, err)
+	}
+	if rl.Type == "" {
+		rl.Type = RateLimitingBasic
+	}
+
+	config, err := createRateLimitingConfig(rl)
+	if err != nil {
+		return err
+	}
+	pluginExtension := "x-kong-plugin-" + rl.Type
+	if props.Extensions[pluginExtension] != nil {
+		return fmt.Errorf("cannot use both '%s' and '%s'", rateLimitingExtension, pluginExtension)
+	}
+
+	plugin, _ := json.Marshal(map[string]interface{}{
+		"config": config,
+	})
+	props.Extensions[pluginExtension] = json.RawMessage(plugin)
+	delete(props.Extensions, rateLimitingExtension)
+	return nil
+}
+
+// convertAllRateLimitingExtensions converts the 'x-kong-rate-limiting' extensions on the
+// document, path, and operation levels.
+func convertAllRateLimitingExtensions(doc *openapi3.T) error {
+	if err := convertRateLimitingExtension(&doc.ExtensionProps); err != nil {
+		return fmt.Errorf("failed to create rate limiting plugin from document root: %w", err)
+	}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		pathitem := doc.Paths[path]
+		if err := convertRateLimitingExtension(&pathitem.ExtensionProps); err != nil {
+			return fmt.Errorf("failed to create rate limiting plugin from path '%s': %w", path, err)
+		}
+		for method, operation := range pathitem.Operations() {
+			if err := convertRateLimitingExtension(&operation.ExtensionProps); err != nil {
+				return fmt.Errorf("failed to create rate limiting plugin from operation '%s %s': %w",
+					path, method, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package openapi2kong
+
+import (
+	"strings"
+
+	"github.com/kong/go-apiops/jsonbasics"
+	uuid "github.com/satori/go.uuid"
+)
+
+const requestTransformerPluginName = "request-transformer"
+
+// entryName returns the name of an entry in "name:value" format. Header names are
+// case-insensitive, so those are returned in lowercase.
+func entryName(section string, entry string) string {
+	name := strings.TrimSpace(strings.SplitN(entry, ":", 2)[0])
+	if section == "headers" {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// insertRequestTransformerPlugin inserts a 'request-transformer' plugin, with the entries
+// (in "name:value" format) added to the 'add' config, by section (eg. "headers" or
+// "querystring"). The plugin is based on a copy of 'base' if given (eg. an existing or
+// inherited 'request-transformer' plugin), where entries are only added if that section
+// doesn't have an entry by the same name yet.
+func insertRequestTransformerPlugin(
+	list *[]*map[string]interface{},
+	base *map[string]interface{},
+	entries map[string][]string,
+	uuidNamespace uuid.UUID,
+	baseName string,
+	tags []string,
+) *[]*map[string]interface{} {
+	return insertTransformerPlugin(requestTransformerPluginName, list, base, entries, uuidNamespace,
+		baseName, tags)
+}
+
+// insertTransformerPlugin inserts a transformer plugin by the given name (eg.
+// 'request-transformer' or 'response-transformer'), see insertRequestTransformerPlugin.
+func insertTransformerPlugin(
+	pluginName string,
+	list *[]*map[string]interface{},
+	base *map[string]interface{},
+	entries map[string][]string,
+	uuidNamespace uuid.UUID,
+	baseName string,
+	tags []string,
+) *[]*map[string]interface{} {
+	plugin := map[string]interface{}{
+		"name": pluginName,
+	}
+	config := make(map[string]interface{})
+	if base != nil {
+		plugin = *(jsonbasics.DeepCopyObject(base))
+		config, _ = jsonbasics.ToObject(plugin["config"])
+		if config == nil {
+			config = make(map[string]interface{})
+		}
+	}
+
+	add, _ := jsonbasics.ToObject(config["add"])
+	if add == nil {
+		add = make(map[string]interface{})
+	}
+	for section, newEntries := range entries {
+		sectionEntries, _ := jsonbasics.GetStringArrayField(add, section)
+		for _, entry := range newEntries {
+			found := false
+			for _, existing := range sectionEntries {
+				if entryName(section, existing) == entryName(section, entry) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				sectionEntries = append(sectionEntries, entry)
+			}
+		}
+		add[section] = sectionEntries
+	}
+	config["add"] = add
+	plugin["config"] = config
+
+	plugin["id"] = createPluginID(uuidNamespace, baseName, plugin)
+	plugin["tags"] = tags
+
+	return insertPlugin(list, &plugin)
+}
@@ -0,0 +1,75 @@
+package openapi2kong
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/logbasics"
+)
+
+// parseSpecBaseURL parses the SpecBaseURL option. Returns nil if it is empty, and an error
+// if it is not an absolute url.
+func parseSpecBaseURL(baseURL string) (*url.URL, error) {
+	if baseURL == "" {
+		return nil, nil
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("expected the spec base url to be an absolute url, got: '%s'", baseURL)
+	}
+	return base, nil
+}
+
+// resolveServerURLs resolves the relative urls of the servers (no scheme, eg. "/api/v1" or
+// "//example.com/api") against the base url, in place. The template variables are replaced
+// by their defaults, before resolving. Without a base url, the relative urls are left
+// as is, with a warning for the ones without a host.
+func resolveServerURLs(servers openapi3.Servers, base *url.URL) error {
+	for _, server := range servers {
+		rendered := renderServerURL(server)
+		ref, err := url.Parse(rendered)
+		if err != nil {
+			return fmt.Errorf("failed to parse uri '%s'; %w", rendered, err)
+		}
+		if ref.Scheme != "" {
+			continue // absolute
+		}
+
+		if base == nil {
+			if ref.Host == "" {
+				logbasics.Warn("server url is relative, and no spec base url is set, assuming host 'localhost'",
+					"url", rendered)
+			}
+			continue
+		}
+		server.URL = base.ResolveReference(ref).String()
+		server.Variables = nil // already rendered into the url
+		logbasics.Debug("resolved relative server url", "url", rendered, "resolved", server.URL)
+	}
+	return nil
+}
+
+// resolveAllServerURLs resolves the relative server urls on the document, paths, and
+// operations against the base url (see resolveServerURLs).
+func resolveAllServerURLs(doc *openapi3.T, base *url.URL) error {
+	if doc.Extensions[serviceURLExtension] == nil { // the servers are unused with a service url
+		if err := resolveServerURLs(doc.Servers, base); err != nil {
+			return fmt.Errorf("failed to resolve the servers of the document: %w", err)
+		}
+	}
+	for path, pathitem := range doc.Paths {
+		if err := resolveServerURLs(pathitem.Servers, base); err != nil {
+			return fmt.Errorf("failed to resolve the servers of path '%s': %w", path, err)
+		}
+		for method, operation := range pathitem.Operations() {
+			if operation.Servers == nil || operation.Extensions[serviceURLExtension] != nil {
+				continue
+			}
+			if err := resolveServerURLs(*operation.Servers, base); err != nil {
+				return fmt.Errorf("failed to resolve the servers of operation '%s %s': %w", path, method, err)
+			}
+		}
+	}
+	return nil
+}
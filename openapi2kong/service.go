@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/logbasics"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -16,6 +17,26 @@ const (
 	httpsScheme = "https"
 )
 
+// validateDefaultProtocol returns an error if the default protocol is not "http" or "https".
+func validateDefaultProtocol(protocol string) error {
+	switch protocol {
+	case "", httpScheme, httpsScheme:
+		return nil
+	}
+	return fmt.Errorf("expected default protocol to be one of '%s', or '%s', got: '%s'",
+		httpScheme, httpsScheme, protocol)
+}
+
+// renderServerURL returns the url of the server, with the template variables replaced by
+// their defaults.
+func renderServerURL(server *openapi3.Server) string {
+	uriString := server.URL
+	for name, svar := range server.Variables {
+		uriString = strings.ReplaceAll(uriString, "{"+name+"}", svar.Default)
+	}
+	return uriString
+}
+
 // parseServerUris parses the server uri's after rendering the template variables.
 // result will always have at least 1 entry, but not necessarily a hostname/port/scheme
 func parseServerUris(servers *openapi3.Servers) ([]*url.URL, error) {
@@ -30,12 +51,14 @@ func parseServerUris(servers *openapi3.Servers) ([]*url.URL, error) {
 		targets = make([]*url.URL, len(*servers))
 
 		for i, server := range *servers {
-			uriString := server.URL
-			for name, svar := range server.Variables {
-				uriString = strings.ReplaceAll(uriString, "{"+name+"}", svar.Default)
-			}
+			uriString := renderServerURL(server)
 
-			uriObject, err := url.ParseRequestURI(uriString)
+			parse := url.ParseRequestURI
+			if strings.HasPrefix(uriString, "//") {
+				// a url without a scheme ("//host/path"), parse the host instead of taking it as a path
+				parse = url.Parse
+			}
+			uriObject, err := parse(uriString)
 			if err != nil {
 				return targets, fmt.Errorf("failed to parse uri '%s'; %w", uriString, err)
 			}
@@ -51,12 +74,35 @@ func parseServerUris(servers *openapi3.Servers) ([]*url.URL, error) {
 	return targets, nil
 }
 
+// getServerHosts returns the unique hostnames (without ports) of the servers, in order.
+// Servers without a hostname are skipped.
+func getServerHosts(servers *openapi3.Servers) ([]string, error) {
+	targets, err := parseServerUris(servers)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := make([]string, 0, len(targets))
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		host := target.Hostname()
+		if host != "" && !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts, nil
+}
+
 // setServerDefaults sets the scheme and port if missing and inferable.
 // It's set based on; scheme given, port (80/443), default-scheme. In that order.
+// Assuming the default-scheme for a url with a hostname is logged as a warning.
 func setServerDefaults(targets []*url.URL, schemeDefault string) {
 	for _, target := range targets {
+		hasHost := target.Host != ""
+
 		// set the hostname if unset
-		if target.Host == "" {
+		if !hasHost {
 			target.Host = "localhost"
 		}
 
@@ -71,6 +117,9 @@ func setServerDefaults(targets []*url.URL, schemeDefault string) {
 				target.Scheme = httpsScheme
 
 			default:
+				if hasHost {
+					logbasics.Warn("server url has no scheme, assuming '"+schemeDefault+"'", "url", target.String())
+				}
 				target.Scheme = schemeDefault
 			}
 		}
@@ -116,12 +165,30 @@ func parseDefaultTargets(targets interface{}, tags []string) ([]map[string]inter
 }
 
 // createKongUpstream create a new upstream entity.
+// serverTagPrefix is the prefix of the tag holding the server description, eg. "server:canary".
+const serverTagPrefix = "server:"
+
+// getTargetTags returns the tags for the target generated from the server at 'index'. If the
+// server has a description, it is added as a sanitized tag, so operators can tell the targets
+// apart (eg. "primary" and "canary").
+func getTargetTags(servers *openapi3.Servers, index int, tags []string) []string {
+	if servers == nil || index >= len(*servers) || (*servers)[index].Description == "" {
+		return tags
+	}
+	description := Slugify((*servers)[index].Description)
+	if description == "" {
+		return tags
+	}
+	return append(append(make([]string, 0, len(tags)+1), tags...), serverTagPrefix+description)
+}
+
 func createKongUpstream(
 	baseName string, // slugified name of the upstream, and uuid input
 	servers *openapi3.Servers, // the OAS3 server block to use for generation
 	upstreamDefaults []byte, // defaults to use (JSON string) or empty if no defaults
 	tags []string, // tags to attach to the new upstream
 	uuidNamespace uuid.UUID,
+	defaultProtocol string, // scheme for server urls without one
 ) (map[string]interface{}, error) {
 	var upstream map[string]interface{}
 
@@ -156,14 +223,14 @@ func createKongUpstream(
 		return nil, fmt.Errorf("failed to generate upstream: %w", err)
 	}
 
-	setServerDefaults(targets, httpsScheme)
+	setServerDefaults(targets, defaultProtocol)
 
 	// now add the targets to the upstream
 	upstreamTargets := make([]map[string]interface{}, len(targets))
 	for i, target := range targets {
 		t := make(map[string]interface{})
 		t["target"] = target.Host
-		t["tags"] = tags
+		t["tags"] = getTargetTags(servers, i, tags)
 		upstreamTargets[i] = t
 	}
 	upstream["targets"] = upstreamTargets
@@ -181,6 +248,20 @@ func CreateKongService(
 	upstreamDefaults []byte,
 	tags []string,
 	uuidNamespace uuid.UUID,
+) (map[string]interface{}, map[string]interface{}, error) {
+	return createKongService(baseName, servers, serviceDefaults, upstreamDefaults, tags, uuidNamespace, httpsScheme)
+}
+
+// createKongService is the same as CreateKongService, with the protocol to use for server
+// urls without a scheme (and no 'protocol' in the service defaults).
+func createKongService(
+	baseName string,
+	servers *openapi3.Servers,
+	serviceDefaults []byte,
+	upstreamDefaults []byte,
+	tags []string,
+	uuidNamespace uuid.UUID,
+	defaultProtocol string,
 ) (map[string]interface{}, map[string]interface{}, error) {
 	var (
 		service  map[string]interface{}
@@ -208,7 +289,7 @@ func CreateKongService(
 	}
 
 	// fill in the scheme of the url if missing. Use service-defaults for the default scheme
-	scheme := httpsScheme
+	scheme := defaultProtocol
 	if service["protocol"] != nil {
 		scheme = service["protocol"].(string)
 	}
@@ -245,7 +326,8 @@ func CreateKongService(
 			service["host"] = targets[0].Hostname()
 		} else {
 			// have to create an upstream with targets
-			upstream, err = createKongUpstream(baseName, servers, upstreamDefaults, tags, uuidNamespace)
+			upstream, err = createKongUpstream(baseName, servers, upstreamDefaults, tags, uuidNamespace,
+				defaultProtocol)
 			if err != nil {
 				return nil, nil, err
 			}
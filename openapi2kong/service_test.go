@@ -6,6 +6,7 @@ import (
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/google/go-cmp/cmp"
+	uuid "github.com/satori/go.uuid"
 )
 
 func Test_parseServerUris(t *testing.T) {
@@ -138,3 +139,72 @@ func Test_setServerDefaults(t *testing.T) {
 		}
 	}
 }
+
+func Test_createKongUpstreamTags(t *testing.T) {
+	tags := []string{"tag1", "tag2"}
+	servers := &openapi3.Servers{
+		{URL: "https://server1.com/"},
+		{URL: "https://server2.com/"},
+	}
+
+	upstreamTests := []struct {
+		name     string
+		defaults []byte
+	}{
+		{"targets from servers", nil},
+		{"targets from upstream-defaults", []byte(`{
+			"tags": ["overwritten"],
+			"targets": [
+				{ "target": "server3.com:443", "tags": ["overwritten"] },
+				{ "target": "server4.com:443" }
+			]
+		}`)},
+	}
+
+	for _, tst := range upstreamTests {
+		upstream, err := createKongUpstream("base", servers, tst.defaults, tags, uuid.NamespaceDNS, httpsScheme)
+		if err != nil {
+			t.Errorf("%s: did not expect error: %v", tst.name, err)
+			continue
+		}
+		if diff := cmp.Diff(upstream["tags"], tags); diff != "" {
+			t.Errorf("%s: upstream tags: %s", tst.name, diff)
+		}
+		targets := upstream["targets"].([]map[string]interface{})
+		if len(targets) != 2 {
+			t.Errorf("%s: expected 2 targets, but got %d", tst.name, len(targets))
+		}
+		for _, target := range targets {
+			if diff := cmp.Diff(target["tags"], tags); diff != "" {
+				t.Errorf("%s: tags of target '%s': %s", tst.name, target["target"], diff)
+			}
+		}
+	}
+}
+
+func Test_createKongUpstreamServerDescriptionTags(t *testing.T) {
+	tags := []string{"tag1"}
+	servers := &openapi3.Servers{
+		{URL: "https://server1.com/", Description: "Primary"},
+		{URL: "https://server2.com/", Description: "Canary (10%)"},
+		{URL: "https://server3.com/"},
+	}
+
+	upstream, err := createKongUpstream("base", servers, nil, tags, uuid.NamespaceDNS, httpsScheme)
+	if err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+	expected := map[string][]string{
+		"server1.com:443": {"tag1", "server:primary"},
+		"server2.com:443": {"tag1", "server:canary-10"},
+		"server3.com:443": {"tag1"},
+	}
+	for _, target := range upstream["targets"].([]map[string]interface{}) {
+		if diff := cmp.Diff(target["tags"], expected[target["target"].(string)]); diff != "" {
+			t.Errorf("tags of target '%s': %s", target["target"], diff)
+		}
+	}
+	if diff := cmp.Diff(upstream["tags"], tags); diff != "" {
+		t.Errorf("upstream tags: %s", diff)
+	}
+}
@@ -0,0 +1,66 @@
+package openapi2kong
+
+import (
+	"github.com/getkin/kin-openapi/openapi3"
+	uuid "github.com/satori/go.uuid"
+)
+
+// createTagService returns a copy of the document level service, for the operations with
+// the given (primary) OpenAPI tag. It gets its own name and id, based on the tag, and a copy
+// of the document level plugins. The consumer bound plugins are added to foreignKeyPlugins,
+// referring to the new service. Returns the new service, and the updated foreignKeyPlugins.
+func createTagService(
+	docService map[string]interface{},
+	docPlugins *[]*map[string]interface{}, // document plugins, including the consumer bound ones
+	tag string,
+	foreignKeyPlugins *[]*map[string]interface{},
+	uuidNamespace uuid.UUID,
+	components *map[string]interface{},
+	tags []string,
+) (map[string]interface{}, *[]*map[string]interface{}) {
+	baseName := docService["name"].(string) + "_" + Slugify(tag)
+
+	service := make(map[string]interface{})
+	for key, value := range docService {
+		service[key] = value
+	}
+	service["id"] = uuid.NewV5(uuidNamespace, baseName+".service").String()
+	service["name"] = baseName
+	service["routes"] = make([]interface{}, 0)
+
+	// copying only inherited plugins cannot fail, no extensions are parsed
+	pluginList, _ := getPluginsList(openapi3.ExtensionProps{}, docPlugins, uuidNamespace, baseName,
+		components, tags)
+	foreignKeyPlugins, pluginList = getForeignKeyPlugins(foreignKeyPlugins, pluginList, "service", baseName)
+	service["plugins"] = pluginList
+
+	return service, foreignKeyPlugins
+}
+
+// removeUnusedDocService removes the document level service if it has no routes, since all
+// operations were moved to the services by tag. The consumer bound plugins referring to it
+// are removed as well. Returns the updated services and foreignKeyPlugins.
+func removeUnusedDocService(
+	services []interface{},
+	docService map[string]interface{},
+	foreignKeyPlugins *[]*map[string]interface{},
+) ([]interface{}, *[]*map[string]interface{}) {
+	if len(docService["routes"].([]interface{})) > 0 {
+		return services, foreignKeyPlugins
+	}
+
+	newServices := make([]interface{}, 0, len(services))
+	for _, service := range services {
+		if service.(map[string]interface{})["name"] != docService["name"] {
+			newServices = append(newServices, service)
+		}
+	}
+
+	newPlugins := make([]*map[string]interface{}, 0, len(*foreignKeyPlugins))
+	for _, plugin := range *foreignKeyPlugins {
+		if (*plugin)["service"] != docService["name"] {
+			newPlugins = append(newPlugins, plugin)
+		}
+	}
+	return newServices, &newPlugins
+}
@@ -0,0 +1,65 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const serviceURLExtension = "x-kong-service-url"
+
+// getServiceURL returns the 'x-kong-service-url' extension, validated to be an absolute url
+// (with a scheme and host). Returns "" if not set.
+func getServiceURL(props openapi3.ExtensionProps) (string, error) {
+	if props.Extensions == nil || props.Extensions[serviceURLExtension] == nil {
+		return "", nil
+	}
+	var serviceURL string
+	if err := json.Unmarshal(props.Extensions[serviceURLExtension].(json.RawMessage), &serviceURL); err != nil {
+		return "", fmt.Errorf("expected '%s' to be a string; %w", serviceURLExtension, err)
+	}
+	parsed, err := url.Parse(serviceURL)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return "", fmt.Errorf("expected '%s' to be an absolute url, got: '%s'", serviceURLExtension, serviceURL)
+	}
+	return serviceURL, nil
+}
+
+// checkServiceURL returns an error if a service url is used together with an upstream,
+// since the url replaces the host the upstream would be bound by.
+func checkServiceURL(serviceURL string, upstreamRef string, upstreamDefaults []byte) error {
+	if serviceURL == "" {
+		return nil
+	}
+	if upstreamRef != "" {
+		return fmt.Errorf("cannot use '%s' together with 'x-kong-upstream'", serviceURLExtension)
+	}
+	if upstreamDefaults != nil {
+		return fmt.Errorf("cannot use '%s' together with 'x-kong-upstream-defaults', it would "+
+			"generate an upstream", serviceURLExtension)
+	}
+	return nil
+}
+
+// serviceServers returns the servers to create the service from. Nil if a service url is
+// set, since the servers are not used then (see applyServiceURL).
+func serviceServers(servers *openapi3.Servers, serviceURL string) *openapi3.Servers {
+	if serviceURL != "" {
+		return nil
+	}
+	return servers
+}
+
+// applyServiceURL sets the 'url' of the service verbatim, replacing the fields derived from
+// the servers (or the service defaults).
+func applyServiceURL(service map[string]interface{}, serviceURL string) {
+	if serviceURL == "" {
+		return
+	}
+	for _, key := range []string{"protocol", "host", "port", "path"} {
+		delete(service, key)
+	}
+	service["url"] = serviceURL
+}
@@ -0,0 +1,104 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// the single-value shorthand extensions, for a field of the service or route defaults
+const (
+	connectTimeoutExtension          = "x-kong-connect-timeout"
+	readTimeoutExtension             = "x-kong-read-timeout"
+	writeTimeoutExtension            = "x-kong-write-timeout"
+	retriesExtension                 = "x-kong-retries"
+	preserveHostExtension            = "x-kong-preserve-host"
+	httpsRedirectStatusCodeExtension = "x-kong-https-redirect-status-code"
+)
+
+// shorthand is the field of the defaults a shorthand extension sets, with its valid values;
+// booleans, or integers in a range, or from a list if 'allowed' is set.
+type shorthand struct {
+	field   string
+	boolean bool
+	min     int64
+	max     int64
+	allowed []int64
+}
+
+// serviceShorthands are the shorthand extensions for the service defaults
+var serviceShorthands = map[string]shorthand{
+	connectTimeoutExtension: {field: "connect_timeout", min: 1, max: 2147483646},
+	readTimeoutExtension:    {field: "read_timeout", min: 1, max: 2147483646},
+	writeTimeoutExtension:   {field: "write_timeout", min: 1, max: 2147483646},
+	retriesExtension:        {field: "retries", min: 0, max: 32767},
+}
+
+// routeShorthands are the shorthand extensions for the route defaults
+var routeShorthands = map[string]shorthand{
+	preserveHostExtension:            {field: "preserve_host", boolean: true},
+	httpsRedirectStatusCodeExtension: {field: "https_redirect_status_code", allowed: []int64{301, 302, 307, 308, 426}},
+}
+
+// getShorthandValue returns the validated value of a shorthand extension.
+func getShorthandValue(props openapi3.ExtensionProps, extensionName string, s shorthand) (interface{}, error) {
+	var value interface{}
+	_ = json.Unmarshal(props.Extensions[extensionName].(json.RawMessage), &value)
+	if s.boolean {
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("expected '%s' to be a boolean", extensionName)
+	}
+
+	number, ok := value.(float64)
+	if !ok || number != math.Trunc(number) {
+		return nil, fmt.Errorf("expected '%s' to be an integer", extensionName)
+	}
+	integer := int64(number)
+	if s.allowed != nil {
+		for _, allowed := range s.allowed {
+			if integer == allowed {
+				return integer, nil
+			}
+		}
+		return nil, fmt.Errorf("expected '%s' to be one of %v, got %d", extensionName, s.allowed, integer)
+	}
+	if integer < s.min || integer > s.max {
+		return nil, fmt.Errorf("expected '%s' to be in the range %d-%d, got %d", extensionName, s.min, s.max, integer)
+	}
+	return integer, nil
+}
+
+// applyShorthands returns the defaults (JSON string) with the fields set by the shorthand
+// extensions added. The shorthands take precedence over the defaults object. If neither
+// is given it returns nil.
+func applyShorthands(props openapi3.ExtensionProps, defaults []byte, shorthands map[string]shorthand,
+) ([]byte, error) {
+	extensionNames := make([]string, 0)
+	for extensionName := range shorthands {
+		if props.Extensions[extensionName] != nil {
+			extensionNames = append(extensionNames, extensionName)
+		}
+	}
+	if len(extensionNames) == 0 {
+		return defaults, nil
+	}
+	sort.Strings(extensionNames)
+
+	object := make(map[string]interface{})
+	if defaults != nil {
+		_ = json.Unmarshal(defaults, &object)
+	}
+	for _, extensionName := range extensionNames {
+		value, err := getShorthandValue(props, extensionName, shorthands[extensionName])
+		if err != nil {
+			return nil, err
+		}
+		object[shorthands[extensionName].field] = value
+	}
+	return json.Marshal(object)
+}
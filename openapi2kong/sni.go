@@ -0,0 +1,52 @@
+package openapi2kong
+
+import (
+	"github.com/getkin/kin-openapi/openapi3"
+	uuid "github.com/satori/go.uuid"
+)
+
+// getCertificatePlaceholderID returns the id of the certificate the generated SNIs refer to.
+// The certificate itself is not generated, it must be created separately using this id.
+func getCertificatePlaceholderID(uuidNamespace uuid.UUID, baseName string) string {
+	return uuid.NewV5(uuidNamespace, baseName+".certificate").String()
+}
+
+// collectSNIHosts appends the hostnames of the servers to hosts, skipping duplicates.
+// Wildcard hostnames (eg. "*.example.com") are retained as is.
+func collectSNIHosts(hosts []string, servers *openapi3.Servers) ([]string, error) {
+	serverHosts, err := getServerHosts(servers)
+	if err != nil {
+		return hosts, err
+	}
+	for _, host := range serverHosts {
+		found := false
+		for _, existing := range hosts {
+			if existing == host {
+				found = true
+				break
+			}
+		}
+		if !found {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts, nil
+}
+
+// createKongSNIs returns the SNI entities for the hostnames, all referring to the
+// same certificate placeholder.
+func createKongSNIs(hosts []string, uuidNamespace uuid.UUID, baseName string, tags []string) []interface{} {
+	certificateID := getCertificatePlaceholderID(uuidNamespace, baseName)
+	snis := make([]interface{}, 0, len(hosts))
+	for _, host := range hosts {
+		snis = append(snis, map[string]interface{}{
+			"id":   uuid.NewV5(uuidNamespace, baseName+".sni."+host).String(),
+			"name": host,
+			"certificate": map[string]interface{}{
+				"id": certificateID,
+			},
+			"tags": tags,
+		})
+	}
+	return snis
+}
@@ -0,0 +1,30 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const streamingExtension = "x-kong-streaming"
+
+// isStreaming returns whether the buffering of the route for the operation must be disabled.
+// Precedence is; 'x-kong-streaming' on the operation -> the DisableBuffering option.
+func isStreaming(props openapi3.ExtensionProps, disableBuffering bool) (bool, error) {
+	if props.Extensions == nil || props.Extensions[streamingExtension] == nil {
+		return disableBuffering, nil
+	}
+	var streaming bool
+	if err := json.Unmarshal(props.Extensions[streamingExtension].(json.RawMessage), &streaming); err != nil {
+		return false, fmt.Errorf("expected '%s' to be a boolean", streamingExtension)
+	}
+	return streaming, nil
+}
+
+// applyStreaming disables the request and response buffering of the route, as required by
+// streaming operations (eg. server-sent events, or chunked transfers).
+func applyStreaming(route map[string]interface{}) {
+	route["request_buffering"] = false
+	route["response_buffering"] = false
+}
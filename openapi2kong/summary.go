@@ -0,0 +1,97 @@
+package openapi2kong
+
+import "sort"
+
+// ServiceSummary describes a generated service, for the conversion summary.
+type ServiceSummary struct {
+	Name    string         `json:"name"`
+	Tags    []string       `json:"tags"`
+	Plugins []string       `json:"plugins"` // names of the plugins attached to the service
+	Routes  []RouteSummary `json:"routes"`
+}
+
+// RouteSummary describes a generated route, and the operation in the spec it was generated from.
+type RouteSummary struct {
+	Name        string   `json:"name"`
+	Path        string   `json:"path"`   // the path of the operation in the spec
+	Method      string   `json:"method"` // the method of the operation, uppercase
+	OperationID string   `json:"operation_id,omitempty"`
+	Tags        []string `json:"tags"`
+	Plugins     []string `json:"plugins"` // names of the plugins attached to the route
+}
+
+// O2kStats are the counts of a conversion, eg. for a summary in CI logs.
+type O2kStats struct {
+	Services          int `json:"services"`
+	Routes            int `json:"routes"`
+	Plugins           int `json:"plugins"` // on the services and routes, and the top-level ones
+	SkippedOperations int `json:"skipped_operations"`
+}
+
+// getPluginNames returns the names of the plugins in a plugin list, sorted.
+func getPluginNames(entity map[string]interface{}) []string {
+	names := make([]string, 0)
+	if plugins, ok := entity["plugins"].(*[]*map[string]interface{}); ok && plugins != nil {
+		for _, plugin := range *plugins {
+			names = append(names, (*plugin)["name"].(string))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getEntityTags returns the tags of a generated entity, or an empty list.
+func getEntityTags(entity map[string]interface{}) []string {
+	if tags, ok := entity["tags"].([]string); ok && tags != nil {
+		return tags
+	}
+	return []string{}
+}
+
+// getSummary returns the summary of the generated services and routes. 'sources' holds the
+// operations the routes were generated from, by route name.
+func getSummary(services []interface{}, sources map[string]RouteSummary) []ServiceSummary {
+	summary := make([]ServiceSummary, 0, len(services))
+	for _, s := range services {
+		service := s.(map[string]interface{})
+		serviceSummary := ServiceSummary{
+			Name:    service["name"].(string),
+			Tags:    getEntityTags(service),
+			Plugins: getPluginNames(service),
+			Routes:  make([]RouteSummary, 0),
+		}
+		routes, _ := service["routes"].([]interface{})
+		for _, r := range routes {
+			route := r.(map[string]interface{})
+			routeSummary := sources[route["name"].(string)]
+			routeSummary.Name = route["name"].(string)
+			routeSummary.Tags = getEntityTags(route)
+			routeSummary.Plugins = getPluginNames(route)
+			serviceSummary.Routes = append(serviceSummary.Routes, routeSummary)
+		}
+		summary = append(summary, serviceSummary)
+	}
+	return summary
+}
+
+// getStats returns the counts of the generated services, routes, and plugins (including
+// the top-level 'foreignKeyPlugins').
+func getStats(services []interface{}, foreignKeyPlugins *[]*map[string]interface{}, skipped int) O2kStats {
+	stats := O2kStats{
+		Services:          len(services),
+		SkippedOperations: skipped,
+	}
+	if foreignKeyPlugins != nil {
+		stats.Plugins = len(*foreignKeyPlugins)
+	}
+	for _, s := range services {
+		service := s.(map[string]interface{})
+		stats.Plugins += len(getPluginNames(service))
+		routes, _ := service["routes"].([]interface{})
+		stats.Routes += len(routes)
+		for _, r := range routes {
+			stats.Plugins += len(getPluginNames(r.(map[string]interface{})))
+		}
+	}
+	return stats
+}
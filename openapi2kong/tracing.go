@@ -0,0 +1,24 @@
+package openapi2kong
+
+import (
+	uuid "github.com/satori/go.uuid"
+)
+
+// DefaultTracingHeaders are the headers added by the AddTracingHeaders option, if no
+// TracingHeaders are specified. It copies the W3C trace context into an 'X-Request-ID'
+// header, for backends that only log the latter.
+var DefaultTracingHeaders = []string{`X-Request-ID:$(headers["traceparent"] or "")`}
+
+// insertTracingHeadersPlugin inserts a 'request-transformer' plugin that adds the headers
+// (in "name:value" format). If the list already has a 'request-transformer' plugin, the
+// headers are added to it. See insertRequestTransformerPlugin.
+func insertTracingHeadersPlugin(
+	list *[]*map[string]interface{},
+	headers []string,
+	uuidNamespace uuid.UUID,
+	baseName string,
+	tags []string,
+) *[]*map[string]interface{} {
+	return insertRequestTransformerPlugin(list, findPlugin(requestTransformerPluginName, list),
+		map[string][]string{"headers": headers}, uuidNamespace, baseName, tags)
+}
@@ -0,0 +1,104 @@
+package openapi2kong
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/kong/go-apiops/logbasics"
+)
+
+const (
+	TrailingSlashStrict = "strict" // keep paths differing only by a trailing slash separate (default)
+	TrailingSlashMerge  = "merge"  // merge them into one, matching with and without trailing slash
+	TrailingSlashStrip  = "strip"  // strip trailing slashes from all paths, merging any duplicates
+)
+
+// hasTrailingSlash returns true if the path ends with a "/" (the root path "/" excluded).
+func hasTrailingSlash(path string) bool {
+	return len(path) > 1 && strings.HasSuffix(path, "/")
+}
+
+// mergePathItems returns a new path item with the operations of 'extra' added to 'base'.
+// Anything in 'extra' that cannot be merged is dropped, and a warning is logged.
+func mergePathItems(base *openapi3.PathItem, basePath string,
+	extra *openapi3.PathItem, extraPath string,
+) *openapi3.PathItem {
+	merged := *base
+
+	if !reflect.DeepEqual(base.ExtensionProps, extra.ExtensionProps) ||
+		!reflect.DeepEqual(base.Servers, extra.Servers) ||
+		!reflect.DeepEqual(base.Parameters, extra.Parameters) {
+		logbasics.Warn("merging paths, the path-level configuration of the second is dropped",
+			"path", basePath, "dropped", extraPath)
+	}
+
+	for method, operation := range extra.Operations() {
+		if merged.GetOperation(method) != nil {
+			logbasics.Warn("merging paths, operation is defined on both, the second is dropped",
+				"method", method, "path", basePath, "dropped", extraPath)
+			continue
+		}
+		merged.SetOperation(method, operation)
+	}
+	return &merged
+}
+
+// applyTrailingSlashPolicy returns the paths to convert, after applying the trailing slash
+// policy. The second map returned contains the paths for which the generated routes must
+// match with and without a trailing slash.
+func applyTrailingSlashPolicy(paths openapi3.Paths, policy string) (openapi3.Paths, map[string]bool, error) {
+	optionalSlash := make(map[string]bool)
+
+	switch policy {
+	case "", TrailingSlashStrict:
+		return paths, optionalSlash, nil
+
+	case TrailingSlashMerge:
+		result := make(openapi3.Paths, len(paths))
+		for path, pathItem := range paths {
+			result[path] = pathItem
+		}
+		for path, pathItem := range paths {
+			if !hasTrailingSlash(path) {
+				continue
+			}
+			stripped := strings.TrimSuffix(path, "/")
+			base := paths[stripped]
+			if base == nil {
+				continue // not a duplicate, leave it as is
+			}
+			logbasics.Info("merging paths differing only by a trailing slash", "path", stripped)
+			result[stripped] = mergePathItems(base, stripped, pathItem, path)
+			delete(result, path)
+			optionalSlash[stripped] = true
+		}
+		return result, optionalSlash, nil
+
+	case TrailingSlashStrip:
+		result := make(openapi3.Paths, len(paths))
+		for path, pathItem := range paths {
+			if !hasTrailingSlash(path) {
+				result[path] = pathItem
+			}
+		}
+		for path, pathItem := range paths {
+			if !hasTrailingSlash(path) {
+				continue
+			}
+			stripped := strings.TrimSuffix(path, "/")
+			if base := result[stripped]; base != nil {
+				logbasics.Info("merging paths differing only by a trailing slash", "path", stripped)
+				result[stripped] = mergePathItems(base, stripped, pathItem, path)
+			} else {
+				logbasics.Debug("stripping trailing slash", "path", path)
+				result[stripped] = pathItem
+			}
+		}
+		return result, optionalSlash, nil
+	}
+
+	return nil, nil, fmt.Errorf("expected trailing slash policy to be one of '%s', '%s', or '%s', got: '%s'",
+		TrailingSlashStrict, TrailingSlashMerge, TrailingSlashStrip, policy)
+}
@@ -0,0 +1,112 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const (
+	uploadExtension           = "x-kong-upload"
+	requestSizeLimitingPlugin = "x-kong-plugin-request-size-limiting"
+)
+
+// uploadSizeUnits are the units supported by the request-size-limiting plugin.
+var uploadSizeUnits = []string{"bytes", "kilobytes", "megabytes"}
+
+// upload is the content of the 'x-kong-upload' extension.
+type upload struct {
+	MaxSize  int    `json:"max_size"`  // the maximum size of the request body, required
+	SizeUnit string `json:"size_unit"` // the unit of MaxSize, defaults to "megabytes"
+}
+
+// getUpload returns the 'x-kong-upload' extension, validated. Returns nil if not set.
+func getUpload(props openapi3.ExtensionProps) (*upload, error) {
+	if props.Extensions == nil || props.Extensions[uploadExtension] == nil {
+		return nil, nil
+	}
+	var up upload
+	if err := json.Unmarshal(props.Extensions[uploadExtension].(json.RawMessage), &up); err != nil {
+		return nil, fmt.Errorf("expected '%s' to be an object with 'max_size' and 'size_unit'; %w",
+			uploadExtension, err)
+	}
+	if up.MaxSize <= 0 {
+		// with buffering disabled, an unlimited size would accept any body
+		return nil, fmt.Errorf("expected '%s.max_size' to be a positive integer", uploadExtension)
+	}
+	if up.SizeUnit == "" {
+		up.SizeUnit = "megabytes"
+	}
+	for _, unit := range uploadSizeUnits {
+		if unit == up.SizeUnit {
+			return &up, nil
+		}
+	}
+	return nil, fmt.Errorf("expected '%s.size_unit' to be one of 'bytes', 'kilobytes', or 'megabytes', got: '%s'",
+		uploadExtension, up.SizeUnit)
+}
+
+// convertUploadExtension adds an 'x-kong-plugin-request-size-limiting' extension for the
+// 'x-kong-upload' extension, such that it follows the same rules as any other plugin. Since
+// the request is not buffered, the size can only be checked upfront, so the Content-Length
+// header is required. The extension itself is kept, to disable the request buffering of
+// the route (see isUpload).
+func convertUploadExtension(props *openapi3.ExtensionProps) error {
+	up, err := getUpload(*props)
+	if err != nil || up == nil {
+		return err
+	}
+	if props.Extensions[requestSizeLimitingPlugin] != nil {
+		return fmt.Errorf("cannot use both '%s' and '%s'", uploadExtension, requestSizeLimitingPlugin)
+	}
+	streaming, err := isStreaming(*props, true)
+	if err != nil {
+		return err
+	}
+	if !streaming {
+		return fmt.Errorf("cannot use '%s' together with '%s: false', uploads require the request "+
+			"buffering to be disabled", uploadExtension, streamingExtension)
+	}
+
+	plugin, _ := json.Marshal(map[string]interface{}{
+		"config": map[string]interface{}{
+			"allowed_payload_size":   up.MaxSize,
+			"size_unit":              up.SizeUnit,
+			"require_content_length": true,
+		},
+	})
+	props.Extensions[requestSizeLimitingPlugin] = json.RawMessage(plugin)
+	return nil
+}
+
+// convertAllUploadExtensions converts the 'x-kong-upload' extensions on the operations.
+func convertAllUploadExtensions(doc *openapi3.T) error {
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		for method, operation := range doc.Paths[path].Operations() {
+			if err := convertUploadExtension(&operation.ExtensionProps); err != nil {
+				return fmt.Errorf("failed to create upload settings from operation '%s %s': %w", path, method, err)
+			}
+		}
+	}
+	return nil
+}
+
+// isUpload returns whether the operation has an 'x-kong-upload' extension, validated by
+// convertAllUploadExtensions.
+func isUpload(props openapi3.ExtensionProps) bool {
+	return props.Extensions != nil && props.Extensions[uploadExtension] != nil
+}
+
+// applyUpload disables the request buffering of the route, such that large bodies are
+// streamed to the upstream. The response buffering is left as is.
+func applyUpload(route map[string]interface{}) {
+	route["request_buffering"] = false
+}
@@ -0,0 +1,27 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// getUpstreamRef returns the name of an existing upstream, as set by 'x-kong-upstream', or
+// an empty string if not set. Since 'x-kong-upstream-defaults' generates an upstream, it
+// cannot be combined with 'x-kong-upstream' on the same level.
+func getUpstreamRef(props openapi3.ExtensionProps) (string, error) {
+	if props.Extensions == nil || props.Extensions["x-kong-upstream"] == nil {
+		return "", nil
+	}
+
+	var name string
+	err := json.Unmarshal(props.Extensions["x-kong-upstream"].(json.RawMessage), &name)
+	if err != nil || name == "" {
+		return "", fmt.Errorf("expected 'x-kong-upstream' to be a non-empty string")
+	}
+	if props.Extensions["x-kong-upstream-defaults"] != nil {
+		return "", fmt.Errorf("'x-kong-upstream' and 'x-kong-upstream-defaults' cannot be used together")
+	}
+	return name, nil
+}
@@ -0,0 +1,280 @@
+package openapi2kong
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/jsonbasics"
+	"github.com/kong/go-apiops/logbasics"
+	uuid "github.com/satori/go.uuid"
+)
+
+// uuidRebaser tracks the ids and names replaced by ReplaceUUIDBase, to update the
+// references to them.
+type uuidRebaser struct {
+	namespace uuid.UUID
+	oldBase   string
+	newBase   string
+	ids       map[string]string // old id to new id
+	names     map[string]string // old name to new name
+	idNames   map[string]string // old id to old name
+}
+
+// rebase replaces the old base prefix with the new one.
+func (r *uuidRebaser) rebase(value string) string {
+	return r.newBase + strings.TrimPrefix(value, r.oldBase)
+}
+
+// reseedID replaces the id of the entity, if it was derived from 'seed' with the old base.
+// Returns whether it was replaced.
+func (r *uuidRebaser) reseedID(entity map[string]interface{}, seed string) bool {
+	id, _ := jsonbasics.GetStringField(entity, "id")
+	if !strings.HasPrefix(seed, r.oldBase) || uuid.NewV5(r.namespace, seed).String() != id {
+		return false
+	}
+	newID := uuid.NewV5(r.namespace, r.rebase(seed)).String()
+	r.ids[id] = newID
+	entity["id"] = newID
+	return true
+}
+
+// reseedNamed replaces the id and name of an entity, whose id is derived from its name
+// and 'suffix' (eg. ".service"). Entities that do not match are left alone with a warning.
+func (r *uuidRebaser) reseedNamed(entityType string, entity map[string]interface{}, suffix string) {
+	name, _ := jsonbasics.GetStringField(entity, "name")
+	id, _ := jsonbasics.GetStringField(entity, "id")
+	if id == "" {
+		return // no id to reseed
+	}
+	r.idNames[id] = name
+	if !r.reseedID(entity, name+suffix) {
+		logbasics.Warn("id does not match the uuid-base, leaving it alone", "type", entityType, "name", name)
+		return
+	}
+	entity["name"] = r.rebase(name)
+	r.names[name] = r.rebase(name)
+}
+
+// pluginOwners returns the names a plugin id can be derived from, given the names of
+// the entities it belongs to. Plugins defined on a path level are derived from the path
+// name, which is a prefix of the route names (eg. "doc_path" for route "doc_path_get").
+func pluginOwners(names []string) []string {
+	owners := make([]string, 0, len(names))
+	for _, name := range names {
+		owners = append(owners, name)
+		for i := strings.LastIndex(name, "_"); i > 0; i = strings.LastIndex(name[:i], "_") {
+			owners = append(owners, name[:i])
+		}
+	}
+	return owners
+}
+
+// reseedPlugin replaces the id of a plugin, derived from the name of one of the 'owners',
+// and the plugin name (and instance). Plugins that do not match are left alone with a warning.
+func (r *uuidRebaser) reseedPlugin(plugin map[string]interface{}, owners []string, count int) {
+	name, _ := jsonbasics.GetStringField(plugin, "name")
+	if id, _ := jsonbasics.GetStringField(plugin, "id"); id == "" {
+		return // no id to reseed
+	}
+	for _, owner := range pluginOwners(owners) {
+		for instance := 0; instance < count; instance++ {
+			seed := owner + ".plugin." + name
+			if instance > 0 {
+				seed = seed + "." + strconv.Itoa(instance)
+			}
+			if r.reseedID(plugin, seed) {
+				return
+			}
+		}
+	}
+	logbasics.Warn("id does not match the uuid-base, leaving it alone", "type", "plugins", "name", name)
+}
+
+// reseedNestedPlugins reseeds the plugins nested in an entity, owned by 'owner'.
+func (r *uuidRebaser) reseedNestedPlugins(parent map[string]interface{}, owner string, path string) error {
+	plugins, err := jsonbasics.GetObjectArrayField(parent, "plugins")
+	if err != nil {
+		return fmt.Errorf("failed to read '%splugins'; %w", path, err)
+	}
+	for _, plugin := range plugins {
+		r.reseedPlugin(plugin, []string{owner}, len(plugins))
+	}
+	return nil
+}
+
+// reseedRoutes reseeds the routes, and their nested plugins.
+func (r *uuidRebaser) reseedRoutes(parent map[string]interface{}, path string) error {
+	routes, err := jsonbasics.GetObjectArrayField(parent, "routes")
+	if err != nil {
+		return fmt.Errorf("failed to read '%sroutes'; %w", path, err)
+	}
+	for i, route := range routes {
+		name, _ := jsonbasics.GetStringField(route, "name")
+		r.reseedNamed("routes", route, ".route")
+		if err := r.reseedNestedPlugins(route, name, fmt.Sprintf("%sroutes[%d].", path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateReference replaces a reference to a reseeded entity. References are either a
+// string (name or id), or an object with a 'name' or 'id'.
+func (r *uuidRebaser) updateReference(entity map[string]interface{}, field string) {
+	switch ref := entity[field].(type) {
+	case string:
+		if r.ids[ref] != "" {
+			entity[field] = r.ids[ref]
+		} else if r.names[ref] != "" {
+			entity[field] = r.names[ref]
+		}
+	case map[string]interface{}:
+		if id, ok := ref["id"].(string); ok && r.ids[id] != "" {
+			ref["id"] = r.ids[id]
+		}
+		if name, ok := ref["name"].(string); ok && r.names[name] != "" {
+			ref["name"] = r.names[name]
+		}
+	}
+}
+
+// ReplaceUUIDBase re-seeds, in place, the ids of a deck file generated by openapi2kong from
+// 'oldBase' to 'newBase' (the resolved uuid-base, see O2kInfo.DocName), as if the file was
+// generated with the new uuid-base. The names derived from the base are replaced as well,
+// and the references to the entities (route to service, plugin to service/route, service
+// to upstream, sni to certificate) are updated. Entities whose id does not match the
+// expected derivation are left alone with a warning. The top-level plugins are re-sorted
+// by name and id. If 'uuidNamespace' is empty, the default namespace is used.
+// Returns deckformat.ErrNilDocument if data is nil.
+func ReplaceUUIDBase(data map[string]interface{}, oldBase string, newBase string, uuidNamespace uuid.UUID) error {
+	if data == nil {
+		return deckformat.ErrNilDocument
+	}
+	if oldBase == "" || newBase == "" {
+		return errors.New("expected both the old and new uuid-base to be non-empty")
+	}
+	var emptyUUID uuid.UUID
+	if uuid.Equal(emptyUUID, uuidNamespace) {
+		uuidNamespace = uuid.NamespaceDNS
+	}
+	r := &uuidRebaser{
+		namespace: uuidNamespace,
+		oldBase:   oldBase,
+		newBase:   newBase,
+		ids:       make(map[string]string),
+		names:     make(map[string]string),
+		idNames:   make(map[string]string),
+	}
+
+	services, err := jsonbasics.GetObjectArrayField(data, "services")
+	if err != nil {
+		return fmt.Errorf("failed to read 'services'; %w", err)
+	}
+	for i, service := range services {
+		name, _ := jsonbasics.GetStringField(service, "name")
+		path := fmt.Sprintf("services[%d].", i)
+		r.reseedNamed("services", service, ".service")
+		if err := r.reseedNestedPlugins(service, name, path); err != nil {
+			return err
+		}
+		if err := r.reseedRoutes(service, path); err != nil {
+			return err
+		}
+	}
+	if err := r.reseedRoutes(data, ""); err != nil {
+		return err
+	}
+
+	upstreams, err := jsonbasics.GetObjectArrayField(data, "upstreams")
+	if err != nil {
+		return fmt.Errorf("failed to read 'upstreams'; %w", err)
+	}
+	for _, upstream := range upstreams {
+		r.reseedNamed("upstreams", upstream, "")
+	}
+
+	certificates, err := jsonbasics.GetObjectArrayField(data, "certificates")
+	if err != nil {
+		return fmt.Errorf("failed to read 'certificates'; %w", err)
+	}
+	for _, certificate := range certificates {
+		if !r.reseedID(certificate, oldBase+".certificate") {
+			logbasics.Warn("id does not match the uuid-base, leaving it alone", "type", "certificates")
+		}
+	}
+
+	snis, err := jsonbasics.GetObjectArrayField(data, "snis")
+	if err != nil {
+		return fmt.Errorf("failed to read 'snis'; %w", err)
+	}
+	for _, sni := range snis {
+		name, _ := jsonbasics.GetStringField(sni, "name")
+		if !r.reseedID(sni, oldBase+".sni."+name) {
+			logbasics.Warn("id does not match the uuid-base, leaving it alone", "type", "snis", "name", name)
+		}
+		if certificate, ok := sni["certificate"].(map[string]interface{}); ok {
+			// the certificate is a placeholder, likely not in the file
+			r.reseedID(certificate, oldBase+".certificate")
+		}
+	}
+
+	// top-level plugins are owned by the document, or by the entity they refer to
+	plugins, err := jsonbasics.GetObjectArrayField(data, "plugins")
+	if err != nil {
+		return fmt.Errorf("failed to read 'plugins'; %w", err)
+	}
+	for _, plugin := range plugins {
+		owners := []string{oldBase}
+		for _, field := range []string{"service", "route", "consumer"} {
+			if ref := getReferenceName(plugin, field, r.idNames); ref != "" {
+				owners = append(owners, ref)
+			}
+		}
+		r.reseedPlugin(plugin, owners, len(plugins))
+	}
+	if len(plugins) > 0 {
+		// keep the order of a conversion; sorted by plugin name + id
+		sort.SliceStable(plugins, func(i, j int) bool {
+			name1, _ := jsonbasics.GetStringField(plugins[i], "name")
+			id1, _ := jsonbasics.GetStringField(plugins[i], "id")
+			name2, _ := jsonbasics.GetStringField(plugins[j], "name")
+			id2, _ := jsonbasics.GetStringField(plugins[j], "id")
+			return name1+id1 < name2+id2
+		})
+		jsonbasics.SetObjectArrayField(data, "plugins", plugins)
+	}
+
+	// update the references to the reseeded entities
+	return deckformat.WalkEntities(data, func(entityType string, entity map[string]interface{}) error {
+		for _, field := range []string{"service", "route", "consumer"} {
+			r.updateReference(entity, field)
+		}
+		if entityType == "services" {
+			r.updateReference(entity, "host") // the upstream name
+		}
+		return nil
+	})
+}
+
+// getReferenceName returns the (old) name of the entity referred to by a foreign key field,
+// which is either a string, or an object with a 'name' or 'id'. Returns "" if not set.
+func getReferenceName(entity map[string]interface{}, field string, idNames map[string]string) string {
+	var ref string
+	switch value := entity[field].(type) {
+	case string:
+		ref = value
+	case map[string]interface{}:
+		if name, ok := value["name"].(string); ok {
+			return name
+		}
+		ref, _ = value["id"].(string)
+	}
+	if idNames[ref] != "" {
+		return idNames[ref]
+	}
+	return ref
+}
@@ -0,0 +1,120 @@
+package openapi2kong
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/jsonbasics"
+	"github.com/kong/go-apiops/refresolver"
+)
+
+// toJSONPointer returns the JSONPointer for a path from jsonbasics.Walk, where array indices
+// are formatted as "[index]".
+func toJSONPointer(path []string) string {
+	pointer := ""
+	for _, segment := range path {
+		if strings.HasPrefix(segment, "[") && strings.HasSuffix(segment, "]") {
+			segment = segment[1 : len(segment)-1]
+		} else {
+			segment = strings.ReplaceAll(strings.ReplaceAll(segment, "~", "~0"), "/", "~1")
+		}
+		pointer = pointer + "/" + segment
+	}
+	return pointer
+}
+
+// relativeName returns the location of an external document relative to the directory of
+// the spec, for messages.
+func relativeName(specLocation string, location string) string {
+	if name, err := filepath.Rel(filepath.Dir(specLocation), location); err == nil {
+		return filepath.ToSlash(name)
+	}
+	return location
+}
+
+// collectRefs returns the '$ref' values in the document, by the JSONPointer of the object
+// holding them.
+func collectRefs(doc interface{}) map[string]string {
+	refs := make(map[string]string)
+	_ = jsonbasics.Walk(doc, func(path []string, value interface{}) error {
+		if object, ok := value.(map[string]interface{}); ok {
+			if ref, ok := object["$ref"].(string); ok {
+				refs[toJSONPointer(path)] = ref
+			}
+		}
+		return nil
+	})
+	return refs
+}
+
+// validateRefs checks that all the '$ref's in the spec resolve; the internal ones, and the
+// ones to external files (relative to the spec 'location'), including the '$ref's in those
+// files. Remote references (urls) are only checked to be allowed (see refresolver.Options),
+// they are not fetched. Returns an error listing every
+// unresolvable reference, with the JSONPointer of its location, or nil if all resolve.
+// Content that cannot be parsed is left for the OAS parser to report.
+func validateRefs(content *[]byte, location string, resolver *refresolver.Resolver) error {
+	spec, err := filebasics.Deserialize(content)
+	if err != nil {
+		return nil
+	}
+
+	type document struct {
+		location string
+		name     string // for messages, the spec itself has no name
+		data     interface{}
+	}
+	queue := []document{{location: location, data: spec}}
+	visited := map[string]bool{location: true}
+	problems := make([]string, 0)
+
+	for len(queue) > 0 {
+		doc := queue[0]
+		queue = queue[1:]
+
+		refs := collectRefs(doc.data)
+		pointers := make([]string, 0, len(refs))
+		for pointer := range refs {
+			pointers = append(pointers, pointer)
+		}
+		sort.Strings(pointers)
+
+		for _, pointer := range pointers {
+			ref := refs[pointer]
+			refLocation, fragment, err := refresolver.Location(ref, doc.location)
+			if err == nil && refresolver.IsRemote(refLocation) {
+				if err = resolver.Allowed(refLocation); err == nil {
+					continue
+				}
+			}
+
+			target := doc.data
+			if err == nil && refLocation != doc.location {
+				var loaded map[string]interface{}
+				if refLocation == location {
+					loaded = spec
+				} else if loaded, err = resolver.Load(refLocation); err == nil && !visited[refLocation] {
+					visited[refLocation] = true
+					queue = append(queue, document{location: refLocation, name: relativeName(location, refLocation),
+						data: loaded})
+				}
+				target = loaded
+			}
+			if err == nil {
+				_, err = refresolver.ResolvePointer(target, fragment)
+			}
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("'%s' at '%s#%s/$ref'; %s", ref, doc.name, pointer,
+					err.Error()))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d unresolvable references: %s", len(problems), strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -29,9 +29,17 @@ func getDefaultParamStyle(givenStyle string, paramType string) string {
 	return givenStyle
 }
 
+// validatorParamLocations are the parameter locations supported by the request-validator plugin
+var validatorParamLocations = map[string]bool{
+	"header": true,
+	"path":   true,
+	"query":  true,
+}
+
 // generateParameterSchema returns the given schema if there is one, a generated
 // schema if it was specified, or nil if there is none.
-// Parameters include path, query, and headers
+// Parameters include path, query, and headers. Cookie parameters are not supported by
+// the plugin, they are skipped with a warning.
 func generateParameterSchema(operation *openapi3.Operation) *[]map[string]interface{} {
 	parameters := operation.Parameters
 	if parameters == nil {
@@ -54,7 +62,11 @@ func generateParameterSchema(operation *openapi3.Operation) *[]map[string]interf
 			explode = *paramValue.Explode
 		}
 
-		if paramValue != nil {
+		if paramValue != nil && !validatorParamLocations[paramValue.In] {
+			logbasics.Warn("the request-validator plugin does not support parameters in '"+paramValue.In+
+				"', skipping it", "parameter", paramValue.Name)
+		} else if paramValue != nil {
+			warnMixedEnumTypes(paramValue)
 			paramConf := make(map[string]interface{})
 			paramConf["explode"] = explode
 			paramConf["in"] = paramValue.In
@@ -76,11 +88,16 @@ func generateParameterSchema(operation *openapi3.Operation) *[]map[string]interf
 		}
 	}
 
+	if i == 0 {
+		return nil
+	}
+	result = result[:i]
 	return &result
 }
 
 // generateBodySchema returns the given schema if there is one, a generated
-// schema if it was specified, or "" if there is none.
+// schema if it was specified, or "" if there is none. The schema only validates a body
+// if present, see requiredSchema for bodies that are 'required'.
 func generateBodySchema(operation *openapi3.Operation) string {
 	requestBody := operation.RequestBody
 	if requestBody == nil {
@@ -144,6 +161,7 @@ func generateContentTypes(operation *openapi3.Operation) *[]string {
 func generateValidatorPlugin(configJSON []byte, operation *openapi3.Operation,
 	uuidNamespace uuid.UUID,
 	baseName string,
+	strict bool, // inject 'additionalProperties: false' in the generated body schema
 ) *map[string]interface{} {
 	if len(configJSON) == 0 {
 		return nil
@@ -172,6 +190,15 @@ func generateValidatorPlugin(configJSON []byte, operation *openapi3.Operation,
 
 	if config["body_schema"] == nil {
 		bodySchema := generateBodySchema(operation)
+		if bodySchema != "" {
+			bodySchema = requestSchema(bodySchema)
+		}
+		if bodySchema != "" && strict {
+			bodySchema = strictSchema(bodySchema)
+		}
+		if bodySchema != "" && operation.RequestBody.Value.Required {
+			bodySchema = requiredSchema(bodySchema)
+		}
 		if bodySchema != "" {
 			config["body_schema"] = bodySchema
 			config["version"] = JSONSchemaVersion
@@ -0,0 +1,55 @@
+package openapi2kong
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	uuid "github.com/satori/go.uuid"
+)
+
+const vaultsExtension = "x-kong-vaults"
+
+// createKongVaults returns the vault entities declared by the 'x-kong-vaults' extension, or
+// nil if it is not set. Every entry requires a 'name' (the vault backend, eg. "env") and a
+// 'prefix' (used in references, eg. "{vault://my-env/secret}"), other fields (eg. 'config')
+// are copied as is. The ids are generated from the prefix, which must be unique.
+func createKongVaults(props openapi3.ExtensionProps, uuidNamespace uuid.UUID, baseName string,
+	tags []string,
+) ([]interface{}, error) {
+	if props.Extensions == nil || props.Extensions[vaultsExtension] == nil {
+		return nil, nil
+	}
+
+	var entries []map[string]interface{}
+	err := json.Unmarshal(props.Extensions[vaultsExtension].(json.RawMessage), &entries)
+	if err != nil {
+		return nil, fmt.Errorf("expected '%s' to be an array of objects; %w", vaultsExtension, err)
+	}
+
+	vaults := make([]interface{}, 0, len(entries))
+	prefixes := make(map[string]bool)
+	for i, vault := range entries {
+		for _, field := range []string{"name", "prefix"} {
+			if value, ok := vault[field].(string); !ok || value == "" {
+				return nil, fmt.Errorf("expected '%s[%d].%s' to be a non-empty string", vaultsExtension, i, field)
+			}
+		}
+		if _, ok := vault["config"].(map[string]interface{}); vault["config"] != nil && !ok {
+			return nil, fmt.Errorf("expected '%s[%d].config' to be an object", vaultsExtension, i)
+		}
+
+		prefix := vault["prefix"].(string)
+		if prefixes[prefix] {
+			return nil, fmt.Errorf("duplicate prefix '%s' in '%s'", prefix, vaultsExtension)
+		}
+		prefixes[prefix] = true
+
+		vault["id"] = uuid.NewV5(uuidNamespace, baseName+".vault."+prefix).String()
+		if vault["tags"] == nil {
+			vault["tags"] = tags
+		}
+		vaults = append(vaults, vault)
+	}
+	return vaults, nil
+}
@@ -25,11 +25,11 @@ func (patchFile *DeckPatchFile) ParseFile(filename string) error {
 		return err
 	}
 
-	if data[deckformat.VersionKey] != nil {
-		logbasics.Debug("parsed patch file", "file", filename, "version", data[deckformat.VersionKey])
+	if data[deckformat.ConfigGet().VersionKey] != nil {
+		logbasics.Debug("parsed patch file", "file", filename, "version", data[deckformat.ConfigGet().VersionKey])
 		patchFile.VersionMajor, patchFile.VersionMinor, err = deckformat.ParseFormatVersion(data)
 		if err != nil {
-			return fmt.Errorf("%s: has an invalid "+deckformat.VersionKey+" specified; %w", filename, err)
+			return fmt.Errorf("%s: has an invalid "+deckformat.ConfigGet().VersionKey+" specified; %w", filename, err)
 		}
 	} else {
 		logbasics.Debug("parsed unversioned patch-file", "file", filename)
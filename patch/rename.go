@@ -0,0 +1,116 @@
+package patch
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kong/go-apiops/deckformat"
+)
+
+// renameReference is a field referring to an entity by name. An empty EntityType means the
+// field is checked on all entity types.
+type renameReference struct {
+	EntityType string
+	Field      string
+}
+
+// renameReferences are the fields referring to the entities that can be renamed, by the
+// entity type they refer to.
+var renameReferences = map[string][]renameReference{
+	"services":        {{"", "service"}},
+	"routes":          {{"", "route"}},
+	"consumers":       {{"", "consumer"}},
+	"consumer_groups": {{"", "consumer_group"}},
+	"upstreams":       {{"services", "host"}, {"targets", "upstream"}},
+}
+
+// getNameField returns the field holding the name of an entity type; "username" for
+// consumers, "name" for others.
+func getNameField(entityType string) string {
+	if entityType == "consumers" {
+		return "username"
+	}
+	return "name"
+}
+
+// renameReferenceValue renames a reference, which is either a name, or an object with a
+// 'name' or 'username'. Returns true if it was renamed.
+func renameReferenceValue(entity map[string]interface{}, field string, oldName string, newName string) bool {
+	switch ref := entity[field].(type) {
+	case string:
+		if ref == oldName {
+			entity[field] = newName
+			return true
+		}
+	case map[string]interface{}:
+		for _, key := range []string{"name", "username"} {
+			if ref[key] == oldName {
+				ref[key] = newName
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RenameEntity renames the entity of the given type (eg. "services") in the deck file, and
+// updates the references to it by name (eg. the 'service' of routes and plugins, or the
+// 'host' of services and the 'upstream' of targets for an upstream). The file is modified
+// in place. Returns the number of updated references. Returns an error if the entity is not
+// found, or if an entity with the new name already exists.
+func RenameEntity(filedata map[string]interface{}, entityType string, oldName string, newName string,
+) (int, error) {
+	if filedata == nil {
+		return 0, deckformat.ErrNilDocument
+	}
+	references, ok := renameReferences[entityType]
+	if !ok {
+		types := make([]string, 0, len(renameReferences))
+		for t := range renameReferences {
+			types = append(types, "'"+t+"'")
+		}
+		sort.Strings(types)
+		return 0, fmt.Errorf("cannot rename '%s' entities, expected one of %s", entityType, strings.Join(types, ", "))
+	}
+	if oldName == "" || newName == "" {
+		return 0, errors.New("expected the old and new names to be non-empty")
+	}
+	nameField := getNameField(entityType)
+
+	targets := make([]map[string]interface{}, 0)
+	err := deckformat.WalkEntities(filedata, func(t string, entity map[string]interface{}) error {
+		if t != entityType {
+			return nil
+		}
+		switch entity[nameField] {
+		case oldName:
+			targets = append(targets, entity)
+		case newName:
+			return fmt.Errorf("cannot rename '%s' entity '%s' to '%s', an entity with that name already exists",
+				entityType, oldName, newName)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(targets) == 0 {
+		return 0, fmt.Errorf("'%s' entity '%s' not found", entityType, oldName)
+	}
+	for _, target := range targets {
+		target[nameField] = newName
+	}
+
+	count := 0
+	err = deckformat.WalkEntities(filedata, func(t string, entity map[string]interface{}) error {
+		for _, ref := range references {
+			if (ref.EntityType == "" || ref.EntityType == t) && renameReferenceValue(entity, ref.Field, oldName, newName) {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
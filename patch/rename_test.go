@@ -0,0 +1,81 @@
+package patch_test
+
+import (
+	. "github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/patch"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RenameEntity", func() {
+	deck := []byte(`{
+		"services": [
+			{ "name": "svc1", "host": "backend" },
+			{ "name": "svc2" }
+		],
+		"routes": [
+			{ "name": "route1", "service": "svc1" },
+			{ "name": "route2", "service": { "name": "svc1" } },
+			{ "name": "route3", "service": "svc2" }
+		],
+		"plugins": [
+			{ "name": "cors", "service": "svc1", "route": "route1" }
+		],
+		"upstreams": [ { "name": "backend" } ],
+		"targets": [ { "target": "10.0.0.1:80", "upstream": "backend" } ]
+	}`)
+
+	It("renames a service referenced by two routes", func() {
+		data := MustDeserialize(&deck)
+		count, err := patch.RenameEntity(data, "services", "svc1", "svc-renamed")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(count).To(Equal(3))
+
+		expected := []byte(`{
+			"services": [
+				{ "name": "svc-renamed", "host": "backend" },
+				{ "name": "svc2" }
+			],
+			"routes": [
+				{ "name": "route1", "service": "svc-renamed" },
+				{ "name": "route2", "service": { "name": "svc-renamed" } },
+				{ "name": "route3", "service": "svc2" }
+			],
+			"plugins": [
+				{ "name": "cors", "service": "svc-renamed", "route": "route1" }
+			],
+			"upstreams": [ { "name": "backend" } ],
+			"targets": [ { "target": "10.0.0.1:80", "upstream": "backend" } ]
+		}`)
+		Expect(*MustSerialize(data, OutputFormatJSON)).To(MatchJSON(expected))
+	})
+
+	It("renames an upstream, the host of the services, and the upstream of the targets", func() {
+		data := MustDeserialize(&deck)
+		count, err := patch.RenameEntity(data, "upstreams", "backend", "backend-v2")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(count).To(Equal(2))
+		Expect(data["services"].([]interface{})[0]).To(HaveKeyWithValue("host", "backend-v2"))
+		Expect(data["targets"].([]interface{})[0]).To(HaveKeyWithValue("upstream", "backend-v2"))
+	})
+
+	It("fails on a name collision", func() {
+		data := MustDeserialize(&deck)
+		_, err := patch.RenameEntity(data, "services", "svc1", "svc2")
+		Expect(err).To(MatchError("cannot rename 'services' entity 'svc1' to 'svc2', " +
+			"an entity with that name already exists"))
+	})
+
+	It("fails if the entity is not found", func() {
+		data := MustDeserialize(&deck)
+		_, err := patch.RenameEntity(data, "routes", "missing", "other")
+		Expect(err).To(MatchError("'routes' entity 'missing' not found"))
+	})
+
+	It("fails on an entity type that cannot be renamed", func() {
+		data := MustDeserialize(&deck)
+		_, err := patch.RenameEntity(data, "snis", "a", "b")
+		Expect(err).To(MatchError("cannot rename 'snis' entities, expected one of 'consumer_groups', " +
+			"'consumers', 'routes', 'services', 'upstreams'"))
+	})
+})
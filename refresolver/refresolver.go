@@ -0,0 +1,285 @@
+package refresolver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/logbasics"
+)
+
+// ErrNotAllowed is wrapped by the errors for documents that the Options of the Resolver do
+// not allow to be loaded.
+var ErrNotAllowed = errors.New("external reference not allowed")
+
+// DefaultTimeout is the time allowed for fetching a remote document, if Options.Timeout is
+// not set.
+const DefaultTimeout = 30 * time.Second
+
+// Options restricts the documents a Resolver loads. Documents added with AddDocument are
+// always available.
+type Options struct {
+	AllowFiles  bool            // load local files
+	AllowRemote bool            // fetch remote documents (http and https urls)
+	BaseDir     string          // if set, local files must be within this directory
+	Context     context.Context // if set, cancelling it aborts the fetching of remote documents
+	Timeout     time.Duration   // the time allowed for fetching a remote document, see DefaultTimeout
+}
+
+// Resolver loads (and caches) documents, and resolves JSON references between them.
+// Documents are identified by their location; the absolute filename for local files, or
+// the url for remote files (http and https).
+type Resolver struct {
+	opts      Options                           // the documents allowed to be loaded
+	client    *http.Client                      // fetches the remote documents
+	docs      map[string]map[string]interface{} // loaded documents, by location
+	resolving map[string]bool                   // references being resolved, to detect cycles
+}
+
+// New returns a new Resolver, that loads any local file or url. For input that is not
+// trusted, use NewWithOptions to restrict that.
+func New() *Resolver {
+	return NewWithOptions(Options{AllowFiles: true, AllowRemote: true})
+}
+
+// NewWithOptions returns a new Resolver, that only loads the documents allowed by the options.
+func NewWithOptions(opts Options) *Resolver {
+	if opts.Context == nil {
+		opts.Context = context.Background()
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Resolver{
+		opts:      opts,
+		client:    &http.Client{Timeout: timeout},
+		docs:      make(map[string]map[string]interface{}),
+		resolving: make(map[string]bool),
+	}
+}
+
+// IsRemote returns true if the location is a url
+func IsRemote(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// SplitRef splits a reference in its document and fragment parts.
+func SplitRef(ref string) (string, string) {
+	parts := strings.SplitN(ref, "#", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// ResolvePointer returns the element in 'doc' referenced by the JSONpointer 'fragment'.
+func ResolvePointer(doc interface{}, fragment string) (interface{}, error) {
+	if fragment == "" {
+		return doc, nil
+	}
+	if !strings.HasPrefix(fragment, "/") {
+		return nil, fmt.Errorf("expected JSONpointer '%s' to start with '/'", fragment)
+	}
+
+	target := doc
+	for _, segment := range strings.Split(fragment[1:], "/") {
+		segment = strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+		switch t := target.(type) {
+		case map[string]interface{}:
+			target = t[segment]
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(t) {
+				return nil, fmt.Errorf("JSONpointer '%s' not found", fragment)
+			}
+			target = t[index]
+		default:
+			target = nil
+		}
+		if target == nil {
+			return nil, fmt.Errorf("JSONpointer '%s' not found", fragment)
+		}
+	}
+	return target, nil
+}
+
+// Location returns the location of the document, and the fragment, referenced by 'ref'.
+// Relative references are resolved against the location of the 'source' document. A
+// reference without a document part refers to the 'source' document itself.
+func Location(ref string, source string) (string, string, error) {
+	refDoc, fragment := SplitRef(ref)
+	switch {
+	case refDoc == "":
+		return source, fragment, nil
+
+	case IsRemote(refDoc):
+		return refDoc, fragment, nil
+
+	case IsRemote(source):
+		base, err := url.Parse(source)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid url '%s'; %w", source, err)
+		}
+		relative, err := url.Parse(refDoc)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid reference '%s'; %w", ref, err)
+		}
+		return base.ResolveReference(relative).String(), fragment, nil
+
+	case filepath.IsAbs(refDoc):
+		return filepath.Clean(refDoc), fragment, nil
+
+	default:
+		return filepath.Join(filepath.Dir(source), refDoc), fragment, nil
+	}
+}
+
+// AddDocument adds an already loaded document to the cache.
+func (r *Resolver) AddDocument(location string, doc map[string]interface{}) {
+	r.docs[location] = doc
+}
+
+// Allowed returns an error wrapping ErrNotAllowed if the Options do not allow loading the
+// document at the location, or nil if it is allowed (or already loaded).
+func (r *Resolver) Allowed(location string) error {
+	if _, found := r.docs[location]; found {
+		return nil
+	}
+	if IsRemote(location) {
+		if !r.opts.AllowRemote {
+			return fmt.Errorf("%w: remote document '%s'", ErrNotAllowed, location)
+		}
+		return nil
+	}
+	if !r.opts.AllowFiles {
+		return fmt.Errorf("%w: local file '%s'", ErrNotAllowed, location)
+	}
+	if r.opts.BaseDir != "" && !isWithin(location, r.opts.BaseDir) {
+		return fmt.Errorf("%w: local file '%s' is outside of '%s'", ErrNotAllowed, location, r.opts.BaseDir)
+	}
+	return nil
+}
+
+// realPath returns the absolute filename with symlinks resolved, or as clean as possible if
+// it does not exist (yet).
+func realPath(filename string) string {
+	if abs, err := filepath.Abs(filename); err == nil {
+		filename = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(filename); err == nil {
+		return resolved
+	}
+	return filepath.Clean(filename)
+}
+
+// isWithin returns true if the file is the directory, or in it (or in a sub directory).
+func isWithin(filename string, dir string) bool {
+	rel, err := filepath.Rel(realPath(dir), realPath(filename))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// Load returns the (cached) document at the location. Returns an error wrapping
+// ErrNotAllowed if the Options do not allow loading it (see Allowed).
+func (r *Resolver) Load(location string) (map[string]interface{}, error) {
+	if doc, found := r.docs[location]; found {
+		return doc, nil
+	}
+	if err := r.Allowed(location); err != nil {
+		return nil, err
+	}
+
+	var (
+		doc map[string]interface{}
+		err error
+	)
+	if IsRemote(location) {
+		logbasics.Debug("loading remote document", "url", location)
+		doc, err = r.loadRemote(location)
+	} else {
+		logbasics.Debug("loading external document", "filename", location)
+		doc, err = filebasics.DeserializeFile(location)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.docs[location] = doc
+	return doc, nil
+}
+
+// LoadJSON returns the (cached) document at the location, serialized as JSON.
+func (r *Resolver) LoadJSON(location string) ([]byte, error) {
+	doc, err := r.Load(location)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// loadRemote fetches and parses a remote document.
+func (r *Resolver) loadRemote(location string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(r.opts.Context, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch '%s'; %w", location, err)
+	}
+	resp, err := r.client.Do(req) // only if allowed by Options.AllowRemote
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch '%s'; status %d", location, resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch '%s'; %w", location, err)
+	}
+	return filebasics.Deserialize(&body)
+}
+
+// Resolve returns the element referenced by 'ref', and the location of the document it
+// belongs to. Relative references are resolved against the location of the 'source' document.
+func (r *Resolver) Resolve(ref string, source string) (interface{}, string, error) {
+	location, fragment, err := Location(ref, source)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve reference '%s' in '%s'; %w", ref, source, err)
+	}
+	doc, err := r.Load(location)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load reference '%s' in '%s'; %w", ref, source, err)
+	}
+	target, err := ResolvePointer(doc, fragment)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve reference '%s' in '%s'; %w", ref, source, err)
+	}
+	return target, location, nil
+}
+
+// Enter marks the reference (by its location and fragment) as being resolved. Returns an
+// error if it already was, since that is a circular reference. Every successful call
+// must be matched by a call to Leave.
+func (r *Resolver) Enter(location string, fragment string) error {
+	key := location + "#" + fragment
+	if r.resolving[key] {
+		return fmt.Errorf("circular reference '%s'", key)
+	}
+	r.resolving[key] = true
+	return nil
+}
+
+// Leave marks the reference as no longer being resolved. See Enter.
+func (r *Resolver) Leave(location string, fragment string) {
+	delete(r.resolving, location+"#"+fragment)
+}
@@ -0,0 +1,13 @@
+package refresolver_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestRefresolver(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Refresolver Suite")
+}
@@ -0,0 +1,181 @@
+package refresolver_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"time"
+
+	"github.com/kong/go-apiops/refresolver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("refresolver", func() {
+	var (
+		resolver *refresolver.Resolver
+		root     string
+		other    string
+	)
+
+	BeforeEach(func() {
+		resolver = refresolver.New()
+		root, _ = filepath.Abs("./refresolver_testfiles/root.yaml")
+		other, _ = filepath.Abs("./refresolver_testfiles/sub/other.yaml")
+	})
+
+	Describe("Location", func() {
+		It("resolves relative file references", func() {
+			location, fragment, err := refresolver.Location("./sub/other.yaml#/definitions/Name", root)
+			Expect(err).To(BeNil())
+			Expect(location).To(Equal(other))
+			Expect(fragment).To(Equal("/definitions/Name"))
+		})
+
+		It("resolves internal references to the source document", func() {
+			location, fragment, err := refresolver.Location("#/components/schemas/Local", root)
+			Expect(err).To(BeNil())
+			Expect(location).To(Equal(root))
+			Expect(fragment).To(Equal("/components/schemas/Local"))
+		})
+
+		It("resolves relative references against a url", func() {
+			location, fragment, err := refresolver.Location("../other.yaml#/a",
+				"https://example.com/specs/v1/root.yaml")
+			Expect(err).To(BeNil())
+			Expect(location).To(Equal("https://example.com/specs/other.yaml"))
+			Expect(fragment).To(Equal("/a"))
+		})
+	})
+
+	Describe("Resolve", func() {
+		It("resolves internal references", func() {
+			target, location, err := resolver.Resolve("#/components/schemas/Local", root)
+			Expect(err).To(BeNil())
+			Expect(location).To(Equal(root))
+			Expect(target).To(BeEquivalentTo(map[string]interface{}{"type": "string"}))
+		})
+
+		It("resolves external references", func() {
+			target, location, err := resolver.Resolve("./sub/other.yaml#/definitions/Name", root)
+			Expect(err).To(BeNil())
+			Expect(location).To(Equal(other))
+			Expect(target).To(BeEquivalentTo(map[string]interface{}{
+				"type":      "string",
+				"maxLength": float64(64),
+			}))
+		})
+
+		It("resolves array indices", func() {
+			target, _, err := resolver.Resolve("#/definitions/List/1", other)
+			Expect(err).To(BeNil())
+			Expect(target).To(Equal("second"))
+		})
+
+		It("uses documents added to the cache", func() {
+			resolver.AddDocument("/not/on/disk.yaml", map[string]interface{}{"key": "value"})
+			target, _, err := resolver.Resolve("#/key", "/not/on/disk.yaml")
+			Expect(err).To(BeNil())
+			Expect(target).To(Equal("value"))
+		})
+
+		It("returns an error including the ref and source on unresolvable pointers", func() {
+			_, _, err := resolver.Resolve("./sub/other.yaml#/definitions/Nope", root)
+			Expect(err).To(MatchError("failed to resolve reference './sub/other.yaml#/definitions/Nope' in '" +
+				root + "'; JSONpointer '/definitions/Nope' not found"))
+		})
+
+		It("returns an error including the ref and source on missing documents", func() {
+			_, _, err := resolver.Resolve("./missing.yaml#/a", root)
+			Expect(err).To(MatchError(ContainSubstring(
+				"failed to load reference './missing.yaml#/a' in '" + root + "'")))
+		})
+	})
+
+	Describe("NewWithOptions", func() {
+		It("does not load local files nor urls by default", func() {
+			restricted := refresolver.NewWithOptions(refresolver.Options{})
+			_, _, err := restricted.Resolve("./sub/other.yaml#/definitions/Name", root)
+			Expect(err).To(MatchError(refresolver.ErrNotAllowed))
+			Expect(err).To(MatchError(ContainSubstring("local file '" + other + "'")))
+
+			_, err = restricted.Load("https://example.com/spec.yaml")
+			Expect(err).To(MatchError(refresolver.ErrNotAllowed))
+			Expect(err).To(MatchError(ContainSubstring("remote document 'https://example.com/spec.yaml'")))
+		})
+
+		It("still resolves documents added to the cache", func() {
+			restricted := refresolver.NewWithOptions(refresolver.Options{})
+			restricted.AddDocument(root, map[string]interface{}{"key": "value"})
+			target, _, err := restricted.Resolve("#/key", root)
+			Expect(err).To(BeNil())
+			Expect(target).To(Equal("value"))
+		})
+
+		It("confines local files to the BaseDir", func() {
+			restricted := refresolver.NewWithOptions(refresolver.Options{
+				AllowFiles: true,
+				BaseDir:    filepath.Dir(other),
+			})
+			_, err := restricted.Load(other)
+			Expect(err).To(BeNil())
+
+			_, err = restricted.Load(root)
+			Expect(err).To(MatchError(refresolver.ErrNotAllowed))
+			Expect(err).To(MatchError(ContainSubstring("is outside of")))
+
+			_, _, err = restricted.Resolve("../root.yaml#/components", other)
+			Expect(err).To(MatchError(refresolver.ErrNotAllowed))
+		})
+
+		Context("with a slow server", func() {
+			var (
+				server  *httptest.Server
+				release chan struct{}
+			)
+
+			BeforeEach(func() {
+				release = make(chan struct{})
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					select {
+					case <-release:
+					case <-r.Context().Done():
+					}
+				}))
+			})
+			AfterEach(func() {
+				close(release)
+				server.Close()
+			})
+
+			It("aborts fetching a remote document after the Timeout", func() {
+				remote := refresolver.NewWithOptions(refresolver.Options{
+					AllowRemote: true,
+					Timeout:     50 * time.Millisecond,
+				})
+				_, err := remote.Load(server.URL + "/spec.yaml")
+				Expect(err).To(MatchError(ContainSubstring("Client.Timeout exceeded")))
+			})
+
+			It("aborts fetching a remote document when the Context is cancelled", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				remote := refresolver.NewWithOptions(refresolver.Options{AllowRemote: true, Context: ctx})
+				time.AfterFunc(50*time.Millisecond, cancel)
+				_, err := remote.Load(server.URL + "/spec.yaml")
+				Expect(err).To(MatchError(context.Canceled))
+			})
+		})
+	})
+
+	Describe("Enter/Leave", func() {
+		It("detects cyclic references", func() {
+			Expect(resolver.Enter(root, "/a")).To(Succeed())
+			Expect(resolver.Enter(other, "/b")).To(Succeed())
+			Expect(resolver.Enter(root, "/a")).To(MatchError("circular reference '" + root + "#/a'"))
+
+			resolver.Leave(root, "/a")
+			Expect(resolver.Enter(root, "/a")).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,70 @@
+package validate
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kong/go-apiops/deckformat"
+)
+
+// DefaultFixFormatVersion is the '_format_version' set by Fix, if the file has none.
+const DefaultFixFormatVersion = "3.0"
+
+// FixOptions are the options for Fix.
+type FixOptions struct {
+	// The '_format_version' to set if it is missing, defaults to DefaultFixFormatVersion
+	FormatVersion string
+	// The tags to set on entities without tags, if empty untagged entities are left as is
+	DefaultTags []string
+}
+
+// Fix applies the safe, mechanical corrections to a deck file, in place; a missing
+// '_format_version' is added, null fields of entities are removed, and entities without
+// tags get the DefaultTags. It returns a description of every change made, sorted. Fixing
+// is idempotent; fixing a fixed file returns no changes. Problems that cannot be fixed
+// (eg. a '_format_version' that is not a string) are left for the validation to report.
+func Fix(deckfile map[string]interface{}, opts FixOptions) ([]string, error) {
+	if deckfile == nil {
+		return nil, deckformat.ErrNilDocument
+	}
+	if opts.FormatVersion == "" {
+		opts.FormatVersion = DefaultFixFormatVersion
+	}
+	versionKey := deckformat.ConfigGet().VersionKey
+
+	changes := make([]string, 0)
+	if deckfile[versionKey] == nil {
+		if err := deckformat.SetFormatVersion(deckfile, opts.FormatVersion); err != nil {
+			return nil, err
+		}
+		changes = append(changes, fmt.Sprintf("added '%s: %s'", versionKey, opts.FormatVersion))
+	}
+
+	err := deckformat.WalkEntities(deckfile, func(entityType string, entity map[string]interface{}) error {
+		location := fmt.Sprintf("%s '%s'", entityType, getEntityName(entity))
+
+		for field, value := range entity {
+			if value == nil {
+				delete(entity, field)
+				changes = append(changes, fmt.Sprintf("%s: removed null field '%s'", location, field))
+			}
+		}
+
+		tags, isArray := entity["tags"].([]interface{})
+		if len(opts.DefaultTags) > 0 && (entity["tags"] == nil || (isArray && len(tags) == 0)) {
+			tags = make([]interface{}, 0, len(opts.DefaultTags))
+			for _, tag := range opts.DefaultTags {
+				tags = append(tags, tag)
+			}
+			entity["tags"] = tags
+			changes = append(changes, fmt.Sprintf("%s: added the default tags", location))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(changes)
+	return changes, nil
+}
@@ -0,0 +1,105 @@
+package validate_test
+
+import (
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/internal/testutil"
+	"github.com/kong/go-apiops/validate"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fix", func() {
+	It("adds a missing format version, and reports no changes when run again", func() {
+		deckfile := map[string]interface{}{
+			"services": []interface{}{
+				map[string]interface{}{"name": "my-service"},
+			},
+		}
+
+		changes, err := validate.Fix(deckfile, validate.FixOptions{})
+		Expect(err).To(BeNil())
+		Expect(changes).To(Equal([]string{"added '_format_version: 3.0'"}))
+		Expect(deckfile["_format_version"]).To(Equal("3.0"))
+
+		changes, err = validate.Fix(deckfile, validate.FixOptions{})
+		Expect(err).To(BeNil())
+		Expect(changes).To(BeEmpty())
+	})
+
+	It("is idempotent", func() {
+		fix := func(input []byte) []byte {
+			deckfile := filebasics.MustDeserialize(&input)
+			_, err := validate.Fix(deckfile, validate.FixOptions{DefaultTags: []string{"team-a"}})
+			Expect(err).To(BeNil())
+			return *filebasics.MustSerialize(deckfile, filebasics.OutputFormatJSON)
+		}
+		input := []byte(`
+services:
+  - name: my-service
+    path: null
+    routes:
+      - name: my-route
+        tags: []
+consumers:
+  - username: my-consumer
+    tags: null
+`)
+		Expect(testutil.AssertIdempotent(GinkgoT(), fix, input)).To(BeTrue())
+	})
+
+	It("removes null fields, and tags untagged entities", func() {
+		deckfile := map[string]interface{}{
+			"_format_version": "3.0",
+			"services": []interface{}{
+				map[string]interface{}{
+					"name": "my-service",
+					"path": nil,
+					"tags": []interface{}{"mine"},
+					"routes": []interface{}{
+						map[string]interface{}{"name": "my-route", "tags": []interface{}{}},
+					},
+				},
+			},
+			"consumers": []interface{}{
+				map[string]interface{}{"username": "my-consumer", "tags": nil},
+			},
+		}
+		opts := validate.FixOptions{DefaultTags: []string{"team-a"}}
+
+		changes, err := validate.Fix(deckfile, opts)
+		Expect(err).To(BeNil())
+		Expect(changes).To(Equal([]string{
+			"consumers 'my-consumer': added the default tags",
+			"consumers 'my-consumer': removed null field 'tags'",
+			"routes 'my-route': added the default tags",
+			"services 'my-service': removed null field 'path'",
+		}))
+		Expect(deckfile).To(Equal(map[string]interface{}{
+			"_format_version": "3.0",
+			"services": []interface{}{
+				map[string]interface{}{
+					"name": "my-service",
+					"tags": []interface{}{"mine"},
+					"routes": []interface{}{
+						map[string]interface{}{"name": "my-route", "tags": []interface{}{"team-a"}},
+					},
+				},
+			},
+			"consumers": []interface{}{
+				map[string]interface{}{"username": "my-consumer", "tags": []interface{}{"team-a"}},
+			},
+		}))
+
+		changes, err = validate.Fix(deckfile, opts)
+		Expect(err).To(BeNil())
+		Expect(changes).To(BeEmpty())
+	})
+
+	It("leaves problems it cannot fix", func() {
+		deckfile := map[string]interface{}{"_format_version": 3}
+		changes, err := validate.Fix(deckfile, validate.FixOptions{})
+		Expect(err).To(BeNil())
+		Expect(changes).To(BeEmpty())
+		Expect(deckfile["_format_version"]).To(Equal(3))
+	})
+})
@@ -0,0 +1,129 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/kong/go-apiops/deckformat"
+	"github.com/kong/go-apiops/jsonbasics"
+)
+
+// referenceFields are the foreign key fields checked by References, with the entity type
+// they refer to.
+var referenceFields = []struct {
+	Field      string
+	EntityType string
+}{
+	{"service", "services"},
+	{"route", "routes"},
+	{"consumer", "consumers"},
+	{"consumer_group", "consumer_groups"},
+}
+
+// credentialTypes are the entity types owned by a consumer.
+var credentialTypes = []string{
+	"acls", "basicauth_credentials", "hmacauth_credentials", "jwt_secrets",
+	"keyauth_credentials", "mtls_auth_credentials", "oauth2_credentials",
+}
+
+// getReference returns the value of a foreign key field, which is either a string, or an
+// object with an 'id', 'name', or 'username'. Returns "" if not set.
+func getReference(entity map[string]interface{}, field string) string {
+	switch ref := entity[field].(type) {
+	case string:
+		return ref
+	case map[string]interface{}:
+		for _, key := range []string{"id", "name", "username"} {
+			if value, ok := ref[key].(string); ok && value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// References checks that the references between the entities in a deck file resolve to
+// entities in the file; the 'service' of the top-level routes, the 'service', 'route',
+// 'consumer', and 'consumer_group' of all plugins, and the 'consumer' owning the top-level
+// credentials (eg. 'keyauth_credentials'). References are by name (username for consumers)
+// or id. Returns an error per dangling reference, sorted, or nil if all resolve.
+func References(filedata map[string]interface{}) []error {
+	if filedata == nil {
+		return []error{deckformat.ErrNilDocument}
+	}
+
+	// index the entities that can be referred to, by name and id
+	known := make(map[string]map[string]bool)
+	for _, ref := range referenceFields {
+		known[ref.EntityType] = make(map[string]bool)
+	}
+	messages := make([]string, 0)
+	err := deckformat.WalkEntities(filedata, func(entityType string, entity map[string]interface{}) error {
+		if known[entityType] != nil {
+			for _, key := range []string{"id", "name", "username"} {
+				if value, ok := entity[key].(string); ok && value != "" {
+					known[entityType][value] = true
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return []error{err}
+	}
+
+	// check reports a dangling reference from the entity, if the field is set
+	check := func(entityType string, entity map[string]interface{}, field string, targetType string) {
+		ref := getReference(entity, field)
+		if ref != "" && !known[targetType][ref] {
+			messages = append(messages, fmt.Sprintf("'%s' entity '%s' refers to %s '%s', which is not in the file",
+				entityType, getEntityName(entity), field, ref))
+		}
+	}
+
+	routes, err := jsonbasics.GetObjectArrayField(filedata, "routes")
+	if err != nil {
+		return []error{fmt.Errorf("failed to read 'routes'; %w", err)}
+	}
+	for _, route := range routes {
+		check("routes", route, "service", "services")
+	}
+
+	for _, credentialType := range credentialTypes {
+		credentials, err := jsonbasics.GetObjectArrayField(filedata, credentialType)
+		if err != nil {
+			return []error{fmt.Errorf("failed to read '%s'; %w", credentialType, err)}
+		}
+		for _, credential := range credentials {
+			if getReference(credential, "consumer") == "" {
+				messages = append(messages, fmt.Sprintf("'%s' entity '%s' has no consumer",
+					credentialType, getEntityName(credential)))
+				continue
+			}
+			check(credentialType, credential, "consumer", "consumers")
+		}
+	}
+
+	err = deckformat.WalkEntities(filedata, func(entityType string, entity map[string]interface{}) error {
+		if entityType == "plugins" {
+			for _, ref := range referenceFields {
+				check(entityType, entity, ref.Field, ref.EntityType)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return []error{err}
+	}
+
+	if len(messages) == 0 {
+		return nil
+	}
+	sort.Strings(messages)
+	errs := make([]error, len(messages))
+	for i, message := range messages {
+		errs[i] = errors.New(message)
+	}
+	return errs
+}
@@ -0,0 +1,56 @@
+package validate_test
+
+import (
+	"github.com/kong/go-apiops/filebasics"
+	"github.com/kong/go-apiops/validate"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("References", func() {
+	messages := func(errs []error) []string {
+		result := make([]string, 0, len(errs))
+		for _, err := range errs {
+			result = append(result, err.Error())
+		}
+		return result
+	}
+
+	It("accepts references that resolve", func() {
+		data := []byte(`{
+			"services": [
+				{ "name": "svc1", "id": "svc-id-1", "routes": [ { "name": "route1" } ] }
+			],
+			"routes": [ { "name": "route2", "service": { "id": "svc-id-1" } } ],
+			"consumers": [ { "username": "alice", "keyauth_credentials": [ { "key": "nested" } ] } ],
+			"keyauth_credentials": [ { "key": "top", "consumer": "alice" } ],
+			"plugins": [
+				{ "name": "cors", "service": "svc1" },
+				{ "name": "acl", "route": "route1", "consumer": { "username": "alice" } },
+				{ "name": "prometheus" }
+			]
+		}`)
+		Expect(validate.References(filebasics.MustDeserialize(&data))).To(BeNil())
+	})
+
+	It("reports dangling references", func() {
+		data := []byte(`{
+			"services": [ { "name": "svc1" } ],
+			"routes": [ { "name": "route1", "service": "missing-svc" } ],
+			"keyauth_credentials": [
+				{ "id": "key-id-1", "consumer": "bob" },
+				{ "id": "key-id-2" }
+			],
+			"plugins": [
+				{ "name": "cors", "service": "svc1" },
+				{ "name": "acl", "route": { "name": "missing-route" } }
+			]
+		}`)
+		Expect(messages(validate.References(filebasics.MustDeserialize(&data)))).To(Equal([]string{
+			"'keyauth_credentials' entity 'key-id-1' refers to consumer 'bob', which is not in the file",
+			"'keyauth_credentials' entity 'key-id-2' has no consumer",
+			"'plugins' entity 'acl' refers to route 'missing-route', which is not in the file",
+			"'routes' entity 'route1' refers to service 'missing-svc', which is not in the file",
+		}))
+	})
+})
@@ -0,0 +1,182 @@
+package validate
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kong/go-apiops/deckformat"
+)
+
+// ErrRemovedFields is matched by the error returned when entities use fields that were
+// removed in the target Kong version.
+var ErrRemovedFields = errors.New("fields removed from the target Kong version are used")
+
+// ErrFormatVersion is matched by the error returned when the '_format_version' of a deck
+// file is missing or malformed.
+var ErrFormatVersion = errors.New("the format version is invalid")
+
+// kongSchemaFS holds the known entity fields per Kong major version. The files are named
+// 'kong-<major>.json' and map the entity types to their fields.
+//
+//go:embed kong_schemas/*.json
+var kongSchemaFS embed.FS
+
+// kongSchemas is a cache of the parsed schemas, by major version
+var kongSchemas = make(map[int]map[string][]string)
+
+// SupportedKongVersions returns the Kong versions with a bundled schema, eg. "3.x".
+func SupportedKongVersions() []string {
+	versions := make([]string, 0)
+	for _, major := range getMajorVersions() {
+		versions = append(versions, fmt.Sprintf("%d.x", major))
+	}
+	return versions
+}
+
+// getMajorVersions returns the major versions with a bundled schema, sorted.
+func getMajorVersions() []int {
+	files, err := kongSchemaFS.ReadDir("kong_schemas")
+	if err != nil {
+		panic(fmt.Sprintf("failed reading embedded Kong schemas: %v", err))
+	}
+	majors := make([]int, 0, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(file.Name(), "kong-"), ".json")
+		if major, err := strconv.Atoi(name); err == nil {
+			majors = append(majors, major)
+		}
+	}
+	sort.Ints(majors)
+	return majors
+}
+
+// getKongSchema returns the known fields per entity type for a Kong major version, or
+// nil if there is no schema for it.
+func getKongSchema(major int) map[string][]string {
+	if schema, found := kongSchemas[major]; found {
+		return schema
+	}
+
+	var schema map[string][]string
+	data, err := kongSchemaFS.ReadFile(fmt.Sprintf("kong_schemas/kong-%d.json", major))
+	if err == nil {
+		if err := json.Unmarshal(data, &schema); err != nil {
+			panic(fmt.Sprintf("bad embedded schema for Kong %d.x: %v", major, err))
+		}
+	}
+	kongSchemas[major] = schema
+	return schema
+}
+
+// parseKongVersion returns the major version from a version string like "3", "3.4",
+// or "3.x".
+func parseKongVersion(version string) (int, error) {
+	major, err := strconv.Atoi(strings.Split(version, ".")[0])
+	if err != nil {
+		return 0, fmt.Errorf("expected the Kong version to be in 'x.y' format, got: '%s'", version)
+	}
+	return major, nil
+}
+
+// contains returns true if the list contains the value.
+func contains(list []string, value string) bool {
+	for _, entry := range list {
+		if entry == value {
+			return true
+		}
+	}
+	return false
+}
+
+// getEntityName returns a name identifying an entity, for use in messages.
+func getEntityName(entity map[string]interface{}) string {
+	for _, key := range []string{"name", "username", "id"} {
+		if name, ok := entity[key].(string); ok && name != "" {
+			return name
+		}
+	}
+	return "<unnamed>"
+}
+
+// FormatVersion checks that the deck file has a '_format_version' in 'x.y' format. Returns
+// an error matching ErrFormatVersion otherwise.
+func FormatVersion(deckfile map[string]interface{}) error {
+	if _, _, err := deckformat.ParseFormatVersion(deckfile); err != nil {
+		return fmt.Errorf("%w; %s", ErrFormatVersion, err.Error())
+	}
+	return nil
+}
+
+// Fields checks the fields of the entities in a deck file against the schema of the target
+// Kong version (eg. "3.4"). It returns warnings for fields unknown to that version (they
+// might be added in a later one), and an error matching ErrRemovedFields for fields only
+// known to earlier versions. Entity types without a schema are not checked.
+func Fields(deckfile map[string]interface{}, kongVersion string) ([]string, error) {
+	major, err := parseKongVersion(kongVersion)
+	if err != nil {
+		return nil, err
+	}
+	schema := getKongSchema(major)
+	if schema == nil {
+		return nil, fmt.Errorf("no schema available for Kong version '%s', expected one of: %s",
+			kongVersion, strings.Join(SupportedKongVersions(), ", "))
+	}
+
+	// collect the schemas of the earlier versions, to detect the removed fields
+	earlierSchemas := make([]map[string][]string, 0)
+	for _, earlier := range getMajorVersions() {
+		if earlier < major {
+			earlierSchemas = append(earlierSchemas, getKongSchema(earlier))
+		}
+	}
+
+	warnings := make([]string, 0)
+	removed := &deckformat.MultiError{}
+	err = deckformat.WalkEntities(deckfile, func(entityType string, entity map[string]interface{}) error {
+		knownFields, found := schema[entityType]
+		if !found {
+			return nil
+		}
+
+		fields := make([]string, 0, len(entity))
+		for field := range entity {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		for _, field := range fields {
+			if contains(knownFields, field) || contains(deckformat.EntityRegistry[entityType], field) {
+				continue // known, or nested entities
+			}
+			location := fmt.Sprintf("%s '%s'", entityType, getEntityName(entity))
+
+			wasRemoved := false
+			for _, earlierSchema := range earlierSchemas {
+				if contains(earlierSchema[entityType], field) {
+					wasRemoved = true
+					break
+				}
+			}
+			if wasRemoved {
+				removed.AddAt(location, fmt.Errorf("field '%s' was removed in Kong %d.x", field, major))
+			} else {
+				warnings = append(warnings, fmt.Sprintf("%s: field '%s' is unknown to Kong %d.x",
+					location, field, major))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if removed.Len() > 0 {
+		return warnings, fmt.Errorf("%w; %s", ErrRemovedFields, removed.Error())
+	}
+	return warnings, nil
+}
@@ -0,0 +1,89 @@
+package validate_test
+
+import (
+	"github.com/kong/go-apiops/validate"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("validate", func() {
+	Describe("SupportedKongVersions", func() {
+		It("lists the bundled versions", func() {
+			Expect(validate.SupportedKongVersions()).To(Equal([]string{"2.x", "3.x"}))
+		})
+	})
+
+	Describe("FormatVersion", func() {
+		It("accepts a version in 'x.y' format", func() {
+			Expect(validate.FormatVersion(map[string]interface{}{"_format_version": "3.0"})).To(Succeed())
+		})
+
+		It("errors on a missing or malformed version", func() {
+			err := validate.FormatVersion(map[string]interface{}{})
+			Expect(err).To(MatchError(validate.ErrFormatVersion))
+			Expect(err).To(MatchError(validate.ErrFormatVersion.Error() +
+				"; expected field '._format_version' to be a string in 'x.y' format"))
+			Expect(validate.FormatVersion(map[string]interface{}{"_format_version": "three"})).
+				To(MatchError(validate.ErrFormatVersion))
+		})
+	})
+
+	Describe("Fields", func() {
+		deckfile := func() map[string]interface{} {
+			return map[string]interface{}{
+				"_format_version": "3.0",
+				"services": []interface{}{
+					map[string]interface{}{
+						"name": "my-service",
+						"url":  "https://example.com",
+						"routes": []interface{}{
+							map[string]interface{}{
+								"name":     "my-route",
+								"paths":    []interface{}{"/"},
+								"priority": 10,
+							},
+						},
+					},
+				},
+			}
+		}
+
+		It("accepts a valid file", func() {
+			warnings, err := validate.Fields(deckfile(), "3.4")
+			Expect(err).To(BeNil())
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("warns on fields unknown to the target version", func() {
+			warnings, err := validate.Fields(deckfile(), "2.8")
+			Expect(err).To(BeNil())
+			Expect(warnings).To(Equal([]string{
+				"routes 'my-route': field 'priority' is unknown to Kong 2.x",
+			}))
+		})
+
+		It("errors on fields removed in the target version", func() {
+			data := deckfile()
+			data["plugins"] = []interface{}{
+				map[string]interface{}{
+					"name":   "cors",
+					"run_on": "first",
+				},
+			}
+			_, err := validate.Fields(data, "3.0")
+			Expect(err).To(MatchError(validate.ErrRemovedFields))
+			Expect(err).To(MatchError(validate.ErrRemovedFields.Error() +
+				"; plugins 'cors': field 'run_on' was removed in Kong 3.x"))
+
+			_, err = validate.Fields(data, "2.8")
+			Expect(err).To(BeNil())
+		})
+
+		It("errors on unsupported versions", func() {
+			_, err := validate.Fields(deckfile(), "1.5")
+			Expect(err).To(MatchError("no schema available for Kong version '1.5', expected one of: 2.x, 3.x"))
+			_, err = validate.Fields(deckfile(), "latest")
+			Expect(err).To(MatchError("expected the Kong version to be in 'x.y' format, got: 'latest'"))
+		})
+	})
+})